@@ -11,7 +11,9 @@ import (
 
 	"github.com/GhostDrew11/vigor-api/internal/api"
 	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/controllers"
 	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
 	"github.com/GhostDrew11/vigor-api/internal/services"
 	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"github.com/gin-contrib/cors"
@@ -45,41 +47,123 @@ func main() {
 	if err := dbService.ConnectDB(ctx, cfg); err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v\n", err)
 	}
-	defer dbService.DisconnectDB(ctx)
+	defer func() {
+		// Runs at exit, long after the startup context expired
+		disconnectCtx, cancelDisconnect := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelDisconnect()
+		dbService.DisconnectDB(disconnectCtx)
+	}()
 
 	// Initialize database 
 	database := dbService.Client.Database(cfg.DatabaseName)
 	
 	// Create required services
 	handler := &utils.DefaultJWTHandler{}
-	hasher := &utils.DefaultHasher{}
+	hasher := utils.NewDefaultHasher(cfg.PasswordHashAlgorithm, utils.Argon2Params{
+		Memory:      cfg.Argon2MemoryKiB,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+	}, cfg.BcryptCost)
 	parser := &utils.DefaultParser{}
 	jwtService := utils.NewJWTService(cfg.JWTSecretKey, handler)
-	adminService := services.NewAdminService(database, hasher, parser)
-	userService := services.NewUserService(database, hasher, parser)
+	adminService := services.NewAdminService(database, hasher, parser, cfg)
+	userService := services.NewUserService(database, hasher, parser, cfg)
+
+	// Passwords chosen at registration are checked against the length range and the breached passwords list
+	passwordBlocklist := utils.DefaultPasswordBlocklist()
+	if cfg.PasswordBlocklistFile != "" {
+		localBlocklist, err := utils.LoadPasswordBlocklist(cfg.PasswordBlocklistFile)
+		if err != nil {
+			log.Fatalf("Failed to load password blocklist: %v\n", err)
+		}
+		passwordBlocklist = append(passwordBlocklist, localBlocklist...)
+	}
+	controllers.SetPasswordPolicy(utils.NewPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordMaxLength, passwordBlocklist))
+
+	// Subscriptions bought in the app stores are verified with the stores
+	if cfg.AppStoreBundleID != "" {
+		roots, err := utils.LoadCertPool(cfg.AppStoreRootCertsFile)
+		if err != nil {
+			log.Fatalf("Failed to load App Store root certificates: %v\n", err)
+		}
+		userService.SetAppStoreVerifier(utils.NewAppStoreVerifier(cfg.AppStoreBundleID, cfg.AppStoreEnvironment, roots))
+	}
+	if cfg.PlayPackageName != "" {
+		account, err := utils.LoadPlayServiceAccount(cfg.PlayServiceAccountFile)
+		if err != nil {
+			log.Fatalf("Failed to load Play service account: %v\n", err)
+		}
+		playVerifier, err := utils.NewPlayVerifier(cfg.PlayPackageName, cfg.PlayAPIURL, account, nil)
+		if err != nil {
+			log.Fatalf("Failed to set up Play purchases: %v\n", err)
+		}
+		userService.SetPlayVerifier(playVerifier)
+	}
+
+	// Seed the built-in admin roles and their permissions
+	if err := adminService.EnsureDefaultRoles(ctx); err != nil {
+		log.Fatalf("Failed to seed admin roles: %v\n", err)
+	}
+
+	// Rate limit buckets are kept in memory unless several instances need to share them
+	var rateLimitStore services.RateLimitStore = services.NewInMemoryRateLimitStore()
+	if cfg.RateLimitStore == "mongo" {
+		rateLimitStore = services.NewMongoRateLimitStore(database)
+	}
+
+	// Time-driven lifecycle tasks, every instance runs the scheduler and the jobs are locked per run in MongoDB
+	scheduler := services.NewScheduler(database)
+	jobs := []struct {
+		name     string
+		schedule string
+		timeout  time.Duration
+		run      services.JobFunc
+	}{
+		{"trial-end", "*/15 * * * *", 10 * time.Minute, userService.ExpireEndedTrials},
+		{"subscription-expiry", "*/15 * * * *", 10 * time.Minute, userService.ExpireEndedSubscriptions},
+		{"renewal-reminders", "0 * * * *", 30 * time.Minute, userService.SendRenewalReminders},
+		{"unpaid-gift-cleanup", "30 3 * * *", 10 * time.Minute, userService.DeleteUnpaidGifts},
+		{"expired-invitation-cleanup", "45 3 * * *", 10 * time.Minute, adminService.DeleteExpiredInvitations},
+	}
+	for _, job := range jobs {
+		if err := scheduler.Register(job.name, job.schedule, job.timeout, job.run); err != nil {
+			log.Fatalf("Failed to register job %s: %v\n", job.name, err)
+		}
+	}
+	if cfg.SchedulerEnabled {
+		scheduler.Start()
+	}
 
 	// Set up your Gin router
 	router := gin.Default()
 
+	// Rate limits and audit logs key on the client IP, X-Forwarded-For is only read from the configured proxies
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v\n", err)
+	}
+
 	// Use Logger and Recovery middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// Tag requests with an ID and the client IP, admin changes are audited with them
+	router.Use(middlewares.RequestMetadata())
+
 	// Set up CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:    []string{"*"}, //Allow all origins for the moment to be adjusted once the frontend is deployed
 		AllowMethods:    []string{"GET", "POST", "PUT", "DELETE"},
 		AllowHeaders:    []string{"Origin", "Content-length", "Content-Type", "Authorization", "RefreshToken", "Accept", "Accept-Encoding", "User-Agent", "Host", "Connection",
-		"Postman-Token", // Included Postman-Token to allow testing with Postman, remove in production,
+		"X-Request-ID", "X-Device-Name", "X-API-Key", "Postman-Token", // Included Postman-Token to allow testing with Postman, remove in production,
 		},
-		ExposeHeaders:   []string{"Content-Length"},
+		ExposeHeaders:   []string{"Content-Length", "X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
 		AllowCredentials: true,
 		AllowWildcard: true,
 		MaxAge: 12 * time.Hour,
 	}))
 
 	// Set up your routes
-	api.SetupRoutes(router, jwtService, *userService, *adminService)
+	api.SetupRoutes(router, jwtService, *userService, *adminService, rateLimitStore)
 
 	server := &http.Server{
 		Addr:    ":8080",
@@ -100,9 +184,20 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
-	// Doesn't block if no connections, but will wait for the duration of the context deadline
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v\n", err)
+	// Doesn't block if no connections, but will wait for the duration of the context deadline. The startup context
+	// expired long ago, the requests in flight get their own deadline
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// The scheduler is stopped all the same
+		log.Printf("Server forced to shutdown: %v\n", err)
+	}
+
+	// Running jobs get their own deadline to finish, they are cancelled after it
+	schedulerCtx, cancelScheduler := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelScheduler()
+	if err := scheduler.Stop(schedulerCtx); err != nil {
+		log.Printf("Scheduler forced to stop: %v\n", err)
 	}
 
 	log.Println("Server exiting")
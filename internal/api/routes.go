@@ -1,77 +1,184 @@
 package api
 
 import (
+	"time"
+
 	"github.com/GhostDrew11/vigor-api/internal/controllers"
 	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/models"
 	"github.com/GhostDrew11/vigor-api/internal/services"
 	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, ts utils.TokenService, userService services.UserService, adminService services.AdminService) {
+func SetupRoutes(router *gin.Engine, ts utils.TokenService, userService services.UserService, adminService services.AdminService, rateLimitStore services.RateLimitStore) {
 	// API root
 	apiRoot := router.Group("/api/v1")
 	adminController := controllers.NewAdminController(adminService, ts)
 	userController := controllers.NewUserController(userService, ts)
 
+	// Rate limits per route group
+	loginRateLimit := middlewares.RateLimit(rateLimitStore, middlewares.RateLimitConfig{
+		Name:       "login",
+		PerIP:      services.RateLimitRule{Limit: 20, Window: time.Minute},
+		PerAccount: &services.RateLimitRule{Limit: 5, Window: time.Minute},
+		Lockout: &services.LockoutPolicy{
+			Threshold:     5,
+			BaseLockout:   time.Minute,
+			MaxLockout:    time.Hour,
+			FailureWindow: 15 * time.Minute,
+		},
+	})
+	authRateLimit := middlewares.RateLimit(rateLimitStore, middlewares.RateLimitConfig{
+		Name:  "auth",
+		PerIP: services.RateLimitRule{Limit: 30, Window: time.Minute},
+	})
+	adminRateLimit := middlewares.RateLimit(rateLimitStore, middlewares.RateLimitConfig{
+		Name:  "admin",
+		PerIP: services.RateLimitRule{Limit: 300, Window: time.Minute},
+	})
+	userRateLimit := middlewares.RateLimit(rateLimitStore, middlewares.RateLimitConfig{
+		Name:  "user",
+		PerIP: services.RateLimitRule{Limit: 120, Window: time.Minute},
+	})
+	catalogRateLimit := middlewares.RateLimit(rateLimitStore, middlewares.RateLimitConfig{
+		Name:  "catalog",
+		PerIP: services.RateLimitRule{Limit: 120, Window: time.Minute},
+	})
+	// Slows down guessing promo codes
+	promoCodeRateLimit := middlewares.RateLimit(rateLimitStore, middlewares.RateLimitConfig{
+		Name:  "promo-code",
+		PerIP: services.RateLimitRule{Limit: 10, Window: time.Minute},
+	})
+
 	// Auth routes
 	authRoutes := apiRoot.Group("/auth")
+	authRoutes.Use(authRateLimit)
 	authRoutes.POST("/admin/register", adminController.Register) 
-    authRoutes.POST("/admin/login", adminController.Login)       
+    authRoutes.POST("/admin/login", loginRateLimit, adminController.Login)       
     authRoutes.POST("/user/register", userController.Register)   
-    authRoutes.POST("/user/login", userController.Login)        
-	authRoutes.POST("/refreshAccessToken", middlewares.RefreshAccessTokenHandler(ts))
-	authRoutes.POST("/renewRefreshToken", middlewares.RenewRefreshTokenHandler(ts))
+    authRoutes.POST("/user/login", loginRateLimit, userController.Login)        
+	// Sign in with an OpenID Connect provider, new identities finish with the registration step
+	authRoutes.GET("/user/oidc/:provider/authorize", userController.BeginOIDCLogin)
+	authRoutes.POST("/user/oidc/:provider/callback", userController.CompleteOIDCLogin)
+	authRoutes.POST("/user/oidc/register", userController.CompleteOIDCRegistration)
+	// MFA login steps, failed codes share the login lockout of the account
+	// No role filter for admins since roles live in the database, the admin services only look the account up in the admins collection
+	adminMFAChallenge := middlewares.RequireChallengeToken(ts, utils.TokenUseMFAChallenge)
+	adminMFAEnrollment := middlewares.RequireChallengeToken(ts, utils.TokenUseMFAEnrollment)
+	userMFAChallenge := middlewares.RequireChallengeToken(ts, utils.TokenUseMFAChallenge, "user")
+	authRoutes.POST("/admin/login/mfa", adminMFAChallenge, loginRateLimit, adminController.VerifyMFALogin)
+	authRoutes.POST("/admin/login/mfa/enroll", adminMFAEnrollment, adminController.BeginMFAEnrollment)
+	authRoutes.POST("/admin/login/mfa/confirm", adminMFAEnrollment, loginRateLimit, adminController.ConfirmMFAEnrollment)
+	authRoutes.POST("/user/login/mfa", userMFAChallenge, loginRateLimit, userController.VerifyMFALogin)
+	authRoutes.POST("/refreshAccessToken", middlewares.RefreshAccessTokenHandler(ts, &userService))
+	authRoutes.POST("/renewRefreshToken", middlewares.RenewRefreshTokenHandler(ts, &userService))
 
 	// Admin routes
 	adminRoutes := apiRoot.Group("/admin")
-	adminRoutes.Use(middlewares.RequireRole(ts, "admin"))
+	adminRoutes.Use(adminRateLimit, middlewares.Authenticate(ts), middlewares.RequireActiveAdmin(&adminService))
+	// Each route requires the permissions it needs, resolved from the admin role
+	can := func(permissions ...string) gin.HandlerFunc {
+		return middlewares.RequirePermission(&adminService, permissions...)
+	}
+	// MFA
+	adminRoutes.POST("/mfa/enroll", adminController.BeginMFAEnrollment)
+	adminRoutes.POST("/mfa/confirm", adminController.ConfirmMFAEnrollment)
+	adminRoutes.POST("/mfa/disable", adminController.DisableMFA)
+	adminRoutes.POST("/mfa/recovery-codes", adminController.RegenerateRecoveryCodes)
 	// // CRUD Exercises
-	adminRoutes.POST("/exercises", adminController.CreateExercise)
-	adminRoutes.POST("/exercises/bulk-insert", adminController.CreateMultipleExercises)
+	adminRoutes.POST("/exercises", can(models.PermExercisesWrite), adminController.CreateExercise)
+	adminRoutes.POST("/exercises/bulk-insert", can(models.PermExercisesWrite), adminController.CreateMultipleExercises)
 	// TODO: Implement sort, filter, and pagination for exercises
-	adminRoutes.GET("/exercises", adminController.GetExercises)
-	adminRoutes.GET("/exercises/:id", adminController.GetExerciseByID)
-	adminRoutes.GET("/exercises/search", adminController.SearchExercisesByName)
-	adminRoutes.PUT("/exercises/:id", adminController.UpdateExercise)
-	adminRoutes.DELETE("/exercises/:id", adminController.DeleteExercise)
+	adminRoutes.GET("/exercises", can(models.PermExercisesRead), adminController.GetExercises)
+	adminRoutes.GET("/exercises/:id", can(models.PermExercisesRead), adminController.GetExerciseByID)
+	adminRoutes.GET("/exercises/search", can(models.PermExercisesRead), adminController.SearchExercisesByName)
+	adminRoutes.PUT("/exercises/:id", can(models.PermExercisesWrite), adminController.UpdateExercise)
+	adminRoutes.DELETE("/exercises/:id", can(models.PermExercisesWrite), adminController.DeleteExercise)
 	// CRUD Workout Plans
-	adminRoutes.POST("/workout-plans", adminController.CreateWorkoutPlan)
-	adminRoutes.GET("/workout-plans/:id", adminController.GetWorkoutPlanByID)
-	adminRoutes.GET("/workout-plans", adminController.GetWorkoutPlans)
-	adminRoutes.GET("/workout-plans/search", adminController.SearchWorkoutPlansByName)
-	adminRoutes.PUT("/workout-plans/:id", adminController.UpdateWorkoutPlan)
-	adminRoutes.DELETE("/workout-plans/:id", adminController.DeleteWorkoutPlan)
+	adminRoutes.POST("/workout-plans", can(models.PermWorkoutPlansWrite), adminController.CreateWorkoutPlan)
+	adminRoutes.GET("/workout-plans/:id", can(models.PermWorkoutPlansRead), adminController.GetWorkoutPlanByID)
+	adminRoutes.GET("/workout-plans", can(models.PermWorkoutPlansRead), adminController.GetWorkoutPlans)
+	adminRoutes.GET("/workout-plans/search", can(models.PermWorkoutPlansRead), adminController.SearchWorkoutPlansByName)
+	adminRoutes.PUT("/workout-plans/:id", can(models.PermWorkoutPlansWrite), adminController.UpdateWorkoutPlan)
+	adminRoutes.GET("/workout-plans/:id/draft", can(models.PermWorkoutPlansRead), adminController.GetWorkoutPlanDraft)
+	adminRoutes.PUT("/workout-plans/:id/status", can(models.PermWorkoutPlansWrite), adminController.TransitionWorkoutPlan)
+	adminRoutes.GET("/workout-plans/:id/versions", can(models.PermWorkoutPlansRead), adminController.GetWorkoutPlanVersions)
+	adminRoutes.GET("/workout-plans/:id/versions/:version", can(models.PermWorkoutPlansRead), adminController.GetWorkoutPlanVersion)
+	adminRoutes.POST("/workout-plans/:id/migrations", can(models.PermWorkoutPlansWrite), adminController.MigrateWorkoutPlanEnrollments)
+	adminRoutes.POST("/workout-plans/:id/clone", can(models.PermWorkoutPlansWrite), adminController.CloneWorkoutPlan)
+	adminRoutes.DELETE("/workout-plans/:id", can(models.PermWorkoutPlansWrite), adminController.DeleteWorkoutPlan)
+	// Workout week and day templates
+	adminRoutes.POST("/workout-templates", can(models.PermWorkoutPlansWrite), adminController.CreateWorkoutTemplate)
+	adminRoutes.GET("/workout-templates", can(models.PermWorkoutPlansRead), adminController.GetWorkoutTemplates)
+	adminRoutes.GET("/workout-templates/:id", can(models.PermWorkoutPlansRead), adminController.GetWorkoutTemplateByID)
+	adminRoutes.PUT("/workout-templates/:id", can(models.PermWorkoutPlansWrite), adminController.UpdateWorkoutTemplate)
+	adminRoutes.DELETE("/workout-templates/:id", can(models.PermWorkoutPlansWrite), adminController.DeleteWorkoutTemplate)
 	// CRUD Meals
-	adminRoutes.POST("/meals", adminController.CreateMeal)
-	adminRoutes.POST("/meals/bulk-insert", adminController.CreateMultipleMeals)
-	adminRoutes.GET("/meals/:id", adminController.GetMealByID)
-	adminRoutes.GET("/meals", adminController.GetMeals)
-	adminRoutes.GET("/meals/search", adminController.SearchMealsByName)
-	adminRoutes.PUT("/meals/:id", adminController.UpdateMeal)
-	adminRoutes.DELETE("/meals/:id", adminController.DeleteMeal)
+	adminRoutes.POST("/meals", can(models.PermMealsWrite), adminController.CreateMeal)
+	adminRoutes.POST("/meals/bulk-insert", can(models.PermMealsWrite), adminController.CreateMultipleMeals)
+	adminRoutes.GET("/meals/:id", can(models.PermMealsRead), adminController.GetMealByID)
+	adminRoutes.GET("/meals", can(models.PermMealsRead), adminController.GetMeals)
+	adminRoutes.GET("/meals/search", can(models.PermMealsRead), adminController.SearchMealsByName)
+	adminRoutes.PUT("/meals/:id", can(models.PermMealsWrite), adminController.UpdateMeal)
+	adminRoutes.DELETE("/meals/:id", can(models.PermMealsWrite), adminController.DeleteMeal)
 	// CRUD Meal Plans
-	adminRoutes.POST("/meal-plans", adminController.CreateMealPlan)
-	adminRoutes.GET("/meal-plans/:id", adminController.GetMealPlanByID)
-	adminRoutes.GET("/meal-plans", adminController.GetMealPlans)
-	adminRoutes.GET("/meal-plans/search", adminController.SearchMealPlansByName)
-	adminRoutes.PUT("/meal-plans/:id", adminController.UpdateMealPlan)
-	adminRoutes.DELETE("/meal-plans/:id", adminController.DeleteMealPlan)
+	adminRoutes.POST("/meal-plans", can(models.PermMealPlansWrite), adminController.CreateMealPlan)
+	adminRoutes.GET("/meal-plans/:id", can(models.PermMealPlansRead), adminController.GetMealPlanByID)
+	adminRoutes.GET("/meal-plans", can(models.PermMealPlansRead), adminController.GetMealPlans)
+	adminRoutes.GET("/meal-plans/search", can(models.PermMealPlansRead), adminController.SearchMealPlansByName)
+	adminRoutes.PUT("/meal-plans/:id", can(models.PermMealPlansWrite), adminController.UpdateMealPlan)
+	adminRoutes.GET("/meal-plans/:id/draft", can(models.PermMealPlansRead), adminController.GetMealPlanDraft)
+	adminRoutes.PUT("/meal-plans/:id/status", can(models.PermMealPlansWrite), adminController.TransitionMealPlan)
+	adminRoutes.POST("/meal-plans/:id/clone", can(models.PermMealPlansWrite), adminController.CloneMealPlan)
+	adminRoutes.DELETE("/meal-plans/:id", can(models.PermMealPlansWrite), adminController.DeleteMealPlan)
 	// CRUD Admins Users
-	adminRoutes.GET("/users", adminController.GetUsers)
-	// other admin routes as needed(eg list users with active subscriptions, list users with pending subscriptions, list of sales, other analytics etc.)
+	adminRoutes.GET("/users", can(models.PermUsersRead), adminController.GetUsers)
+	adminRoutes.GET("/users/:id", can(models.PermUsersRead), adminController.GetUserDetail)
+	adminRoutes.POST("/users/:id/suspension", can(models.PermUsersWrite), adminController.SuspendUser)
+	adminRoutes.POST("/users/:id/ban", can(models.PermUsersWrite), adminController.BanUser)
+	adminRoutes.DELETE("/users/:id/restriction", can(models.PermUsersWrite), adminController.LiftUserRestriction)
+	adminRoutes.POST("/users/:id/password-reset", can(models.PermUsersWrite), adminController.ResetUserPassword)
+	adminRoutes.POST("/users/:id/impersonate", can(models.PermUsersImpersonate), adminController.ImpersonateUser)
+	adminRoutes.PUT("/users/:id/subscription", can(models.PermSubscriptionsManage), adminController.TransitionUserSubscription)
+	adminRoutes.PUT("/users/:id/subscription/override", can(models.PermSubscriptionsManage), adminController.OverrideUserSubscription)
+	// Analytics, each report takes from and to days, day, week or month intervals where it has periods, and format=csv to export it
+	adminRoutes.GET("/analytics/subscriptions", can(models.PermAnalyticsRead), adminController.GetSubscriptionAnalytics)
+	adminRoutes.GET("/analytics/signups", can(models.PermAnalyticsRead), adminController.GetSignupAnalytics)
+	adminRoutes.GET("/analytics/revenue", can(models.PermAnalyticsRead), adminController.GetRevenueAnalytics)
+	adminRoutes.GET("/analytics/churn", can(models.PermAnalyticsRead), adminController.GetChurnAnalytics)
+	adminRoutes.GET("/analytics/trial-conversion", can(models.PermAnalyticsRead), adminController.GetTrialConversionAnalytics)
+	adminRoutes.GET("/analytics/workout-plans", can(models.PermAnalyticsRead), adminController.GetWorkoutPlanEngagement)
+	adminRoutes.GET("/analytics/workout-plans/:id", can(models.PermAnalyticsRead), adminController.GetWorkoutPlanFunnel)
+	adminRoutes.GET("/analytics/cohorts", can(models.PermAnalyticsRead), adminController.GetCohortRetention)
 	
 	// User routes
 	userRoutes := apiRoot.Group("/user")
-	userRoutes.Use(middlewares.RequireRole(ts, "user"))
+	// Impersonation tokens of support are read-only by default and every request made with them is recorded
+	userRoutes.Use(userRateLimit, middlewares.RequireRole(ts, &userService, "user"), middlewares.GuardImpersonation(&userService, userRoutes.BasePath()+"/workout-plans/daily-exercises"))
 	// CRUD User data
+	userRoutes.GET("/me", userController.GetCurrentUser)
 	userRoutes.GET("/profile", userController.GetUserProfile)
 	userRoutes.PUT("/profile", userController.UpdateUserProfile)
 	userRoutes.GET("/preferences", userController.GetUserPreferences)
 	userRoutes.PUT("/preferences", userController.UpdateUserSystemPreferences)
 	userRoutes.GET("/subscription", userController.GetUserSubsctiption)
-	userRoutes.PUT("/subscription", userController.UpdateUserSubscription)
+	userRoutes.PUT("/subscription", userController.RequestSubscriptionChange)
 	userRoutes.PUT("/subscription/cancel", userController.CancelUserSubscription)
+	userRoutes.POST("/subscription/app-store", userController.VerifyAppStorePurchase)
+	userRoutes.POST("/subscription/play", userController.VerifyPlayPurchase)
+	userRoutes.POST("/subscription/redeem", promoCodeRateLimit, userController.RedeemPromoCode)
+	userRoutes.POST("/gifts", userController.PurchaseGift)
+	userRoutes.GET("/gifts", userController.GetPurchasedGifts)
+	userRoutes.GET("/invoices", userController.GetInvoices)
+	userRoutes.GET("/invoices/:id/pdf", userController.DownloadInvoicePDF)
+	userRoutes.POST("/mfa/enroll", userController.BeginMFAEnrollment)
+	userRoutes.POST("/mfa/confirm", userController.ConfirmMFAEnrollment)
+	userRoutes.POST("/mfa/disable", userController.DisableMFA)
+	userRoutes.POST("/mfa/recovery-codes", userController.RegenerateRecoveryCodes)
+	// Devices the user is signed in on
+	userRoutes.GET("/sessions", userController.GetSessions)
+	userRoutes.DELETE("/sessions/:id", userController.RevokeSession)
 	// userRoutes.DELETE("/account", deleteUserAccount)
 	// // other user routes as needed(eg list user workout plans, list user meal plans, list user progress, other analytics etc.)
 
@@ -89,7 +196,7 @@ func SetupRoutes(router *gin.Engine, ts utils.TokenService, userService services
 	// userRoutes.POST("/workout-plans/:workoutPlanId/progress", createWorkoutPlanProgress)
 	// userRoutes.GET("/workout-plans/:workoutPlanId/progress", getWorkoutPlanProgress)
 	// userRoutes.PUT("/workout-plans/:workoutPlanId/progress", updateWorkoutPlanProgress)
-	// // Meal Plan
+	// // Meal Plan, handlers check the accessTier of the plan and its meals with UserService.CheckEntitlement
 	// userRoutes.POST("/meal-plans/:mealPlanId/progress", createMealPlanProgress)
 	// userRoutes.GET("/meal-plans/:mealPlanId/progress", getMealPlanProgress)
 	// userRoutes.PUT("/meal-plans/:mealPlanId/progress", updateMealPlanProgress)
@@ -142,9 +249,58 @@ func SetupRoutes(router *gin.Engine, ts utils.TokenService, userService services
 	// userRoutes.DELETE("/groups/:groupId/members/:userId", removeGroupMember)
 	// Other group functionalities as needed (e.g, add member, join a group, having a group live workout party etc.)
 
-	// // CRUD Super Admins
-	// adminRoutes.POST("/admins", createAdmin)
-	// adminRoutes.PUT("/admins/:id", updateAdmin)
-	// adminRoutes.DELETE("/admins/:id", deleteAdmin)
-	// adminRoutes.GET("/admins", getAdmins)	
+	// Admin management, admins are onboarded through invitations
+	adminRoutes.POST("/invitations", can(models.PermAdminsManage), adminController.CreateAdminInvitation)
+	adminRoutes.GET("/invitations", can(models.PermAdminsManage), adminController.GetAdminInvitations)
+	adminRoutes.DELETE("/invitations/:id", can(models.PermAdminsManage), adminController.RevokeAdminInvitation)
+	adminRoutes.GET("/admins", can(models.PermAdminsManage), adminController.GetAdmins)
+	adminRoutes.PUT("/admins/:id", can(models.PermAdminsManage), adminController.UpdateAdmin)
+	adminRoutes.PUT("/admins/:id/disable", can(models.PermAdminsManage), adminController.DisableAdmin)
+	adminRoutes.PUT("/admins/:id/enable", can(models.PermAdminsManage), adminController.EnableAdmin)
+	adminRoutes.DELETE("/admins/:id", can(models.PermAdminsManage), adminController.DeleteAdmin)
+	// Roles and their permissions
+	adminRoutes.GET("/roles", can(models.PermRolesManage), adminController.GetRoles)
+	adminRoutes.POST("/roles", can(models.PermRolesManage), adminController.CreateRole)
+	adminRoutes.PUT("/roles/:name", can(models.PermRolesManage), adminController.UpdateRole)
+	adminRoutes.DELETE("/roles/:name", can(models.PermRolesManage), adminController.DeleteRole)
+	// Audit trail of every change made by admins
+	adminRoutes.GET("/audit-logs", can(models.PermAuditRead), adminController.GetAuditLogs)
+	// History of the background jobs
+	adminRoutes.GET("/job-runs", can(models.PermAuditRead), adminController.GetJobRuns)
+	// API keys of partner and internal integrations
+	adminRoutes.GET("/api-keys", can(models.PermAPIKeysManage), adminController.GetAPIKeys)
+	adminRoutes.POST("/api-keys", can(models.PermAPIKeysManage), adminController.CreateAPIKey)
+	adminRoutes.DELETE("/api-keys/:id", can(models.PermAPIKeysManage), adminController.RevokeAPIKey)
+	// Promo and gift codes
+	adminRoutes.GET("/promo-codes", can(models.PermPromotionsManage), adminController.GetPromoCodes)
+	adminRoutes.POST("/promo-codes", can(models.PermPromotionsManage), adminController.CreatePromoCode)
+	adminRoutes.DELETE("/promo-codes/:id", can(models.PermPromotionsManage), adminController.DisablePromoCode)
+	adminRoutes.GET("/promo-codes/:id/stats", can(models.PermPromotionsManage), adminController.GetPromoCodeStats)
+	// Invoices for the finance team
+	adminRoutes.GET("/invoices/export", can(models.PermInvoicesRead), adminController.ExportInvoices)
+
+	// Catalog routes, read by users and by integrations with an API key of the matching scope
+	catalogRoutes := apiRoot.Group("/catalog")
+	catalogRoutes.Use(catalogRateLimit, middlewares.RequireAPIKeyOrRole(ts, &userService, &adminService, "user"), middlewares.GuardImpersonation(&userService))
+	scope := middlewares.RequireScope
+	// Users only get the meals and meal plans their subscription covers
+	entitled := middlewares.LoadAccessTier(&userService)
+	catalogRoutes.GET("/exercises", scope(models.PermExercisesRead), adminController.GetExercises)
+	catalogRoutes.GET("/exercises/:id", scope(models.PermExercisesRead), adminController.GetExerciseByID)
+	catalogRoutes.GET("/exercises/search", scope(models.PermExercisesRead), adminController.SearchExercisesByName)
+	catalogRoutes.GET("/workout-plans", scope(models.PermWorkoutPlansRead), adminController.GetPublishedWorkoutPlans)
+	catalogRoutes.GET("/workout-plans/:id", scope(models.PermWorkoutPlansRead), adminController.GetPublishedWorkoutPlanByID)
+	catalogRoutes.GET("/workout-plans/search", scope(models.PermWorkoutPlansRead), adminController.SearchPublishedWorkoutPlansByName)
+	catalogRoutes.GET("/meals", scope(models.PermMealsRead), entitled, adminController.GetMeals)
+	catalogRoutes.GET("/meals/:id", scope(models.PermMealsRead), entitled, adminController.GetMealByID)
+	catalogRoutes.GET("/meals/search", scope(models.PermMealsRead), entitled, adminController.SearchMealsByName)
+	catalogRoutes.GET("/meal-plans", scope(models.PermMealPlansRead), entitled, adminController.GetPublishedMealPlans)
+	catalogRoutes.GET("/meal-plans/:id", scope(models.PermMealPlansRead), entitled, adminController.GetPublishedMealPlanByID)
+	catalogRoutes.GET("/meal-plans/search", scope(models.PermMealPlansRead), entitled, adminController.SearchPublishedMealPlansByName)
+
+	// Webhook routes, called by the payment provider and authenticated by their signature
+	webhookRoutes := apiRoot.Group("/webhooks")
+	webhookRoutes.POST("/payments", userController.HandlePaymentWebhook)
+	webhookRoutes.POST("/app-store", userController.HandleAppStoreNotification)
+	webhookRoutes.POST("/play", userController.HandlePlayNotification)
 }
@@ -2,7 +2,12 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"github.com/spf13/viper"
 )
 
@@ -10,12 +15,51 @@ var (
 	ErrMissingDBURI     = errors.New("missing VIGOR_DB_URI")
 	ErrMissingDBNAME    = errors.New("missing VIGOR_DB_NAME")
 	ErrMissingSecretKey = errors.New("missing JWT_SECRET_KEY")
+	ErrInvalidHashAlgorithm = errors.New("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt")
+	ErrInvalidPasswordLength = errors.New("PASSWORD_MIN_LENGTH must be at least 1 and not above PASSWORD_MAX_LENGTH")
+	ErrIncompleteOIDCProvider = errors.New("OIDC provider needs an issuer, a client ID and a redirect URI")
+	ErrInvalidTrialDays = errors.New("TRIAL_DAYS must be between 1 and 365")
+	ErrInvalidWebhookTolerance = errors.New("PAYMENT_WEBHOOK_TOLERANCE_SECONDS must be at least 1")
+	ErrIncompleteStoreConfig = errors.New("APPSTORE_BUNDLE_ID needs APPSTORE_ROOT_CERTS_FILE and PLAY_PACKAGE_NAME needs PLAY_SERVICE_ACCOUNT_FILE")
+	ErrInvalidStoreProducts = errors.New("STORE_PRODUCTS must be a comma separated list of <product ID>=<basic|premium>")
+	ErrInvalidGiftPrices = errors.New("GIFT_MONTHLY_PRICES must be a comma separated list of <basic|premium>=<amount>")
 )
 
 type Config struct {
-	MongoDBURI   string
-	DatabaseName string
-	JWTSecretKey string
+	MongoDBURI     string
+	DatabaseName   string
+	JWTSecretKey   string
+	RateLimitStore   string // "memory" for a single instance, "mongo" to share limits between instances
+	AdminMFARequired bool   // Admins must enroll a TOTP authenticator before they get an access token
+	MFAIssuer        string // Issuer shown in authenticator apps
+	AdminBootstrap   bool   // Allows registering the first super admin without an invitation while no admin exists
+	PasswordHashAlgorithm string // "argon2id" or "bcrypt", existing hashes of the other algorithm are upgraded at login
+	Argon2MemoryKiB       uint32
+	Argon2Iterations      uint32
+	Argon2Parallelism     uint8
+	BcryptCost            int
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordBlocklistFile string // Optional local list of breached passwords, added to the built-in one
+	OIDCProviders         []utils.OIDCProviderConfig // Providers users can sign in with, from OIDC_PROVIDERS
+	TrialDays             int                        // Length of the premium trial new users start with
+	PaymentWebhookSecret    string        // Secret signing the payment provider webhooks, payments are disabled when empty
+	PaymentWebhookTolerance time.Duration // Webhooks signed further away from now are rejected as replays
+	AppStoreBundleID        string            // App Store purchases are disabled when empty
+	AppStoreEnvironment     string            // "Production" or "Sandbox", transactions of the other one are rejected
+	AppStoreRootCertsFile   string            // PEM file of the Apple root CAs signed transactions must chain to
+	PlayPackageName         string            // Play purchases are disabled when empty
+	PlayServiceAccountFile  string            // Key file of the service account calling the Play Developer API
+	PlayAPIURL              string
+	StoreProducts           map[string]string // Subscription plan of each store product ID
+	GiftMonthlyPrices       map[string]int64  // Price of a gifted month of each plan, taxes included, in the smallest unit of GiftCurrency
+	GiftCurrency            string            // ISO 4217 code, upper case
+	SchedulerEnabled        bool              // Runs the background jobs, instances share them through Mongo locks
+	InvoiceCompanyName      string            // Company details printed on the invoices
+	InvoiceCompanyAddress   []string          // Lines of the address, "|" separated in INVOICE_COMPANY_ADDRESS
+	InvoiceCompanyTaxID     string
+	InvoiceCompanyEmail     string
+	TrustedProxies          []string // Proxies allowed to set the client IP with X-Forwarded-For, none by default
 }
 
 func LoadConfig() (*Config, error) {
@@ -25,6 +69,37 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("VIGOR_DB_URI", "")
 	viper.SetDefault("VIGOR_DB_NAME", "")
 	viper.SetDefault("JWT_SECRET_KEY", "")
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
+	viper.SetDefault("ADMIN_MFA_REQUIRED", true)
+	viper.SetDefault("MFA_ISSUER", "Vigor")
+	viper.SetDefault("ADMIN_BOOTSTRAP_ENABLED", false)
+	viper.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+	viper.SetDefault("ARGON2_MEMORY_KIB", 19456)
+	viper.SetDefault("ARGON2_ITERATIONS", 2)
+	viper.SetDefault("ARGON2_PARALLELISM", 1)
+	viper.SetDefault("BCRYPT_COST", 10)
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 128)
+	viper.SetDefault("PASSWORD_BLOCKLIST_FILE", "")
+	viper.SetDefault("OIDC_PROVIDERS", "")
+	viper.SetDefault("TRIAL_DAYS", 14)
+	viper.SetDefault("PAYMENT_WEBHOOK_SECRET", "")
+	viper.SetDefault("PAYMENT_WEBHOOK_TOLERANCE_SECONDS", 300)
+	viper.SetDefault("APPSTORE_BUNDLE_ID", "")
+	viper.SetDefault("APPSTORE_ENVIRONMENT", "Production")
+	viper.SetDefault("APPSTORE_ROOT_CERTS_FILE", "")
+	viper.SetDefault("PLAY_PACKAGE_NAME", "")
+	viper.SetDefault("PLAY_SERVICE_ACCOUNT_FILE", "")
+	viper.SetDefault("PLAY_API_URL", "https://androidpublisher.googleapis.com")
+	viper.SetDefault("STORE_PRODUCTS", "")
+	viper.SetDefault("GIFT_MONTHLY_PRICES", "")
+	viper.SetDefault("GIFT_CURRENCY", "EUR")
+	viper.SetDefault("SCHEDULER_ENABLED", true)
+	viper.SetDefault("INVOICE_COMPANY_NAME", "Vigor")
+	viper.SetDefault("INVOICE_COMPANY_ADDRESS", "")
+	viper.SetDefault("INVOICE_COMPANY_TAX_ID", "")
+	viper.SetDefault("INVOICE_COMPANY_EMAIL", "")
+	viper.SetDefault("TRUSTED_PROXIES", "")
 
 	// Check for test environment
 	environment := viper.GetString("VIGOR_ENV")
@@ -55,11 +130,180 @@ func LoadConfig() (*Config, error) {
 		return nil, ErrMissingSecretKey
 	}
 
+	hashAlgorithm := viper.GetString("PASSWORD_HASH_ALGORITHM")
+	if hashAlgorithm != "argon2id" && hashAlgorithm != "bcrypt" {
+		return nil, ErrInvalidHashAlgorithm
+	}
+
+	passwordMinLength := viper.GetInt("PASSWORD_MIN_LENGTH")
+	passwordMaxLength := viper.GetInt("PASSWORD_MAX_LENGTH")
+	if passwordMinLength < 1 || passwordMinLength > passwordMaxLength {
+		return nil, ErrInvalidPasswordLength
+	}
+
+	trialDays := viper.GetInt("TRIAL_DAYS")
+	if trialDays < 1 || trialDays > 365 {
+		return nil, ErrInvalidTrialDays
+	}
+
+	webhookTolerance := viper.GetInt("PAYMENT_WEBHOOK_TOLERANCE_SECONDS")
+	if webhookTolerance < 1 {
+		return nil, ErrInvalidWebhookTolerance
+	}
+
+	if (viper.GetString("APPSTORE_BUNDLE_ID") != "" && viper.GetString("APPSTORE_ROOT_CERTS_FILE") == "") ||
+		(viper.GetString("PLAY_PACKAGE_NAME") != "" && viper.GetString("PLAY_SERVICE_ACCOUNT_FILE") == "") {
+		return nil, ErrIncompleteStoreConfig
+	}
+
+	storeProducts, err := loadStoreProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	giftPrices, err := loadGiftPrices()
+	if err != nil {
+		return nil, err
+	}
+
+	oidcProviders, err := loadOIDCProviders()
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{
-		MongoDBURI:   mongoDBURI,
-		DatabaseName: databaseName,
-		JWTSecretKey: jwtSecretKey,
+		MongoDBURI:       mongoDBURI,
+		DatabaseName:     databaseName,
+		JWTSecretKey:     jwtSecretKey,
+		RateLimitStore:   viper.GetString("RATE_LIMIT_STORE"),
+		AdminMFARequired: viper.GetBool("ADMIN_MFA_REQUIRED"),
+		MFAIssuer:        viper.GetString("MFA_ISSUER"),
+		AdminBootstrap:   viper.GetBool("ADMIN_BOOTSTRAP_ENABLED"),
+		PasswordHashAlgorithm: hashAlgorithm,
+		Argon2MemoryKiB:       viper.GetUint32("ARGON2_MEMORY_KIB"),
+		Argon2Iterations:      viper.GetUint32("ARGON2_ITERATIONS"),
+		Argon2Parallelism:     uint8(viper.GetUint("ARGON2_PARALLELISM")),
+		BcryptCost:            viper.GetInt("BCRYPT_COST"),
+		PasswordMinLength:     passwordMinLength,
+		PasswordMaxLength:     passwordMaxLength,
+		PasswordBlocklistFile: viper.GetString("PASSWORD_BLOCKLIST_FILE"),
+		OIDCProviders:         oidcProviders,
+		TrialDays:             trialDays,
+		PaymentWebhookSecret:    viper.GetString("PAYMENT_WEBHOOK_SECRET"),
+		PaymentWebhookTolerance: time.Duration(webhookTolerance) * time.Second,
+		AppStoreBundleID:        viper.GetString("APPSTORE_BUNDLE_ID"),
+		AppStoreEnvironment:     viper.GetString("APPSTORE_ENVIRONMENT"),
+		AppStoreRootCertsFile:   viper.GetString("APPSTORE_ROOT_CERTS_FILE"),
+		PlayPackageName:         viper.GetString("PLAY_PACKAGE_NAME"),
+		PlayServiceAccountFile:  viper.GetString("PLAY_SERVICE_ACCOUNT_FILE"),
+		PlayAPIURL:              viper.GetString("PLAY_API_URL"),
+		StoreProducts:           storeProducts,
+		GiftMonthlyPrices:       giftPrices,
+		GiftCurrency:            strings.ToUpper(viper.GetString("GIFT_CURRENCY")),
+		SchedulerEnabled:        viper.GetBool("SCHEDULER_ENABLED"),
+		InvoiceCompanyName:      viper.GetString("INVOICE_COMPANY_NAME"),
+		InvoiceCompanyAddress:   loadAddressLines(viper.GetString("INVOICE_COMPANY_ADDRESS")),
+		InvoiceCompanyTaxID:     viper.GetString("INVOICE_COMPANY_TAX_ID"),
+		InvoiceCompanyEmail:     viper.GetString("INVOICE_COMPANY_EMAIL"),
+		TrustedProxies:          loadTrustedProxies(viper.GetString("TRUSTED_PROXIES")),
 	}
 
 	return config, nil
 }
+
+// loadOIDCProviders reads OIDC_PROVIDERS, a comma separated list of names, and the OIDC_<NAME>_* settings of each.
+func loadOIDCProviders() ([]utils.OIDCProviderConfig, error) {
+	var providers []utils.OIDCProviderConfig
+	for _, name := range strings.Split(viper.GetString("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := utils.OIDCProviderConfig{
+			Name:         name,
+			Issuer:       viper.GetString(prefix + "ISSUER"),
+			ClientID:     viper.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: viper.GetString(prefix + "CLIENT_SECRET"),
+			RedirectURI:  viper.GetString(prefix + "REDIRECT_URI"),
+			Scopes:       strings.Fields(viper.GetString(prefix + "SCOPES")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURI == "" {
+			return nil, fmt.Errorf("%w: %s", ErrIncompleteOIDCProvider, name)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
+// loadStoreProducts reads STORE_PRODUCTS, e.g. "vigor.premium.monthly=premium,vigor.basic.monthly=basic".
+func loadStoreProducts() (map[string]string, error) {
+	var products map[string]string
+	for _, entry := range strings.Split(viper.GetString("STORE_PRODUCTS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		productID, plan, found := strings.Cut(entry, "=")
+		productID, plan = strings.TrimSpace(productID), strings.TrimSpace(plan)
+		if !found || productID == "" || (plan != "basic" && plan != "premium") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidStoreProducts, entry)
+		}
+		if products == nil {
+			products = make(map[string]string)
+		}
+		products[productID] = plan
+	}
+
+	return products, nil
+}
+
+// loadGiftPrices reads GIFT_MONTHLY_PRICES, e.g. "basic=699,premium=1299".
+func loadGiftPrices() (map[string]int64, error) {
+	var prices map[string]int64
+	for _, entry := range strings.Split(viper.GetString("GIFT_MONTHLY_PRICES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		plan, amount, found := strings.Cut(entry, "=")
+		plan = strings.TrimSpace(plan)
+		price, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+		if !found || (plan != "basic" && plan != "premium") || err != nil || price < 1 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidGiftPrices, entry)
+		}
+		if prices == nil {
+			prices = make(map[string]int64)
+		}
+		prices[plan] = price
+	}
+
+	return prices, nil
+}
+
+// loadAddressLines splits a "|" separated address, nil when it is empty.
+func loadAddressLines(address string) []string {
+	var lines []string
+	for _, line := range strings.Split(address, "|") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// loadTrustedProxies splits the comma separated IPs and CIDRs of TRUSTED_PROXIES, nil when it is empty.
+func loadTrustedProxies(proxies string) []string {
+	var trusted []string
+	for _, proxy := range strings.Split(proxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trusted = append(trusted, proxy)
+		}
+	}
+	return trusted
+}
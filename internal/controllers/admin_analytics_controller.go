@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reports cover the last 30 days, today included, unless a range is given
+const defaultAnalyticsDays = 30
+
+type analyticsReportFunc func(ctx context.Context, from, to time.Time, interval string) (models.AnalyticsReport, error)
+
+// GetSubscriptionAnalytics counts the users who signed up in the range by subscription status and plan.
+func (ac *AdminController) GetSubscriptionAnalytics(c *gin.Context) {
+	ac.respondAnalytics(c, "subscriptions", "", func(ctx context.Context, from, to time.Time, _ string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetSubscriptionBreakdown(ctx, from, to)
+	})
+}
+
+// GetSignupAnalytics counts the signups and conversions per day, week or month.
+func (ac *AdminController) GetSignupAnalytics(c *gin.Context) {
+	ac.respondAnalytics(c, "signups", models.AnalyticsIntervalDay, func(ctx context.Context, from, to time.Time, interval string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetSignupAnalytics(ctx, from, to, interval)
+	})
+}
+
+// GetRevenueAnalytics sums the sales per day, week or month and gives the MRR at the end of the range.
+func (ac *AdminController) GetRevenueAnalytics(c *gin.Context) {
+	ac.respondAnalytics(c, "revenue", models.AnalyticsIntervalDay, func(ctx context.Context, from, to time.Time, interval string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetRevenueAnalytics(ctx, from, to, interval)
+	})
+}
+
+// GetChurnAnalytics gives the churn of paid subscriptions over the range.
+func (ac *AdminController) GetChurnAnalytics(c *gin.Context) {
+	ac.respondAnalytics(c, "churn", "", func(ctx context.Context, from, to time.Time, _ string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetChurnAnalytics(ctx, from, to)
+	})
+}
+
+// GetTrialConversionAnalytics gives the share of the trials ended in the range that converted to a paid subscription.
+func (ac *AdminController) GetTrialConversionAnalytics(c *gin.Context) {
+	ac.respondAnalytics(c, "trial-conversion", "", func(ctx context.Context, from, to time.Time, _ string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetTrialConversionAnalytics(ctx, from, to)
+	})
+}
+
+// GetWorkoutPlanEngagement compares the enrollments and completions of the workout plans users joined in the range.
+func (ac *AdminController) GetWorkoutPlanEngagement(c *gin.Context) {
+	ac.respondAnalytics(c, "workout-plans", "", func(ctx context.Context, from, to time.Time, _ string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetWorkoutPlanEngagement(ctx, from, to)
+	})
+}
+
+// GetWorkoutPlanFunnel follows the users who joined the workout plan in the range week by week.
+func (ac *AdminController) GetWorkoutPlanFunnel(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	ac.respondAnalytics(c, "workout-plan-"+workoutPlanID.Hex(), "", func(ctx context.Context, from, to time.Time, _ string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetWorkoutPlanFunnel(ctx, workoutPlanID, from, to)
+	})
+}
+
+// GetCohortRetention reports the retention of the weekly or monthly signup cohorts of the range.
+func (ac *AdminController) GetCohortRetention(c *gin.Context) {
+	ac.respondAnalytics(c, "cohorts", models.AnalyticsIntervalWeek, func(ctx context.Context, from, to time.Time, interval string) (models.AnalyticsReport, error) {
+		return ac.AdminService.GetCohortRetention(ctx, from, to, interval)
+	})
+}
+
+// respondAnalytics runs a report over the from and to days of the query (YYYY-MM-DD, both included) and sends it
+// as JSON, or as CSV with format=csv. Reports grouped by period use the default interval unless one is given.
+func (ac *AdminController) respondAnalytics(c *gin.Context, name, defaultInterval string, report analyticsReportFunc) {
+	var query models.AnalyticsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		log.Printf("Error parsing query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse query parameters"})
+		return
+	}
+
+	if err := validate.Struct(query); err != nil {
+		log.Printf("Error validating query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	// The range ends at the end of the to day
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	if query.To != nil {
+		to = query.To.AddDate(0, 0, 1)
+	}
+	from := to.AddDate(0, 0, -defaultAnalyticsDays)
+	if query.From != nil {
+		from = *query.From
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The end of the period must be after its start"})
+		return
+	}
+	interval := query.Interval
+	if interval == "" {
+		interval = defaultInterval
+	}
+	if err := services.CheckAnalyticsRange(from, to, interval); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := report(c.Request.Context(), from, to, interval)
+	if err != nil {
+		if errors.Is(err, services.ErrWorkoutPlanNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout plan not found"})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCohortInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cohorts are grouped by week or month"})
+			return
+		}
+
+		log.Printf("Error getting %s analytics: %v\n", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	// Cached reports don't change until they expire, clients can keep them as long
+	if cached, ok := result.(models.CachedReport); ok {
+		generatedAt, expiresAt := cached.CachedUntil()
+		etag := fmt.Sprintf(`"%s-%s-%d"`, name, query.Format, generatedAt.UnixNano())
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", max(int(time.Until(expiresAt).Seconds()), 0)))
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	if query.Format != "csv" {
+		c.JSON(http.StatusOK, gin.H{"message": "Analytics retrieved successfully", "data": result})
+		return
+	}
+
+	var output bytes.Buffer
+	writer := csv.NewWriter(&output)
+	if err := writer.WriteAll(result.CSVRecords()); err != nil {
+		log.Printf("Error writing %s analytics: %v\n", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export analytics"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s-%s.csv", name, from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", output.Bytes())
+}
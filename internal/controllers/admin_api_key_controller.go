@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/responses"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handlers for the API keys of integrations, restricted to the api-keys:manage permission
+
+func (ac *AdminController) CreateAPIKey(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.APIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	apiKey, key, err := ac.AdminService.CreateAPIKey(c.Request.Context(), actorID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKeyScope) || errors.Is(err, services.ErrInvalidAPIKeyExpiry) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log.Printf("Error creating API key: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	// The key is only shown once
+	c.JSON(http.StatusCreated, gin.H{"message": "API key created successfully", "data": responses.NewAPIKey(*apiKey), "apiKey": key})
+}
+
+func (ac *AdminController) GetAPIKeys(c *gin.Context) {
+	apiKeys, err := ac.AdminService.GetAPIKeys(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting API keys: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewAPIKeys(apiKeys))
+}
+
+func (ac *AdminController) RevokeAPIKey(c *gin.Context) {
+	apiKeyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := ac.AdminService.RevokeAPIKey(c.Request.Context(), apiKeyID); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Active API key not found"})
+			return
+		}
+
+		log.Printf("Error revoking API key: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs lists audit entries, filtered by the query string and paginated with page and limit.
+func (ac *AdminController) GetAuditLogs(c *gin.Context) {
+	var query models.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		log.Printf("Error parsing query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse query parameters"})
+		return
+	}
+
+	if err := validate.Struct(query); err != nil {
+		log.Printf("Error validating query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	if query.From != nil && query.To != nil && query.To.Before(*query.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The end of the period must be after its start"})
+		return
+	}
+
+	page, err := ac.AdminService.GetAuditLogs(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("Error getting audit logs: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Audit logs retrieved successfully", "data": page})
+}
@@ -33,6 +33,11 @@ func (ac *AdminController) Register(c *gin.Context) {
 
 	if err := validate.Struct(input); err != nil {
 		log.Printf("Error validating input: %v\n", err)
+		// Tell the admin why the password was refused, the other fields keep the generic message
+		if policyErr := passwordPolicy.Validate(input.Password); policyErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password: " + policyErr.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
@@ -43,6 +48,11 @@ func (ac *AdminController) Register(c *gin.Context) {
 			return
 		}
 
+		if errors.Is(err, services.ErrAdminInvitationRequired) || errors.Is(err, services.ErrInvalidAdminInvitation) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "A valid invitation is required to register"})
+			return
+		}
+
 		log.Printf("Error registering admin: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register admin"})
 		return
@@ -61,19 +71,36 @@ func (ac *AdminController) Login(c *gin.Context) {
 
 	admin, err := ac.AdminService.GetAdminByEmail(c.Request.Context(), loginDetails.Email, loginDetails.Password)
 	if err != nil {
+		if errors.Is(err, services.ErrAdminDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin account is disabled"})
+			return
+		}
+
 		log.Printf("Error authenticating admin: %v\n", err)
+		c.Error(err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	accessToken, err := ac.JWTService.GenerateAccessToken(admin.ID, admin.Email, admin.Role)
+	// Second step: prove possession of the authenticator, or enroll one when the policy requires it
+	if admin.MFA != nil && admin.MFA.Enabled {
+		respondWithMFAChallenge(c, ac.JWTService, admin.ID, admin.Email, admin.Role, utils.TokenUseMFAChallenge)
+		return
+	}
+
+	if ac.AdminService.MFARequired() {
+		respondWithMFAChallenge(c, ac.JWTService, admin.ID, admin.Email, admin.Role, utils.TokenUseMFAEnrollment)
+		return
+	}
+
+	accessToken, err := ac.JWTService.GenerateAccessToken(admin.ID, admin.Email, admin.Role, "")
 	if err != nil {	
 		log.Printf("Error generating access token: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
 		return
 	}
 
-	refreshToken, err := ac.JWTService.GenerateRefreshToken(admin.ID, admin.Email, admin.Role)
+	refreshToken, err := ac.JWTService.GenerateRefreshToken(admin.ID, admin.Email, admin.Role, "")
 	if err != nil {
 		log.Printf("Error generating refresh token: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
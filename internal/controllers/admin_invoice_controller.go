@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportInvoices sends the invoices issued between the from and to days (YYYY-MM-DD, both included) as CSV.
+func (ac *AdminController) ExportInvoices(c *gin.Context) {
+	var query models.InvoiceExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		log.Printf("Error parsing query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse query parameters"})
+		return
+	}
+
+	if err := validate.Struct(query); err != nil {
+		log.Printf("Error validating query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	if query.To.Before(*query.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The end of the period must be after its start"})
+		return
+	}
+
+	export, err := ac.AdminService.ExportInvoicesCSV(c.Request.Context(), *query.From, query.To.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error exporting invoices: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export invoices"})
+		return
+	}
+
+	filename := fmt.Sprintf("invoices-%s-%s.csv", query.From.Format("2006-01-02"), query.To.Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", export)
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetJobRuns lists the last runs of the scheduled jobs, filtered by job and status.
+func (ac *AdminController) GetJobRuns(c *gin.Context) {
+	var query models.JobRunQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		log.Printf("Error parsing query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse query parameters"})
+		return
+	}
+
+	if err := validate.Struct(query); err != nil {
+		log.Printf("Error validating query: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+		return
+	}
+
+	runs, err := ac.AdminService.GetJobRuns(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("Error getting job runs: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job runs retrieved successfully", "data": runs})
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/responses"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handlers for onboarding and managing admins, restricted to the admins:manage permission
+
+func (ac *AdminController) CreateAdminInvitation(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.AdminInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	invitation, token, err := ac.AdminService.CreateAdminInvitation(c.Request.Context(), actorID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrAdminAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Admin already exists"})
+			return
+		}
+
+		if errors.Is(err, services.ErrRoleNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found"})
+			return
+		}
+
+		if errors.Is(err, services.ErrSuperAdminRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only a super admin can grant the super admin role"})
+			return
+		}
+
+		log.Printf("Error creating admin invitation: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin invitation"})
+		return
+	}
+
+	// The token is only shown once
+	c.JSON(http.StatusCreated, gin.H{"message": "Invitation created successfully", "data": responses.NewAdminInvitation(*invitation), "invitationToken": token})
+}
+
+func (ac *AdminController) GetAdminInvitations(c *gin.Context) {
+	invitations, err := ac.AdminService.GetAdminInvitations(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting admin invitations: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get admin invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewAdminInvitations(invitations))
+}
+
+func (ac *AdminController) RevokeAdminInvitation(c *gin.Context) {
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := ac.AdminService.RevokeAdminInvitation(c.Request.Context(), invitationID); err != nil {
+		if errors.Is(err, services.ErrAdminInvitationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending invitation not found"})
+			return
+		}
+
+		log.Printf("Error revoking admin invitation: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke admin invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+func (ac *AdminController) GetAdmins(c *gin.Context) {
+	admins, err := ac.AdminService.GetAdmins(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting admins: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get admins"})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewAdmins(admins))
+}
+
+func (ac *AdminController) UpdateAdmin(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input models.AdminUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	admin, err := ac.AdminService.UpdateAdmin(c.Request.Context(), actorID, adminID, input)
+	if err != nil {
+		respondWithAdminManagementError(c, err, "to update admin")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin updated successfully", "data": responses.NewAdmin(*admin)})
+}
+
+func (ac *AdminController) DisableAdmin(c *gin.Context) {
+	ac.setAdminDisabled(c, true)
+}
+
+func (ac *AdminController) EnableAdmin(c *gin.Context) {
+	ac.setAdminDisabled(c, false)
+}
+
+func (ac *AdminController) setAdminDisabled(c *gin.Context, disabled bool) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := ac.AdminService.SetAdminDisabled(c.Request.Context(), actorID, adminID, disabled); err != nil {
+		respondWithAdminManagementError(c, err, "to update admin status")
+		return
+	}
+
+	if disabled {
+		c.JSON(http.StatusOK, gin.H{"message": "Admin disabled successfully"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Admin enabled successfully"})
+}
+
+func (ac *AdminController) DeleteAdmin(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := ac.AdminService.DeleteAdmin(c.Request.Context(), actorID, adminID); err != nil {
+		respondWithAdminManagementError(c, err, "to delete admin")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin deleted successfully"})
+}
+
+func respondWithAdminManagementError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrAdminNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+	case errors.Is(err, services.ErrAdminAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Admin already exists"})
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found"})
+	case errors.Is(err, services.ErrCannotModifySelf):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change the role or status of your own account"})
+	case errors.Is(err, services.ErrLastSuperAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": "At least one active super admin is required"})
+	case errors.Is(err, services.ErrSuperAdminRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only a super admin can grant or take away the super admin role"})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
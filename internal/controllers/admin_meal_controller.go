@@ -31,6 +31,10 @@ func (ac *AdminController) GetMealByID(c *gin.Context) {
 		return
 	}
 
+	if refuseUncoveredCatalogContent(c, meal.AccessTier) {
+		return
+	}
+
 	c.JSON(http.StatusOK, meal)
 }
 
@@ -42,7 +46,7 @@ func (ac *AdminController) GetMeals(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, meals)
+	c.JSON(http.StatusOK, coveredCatalogContent(c, meals, mealAccessTier))
 }
 
 
@@ -179,5 +183,9 @@ func (ac *AdminController) SearchMealsByName(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, meals)
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, coveredCatalogContent(c, meals, mealAccessTier))
+}
+
+func mealAccessTier(meal models.Meal) string {
+	return meal.AccessTier
+}
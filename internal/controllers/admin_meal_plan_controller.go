@@ -12,6 +12,15 @@ import (
 )
 
 func (ac *AdminController) GetMealPlanByID(c *gin.Context) {
+	ac.getMealPlanByID(c, false)
+}
+
+// GetPublishedMealPlanByID serves the catalog, drafts and archived plans aren't found.
+func (ac *AdminController) GetPublishedMealPlanByID(c *gin.Context) {
+	ac.getMealPlanByID(c, true)
+}
+
+func (ac *AdminController) getMealPlanByID(c *gin.Context, publishedOnly bool) {
 	mealPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		log.Printf("Error parsing meal plan ID: %v\n", err)
@@ -20,6 +29,9 @@ func (ac *AdminController) GetMealPlanByID(c *gin.Context) {
 	}
 
 	mealPlan, err := ac.AdminService.GetMealPlanByID(c.Request.Context(), mealPlanID)
+	if err == nil && publishedOnly && mealPlan.PublicationStatus() != models.PlanStatusPublished {
+		err = services.ErrMealPlanNotFound
+	}
 	if err != nil {
 		if errors.Is(err, services.ErrMealPlanNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Meal plan not found"})
@@ -31,21 +43,49 @@ func (ac *AdminController) GetMealPlanByID(c *gin.Context) {
 		return
 	}
 
+	if refuseUncoveredCatalogContent(c, mealPlan.AccessTier) {
+		return
+	}
+
 	c.JSON(http.StatusOK, mealPlan)
 }
 
+// GetMealPlans lists the meal plans, of the publication status given by the status query parameter if any.
 func (ac *AdminController) GetMealPlans(c *gin.Context) {
-	mealPlans, err := ac.AdminService.GetMealPlans(c.Request.Context())
+	ac.getMealPlans(c, c.Query("status"))
+}
+
+// GetPublishedMealPlans serves the catalog.
+func (ac *AdminController) GetPublishedMealPlans(c *gin.Context) {
+	ac.getMealPlans(c, models.PlanStatusPublished)
+}
+
+func (ac *AdminController) getMealPlans(c *gin.Context, status string) {
+	mealPlans, err := ac.AdminService.GetMealPlans(c.Request.Context(), status)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidPlanStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
 		log.Printf("Error getting meal plans: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get meal plans"})
 		return
 	}
 
-	c.JSON(http.StatusOK, mealPlans)
+	c.JSON(http.StatusOK, coveredCatalogContent(c, mealPlans, mealPlanAccessTier))
 }
 
 func (ac *AdminController) SearchMealPlansByName(c *gin.Context) {
+	ac.searchMealPlansByName(c, c.Query("status"))
+}
+
+// SearchPublishedMealPlansByName serves the catalog.
+func (ac *AdminController) SearchPublishedMealPlansByName(c *gin.Context) {
+	ac.searchMealPlansByName(c, models.PlanStatusPublished)
+}
+
+func (ac *AdminController) searchMealPlansByName(c *gin.Context, status string) {
 	name := c.Query("name")
 
 	if name == "" {
@@ -53,14 +93,23 @@ func (ac *AdminController) SearchMealPlansByName(c *gin.Context) {
 		return
 	}
 
-	mealPlans, err := ac.AdminService.SearchMealPlansByName(c.Request.Context(), name)
+	mealPlans, err := ac.AdminService.SearchMealPlansByName(c.Request.Context(), name, status)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidPlanStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
 		log.Printf("Error searching meal plans by name: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search meal plans"})
 		return
 	}
 
-	c.JSON(http.StatusOK, mealPlans)
+	c.JSON(http.StatusOK, coveredCatalogContent(c, mealPlans, mealPlanAccessTier))
+}
+
+func mealPlanAccessTier(mealPlan models.MealPlan) string {
+	return mealPlan.AccessTier
 }
 
 func (ac *AdminController) CreateMealPlan(c *gin.Context) {
@@ -89,7 +138,38 @@ func (ac *AdminController) CreateMealPlan(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Meal plan created successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Meal plan created as a draft"})
+}
+
+// CloneMealPlan copies a meal plan under a new name, as a draft coaches can then vary.
+func (ac *AdminController) CloneMealPlan(c *gin.Context) {
+	mealPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing meal plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meal plan ID"})
+		return
+	}
+
+	var input models.PlanCloneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	clone, err := ac.AdminService.CloneMealPlan(c.Request.Context(), mealPlanID, input)
+	if err != nil {
+		respondWithPlanError(c, err, "Meal plan", "to clone meal plan")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Meal plan cloned as a draft", "data": clone})
 }
 
 func (ac *AdminController) UpdateMealPlan(c *gin.Context) {
@@ -114,18 +194,62 @@ func (ac *AdminController) UpdateMealPlan(c *gin.Context) {
 		return
 	}
 
-	if err := ac.AdminService.UpdateMealPlan(c.Request.Context(), mealPlanID, mealPlan); err != nil {
-		if errors.Is(err, services.ErrMealPlanNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Meal plan not found"})
-			return
-		}
+	draft, err := ac.AdminService.UpdateMealPlan(c.Request.Context(), mealPlanID, mealPlan)
+	if err != nil {
+		respondWithPlanError(c, err, "Meal plan", "to update meal plan")
+		return
+	}
 
-		log.Printf("Error updating meal plan: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meal plan"})
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan draft updated successfully", "data": draft})
+}
+
+// GetMealPlanDraft shows the pending edits of a published meal plan.
+func (ac *AdminController) GetMealPlanDraft(c *gin.Context) {
+	mealPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing meal plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meal plan ID"})
+		return
+	}
+
+	draft, err := ac.AdminService.GetMealPlanDraft(c.Request.Context(), mealPlanID)
+	if err != nil {
+		respondWithPlanError(c, err, "Meal plan", "to get meal plan draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": draft})
+}
+
+// TransitionMealPlan submits a meal plan for review, sends it back to draft, publishes or archives it.
+func (ac *AdminController) TransitionMealPlan(c *gin.Context) {
+	mealPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing meal plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meal plan ID"})
+		return
+	}
+
+	var input models.PlanTransitionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	mealPlan, err := ac.AdminService.TransitionMealPlan(c.Request.Context(), mealPlanID, input)
+	if err != nil {
+		respondWithPlanError(c, err, "Meal plan", "to update meal plan status")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Meal plan updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan status updated successfully", "data": mealPlan})
 }
 
 
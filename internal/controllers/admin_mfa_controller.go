@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyMFALogin completes a login started with an MFA challenge token.
+func (ac *AdminController) VerifyMFALogin(c *gin.Context) {
+	adminID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFALoginInput(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.AdminService.VerifyMFA(c.Request.Context(), adminID, input); err != nil {
+		respondWithMFAError(c, err, "to verify authentication code")
+		return
+	}
+
+	respondWithTokens(c, ac.JWTService, adminID, c.GetString("email"), c.GetString("role"), gin.H{})
+}
+
+// BeginMFAEnrollment is reachable with an access token or, when MFA is mandatory, the enrollment token from Login.
+func (ac *AdminController) BeginMFAEnrollment(c *gin.Context) {
+	adminID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := ac.AdminService.BeginMFAEnrollment(c.Request.Context(), adminID)
+	if err != nil {
+		respondWithMFAError(c, err, "to start MFA enrollment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scan the provisioning URI and confirm with a code", "data": enrollment})
+}
+
+func (ac *AdminController) ConfirmMFAEnrollment(c *gin.Context) {
+	adminID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFACodeInput(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := ac.AdminService.ConfirmMFAEnrollment(c.Request.Context(), adminID, input.Code)
+	if err != nil {
+		respondWithMFAError(c, err, "to confirm MFA enrollment")
+		return
+	}
+
+	response := gin.H{"message": "Multi-factor authentication enabled", "recoveryCodes": recoveryCodes}
+
+	// Enrolling was the last step of a login, hand out the session tokens
+	if c.GetString("tokenUse") == utils.TokenUseMFAEnrollment {
+		respondWithTokens(c, ac.JWTService, adminID, c.GetString("email"), c.GetString("role"), response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (ac *AdminController) DisableMFA(c *gin.Context) {
+	adminID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFACodeInput(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.AdminService.DisableMFA(c.Request.Context(), adminID, input.Code); err != nil {
+		respondWithMFAError(c, err, "to disable MFA")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Multi-factor authentication disabled"})
+}
+
+func (ac *AdminController) RegenerateRecoveryCodes(c *gin.Context) {
+	adminID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFACodeInput(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := ac.AdminService.RegenerateRecoveryCodes(c.Request.Context(), adminID, input.Code)
+	if err != nil {
+		respondWithMFAError(c, err, "to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recovery codes regenerated", "recoveryCodes": recoveryCodes})
+}
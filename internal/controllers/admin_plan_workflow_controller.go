@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// respondWithPlanError answers the errors of the plan review workflow, planName is "Workout plan" or "Meal plan".
+func respondWithPlanError(c *gin.Context, err error, planName, action string) {
+	switch {
+	case errors.Is(err, services.ErrWorkoutPlanNotFound), errors.Is(err, services.ErrMealPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": planName + " not found"})
+	case errors.Is(err, services.ErrPlanDraftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": planName + " has no pending draft"})
+	case errors.Is(err, services.ErrWorkoutPlanAlreadyExists), errors.Is(err, services.ErrMealPlanAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": planName + " already exists"})
+	case errors.Is(err, services.ErrInvalidPlanStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+	case errors.Is(err, services.ErrInvalidPlanTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Status transition not allowed"})
+	case errors.Is(err, services.ErrPlanNotPublishable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handlers for the promo codes of marketing campaigns, restricted to the promotions:manage permission
+
+func (ac *AdminController) CreatePromoCode(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.PromoCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	promoCode, err := ac.AdminService.CreatePromoCode(c.Request.Context(), actorID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPromoCode), errors.Is(err, services.ErrInvalidPromoCodeExpiry):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPromoCodeTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Promo code already exists"})
+		default:
+			log.Printf("Error creating promo code: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promo code"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Promo code created successfully", "data": promoCode})
+}
+
+// GetPromoCodes lists the promo codes, the type query parameter filters them (e.g. gift).
+func (ac *AdminController) GetPromoCodes(c *gin.Context) {
+	promoCodes, err := ac.AdminService.GetPromoCodes(c.Request.Context(), c.Query("type"))
+	if err != nil {
+		log.Printf("Error getting promo codes: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get promo codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promoCodes)
+}
+
+func (ac *AdminController) DisablePromoCode(c *gin.Context) {
+	promoCodeID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := ac.AdminService.DisablePromoCode(c.Request.Context(), promoCodeID); err != nil {
+		if errors.Is(err, services.ErrPromoCodeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Enabled promo code not found"})
+			return
+		}
+
+		log.Printf("Error disabling promo code: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable promo code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code disabled successfully"})
+}
+
+func (ac *AdminController) GetPromoCodeStats(c *gin.Context) {
+	promoCodeID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	stats, err := ac.AdminService.GetPromoCodeStats(c.Request.Context(), promoCodeID)
+	if err != nil {
+		if errors.Is(err, services.ErrPromoCodeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+			return
+		}
+
+		log.Printf("Error getting promo code stats: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get promo code stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code stats retrieved successfully", "data": stats})
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (ac *AdminController) GetRoles(c *gin.Context) {
+	roles, err := ac.AdminService.GetRoles(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting roles: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "permissions": models.AllPermissions})
+}
+
+func (ac *AdminController) CreateRole(c *gin.Context) {
+	var input models.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	role, err := ac.AdminService.CreateRole(c.Request.Context(), input)
+	if err != nil {
+		respondWithRoleError(c, err, "to create role")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Role created successfully", "data": role})
+}
+
+func (ac *AdminController) UpdateRole(c *gin.Context) {
+	var input models.RoleUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	role, err := ac.AdminService.UpdateRole(c.Request.Context(), c.Param("name"), input)
+	if err != nil {
+		respondWithRoleError(c, err, "to update role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "data": role})
+}
+
+func (ac *AdminController) DeleteRole(c *gin.Context) {
+	if err := ac.AdminService.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		respondWithRoleError(c, err, "to delete role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+func respondWithRoleError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case errors.Is(err, services.ErrRoleAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+	case errors.Is(err, services.ErrRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is assigned to at least one admin"})
+	case errors.Is(err, services.ErrBuiltInRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in roles cannot be deleted"})
+	case errors.Is(err, services.ErrSuperAdminRoleLock):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The super admin role always has every permission"})
+	case errors.Is(err, services.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
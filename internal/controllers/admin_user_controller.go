@@ -1,10 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/responses"
+	"github.com/GhostDrew11/vigor-api/internal/services"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func (ac *AdminController) GetUsers(c *gin.Context) {
@@ -15,5 +20,245 @@ func (ac *AdminController) GetUsers(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, responses.NewUserAdminViews(users))
+}
+
+func (ac *AdminController) TransitionUserSubscription(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input models.SubscriptionTransitionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	subscription, err := ac.AdminService.TransitionUserSubscription(c.Request.Context(), userID, input.Status)
+	if err != nil {
+		respondWithSubscriptionError(c, err, "transitioning user subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User subscription updated successfully", "data": subscription})
+}
+
+// GetUserDetail shows one user with their workout plan enrollments and subscription history.
+func (ac *AdminController) GetUserDetail(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	detail, err := ac.AdminService.GetUserDetail(c.Request.Context(), userID)
+	if err != nil {
+		respondWithUserManagementError(c, err, "to get user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User retrieved successfully", "data": responses.NewUserAdminDetail(*detail)})
+}
+
+func (ac *AdminController) SuspendUser(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input models.UserSuspensionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	restriction, err := ac.AdminService.SuspendUser(c.Request.Context(), actorID, userID, input)
+	if err != nil {
+		respondWithUserManagementError(c, err, "to suspend user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User suspended successfully", "data": restriction})
+}
+
+func (ac *AdminController) BanUser(c *gin.Context) {
+	actorID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input models.UserBanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	restriction, err := ac.AdminService.BanUser(c.Request.Context(), actorID, userID, input)
+	if err != nil {
+		respondWithUserManagementError(c, err, "to ban user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User banned successfully", "data": restriction})
+}
+
+func (ac *AdminController) LiftUserRestriction(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	if err := ac.AdminService.LiftUserRestriction(c.Request.Context(), userID); err != nil {
+		respondWithUserManagementError(c, err, "to lift user restriction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User restriction lifted successfully"})
+}
+
+// ResetUserPassword gives the user a new random password, returned once so support can hand it over.
+func (ac *AdminController) ResetUserPassword(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	password, err := ac.AdminService.ResetUserPassword(c.Request.Context(), userID)
+	if err != nil {
+		respondWithUserManagementError(c, err, "to reset user password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User password reset successfully", "temporaryPassword": password})
+}
+
+func (ac *AdminController) OverrideUserSubscription(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input models.SubscriptionOverrideInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	subscription, err := ac.AdminService.OverrideUserSubscription(c.Request.Context(), userID, input)
+	if err != nil {
+		respondWithSubscriptionError(c, err, "overriding user subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User subscription overridden successfully", "data": subscription})
+}
+
+func respondWithUserManagementError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrInvalidSuspensionEnd):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The suspension must end in the future"})
+	case errors.Is(err, services.ErrUserNotRestricted):
+		c.JSON(http.StatusConflict, gin.H{"error": "User is neither suspended nor banned"})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
+
+// ImpersonateUser issues a short-lived access token of the user to the admin, read-only unless write access is
+// asked for. Every request made with it is recorded.
+func (ac *AdminController) ImpersonateUser(c *gin.Context) {
+	adminID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var input models.ImpersonationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	impersonation, user, err := ac.AdminService.StartImpersonation(c.Request.Context(), adminID, userID, input)
+	if err != nil {
+		respondWithUserManagementError(c, err, "to impersonate user")
+		return
+	}
+
+	token, err := ac.JWTService.GenerateImpersonationToken(user.ID, user.Email, adminID, impersonation.ID.Hex(), impersonation.ReadOnly)
+	if err != nil {
+		log.Printf("Error generating impersonation token: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate impersonation token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation started successfully", "data": impersonation, "accessToken": token})
+}
@@ -12,6 +12,15 @@ import (
 )
 
 func (ac *AdminController) GetWorkoutPlanByID(c *gin.Context) {
+	ac.getWorkoutPlanByID(c, false)
+}
+
+// GetPublishedWorkoutPlanByID serves the catalog, drafts and archived plans aren't found.
+func (ac *AdminController) GetPublishedWorkoutPlanByID(c *gin.Context) {
+	ac.getWorkoutPlanByID(c, true)
+}
+
+func (ac *AdminController) getWorkoutPlanByID(c *gin.Context, publishedOnly bool) {
 	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		log.Printf("Error parsing workout plan ID: %v\n", err)
@@ -20,6 +29,9 @@ func (ac *AdminController) GetWorkoutPlanByID(c *gin.Context) {
 	}
 
 	workoutPlan, err := ac.AdminService.GetWorkoutPlanByID(c.Request.Context(), workoutPlanID)
+	if err == nil && publishedOnly && workoutPlan.PublicationStatus() != models.PlanStatusPublished {
+		err = services.ErrWorkoutPlanNotFound
+	}
 	if err != nil {
 		if errors.Is(err, services.ErrWorkoutPlanNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Workout plan not found"})
@@ -34,9 +46,24 @@ func (ac *AdminController) GetWorkoutPlanByID(c *gin.Context) {
 	c.JSON(http.StatusOK, workoutPlan)
 }
 
+// GetWorkoutPlans lists the workout plans, of the publication status given by the status query parameter if any.
 func (ac *AdminController) GetWorkoutPlans(c *gin.Context) {
-	workoutPlans, err := ac.AdminService.GetWorkoutPlans(c.Request.Context())
+	ac.getWorkoutPlans(c, c.Query("status"))
+}
+
+// GetPublishedWorkoutPlans serves the catalog.
+func (ac *AdminController) GetPublishedWorkoutPlans(c *gin.Context) {
+	ac.getWorkoutPlans(c, models.PlanStatusPublished)
+}
+
+func (ac *AdminController) getWorkoutPlans(c *gin.Context, status string) {
+	workoutPlans, err := ac.AdminService.GetWorkoutPlans(c.Request.Context(), status)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidPlanStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
 		log.Printf("Error getting workout plans: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workout plans"})
 		return
@@ -46,6 +73,15 @@ func (ac *AdminController) GetWorkoutPlans(c *gin.Context) {
 }
 
 func (ac *AdminController) SearchWorkoutPlansByName(c *gin.Context) {
+	ac.searchWorkoutPlansByName(c, c.Query("status"))
+}
+
+// SearchPublishedWorkoutPlansByName serves the catalog.
+func (ac *AdminController) SearchPublishedWorkoutPlansByName(c *gin.Context) {
+	ac.searchWorkoutPlansByName(c, models.PlanStatusPublished)
+}
+
+func (ac *AdminController) searchWorkoutPlansByName(c *gin.Context, status string) {
 	name := c.Query("name")
 	
 	if name == "" {
@@ -53,8 +89,13 @@ func (ac *AdminController) SearchWorkoutPlansByName(c *gin.Context) {
 		return
 	}
 
-	workoutPlans, err := ac.AdminService.SearchWorkoutPlansByName(c.Request.Context(), name)
+	workoutPlans, err := ac.AdminService.SearchWorkoutPlansByName(c.Request.Context(), name, status)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidPlanStatus) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
 		log.Printf("Error searching workout plans by name: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search workout plans"})
 		return
@@ -71,13 +112,19 @@ func (ac *AdminController) CreateWorkoutPlan(c *gin.Context) {
 		return
 	}
 
+	// Weeks and days may refer to templates, the plan is validated once they are copied in
+	if err := ac.AdminService.ExpandWorkoutPlanTemplates(c.Request.Context(), &workoutPlan); err != nil {
+		respondWithWorkoutTemplateError(c, err, "to expand workout templates")
+		return
+	}
+
 	if err := validate.Struct(workoutPlan); err != nil {
 		log.Printf("Error validating input: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	if err := ac.AdminService.CreateWorkoutPlan(c, workoutPlan); err != nil {
+	if err := ac.AdminService.CreateWorkoutPlan(c.Request.Context(), workoutPlan); err != nil {
 		if errors.Is(err, services.ErrWorkoutPlanAlreadyExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": "Workout plan already exists"})
 			return
@@ -88,7 +135,38 @@ func (ac *AdminController) CreateWorkoutPlan(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Workout plan created successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Workout plan created as a draft"})
+}
+
+// CloneWorkoutPlan copies a workout plan under a new name, as a draft coaches can then vary.
+func (ac *AdminController) CloneWorkoutPlan(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	var input models.PlanCloneInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	clone, err := ac.AdminService.CloneWorkoutPlan(c.Request.Context(), workoutPlanID, input)
+	if err != nil {
+		respondWithPlanError(c, err, "Workout plan", "to clone workout plan")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Workout plan cloned as a draft", "data": clone})
 }
 
 func (ac *AdminController) UpdateWorkoutPlan(c *gin.Context) {
@@ -113,18 +191,62 @@ func (ac *AdminController) UpdateWorkoutPlan(c *gin.Context) {
 		return
 	}
 
-	if err := ac.AdminService.UpdateWorkoutPlan(c, workoutPlanID, updateInput); err != nil {
-		if errors.Is(err, services.ErrWorkoutPlanNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Workout plan not found"})
-			return
-		}
+	draft, err := ac.AdminService.UpdateWorkoutPlan(c.Request.Context(), workoutPlanID, updateInput)
+	if err != nil {
+		respondWithPlanError(c, err, "Workout plan", "to update workout plan")
+		return
+	}
 
-		log.Printf("Error updating workout plan: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"message": "Workout plan draft updated successfully", "data": draft})
+}
+
+// GetWorkoutPlanDraft shows the pending edits of a published workout plan.
+func (ac *AdminController) GetWorkoutPlanDraft(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	draft, err := ac.AdminService.GetWorkoutPlanDraft(c.Request.Context(), workoutPlanID)
+	if err != nil {
+		respondWithPlanError(c, err, "Workout plan", "to get workout plan draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": draft})
+}
+
+// TransitionWorkoutPlan submits a workout plan for review, sends it back to draft, publishes or archives it.
+func (ac *AdminController) TransitionWorkoutPlan(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	var input models.PlanTransitionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	workoutPlan, err := ac.AdminService.TransitionWorkoutPlan(c.Request.Context(), workoutPlanID, input)
+	if err != nil {
+		respondWithPlanError(c, err, "Workout plan", "to update workout plan status")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Workout plan updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Workout plan status updated successfully", "data": workoutPlan})
 }
 
 func (ac *AdminController) DeleteWorkoutPlan(c *gin.Context) {
@@ -135,7 +257,7 @@ func (ac *AdminController) DeleteWorkoutPlan(c *gin.Context) {
 		return
 	}
 
-	if err := ac.AdminService.DeleteWorkoutPlan(c, workoutPlanID); err != nil {
+	if err := ac.AdminService.DeleteWorkoutPlan(c.Request.Context(), workoutPlanID); err != nil {
 		if errors.Is(err, services.ErrWorkoutPlanNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Workout plan not found"})
 			return
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetWorkoutPlanVersions lists the published versions of a workout plan, latest first.
+func (ac *AdminController) GetWorkoutPlanVersions(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	versions, err := ac.AdminService.GetWorkoutPlanVersions(c.Request.Context(), workoutPlanID)
+	if err != nil {
+		log.Printf("Error getting workout plan versions: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workout plan versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
+
+// GetWorkoutPlanVersion shows the content of a workout plan as published in one version.
+func (ac *AdminController) GetWorkoutPlanVersion(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	workoutPlanVersion, err := ac.AdminService.GetWorkoutPlanVersion(c.Request.Context(), workoutPlanID, version)
+	if err != nil {
+		respondWithWorkoutPlanVersionError(c, err, "to get workout plan version")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": workoutPlanVersion})
+}
+
+// MigrateWorkoutPlanEnrollments moves the users following older versions of a workout plan to a newer one, and
+// reports the progress that could not be carried over. A dry run only reports.
+func (ac *AdminController) MigrateWorkoutPlanEnrollments(c *gin.Context) {
+	workoutPlanID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout plan ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout plan ID"})
+		return
+	}
+
+	var input models.WorkoutPlanMigrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	report, err := ac.AdminService.MigrateWorkoutPlanEnrollments(c.Request.Context(), workoutPlanID, input)
+	if err != nil {
+		respondWithWorkoutPlanVersionError(c, err, "to migrate workout plan enrollments")
+		return
+	}
+
+	message := "Workout plan enrollments migrated successfully"
+	if input.DryRun {
+		message = "Workout plan enrollments migration simulated successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": report})
+}
+
+func respondWithWorkoutPlanVersionError(c *gin.Context, err error, action string) {
+	if errors.Is(err, services.ErrWorkoutPlanVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout plan version not found"})
+		return
+	}
+
+	log.Printf("Error %s: %v\n", action, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetWorkoutTemplates lists the workout templates, only the weeks or the days with ?kind=week or ?kind=day.
+func (ac *AdminController) GetWorkoutTemplates(c *gin.Context) {
+	templates, err := ac.AdminService.GetWorkoutTemplates(c.Request.Context(), c.Query("kind"))
+	if err != nil {
+		respondWithWorkoutTemplateError(c, err, "to get workout templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": templates})
+}
+
+func (ac *AdminController) GetWorkoutTemplateByID(c *gin.Context) {
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout template ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout template ID"})
+		return
+	}
+
+	template, err := ac.AdminService.GetWorkoutTemplateByID(c.Request.Context(), templateID)
+	if err != nil {
+		respondWithWorkoutTemplateError(c, err, "to get workout template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": template})
+}
+
+func (ac *AdminController) CreateWorkoutTemplate(c *gin.Context) {
+	input, ok := ac.bindWorkoutTemplateInput(c)
+	if !ok {
+		return
+	}
+
+	template, err := ac.AdminService.CreateWorkoutTemplate(c.Request.Context(), input)
+	if err != nil {
+		respondWithWorkoutTemplateError(c, err, "to create workout template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Workout template created successfully", "data": template})
+}
+
+// UpdateWorkoutTemplate replaces a workout template, the plans already built from it don't change.
+func (ac *AdminController) UpdateWorkoutTemplate(c *gin.Context) {
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout template ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout template ID"})
+		return
+	}
+
+	input, ok := ac.bindWorkoutTemplateInput(c)
+	if !ok {
+		return
+	}
+
+	template, err := ac.AdminService.UpdateWorkoutTemplate(c.Request.Context(), templateID, input)
+	if err != nil {
+		respondWithWorkoutTemplateError(c, err, "to update workout template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workout template updated successfully", "data": template})
+}
+
+func (ac *AdminController) DeleteWorkoutTemplate(c *gin.Context) {
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing workout template ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workout template ID"})
+		return
+	}
+
+	if err := ac.AdminService.DeleteWorkoutTemplate(c.Request.Context(), templateID); err != nil {
+		respondWithWorkoutTemplateError(c, err, "to delete workout template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workout template deleted successfully"})
+}
+
+// bindWorkoutTemplateInput parses the template from the request body and copies in the day templates its days refer
+// to before validating it, ok is false once the error is answered.
+func (ac *AdminController) bindWorkoutTemplateInput(c *gin.Context) (input models.WorkoutTemplateInput, ok bool) {
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return input, false
+	}
+
+	if err := ac.AdminService.ExpandWorkoutDayTemplates(c.Request.Context(), input.Days); err != nil {
+		respondWithWorkoutTemplateError(c, err, "to expand workout templates")
+		return input, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return input, false
+	}
+
+	return input, true
+}
+
+func respondWithWorkoutTemplateError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrWorkoutTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout template not found"})
+	case errors.Is(err, services.ErrWorkoutTemplateAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Workout template already exists"})
+	case errors.Is(err, services.ErrInvalidWorkoutTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWorkoutTemplateReference):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
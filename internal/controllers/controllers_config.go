@@ -1,7 +1,27 @@
 package controllers
 
-import "github.com/go-playground/validator/v10"
+import (
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/go-playground/validator/v10"
+)
 
 var (
-	validate = validator.New()
-)
\ No newline at end of file
+	validate = newValidator()
+	passwordPolicy = utils.DefaultPasswordPolicy()
+)
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// "password" checks a field against the password policy set at startup
+	v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return passwordPolicy.Validate(fl.Field().String()) == nil
+	})
+
+	return v
+}
+
+// SetPasswordPolicy replaces the default policy used to validate new passwords.
+func SetPasswordPolicy(policy *utils.PasswordPolicy) {
+	passwordPolicy = policy
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// accountIDFromContext returns the ID of the authenticated admin or user, writing the error response when missing.
+func accountIDFromContext(c *gin.Context) (primitive.ObjectID, bool) {
+	accountID, exists := c.Get("userId")
+	if !exists {
+		log.Printf("Error retrieving userID from context\n")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to retrieve user ID from context"})
+		return primitive.NilObjectID, false
+	}
+
+	objID, ok := accountID.(primitive.ObjectID)
+	if !ok {
+		log.Printf("Error converting userID from type interface {} to primitive.ObjectID\n")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert user ID to string"})
+		return primitive.NilObjectID, false
+	}
+
+	return objID, true
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Helpers shared by the admin and user MFA handlers
+
+func bindMFACodeInput(c *gin.Context) (models.MFACodeInput, bool) {
+	var input models.MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return input, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return input, false
+	}
+
+	return input, true
+}
+
+func bindMFALoginInput(c *gin.Context) (models.MFALoginInput, bool) {
+	var input models.MFALoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return input, false
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return input, false
+	}
+
+	return input, true
+}
+
+func respondWithMFAError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		// Recorded so the login rate limiter counts it as a failed attempt
+		c.Error(err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Multi-factor authentication is already enabled"})
+	case errors.Is(err, services.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multi-factor authentication is not enabled"})
+	case errors.Is(err, services.ErrMFAEnrollmentNotStarted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multi-factor authentication enrollment has not been started"})
+	case errors.Is(err, services.ErrMFARequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Multi-factor authentication is mandatory for this account"})
+	case errors.Is(err, services.ErrAdminNotFound), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
+
+// respondWithMFAChallenge ends the password step of a login, the client continues with the returned token.
+func respondWithMFAChallenge(c *gin.Context, ts utils.TokenService, accountID primitive.ObjectID, email, role, tokenUse string) {
+	mfaToken, err := ts.GenerateChallengeToken(accountID, email, role, tokenUse)
+	if err != nil {
+		log.Printf("Error generating MFA token: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate MFA token"})
+		return
+	}
+
+	response := gin.H{"mfaToken": mfaToken}
+	if tokenUse == utils.TokenUseMFAEnrollment {
+		response["mfaEnrollmentRequired"] = true
+	} else {
+		response["mfaRequired"] = true
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func respondWithTokens(c *gin.Context, ts utils.TokenService, accountID primitive.ObjectID, email, role string, response gin.H) {
+	accessToken, refreshToken, ok := generateTokens(c, ts, accountID, email, role, "")
+	if !ok {
+		return
+	}
+
+	response["accessToken"] = accessToken
+	response["refreshToken"] = refreshToken
+	c.JSON(http.StatusOK, response)
+}
+
+// generateTokens issues the token pair of a login, writing the error response when it fails.
+func generateTokens(c *gin.Context, ts utils.TokenService, accountID primitive.ObjectID, email, role, sessionID string) (string, string, bool) {
+	accessToken, err := ts.GenerateAccessToken(accountID, email, role, sessionID)
+	if err != nil {
+		log.Printf("Error generating access token: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		return "", "", false
+	}
+
+	refreshToken, err := ts.GenerateRefreshToken(accountID, email, role, sessionID)
+	if err != nil {
+		log.Printf("Error generating refresh token: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		return "", "", false
+	}
+
+	return accessToken, refreshToken, true
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Provider events are small, larger bodies aren't read
+const maxPaymentWebhookSize = 64 << 10
+
+// HandlePaymentWebhook receives the events of the payment provider. Any non 2xx response makes the provider
+// deliver the event again later.
+func (uc *UserController) HandlePaymentWebhook(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPaymentWebhookSize)
+	payload, err := c.GetRawData()
+	if err != nil {
+		log.Printf("Error reading payment webhook: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+		return
+	}
+
+	err = uc.UserService.ProcessPaymentWebhook(c.Request.Context(), payload, c.Request.Header)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
+	case errors.Is(err, services.ErrPaymentEventAlreadyReceived):
+		c.JSON(http.StatusOK, gin.H{"message": "Event already processed"})
+	case errors.Is(err, services.ErrPaymentEventInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "Event is being processed"})
+	case errors.Is(err, utils.ErrInvalidWebhookSignature), errors.Is(err, utils.ErrInvalidWebhookPayload):
+		log.Printf("Rejected payment webhook: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook"})
+	case errors.Is(err, services.ErrPaymentsNotConfigured):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payments are not enabled"})
+	default:
+		log.Printf("Error processing payment webhook: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process event"})
+	}
+}
+
+// HandleAppStoreNotification receives the App Store Server Notifications V2 of the app.
+func (uc *UserController) HandleAppStoreNotification(c *gin.Context) {
+	var body struct {
+		SignedPayload string `json:"signedPayload" binding:"required"`
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPaymentWebhookSize)
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.Printf("Error binding App Store notification: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	respondToStoreNotification(c, uc.UserService.ProcessAppStoreNotification(c.Request.Context(), body.SignedPayload))
+}
+
+// HandlePlayNotification receives the real-time developer notifications Pub/Sub pushes for the app.
+func (uc *UserController) HandlePlayNotification(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPaymentWebhookSize)
+	body, err := c.GetRawData()
+	if err != nil {
+		log.Printf("Error reading Play notification: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+		return
+	}
+
+	respondToStoreNotification(c, uc.UserService.ProcessPlayNotification(c.Request.Context(), body))
+}
+
+func respondToStoreNotification(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Notification processed"})
+	case errors.Is(err, services.ErrPaymentEventAlreadyReceived):
+		c.JSON(http.StatusOK, gin.H{"message": "Notification already processed"})
+	case errors.Is(err, services.ErrPaymentEventInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "Notification is being processed"})
+	case errors.Is(err, utils.ErrInvalidStoreNotification), errors.Is(err, utils.ErrInvalidStoreTransaction):
+		log.Printf("Rejected store notification: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification"})
+	case errors.Is(err, services.ErrStoreNotConfigured):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purchases of this store are not enabled"})
+	default:
+		log.Printf("Error processing store notification: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process notification"})
+	}
+}
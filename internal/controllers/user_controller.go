@@ -79,26 +79,24 @@ func (uc *UserController) Login(c *gin.Context) {
 	user, err := uc.UserService.GetUserByEmail(c.Request.Context(), loginDetails.Email, loginDetails.Password)
 	if err != nil {
 		log.Printf("Error authenticating user: %v\n", err)
+		c.Error(err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
-	
-	accessToken, err := uc.JWTService.GenerateAccessToken(user.ID, user.Email, user.Role)
-	if err != nil {
-		log.Printf("Error generating access token: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
-		return
+
+	// The password proves the account is the user's, the identity of a previous provider login can be linked to it
+	if loginDetails.LinkToken != "" {
+		if err := uc.UserService.LinkPendingIdentity(c.Request.Context(), user.ID, loginDetails.LinkToken); err != nil {
+			respondWithOIDCError(c, err, "to link identity")
+			return
+		}
 	}
 
-	refreshToken, err := uc.JWTService.GenerateRefreshToken(user.ID, user.Email, user.Role)
-	if err != nil {
-		log.Printf("Error generating refresh token: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+	// Users who opted into MFA finish the login with a code
+	if user.MFA != nil && user.MFA.Enabled {
+		respondWithMFAChallenge(c, uc.JWTService, user.ID, user.Email, user.Role, utils.TokenUseMFAChallenge)
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"accessToken":  accessToken,
-		"refreshToken": refreshToken,
-	})
+	
+	uc.respondWithSession(c, user.ID, user.Email, user.Role, gin.H{})
 }
\ No newline at end of file
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (uc *UserController) GetInvoices(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	invoices, err := uc.UserService.GetInvoices(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting invoices: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoices)
+}
+
+// DownloadInvoicePDF sends the receipt of an invoice of the user as a PDF file.
+func (uc *UserController) DownloadInvoicePDF(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	invoiceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing ID: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	invoice, pdf, err := uc.UserService.GetInvoicePDF(c.Request.Context(), userID, invoiceID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvoiceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+			return
+		}
+
+		log.Printf("Error rendering invoice: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "receipt-"+invoice.Number+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
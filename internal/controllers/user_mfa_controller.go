@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyMFALogin completes a login started with an MFA challenge token.
+func (uc *UserController) VerifyMFALogin(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFALoginInput(c)
+	if !ok {
+		return
+	}
+
+	if err := uc.UserService.VerifyMFA(c.Request.Context(), userID, input); err != nil {
+		respondWithMFAError(c, err, "to verify authentication code")
+		return
+	}
+
+	uc.respondWithSession(c, userID, c.GetString("email"), c.GetString("role"), gin.H{})
+}
+
+func (uc *UserController) BeginMFAEnrollment(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := uc.UserService.BeginMFAEnrollment(c.Request.Context(), userID)
+	if err != nil {
+		respondWithMFAError(c, err, "to start MFA enrollment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scan the provisioning URI and confirm with a code", "data": enrollment})
+}
+
+func (uc *UserController) ConfirmMFAEnrollment(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFACodeInput(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := uc.UserService.ConfirmMFAEnrollment(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		respondWithMFAError(c, err, "to confirm MFA enrollment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Multi-factor authentication enabled", "recoveryCodes": recoveryCodes})
+}
+
+func (uc *UserController) DisableMFA(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFACodeInput(c)
+	if !ok {
+		return
+	}
+
+	if err := uc.UserService.DisableMFA(c.Request.Context(), userID, input.Code); err != nil {
+		respondWithMFAError(c, err, "to disable MFA")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Multi-factor authentication disabled"})
+}
+
+func (uc *UserController) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	input, ok := bindMFACodeInput(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := uc.UserService.RegenerateRecoveryCodes(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		respondWithMFAError(c, err, "to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recovery codes regenerated", "recoveryCodes": recoveryCodes})
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// BeginOIDCLogin returns the provider URL to open, and the state the client sends back with the code.
+func (uc *UserController) BeginOIDCLogin(c *gin.Context) {
+	authorization, err := uc.UserService.BeginOIDCLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		respondWithOIDCError(c, err, "to start provider login")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Open the authorization URL to sign in", "data": authorization})
+}
+
+// CompleteOIDCLogin signs in with the code returned by the provider. Identities without an account get a
+// registration token and the profile shared by the provider, to finish with CompleteOIDCRegistration. Identities
+// whose email has an account that never verified it get a link token, to send with the password to Login.
+func (uc *UserController) CompleteOIDCLogin(c *gin.Context) {
+	var input models.OIDCCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	result, err := uc.UserService.CompleteOIDCLogin(c.Request.Context(), c.Param("provider"), input)
+	if err != nil {
+		respondWithOIDCError(c, err, "to sign in with provider")
+		return
+	}
+
+	if result.LinkToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"linkRequired": true,
+			"linkToken":    result.LinkToken,
+			"email":        result.Profile.Email,
+		})
+		return
+	}
+
+	if result.User == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"registrationRequired": true,
+			"registrationToken":    result.RegistrationToken,
+			"profile":              result.Profile,
+		})
+		return
+	}
+
+	user := result.User
+	if user.MFA != nil && user.MFA.Enabled {
+		respondWithMFAChallenge(c, uc.JWTService, user.ID, user.Email, user.Role, utils.TokenUseMFAChallenge)
+		return
+	}
+
+	uc.respondWithSession(c, user.ID, user.Email, user.Role, gin.H{})
+}
+
+// CompleteOIDCRegistration creates the account of a provider login with the missing profile fields and signs it in.
+func (uc *UserController) CompleteOIDCRegistration(c *gin.Context) {
+	var input models.OIDCRegistrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error parsing JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			detailedErrors := make(map[string]string)
+			for _, valErr := range validationErrors {
+				detailedErrors[valErr.StructField()] = valErr.Tag()
+			}
+			log.Printf("Validation errors: %v\n", detailedErrors)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": detailedErrors})
+			return
+		}
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	user, err := uc.UserService.CompleteOIDCRegistration(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
+		case errors.Is(err, services.ErrUsernameAlreadyTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+		default:
+			respondWithOIDCError(c, err, "to register user")
+		}
+		return
+	}
+
+	uc.respondWithSession(c, user.ID, user.Email, user.Role, gin.H{"message": "User registered successfully"})
+}
+
+func respondWithOIDCError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrUnknownOIDCProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login session is invalid or has expired, please try again"})
+	case errors.Is(err, services.ErrInvalidRegistrationToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Registration is invalid or has expired, please sign in again"})
+	case errors.Is(err, services.ErrInvalidLinkToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account link is invalid or has expired, please sign in with the provider again"})
+	case errors.Is(err, services.ErrOIDCEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The identity provider did not confirm your email address"})
+	case errors.Is(err, utils.ErrOIDCTokenExchange), errors.Is(err, utils.ErrInvalidIDToken):
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "The identity provider could not confirm the login"})
+	case errors.Is(err, utils.ErrOIDCDiscovery):
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "The identity provider is unavailable"})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed " + action})
+	}
+}
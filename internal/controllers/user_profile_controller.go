@@ -6,11 +6,34 @@ import (
 	"net/http"
 
 	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/responses"
 	"github.com/GhostDrew11/vigor-api/internal/services"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// GetCurrentUser returns the account of the signed in user, without its credentials.
+func (uc *UserController) GetCurrentUser(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	user, err := uc.UserService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		log.Printf("Error getting user: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewUserSelf(*user))
+}
+
 func (uc *UserController) GetUserProfile(c *gin.Context) {
 	userID, exists := c.Get("userId")
 	if !exists {
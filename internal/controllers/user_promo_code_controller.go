@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RedeemPromoCode applies a promo or gift code to the subscription of the user.
+func (uc *UserController) RedeemPromoCode(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.PromoCodeRedemptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error binding JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	userSubscription, err := uc.UserService.RedeemPromoCode(c.Request.Context(), userID, input.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPromoCodeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+		case errors.Is(err, services.ErrPromoCodeExpired), errors.Is(err, services.ErrPromoCodeExhausted):
+			c.JSON(http.StatusGone, gin.H{"error": "Promo code is no longer valid"})
+		case errors.Is(err, services.ErrPromoCodeAlreadyRedeemed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Promo code was already redeemed"})
+		case errors.Is(err, services.ErrPromoCodeNotApplicable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			respondWithSubscriptionError(c, err, "redeeming promo code")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promo code redeemed successfully", "data": userSubscription})
+}
+
+// PurchaseGift creates the gift code the app then pays for with a checkout.
+func (uc *UserController) PurchaseGift(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.GiftPurchaseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error binding JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	gift, err := uc.UserService.PurchaseGift(c.Request.Context(), userID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrPaymentsNotConfigured) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payments are not enabled"})
+			return
+		}
+
+		log.Printf("Error creating gift code: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create gift"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Gift created, it can be redeemed once paid", "data": gift})
+}
+
+func (uc *UserController) GetPurchasedGifts(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	gifts, err := uc.UserService.GetPurchasedGifts(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting gifts: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get gifts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gifts)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/responses"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeviceNameHeader lets the apps name the device a session is opened on, e.g. "Jane's iPhone".
+const DeviceNameHeader = "X-Device-Name"
+
+const (
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 512
+)
+
+// respondWithSession ends a user login: it opens a session for the requesting device and returns its tokens.
+func (uc *UserController) respondWithSession(c *gin.Context, userID primitive.ObjectID, email, role string, response gin.H) {
+	sessionID := primitive.NewObjectID()
+	accessToken, refreshToken, ok := generateTokens(c, uc.JWTService, userID, email, role, sessionID.Hex())
+	if !ok {
+		return
+	}
+
+	session := models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		DeviceName: truncate(strings.TrimSpace(c.GetHeader(DeviceNameHeader)), maxDeviceNameLength),
+		UserAgent:  truncate(c.Request.UserAgent(), maxUserAgentLength),
+		IP:         c.ClientIP(),
+	}
+	if err := uc.UserService.CreateSession(c.Request.Context(), session, refreshToken); err != nil {
+		if errors.Is(err, services.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended"})
+			return
+		}
+		if errors.Is(err, services.ErrUserBanned) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account banned"})
+			return
+		}
+
+		log.Printf("Error creating session: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	response["accessToken"] = accessToken
+	response["refreshToken"] = refreshToken
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSessions lists the devices the user is signed in on.
+func (uc *UserController) GetSessions(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := uc.UserService.GetSessions(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Error getting sessions: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sessions retrieved successfully", "data": responses.NewSessions(sessions, c.GetString("sessionId"))})
+}
+
+// RevokeSession signs a device out, revoking the current session logs the user out.
+func (uc *UserController) RevokeSession(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := uc.UserService.RevokeSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+
+		log.Printf("Error revoking session: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+func truncate(value string, maxLength int) string {
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength])
+}
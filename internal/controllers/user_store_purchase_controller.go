@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyAppStorePurchase links a subscription bought in the App Store to the user.
+func (uc *UserController) VerifyAppStorePurchase(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.AppStorePurchaseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error binding JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	userSubscription, err := uc.UserService.VerifyAppStorePurchase(c.Request.Context(), userID, input.SignedTransaction)
+	if err != nil {
+		respondWithStorePurchaseError(c, err, "verifying App Store purchase")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase verified successfully", "data": userSubscription})
+}
+
+// VerifyPlayPurchase links a subscription bought in Google Play to the user.
+func (uc *UserController) VerifyPlayPurchase(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input models.PlayPurchaseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Printf("Error binding JSON: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	userSubscription, err := uc.UserService.VerifyPlayPurchase(c.Request.Context(), userID, input.PurchaseToken)
+	if err != nil {
+		respondWithStorePurchaseError(c, err, "verifying Play purchase")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase verified successfully", "data": userSubscription})
+}
+
+func respondWithStorePurchaseError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrStoreNotConfigured):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purchases of this store are not enabled"})
+	case errors.Is(err, utils.ErrInvalidStoreTransaction), errors.Is(err, utils.ErrStorePurchaseNotFound):
+		log.Printf("Rejected store purchase: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase"})
+	case errors.Is(err, utils.ErrStorePurchasePending):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase is not paid yet"})
+	case errors.Is(err, services.ErrUnknownStoreProduct):
+		log.Printf("Rejected store purchase: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Product is not a subscription plan"})
+	case errors.Is(err, services.ErrStorePurchaseExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase has expired"})
+	case errors.Is(err, services.ErrStorePurchaseOwned):
+		c.JSON(http.StatusConflict, gin.H{"error": "Purchase belongs to another account"})
+	default:
+		respondWithSubscriptionError(c, err, action)
+	}
+}
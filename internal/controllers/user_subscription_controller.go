@@ -41,7 +41,8 @@ func (uc *UserController) GetUserSubsctiption(c *gin.Context) {
 	c.JSON(http.StatusOK, userSubscription)
 }
 
-func (uc *UserController) UpdateUserSubscription(c *gin.Context) {
+// RequestSubscriptionChange lets the user ask for another plan, the subscription itself is managed by the server.
+func (uc *UserController) RequestSubscriptionChange(c *gin.Context) {
 	userID, exists := c.Get("userId")
 	if !exists {
 		log.Printf("Error retrieving userID from context\n")
@@ -56,26 +57,26 @@ func (uc *UserController) UpdateUserSubscription(c *gin.Context) {
 		return
 	}
 
-	var updateInput models.UserSubscriptionUpdateInput
-	if err := c.ShouldBindJSON(&updateInput); err != nil {
+	var input models.SubscriptionChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
 		log.Printf("Error binding JSON: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	userSubscription, err := uc.UserService.UpdateUserSubscription(c.Request.Context(), objID, updateInput)
-	if err != nil {
-		if errors.Is(err, services.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
+	if err := validate.Struct(input); err != nil {
+		log.Printf("Error validating input: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
 
-		log.Printf("Error updating user subscription: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user subscription"})
+	userSubscription, err := uc.UserService.RequestSubscriptionChange(c.Request.Context(), objID, input)
+	if err != nil {
+		respondWithSubscriptionError(c, err, "requesting subscription change")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User subscription updated successfully", "data": userSubscription})
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription change requested successfully", "data": userSubscription})
 }
 
 func (uc *UserController) CancelUserSubscription(c *gin.Context) {
@@ -95,15 +96,85 @@ func (uc *UserController) CancelUserSubscription(c *gin.Context) {
 
 	userSubscription, err := uc.UserService.CancelUserSubscription(c.Request.Context(), objID)
 	if err != nil {
-		if errors.Is(err, services.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-			return
-		}
-
-		log.Printf("Error cancelling user subscription: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel user subscription"})
+		respondWithSubscriptionError(c, err, "cancelling user subscription")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User subscription cancelled successfully", "data": userSubscription})
-}
\ No newline at end of file
+}
+
+// respondWithSubscriptionError maps the errors of the subscription lifecycle, shared with the admin handlers.
+func respondWithSubscriptionError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrInvalidSubscriptionTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSubscriptionChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": "Subscription was changed by another request, try again"})
+	default:
+		log.Printf("Error %s: %v\n", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user subscription"})
+	}
+}
+
+// Codes of the entitlement errors, the app turns them into an upsell
+const (
+	entitlementCodeSubscriptionRequired = "subscription_required"
+	entitlementCodeUpgradeRequired      = "upgrade_required"
+)
+
+// respondWithEntitlementError writes the response when content isn't covered by the user's subscription,
+// returning false for any other error: 402 without subscription or trial, 403 when another plan is needed.
+func respondWithEntitlementError(c *gin.Context, err error) bool {
+	var entitlementErr *services.EntitlementError
+	if !errors.As(err, &entitlementErr) {
+		return false
+	}
+
+	status, code, message := http.StatusForbidden, entitlementCodeUpgradeRequired, "Your subscription plan does not include this content"
+	if errors.Is(err, services.ErrSubscriptionRequired) {
+		status, code, message = http.StatusPaymentRequired, entitlementCodeSubscriptionRequired, "An active subscription is required"
+	}
+	c.JSON(status, gin.H{
+		"error":        message,
+		"code":         code,
+		"requiredTier": entitlementErr.RequiredTier,
+		"currentTier":  entitlementErr.CurrentTier,
+	})
+	return true
+}
+
+// refuseUncoveredCatalogContent answers with the entitlement error when the user reading the catalog can't open
+// content of the tier. Integrations and admin routes don't load a tier and are let through.
+func refuseUncoveredCatalogContent(c *gin.Context, requiredTier string) bool {
+	value, exists := c.Get("accessTier")
+	if !exists {
+		return false
+	}
+
+	currentTier := value.(string)
+	if requiredTier == "" {
+		requiredTier = models.AccessTierBasic
+	}
+	if models.TierCovers(currentTier, requiredTier) {
+		return false
+	}
+	return respondWithEntitlementError(c, &services.EntitlementError{RequiredTier: requiredTier, CurrentTier: currentTier})
+}
+
+// coveredCatalogContent drops the content the user reading the catalog can't open from a listing.
+func coveredCatalogContent[T any](c *gin.Context, items []T, accessTier func(T) string) []T {
+	value, exists := c.Get("accessTier")
+	if !exists {
+		return items
+	}
+
+	covered := make([]T, 0, len(items))
+	for _, item := range items {
+		if models.TierCovers(value.(string), accessTier(item)) {
+			covered = append(covered, item)
+		}
+	}
+	return covered
+}
@@ -72,6 +72,9 @@ func (uc *UserController) JoinWorkoutPlan(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "User has already joined this workout plan"})
 			return
 		}
+		if respondWithEntitlementError(c, err) {
+			return
+		}
 
 		log.Printf("Error joining workout plan: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join workout plan"})
@@ -39,8 +39,8 @@ func (uc *UserController) GetStandardWorkoutPlan(c *gin.Context) {
 		return
 	}
 
-	//Get Standard WorkoutPlan
-	standardWorkoutPlan, err := uc.UserService.GetWorkoutPlanByID(c.Request.Context(), activeWorkoutPlan.WorkoutPlanID)
+	//Get Standard WorkoutPlan, as of the version the user joined
+	standardWorkoutPlan, err := uc.UserService.GetWorkoutPlanVersion(c.Request.Context(), activeWorkoutPlan.WorkoutPlanID, activeWorkoutPlan.Version)
 	if err != nil {
 		if errors.Is(err, services.ErrWorkoutPlanNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Workout plan not found"})
@@ -56,6 +56,11 @@ func (uc *UserController) GetStandardWorkoutPlan(c *gin.Context) {
 }
 
 func (uc *UserController) GetDailyExercisesByIDs(c *gin.Context) {
+	userID, ok := accountIDFromContext(c)
+	if !ok {
+		return
+	}
+
 	//Get the daily exercises by ID's sent in the request body
 	var requestBody struct {
 		DailyExercisesIDs []primitive.ObjectID `json:"dailyExercisesIDs"`
@@ -67,8 +72,19 @@ func (uc *UserController) GetDailyExercisesByIDs(c *gin.Context) {
 		return
 	}
 
-	dailyExercises, err := uc.UserService.GetDailyExercisesByIDs(c.Request.Context(), requestBody.DailyExercisesIDs)
+	dailyExercises, err := uc.UserService.GetDailyExercisesByIDs(c.Request.Context(), userID, requestBody.DailyExercisesIDs)
 	if err != nil {
+		if respondWithEntitlementError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrActiveWorkoutPlanNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User has no active workout plan"})
+			return
+		}
+		if errors.Is(err, services.ErrExerciseNotInWorkoutPlan) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Exercise is not part of your workout plan"})
+			return
+		}
 		log.Printf("Error getting daily exercises: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get daily exercises"})
 		return
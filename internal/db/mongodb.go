@@ -50,10 +50,21 @@ func (ms *MongoDBService) EnsureIndexes(ctx context.Context, db MongoDatabase) e
 		"users": {
 			{Keys: bson.M{"email": 1}, Options: options.Index().SetUnique(true)},
 			{Keys: bson.M{"profileInformation.username": 1}, Options: options.Index().SetUnique(true)},
+			// Only users with a linked provider are indexed, the others would all collide on a missing field
+			{Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"identities": bson.M{"$exists": true}})},
+			{Keys: bson.M{"subscription.providerSubscriptionId": 1}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"subscription.providerSubscriptionId": bson.M{"$exists": true}})},
+			// Lifecycle jobs and analytics select subscriptions by status and dates
+			{Keys: bson.D{{Key: "subscription.status", Value: 1}, {Key: "subscription.endDate", Value: 1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.M{"subscription.convertedAt": 1}, Options: options.Index().SetUnique(false).SetPartialFilterExpression(bson.M{"subscription.convertedAt": bson.M{"$exists": true}})},
+			{Keys: bson.M{"trialEndsAt": 1}, Options: options.Index().SetUnique(false)},
 		},
 		"admins": {
 			{Keys: bson.M{"email": 1}, Options: options.Index().SetUnique(true)},
 		},
+		"adminInvitations": {
+			{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"email": 1}, Options: options.Index().SetUnique(false)},
+		},
 		"meals": {
 			{Keys: bson.M{"name": 1}, Options: options.Index().SetUnique(true)},
 		},
@@ -83,9 +94,21 @@ func (ms *MongoDBService) EnsureIndexes(ctx context.Context, db MongoDatabase) e
 		},
 		"workoutPlans": {
 				{Keys: bson.M{"name": 1}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.M{"status": 1}, Options: options.Index().SetUnique(false)},
+		},
+		"workoutTemplates": {
+			{Keys: bson.M{"name": 1}, Options: options.Index().SetUnique(true)},
+		},
+		"workoutPlanVersions": {
+			{Keys: bson.D{{Key: "workoutPlanId", Value: 1}, {Key: "version", Value: -1}}, Options: options.Index().SetUnique(true)},
+		},
+		"mealPlans": {
+			{Keys: bson.M{"status": 1}, Options: options.Index().SetUnique(false)},
 		},
 		"userWorkoutPlanStatus": {
 			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "workoutPlanId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			// Engagement analytics select the enrollments of a plan by join date
+			{Keys: bson.D{{Key: "workoutPlanId", Value: 1}, {Key: "startDate", Value: 1}}, Options: options.Index().SetUnique(false)},
 		},
 		"userDailyNutritionalLogs": {
 			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "date", Value: 1}}, Options: options.Index().SetUnique(true)},
@@ -100,6 +123,77 @@ func (ms *MongoDBService) EnsureIndexes(ctx context.Context, db MongoDatabase) e
 			{Keys: bson.M{"conversationId": 1}, Options: options.Index().SetUnique(false)},
 			{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "sentAt", Value: 1}}, Options: options.Index().SetUnique(false)},
 		},
+		"rateLimits": {
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"loginAttempts": {
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"oidcLoginStates": {
+			{Keys: bson.M{"stateHash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"pendingRegistrations": {
+			{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"pendingIdentityLinks": {
+			{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"apiKeys": {
+			{Keys: bson.M{"keyHash": 1}, Options: options.Index().SetUnique(true)},
+		},
+		"sessions": {
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "lastUsedAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"paymentEvents": {
+			// Providers stop redelivering after a few days, older events can go
+			{Keys: bson.M{"processedAt": 1}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+			// Claims never marked done once the provider stopped redelivering the event
+			{Keys: bson.M{"claimedAt": 1}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+		},
+		"promoCodes": {
+			{Keys: bson.M{"code": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "createdBy", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+		},
+		"promoRedemptions": {
+			// A user redeems each code once
+			{Keys: bson.D{{Key: "codeId", Value: 1}, {Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "codeId", Value: 1}, {Key: "redeemedAt", Value: 1}}, Options: options.Index().SetUnique(false)},
+		},
+		"invoices": {
+			// A payment event creates one invoice at most, even when it is redelivered after paymentEvents forgot it
+			{Keys: bson.M{"paymentEventId": 1}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "issuedAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.M{"issuedAt": 1}, Options: options.Index().SetUnique(false)},
+		},
+		"subscriptionEvents": {
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+		},
+		"jobRuns": {
+			{Keys: bson.D{{Key: "job", Value: 1}, {Key: "startedAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+			// The history of the last month is enough to follow the jobs
+			{Keys: bson.M{"startedAt": 1}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+		},
+		"cohortReports": {
+			{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"impersonations": {
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.D{{Key: "adminId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+		},
+		"impersonatedRequests": {
+			{Keys: bson.D{{Key: "impersonationId", Value: 1}, {Key: "createdAt", Value: 1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+		},
+		"auditLogs": {
+			{Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.D{{Key: "actorId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.D{{Key: "targetCollection", Value: 1}, {Key: "targetId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetUnique(false)},
+			{Keys: bson.M{"requestId": 1}, Options: options.Index().SetUnique(false)},
+		},
 	}
 
 	for collection, indexes := range collections {
@@ -131,6 +225,8 @@ func (ms *MongoDBService) InitializeCollections(ctx context.Context, db MongoDat
 	}{
 		{"users", "schemas/user/userSchema.json"},
 		{"admins", "schemas/user/adminSchema.json"},
+		{"adminInvitations", "schemas/user/adminInvitationSchema.json"},
+		{"roles", "schemas/user/roleSchema.json"},
 		{"exercises", "schemas/workoutPlan/exerciseSchema.json"},
 		{"userExerciseStatus", "schemas/workoutPlan/userExerciseStatusSchema.json"},
 		{"userCircuitStatus", "schemas/workoutPlan/userCircuitStatusSchema.json"},
@@ -138,15 +234,37 @@ func (ms *MongoDBService) InitializeCollections(ctx context.Context, db MongoDat
 		{"userWorkoutWeekStatus", "schemas/workoutPlan/userWorkoutWeekStatusSchema.json"},
 		{"userWorkoutPlanStatus", "schemas/workoutPlan/userWorkoutPlanStatusSchema.json"},
 		{"workoutPlans", "schemas/workoutPlan/workoutPlanSchema.json"},
+		{"workoutPlanDrafts", "schemas/workoutPlan/workoutPlanSchema.json"},
+		{"workoutPlanVersions", "schemas/workoutPlan/workoutPlanVersionSchema.json"},
+		{"workoutTemplates", "schemas/workoutPlan/workoutTemplateSchema.json"},
 		{"meals", "schemas/mealPlan/mealSchema.json"},
 		{"userMealStatus", "schemas/mealPlan/userMealStatusSchema.json"},
 		{"userWeeklyMealPlanStatus", "schemas/mealPlan/userWeeklyPlanStatusSchema.json"},
 		{"userMealPlanStatus", "schemas/mealPlan/userMealPlanStatusSchema.json"},
 		{"mealPlans", "schemas/mealPlan/mealPlanSchema.json"},
+		{"mealPlanDrafts", "schemas/mealPlan/mealPlanSchema.json"},
 		{"userDailyNutritionalLogs", "schemas/mealPlan/userDailyNutritionalLogSchema.json"},
 		{"messages", "schemas/messaging/messageSchema.json"},
 		{"conversations", "schemas/messaging/conversationSchema.json"},
 		{"groups", "schemas/messaging/groupSchema.json"},
+		{"rateLimits", "schemas/security/rateLimitSchema.json"},
+		{"loginAttempts", "schemas/security/loginAttemptSchema.json"},
+		{"auditLogs", "schemas/security/auditLogSchema.json"},
+		{"impersonations", "schemas/security/impersonationSchema.json"},
+		{"impersonatedRequests", "schemas/security/impersonatedRequestSchema.json"},
+		{"oidcLoginStates", "schemas/security/oidcLoginStateSchema.json"},
+		{"pendingRegistrations", "schemas/user/pendingRegistrationSchema.json"},
+		{"pendingIdentityLinks", "schemas/user/pendingIdentityLinkSchema.json"},
+		{"sessions", "schemas/user/sessionSchema.json"},
+		{"apiKeys", "schemas/security/apiKeySchema.json"},
+		{"paymentEvents", "schemas/security/paymentEventSchema.json"},
+		{"promoCodes", "schemas/billing/promoCodeSchema.json"},
+		{"promoRedemptions", "schemas/billing/promoRedemptionSchema.json"},
+		{"invoices", "schemas/billing/invoiceSchema.json"},
+		{"subscriptionEvents", "schemas/billing/subscriptionEventSchema.json"},
+		{"jobLocks", "schemas/jobs/jobLockSchema.json"},
+		{"jobRuns", "schemas/jobs/jobRunSchema.json"},
+		{"cohortReports", "schemas/analytics/cohortReportSchema.json"},
 	}
 
 	for _, s := range schemas {
@@ -43,13 +43,17 @@ type MongoDatabase interface {
 type MongoCollection interface {
 	CountDocuments(ctx context.Context, filter interface{}) (int64, error)
 	Indexes() MongoIndexView
-	Find(ctx context.Context, filter interface{}) (MongoCursor, error)
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (MongoCursor, error)
 	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) MongoSingleResult
+	Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (MongoCursor, error)
 	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) MongoSingleResult
+	FindOneAndDelete(ctx context.Context, filter interface{}) MongoSingleResult
 	InsertMany(ctx context.Context, documents []interface{}) (MongoInsertManyResult, error)
 	InsertOne(ctx context.Context, document interface{}) (MongoInsertOneResult, error)
 	UpdateOne(ctx context.Context, filter interface{}, update interface{}) (MongoUpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}) (MongoUpdateResult, error)
 	DeleteOne(ctx context.Context, filter interface{}) (MongoDeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}) (MongoDeleteResult, error)
 }
 
 type MongoSingleResult interface {
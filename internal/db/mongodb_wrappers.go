@@ -89,8 +89,16 @@ func (mdc *mongoCollectionWrapper) Indexes() MongoIndexView {
 	return &mongoIndexViewWrapper{indexView: mdc.collection.Indexes()}
 }
 
-func (mdc *mongoCollectionWrapper) Find(ctx context.Context, filter interface{}) (MongoCursor, error) {
-	cursor, err := mdc.collection.Find(ctx, filter)
+func (mdc *mongoCollectionWrapper) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (MongoCursor, error) {
+	cursor, err := mdc.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mongoCursorWrapper{cursor: cursor}, nil
+}
+
+func (mdc *mongoCollectionWrapper) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (MongoCursor, error) {
+	cursor, err := mdc.collection.Aggregate(ctx, pipeline, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +113,10 @@ func (mdc *mongoCollectionWrapper) FindOneAndUpdate(ctx context.Context, filter
 	return &mongoSingleResultWrapper{singleResult: mdc.collection.FindOneAndUpdate(ctx, filter, update, opts...)}
 }
 
+func (mdc *mongoCollectionWrapper) FindOneAndDelete(ctx context.Context, filter interface{}) MongoSingleResult {
+	return &mongoSingleResultWrapper{singleResult: mdc.collection.FindOneAndDelete(ctx, filter)}
+}
+
 func (mdc *mongoCollectionWrapper) InsertMany(ctx context.Context, documents []interface{}) (MongoInsertManyResult, error) {
 	result, err := mdc.collection.InsertMany(ctx, documents)
 	if err != nil {
@@ -134,6 +146,19 @@ func (mdc *mongoCollectionWrapper) UpdateOne(ctx context.Context, filter interfa
 	}, nil
 }
 
+func (mdc *mongoCollectionWrapper) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (MongoUpdateResult, error) {
+	result, err := mdc.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return MongoUpdateResult{}, err
+	}
+	return MongoUpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+		UpsertedCount: result.UpsertedCount,
+		UpsertedID:    result.UpsertedID,
+	}, nil
+}
+
 func (mdc *mongoCollectionWrapper) DeleteOne(ctx context.Context, filter interface{}) (MongoDeleteResult, error) {
 	result, err := mdc.collection.DeleteOne(ctx, filter)
 	if err != nil {
@@ -142,6 +167,14 @@ func (mdc *mongoCollectionWrapper) DeleteOne(ctx context.Context, filter interfa
 	return MongoDeleteResult{DeletedCount: result.DeletedCount}, nil
 }
 
+func (mdc *mongoCollectionWrapper) DeleteMany(ctx context.Context, filter interface{}) (MongoDeleteResult, error) {
+	result, err := mdc.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return MongoDeleteResult{}, err
+	}
+	return MongoDeleteResult{DeletedCount: result.DeletedCount}, nil
+}
+
 type mongoIndexViewWrapper struct {
 	indexView mongo.IndexView
 }
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AdminStatusChecker interface {
+	GetActiveAdminRole(ctx context.Context, adminID primitive.ObjectID) (string, error)
+}
+
+// RequireActiveAdmin rejects tokens of users and of admins that were disabled or deleted after the token was issued.
+// It replaces the role from the token with the current one and records the admin as the actor of the request.
+// Must run after Authenticate.
+func RequireActiveAdmin(checker AdminStatusChecker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		adminID, ok := ctx.MustGet("userId").(primitive.ObjectID)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+			return
+		}
+
+		role, err := checker.GetActiveAdminRole(ctx.Request.Context(), adminID)
+		if err != nil {
+			log.Printf("Error checking admin status: %v\n", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to check admin status"})
+			return
+		}
+
+		if role == "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden"})
+			return
+		}
+
+		ctx.Set("role", role)
+
+		metadata := utils.RequestMetadataFromContext(ctx.Request.Context())
+		metadata.ActorID = adminID
+		metadata.ActorRole = role
+		ctx.Request = ctx.Request.WithContext(utils.WithRequestMetadata(ctx.Request.Context(), metadata))
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the key of integrations, people keep using bearer tokens.
+const APIKeyHeader = "X-API-Key"
+
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// RequireAPIKeyOrRole accepts either an API key or an access token of the given roles.
+// Requests made with a key must also pass RequireScope on each route.
+func RequireAPIKeyOrRole(ts utils.TokenService, sessions SessionChecker, keys APIKeyAuthenticator, requiredRoles ...string) gin.HandlerFunc {
+	requireRole := RequireRole(ts, sessions, requiredRoles...)
+
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(APIKeyHeader)
+		if key == "" {
+			requireRole(ctx)
+			return
+		}
+
+		apiKey, err := keys.AuthenticateAPIKey(ctx.Request.Context(), key)
+		if err != nil {
+			log.Printf("Error authenticating API key: %v\n", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to check API key"})
+			return
+		}
+
+		if apiKey == nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+			return
+		}
+
+		ctx.Set("apiKey", apiKey)
+		ctx.Next()
+	}
+}
+
+// RequireScope checks that the API key of the request grants every listed scope. Requests authenticated with
+// an access token already passed the role check and are let through.
+func RequireScope(requiredScopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, exists := ctx.Get("apiKey")
+		if !exists {
+			ctx.Next()
+			return
+		}
+
+		apiKey := value.(*models.APIKey)
+		for _, scope := range requiredScopes {
+			if !apiKey.HasScope(scope) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden", "missingScope": scope})
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
@@ -1,25 +1,55 @@
 package middlewares
 
 import (
+	"context"
+	"log"
 	"net/http"
 
 	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-func RequireRole(ts utils.TokenService, requiredRoles ...string) gin.HandlerFunc {
+// SessionChecker looks up the user session a token was issued for and the restriction on the user, so revoked
+// sessions and suspended or banned users lose access right away.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, userID primitive.ObjectID, sessionID string) (bool, error)
+	GetAccountRestriction(ctx context.Context, userID primitive.ObjectID) (string, error)
+	CheckSessionRefreshToken(ctx context.Context, userID primitive.ObjectID, sessionID, refreshToken string) (bool, error)
+	RotateSessionRefreshToken(ctx context.Context, userID primitive.ObjectID, sessionID, currentToken, newToken string) (bool, error)
+}
+
+// Every user login opens a session, tokens of users without one predate sessions and must sign in again.
+// Admin tokens have no session, neither have the short-lived impersonation tokens.
+func requiresSession(claims *utils.Claims) bool {
+	if claims.IsImpersonation() {
+		return false
+	}
+	return claims.SessionID != "" || claims.Role == "user"
+}
+
+// Authenticate accepts any valid access token and sets the user info in the context.
+// Impersonation tokens are refused, they only give access to the user routes.
+func Authenticate(ts utils.TokenService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token := ctx.GetHeader("Authorization")
-		if token == "" {
+		claims, ok := authenticate(ctx, ts)
+		if !ok {
+			return
+		}
+		if claims.IsImpersonation() {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
 			return
 		}
+		ctx.Next()
+	}
+}
 
-		token = token[7:] // Remove "Bearer " from the token
-
-		claims, err := ts.VerifyToken(token)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+// RequireRole accepts access tokens of the given roles. With a session checker the session of the token must still be
+// active and the user must not be suspended or banned.
+func RequireRole(ts utils.TokenService, sessions SessionChecker, requiredRoles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := authenticate(ctx, ts)
+		if !ok {
 			return
 		}
 
@@ -37,15 +67,130 @@ func RequireRole(ts utils.TokenService, requiredRoles ...string) gin.HandlerFunc
 			return
 		}
 
-		// Set user info and role in the context
+		if sessions != nil && requiresSession(claims) {
+			active, err := sessions.IsSessionActive(ctx.Request.Context(), claims.UserId, claims.SessionID)
+			if err != nil {
+				log.Printf("Error checking session: %v\n", err)
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to check session"})
+				return
+			}
+			if !active {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Session expired or revoked"})
+				return
+			}
+
+			restriction, err := sessions.GetAccountRestriction(ctx.Request.Context(), claims.UserId)
+			if err != nil {
+				log.Printf("Error checking account restriction: %v\n", err)
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to check account status"})
+				return
+			}
+			if restriction != "" {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Account " + restriction})
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+func authenticate(ctx *gin.Context, ts utils.TokenService) (*utils.Claims, bool) {
+	token := ctx.GetHeader("Authorization")
+	if len(token) <= 7 {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return nil, false
+	}
+
+	token = token[7:] // Remove "Bearer " from the token
+
+	claims, err := ts.VerifyToken(token)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return nil, false
+	}
+
+	// Refresh and MFA challenge tokens can't be used to call the API
+	if claims.TokenUse != "" && claims.TokenUse != utils.TokenUseAccess && !claims.IsImpersonation() {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		return nil, false
+	}
+
+	// Set user info and role in the context
+	ctx.Set("userId", claims.UserId)
+	ctx.Set("email", claims.Email)
+	ctx.Set("role", claims.Role)
+	ctx.Set("sessionId", claims.SessionID)
+	if claims.IsImpersonation() {
+		ctx.Set("impersonatorId", *claims.ImpersonatorID)
+		ctx.Set("impersonationId", claims.ID)
+		ctx.Set("impersonationReadOnly", claims.ReadOnly)
+	}
+	return claims, true
+}
+
+// RequireChallengeToken guards the MFA login steps, which only accept the short-lived token issued by Login.
+// Without requiredRoles any role is accepted, admin roles are defined in the database.
+func RequireChallengeToken(ts utils.TokenService, tokenUse string, requiredRoles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.GetHeader("Authorization")
+		if len(token) <= 7 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+			return
+		}
+
+		claims, err := ts.VerifyToken(token[7:])
+		if err != nil || claims.TokenUse != tokenUse {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+			return
+		}
+
+		roleIsAllowed := len(requiredRoles) == 0
+		for _, role := range requiredRoles {
+			if claims.Role == role {
+				roleIsAllowed = true
+				break
+			}
+		}
+
+		if !roleIsAllowed {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden"})
+			return
+		}
+
 		ctx.Set("userId", claims.UserId)
 		ctx.Set("email", claims.Email)
 		ctx.Set("role", claims.Role)
+		ctx.Set("tokenUse", claims.TokenUse)
 		ctx.Next()
 	}
 }
 
-func RefreshAccessTokenHandler(ts utils.TokenService) gin.HandlerFunc {
+func isRefreshToken(claims *utils.Claims) bool {
+	return claims.TokenUse == "" || claims.TokenUse == utils.TokenUseRefresh
+}
+
+// checkRefreshSession rejects refresh tokens of revoked sessions and refresh tokens that were already rotated.
+func checkRefreshSession(ctx *gin.Context, sessions SessionChecker, claims *utils.Claims, refreshToken string) bool {
+	if sessions == nil || !requiresSession(claims) {
+		return true
+	}
+
+	valid, err := sessions.CheckSessionRefreshToken(ctx.Request.Context(), claims.UserId, claims.SessionID, refreshToken)
+	if err != nil {
+		log.Printf("Error checking session: %v\n", err)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to check session"})
+		return false
+	}
+	if !valid {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+		return false
+	}
+
+	return true
+}
+
+func RefreshAccessTokenHandler(ts utils.TokenService, sessions SessionChecker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		refreshToken := ctx.GetHeader("Refresh-Token")
 		if refreshToken == "" {
@@ -54,12 +199,16 @@ func RefreshAccessTokenHandler(ts utils.TokenService) gin.HandlerFunc {
 		}
 
 		claims, err := ts.VerifyToken(refreshToken)
-		if err != nil {
+		if err != nil || !isRefreshToken(claims) {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
 			return
 		}
 
-		newAccessToken, err := ts.GenerateAccessToken(claims.UserId, claims.Email, claims.Role)
+		if !checkRefreshSession(ctx, sessions, claims, refreshToken) {
+			return
+		}
+
+		newAccessToken, err := ts.GenerateAccessToken(claims.UserId, claims.Email, claims.Role, claims.SessionID)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate new access token"})
 			return
@@ -72,7 +221,8 @@ func RefreshAccessTokenHandler(ts utils.TokenService) gin.HandlerFunc {
 	}
 }
 
-func RenewRefreshTokenHandler(ts utils.TokenService) gin.HandlerFunc {
+// RenewRefreshTokenHandler rotates the refresh token, the previous one stops working for sessions.
+func RenewRefreshTokenHandler(ts utils.TokenService, sessions SessionChecker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		refreshToken := ctx.GetHeader("Refresh-Token")
 		if refreshToken == "" {
@@ -81,17 +231,35 @@ func RenewRefreshTokenHandler(ts utils.TokenService) gin.HandlerFunc {
 		}
 
 		claims, err := ts.VerifyToken(refreshToken)
-		if err != nil {
+		if err != nil || !isRefreshToken(claims) {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
 			return
 		}
 
-		newRefreshToken, err := ts.GenerateRefreshToken(claims.UserId, claims.Email, claims.Role)
+		if !checkRefreshSession(ctx, sessions, claims, refreshToken) {
+			return
+		}
+
+		newRefreshToken, err := ts.GenerateRefreshToken(claims.UserId, claims.Email, claims.Role, claims.SessionID)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate new refresh token"})
 			return
 		}
 
+		if sessions != nil && claims.SessionID != "" {
+			rotated, err := sessions.RotateSessionRefreshToken(ctx.Request.Context(), claims.UserId, claims.SessionID, refreshToken, newRefreshToken)
+			if err != nil {
+				log.Printf("Error rotating session refresh token: %v\n", err)
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate new refresh token"})
+				return
+			}
+			// Another request rotated the same token first
+			if !rotated {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid refresh token"})
+				return
+			}
+		}
+
 		// Return new refresh toke to the client
 		ctx.JSON(http.StatusOK, gin.H{
 			"refreshToken": newRefreshToken,
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AccessTierReader interface {
+	GetAccessTier(ctx context.Context, userID primitive.ObjectID) (string, error)
+}
+
+// LoadAccessTier keeps the tier of content the user can open in the context, for the catalog routes that hide or
+// refuse the content it doesn't cover. Integrations authenticated with an API key aren't limited by a subscription.
+// Must run after RequireAPIKeyOrRole.
+func LoadAccessTier(tiers AccessTierReader) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := ctx.Get("userId")
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		tier, err := tiers.GetAccessTier(ctx.Request.Context(), userID.(primitive.ObjectID))
+		if err != nil {
+			log.Printf("Error getting access tier: %v\n", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to check subscription"})
+			return
+		}
+
+		ctx.Set("accessTier", tier)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImpersonationRecorder interface {
+	RecordImpersonatedRequest(ctx context.Context, request models.ImpersonatedRequest)
+}
+
+// GuardImpersonation refuses the writes of read-only impersonation tokens and records every request made with an
+// impersonation token once it is answered. Requests made by the users themselves go through untouched.
+// readRoutes lists the full paths of routes that don't change anything despite their method. Must run after RequireRole.
+func GuardImpersonation(recorder ImpersonationRecorder, readRoutes ...string) gin.HandlerFunc {
+	reads := make(map[string]bool, len(readRoutes))
+	for _, route := range readRoutes {
+		reads[route] = true
+	}
+
+	return func(ctx *gin.Context) {
+		impersonatorID, ok := ctx.Get("impersonatorId")
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		defer func() {
+			// The token ID was issued as the hex impersonation ID
+			impersonationID, _ := primitive.ObjectIDFromHex(ctx.GetString("impersonationId"))
+			userID, _ := ctx.Get("userId")
+			request := models.ImpersonatedRequest{
+				ID:              primitive.NewObjectID(),
+				ImpersonationID: impersonationID,
+				AdminID:         impersonatorID.(primitive.ObjectID),
+				UserID:          userID.(primitive.ObjectID),
+				Method:          ctx.Request.Method,
+				Path:            ctx.Request.URL.Path,
+				Status:          ctx.Writer.Status(),
+				RequestID:       utils.RequestMetadataFromContext(ctx.Request.Context()).RequestID,
+				IP:              ctx.ClientIP(),
+				CreatedAt:       time.Now(),
+			}
+			recorder.RecordImpersonatedRequest(ctx.Request.Context(), request)
+		}()
+
+		if ctx.GetBool("impersonationReadOnly") && !isReadOnlyMethod(ctx.Request.Method) && !reads[ctx.FullPath()] {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Impersonation is read-only"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionResolver interface {
+	PermissionsForRole(ctx context.Context, role string) ([]string, error)
+}
+
+// RequirePermission lets the request through when the role in the context grants every listed permission.
+func RequirePermission(resolver PermissionResolver, requiredPermissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		permissions, err := resolver.PermissionsForRole(ctx.Request.Context(), ctx.GetString("role"))
+		if err != nil {
+			log.Printf("Error resolving permissions: %v\n", err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to resolve permissions"})
+			return
+		}
+
+		granted := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			granted[permission] = true
+		}
+
+		for _, permission := range requiredPermissions {
+			if !granted[permission] {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Forbidden", "missingPermission": permission})
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
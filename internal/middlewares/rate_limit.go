@@ -0,0 +1,149 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig configures the limits applied to a route group.
+// PerAccount and Lockout key on the email of an MFA challenge token when one was checked first,
+// otherwise on the "email" field of the JSON request body.
+type RateLimitConfig struct {
+	Name       string // Namespace for the bucket keys, so groups don't share buckets.
+	PerIP      services.RateLimitRule
+	PerAccount *services.RateLimitRule
+	Lockout    *services.LockoutPolicy
+}
+
+func RateLimit(store services.RateLimitStore, cfg RateLimitConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ipResult, err := store.Take(ctx.Request.Context(), cfg.Name+":ip:"+ctx.ClientIP(), cfg.PerIP)
+		if err != nil {
+			// Fail open, an unavailable store shouldn't take the whole API down with it
+			log.Printf("Error checking rate limit: %v\n", err)
+			ctx.Next()
+			return
+		}
+		setRateLimitHeaders(ctx, ipResult)
+		if !ipResult.Allowed {
+			abortTooManyRequests(ctx, ipResult.RetryAfter, "Too many requests")
+			return
+		}
+
+		account := ""
+		if cfg.PerAccount != nil || cfg.Lockout != nil {
+			account = accountFromRequest(ctx)
+		}
+
+		lockoutKey := cfg.Name + ":lockout:" + account
+		if account != "" && cfg.Lockout != nil {
+			lockedUntil, err := store.LockedUntil(ctx.Request.Context(), lockoutKey)
+			if err != nil {
+				log.Printf("Error checking account lockout: %v\n", err)
+			} else if time.Now().Before(lockedUntil) {
+				abortTooManyRequests(ctx, time.Until(lockedUntil), "Too many failed login attempts, try again later")
+				return
+			}
+		}
+
+		if account != "" && cfg.PerAccount != nil {
+			accountResult, err := store.Take(ctx.Request.Context(), cfg.Name+":account:"+account, *cfg.PerAccount)
+			if err != nil {
+				log.Printf("Error checking rate limit: %v\n", err)
+			} else {
+				// Report whichever bucket is closest to running out
+				if !accountResult.Allowed || accountResult.Remaining < ipResult.Remaining {
+					setRateLimitHeaders(ctx, accountResult)
+				}
+				if !accountResult.Allowed {
+					abortTooManyRequests(ctx, accountResult.RetryAfter, "Too many requests")
+					return
+				}
+			}
+		}
+
+		ctx.Next()
+
+		if account == "" || cfg.Lockout == nil {
+			return
+		}
+
+		if hasInvalidCredentialsError(ctx) {
+			if _, err := store.RegisterFailure(ctx.Request.Context(), lockoutKey, *cfg.Lockout); err != nil {
+				log.Printf("Error registering failed login attempt: %v\n", err)
+			}
+			return
+		}
+
+		if ctx.Writer.Status() == http.StatusOK {
+			if err := store.ResetFailures(ctx.Request.Context(), lockoutKey); err != nil {
+				log.Printf("Error resetting failed login attempts: %v\n", err)
+			}
+		}
+	}
+}
+
+func setRateLimitHeaders(ctx *gin.Context, result services.RateLimitResult) {
+	ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+func abortTooManyRequests(ctx *gin.Context, retryAfter time.Duration, message string) {
+	ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message})
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// accountFromRequest reads the email from the JSON body and puts the body back for the handler.
+func accountFromRequest(ctx *gin.Context) string {
+	if email := ctx.GetString("email"); email != "" {
+		return strings.ToLower(email)
+	}
+
+	if ctx.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return ""
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+func hasInvalidCredentialsError(ctx *gin.Context) bool {
+	for _, ginErr := range ctx.Errors {
+		if errors.Is(ginErr.Err, services.ErrInvalidUserCredentials) || errors.Is(ginErr.Err, services.ErrInvalidAdminCredentials) ||
+			errors.Is(ginErr.Err, services.ErrInvalidMFACode) {
+			return true
+		}
+	}
+	return false
+}
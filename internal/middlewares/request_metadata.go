@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// RequestMetadata tags every request with an ID, reusing the one sent by a proxy when it looks sane,
+// and stores it with the client IP in the request context. The ID is echoed back in the response headers.
+func RequestMetadata() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		ctx.Set("requestId", requestID)
+		ctx.Header(RequestIDHeader, requestID)
+
+		metadata := utils.RequestMetadataFromContext(ctx.Request.Context())
+		metadata.RequestID = requestID
+		metadata.IP = ctx.ClientIP()
+		ctx.Request = ctx.Request.WithContext(utils.WithRequestMetadata(ctx.Request.Context(), metadata))
+
+		ctx.Next()
+	}
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(raw)
+}
@@ -1,19 +1,42 @@
 package models
 
 import (
+	"time"
+
 	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	AdminRole      = "admin"
+	SuperAdminRole = "superadmin" // Manages the other admins and their invitations
+)
+
 type Admin struct {
-	ID           primitive.ObjectID `bson:"_id" json:"id"`                       // Automatically generated by MongoDB.
-	Role         string             `bson:"role" json:"role" binding:"required"` // Explicitly set to "admin"
-	Email        string             `bson:"email" json:"email" binding:"required"`
-	PasswordHash string             `bson:"passwordHash" json:"passwordHash" binding:"required"`
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`                       // Automatically generated by MongoDB.
+	Role         string              `bson:"role" json:"role" binding:"required"` // "admin" or "superadmin"
+	Email        string              `bson:"email" json:"email" binding:"required"`
+	PasswordHash string              `bson:"passwordHash" json:"-" binding:"required"`
+	MFA          *MFASettings        `bson:"mfa,omitempty" json:"-"`
+	Disabled     bool                `bson:"disabled" json:"disabled"`
+	InvitedBy    *primitive.ObjectID `bson:"invitedBy,omitempty" json:"invitedBy,omitempty"` // Empty for the bootstrap super admin
+	CreatedAt    time.Time           `bson:"createdAt,omitempty" json:"createdAt,omitempty"`
+}
+
+// AdminInvitation lets a super admin onboard a specific email. Only the token hash is stored.
+type AdminInvitation struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Email      string             `bson:"email" json:"email"`
+	Role       string             `bson:"role" json:"role"`
+	TokenHash  string             `bson:"tokenHash" json:"-"`
+	InvitedBy  primitive.ObjectID `bson:"invitedBy" json:"invitedBy"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  time.Time          `bson:"expiresAt" json:"expiresAt"`
+	AcceptedAt *time.Time         `bson:"acceptedAt,omitempty" json:"acceptedAt,omitempty"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
 }
 
-func NewAdminfromInput(input AdminRegistrationInput) (Admin, error) {
-	hasher := &utils.DefaultHasher{}
+func NewAdminfromInput(input AdminRegistrationInput, hasher utils.HashPasswordService, role string, invitedBy *primitive.ObjectID) (Admin, error) {
 	hashedPassword, err := hasher.HashPassword(input.Password)
 	if err != nil {
 		return Admin{}, err
@@ -21,8 +44,10 @@ func NewAdminfromInput(input AdminRegistrationInput) (Admin, error) {
 
 	return Admin{
 		ID:           primitive.NewObjectID(),
-		Role:         "admin",
+		Role:         role,
 		Email:        input.Email,
 		PasswordHash: hashedPassword,
+		InvitedBy:    invitedBy,
+		CreatedAt:    time.Now(),
 	}, nil
 }
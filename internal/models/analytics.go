@@ -0,0 +1,294 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Periods analytics are grouped by, in UTC. Weeks start on Monday.
+const (
+	AnalyticsIntervalDay   = "day"
+	AnalyticsIntervalWeek  = "week"
+	AnalyticsIntervalMonth = "month"
+)
+
+// AnalyticsQuery is the range of an analytics report, from the start of the from day to the end of the to day.
+type AnalyticsQuery struct {
+	From     *time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To       *time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+	Interval string     `form:"interval" validate:"omitempty,oneof=day week month"`
+	Format   string     `form:"format" validate:"omitempty,oneof=json csv"`
+}
+
+// AnalyticsRange is the resolved range of a report, To is excluded.
+type AnalyticsRange struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Interval string    `json:"interval,omitempty"`
+}
+
+// AnalyticsReport is a report that can be exported as CSV, the first record is the header.
+type AnalyticsReport interface {
+	CSVRecords() [][]string
+}
+
+type AnalyticsCount struct {
+	Key   string `bson:"_id" json:"key"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// SubscriptionBreakdown counts the users who signed up in the range by subscription status, and the plans of those
+// who have access.
+type SubscriptionBreakdown struct {
+	AnalyticsRange
+	ByStatus []AnalyticsCount `json:"byStatus"`
+	ByPlan   []AnalyticsCount `json:"byPlan"`
+}
+
+func (b SubscriptionBreakdown) CSVRecords() [][]string {
+	records := [][]string{{"group", "key", "count"}}
+	for _, count := range b.ByStatus {
+		records = append(records, []string{"status", utils.CSVSafe(count.Key), strconv.FormatInt(count.Count, 10)})
+	}
+	for _, count := range b.ByPlan {
+		records = append(records, []string{"plan", utils.CSVSafe(count.Key), strconv.FormatInt(count.Count, 10)})
+	}
+	return records
+}
+
+type SignupPoint struct {
+	Period      time.Time `json:"period"`
+	Signups     int64     `json:"signups"`
+	Conversions int64     `json:"conversions"` // Users whose first paid activation was in the period
+}
+
+type SignupAnalytics struct {
+	AnalyticsRange
+	Points []SignupPoint `json:"points"`
+}
+
+func (a SignupAnalytics) CSVRecords() [][]string {
+	records := [][]string{{"period", "signups", "conversions"}}
+	for _, point := range a.Points {
+		records = append(records, []string{point.Period.Format(time.DateOnly), strconv.FormatInt(point.Signups, 10), strconv.FormatInt(point.Conversions, 10)})
+	}
+	return records
+}
+
+// RevenuePoint sums the invoices of a period in one currency, amounts are in the smallest unit of the currency.
+type RevenuePoint struct {
+	Period              time.Time `json:"period"`
+	Currency            string    `json:"currency"`
+	Invoices            int64     `json:"invoices"`
+	SubscriptionRevenue int64     `json:"subscriptionRevenue"` // Before tax
+	GiftRevenue         int64     `json:"giftRevenue"`         // Before tax
+	Tax                 int64     `json:"tax"`
+	Total               int64     `json:"total"`
+}
+
+type CurrencyAmount struct {
+	Currency string `bson:"_id" json:"currency"`
+	Amount   int64  `bson:"amount" json:"amount"`
+}
+
+// RevenueAnalytics lists the sales of the range. Subscriptions are billed monthly, so the MRR is the subscription
+// revenue before tax of the 30 days before the end of the range. App store sales are reported by the stores.
+type RevenueAnalytics struct {
+	AnalyticsRange
+	Points []RevenuePoint   `json:"points"`
+	MRR    []CurrencyAmount `json:"mrr"`
+}
+
+func (a RevenueAnalytics) CSVRecords() [][]string {
+	records := [][]string{{"period", "currency", "invoices", "subscription_revenue", "gift_revenue", "tax", "total"}}
+	for _, point := range a.Points {
+		records = append(records, []string{
+			point.Period.Format(time.DateOnly),
+			utils.CSVSafe(point.Currency),
+			strconv.FormatInt(point.Invoices, 10),
+			strconv.FormatInt(point.SubscriptionRevenue, 10),
+			strconv.FormatInt(point.GiftRevenue, 10),
+			strconv.FormatInt(point.Tax, 10),
+			strconv.FormatInt(point.Total, 10),
+		})
+	}
+	for _, mrr := range a.MRR {
+		records = append(records, []string{"mrr", utils.CSVSafe(mrr.Currency), "", strconv.FormatInt(mrr.Amount, 10), "", "", ""})
+	}
+	return records
+}
+
+// ChurnAnalytics compares the paid subscriptions that ended in the range to the ones running at its start.
+type ChurnAnalytics struct {
+	AnalyticsRange
+	PayingAtStart int64   `bson:"payingAtStart" json:"payingAtStart"`
+	Churned       int64   `bson:"churned" json:"churned"`
+	Cancellations int64   `bson:"cancellations" json:"cancellations"` // Cancelled in the range, they may still have access
+	ChurnRate     float64 `bson:"-" json:"churnRate"`
+}
+
+func (a ChurnAnalytics) CSVRecords() [][]string {
+	return [][]string{
+		{"from", "to", "paying_at_start", "churned", "cancellations", "churn_rate"},
+		{
+			a.From.Format(time.DateOnly), a.To.Format(time.DateOnly),
+			strconv.FormatInt(a.PayingAtStart, 10), strconv.FormatInt(a.Churned, 10), strconv.FormatInt(a.Cancellations, 10),
+			strconv.FormatFloat(a.ChurnRate, 'f', 4, 64),
+		},
+	}
+}
+
+// TrialConversionAnalytics is the share of the trials that ended in the range whose user went on to pay.
+type TrialConversionAnalytics struct {
+	AnalyticsRange
+	TrialsEnded    int64   `bson:"trialsEnded" json:"trialsEnded"`
+	Converted      int64   `bson:"converted" json:"converted"`
+	ConversionRate float64 `bson:"-" json:"conversionRate"`
+}
+
+func (a TrialConversionAnalytics) CSVRecords() [][]string {
+	return [][]string{
+		{"from", "to", "trials_ended", "converted", "conversion_rate"},
+		{
+			a.From.Format(time.DateOnly), a.To.Format(time.DateOnly),
+			strconv.FormatInt(a.TrialsEnded, 10), strconv.FormatInt(a.Converted, 10),
+			strconv.FormatFloat(a.ConversionRate, 'f', 4, 64),
+		},
+	}
+}
+
+// WorkoutPlanEngagement sums up the enrollments of a workout plan. Active enrollments aren't completed yet.
+type WorkoutPlanEngagement struct {
+	WorkoutPlanID   primitive.ObjectID `bson:"_id" json:"workoutPlanId"`
+	WorkoutPlanName string             `bson:"workoutPlanName" json:"workoutPlanName"`
+	Enrollments     int64              `bson:"enrollments" json:"enrollments"`
+	Active          int64              `bson:"active" json:"active"`
+	Completed       int64              `bson:"completed" json:"completed"`
+	CompletionRate  float64            `bson:"-" json:"completionRate"`
+}
+
+func (e WorkoutPlanEngagement) csvRecord() []string {
+	return []string{
+		e.WorkoutPlanID.Hex(), utils.CSVSafe(e.WorkoutPlanName),
+		strconv.FormatInt(e.Enrollments, 10), strconv.FormatInt(e.Active, 10), strconv.FormatInt(e.Completed, 10),
+		strconv.FormatFloat(e.CompletionRate, 'f', 4, 64),
+	}
+}
+
+var workoutPlanEngagementCSVHeader = []string{"workout_plan_id", "workout_plan_name", "enrollments", "active", "completed", "completion_rate"}
+
+// WorkoutPlanEngagementList compares the engagement of the plans users joined in the range, most joined first.
+type WorkoutPlanEngagementList struct {
+	AnalyticsRange
+	Plans []WorkoutPlanEngagement `json:"plans"`
+}
+
+func (l WorkoutPlanEngagementList) CSVRecords() [][]string {
+	records := [][]string{workoutPlanEngagementCSVHeader}
+	for _, plan := range l.Plans {
+		records = append(records, plan.csvRecord())
+	}
+	return records
+}
+
+// WorkoutWeekFunnel follows the enrollments through a week of the plan. A week starts when the previous one is
+// completed, or when the user joins the plan for the first week.
+type WorkoutWeekFunnel struct {
+	WorkoutWeekID primitive.ObjectID `json:"workoutWeekId"`
+	WeekNumber    int                `json:"weekNumber"`
+	Completed     int64              `json:"completed"`
+	DropOff       int64              `json:"dropOff"`    // Completed the previous week, or joined, but not this one
+	Retention     float64            `json:"retention"`  // Share of the enrollments that completed the week
+	MedianDays    *float64           `json:"medianDays"` // Median time to complete the week, nil without timed completions
+}
+
+// SkippedExercise is an exercise left incomplete in circuits the users worked on.
+type SkippedExercise struct {
+	ExerciseID primitive.ObjectID `bson:"_id" json:"exerciseId"`
+	Name       string             `bson:"name" json:"name"`
+	Skipped    int64              `bson:"skipped" json:"skipped"`
+}
+
+// WorkoutPlanFunnel details the engagement of the users who joined a plan in the range.
+type WorkoutPlanFunnel struct {
+	AnalyticsRange
+	WorkoutPlanEngagement
+	Weeks            []WorkoutWeekFunnel `json:"weeks"`
+	SkippedExercises []SkippedExercise   `json:"skippedExercises"`
+}
+
+func (f WorkoutPlanFunnel) CSVRecords() [][]string {
+	records := [][]string{workoutPlanEngagementCSVHeader, f.WorkoutPlanEngagement.csvRecord(), {}}
+	records = append(records, []string{"week_number", "workout_week_id", "completed", "drop_off", "retention", "median_days"})
+	for _, week := range f.Weeks {
+		median := ""
+		if week.MedianDays != nil {
+			median = strconv.FormatFloat(*week.MedianDays, 'f', 2, 64)
+		}
+		records = append(records, []string{
+			strconv.Itoa(week.WeekNumber), week.WorkoutWeekID.Hex(),
+			strconv.FormatInt(week.Completed, 10), strconv.FormatInt(week.DropOff, 10),
+			strconv.FormatFloat(week.Retention, 'f', 4, 64), median,
+		})
+	}
+	records = append(records, []string{}, []string{"exercise_id", "exercise_name", "skipped"})
+	for _, exercise := range f.SkippedExercises {
+		records = append(records, []string{exercise.ExerciseID.Hex(), utils.CSVSafe(exercise.Name), strconv.FormatInt(exercise.Skipped, 10)})
+	}
+	return records
+}
+
+// CohortPeriod is the retention of a cohort in a period after its signup, shares are of the users of the cohort.
+type CohortPeriod struct {
+	Offset           int     `bson:"offset" json:"offset"` // Periods since the signup period, which is 0
+	Exercised        int64   `bson:"exercised" json:"exercised"`
+	ExerciseRate     float64 `bson:"exerciseRate" json:"exerciseRate"`
+	Subscribed       int64   `bson:"subscribed" json:"subscribed"` // Had a paid subscription
+	SubscriptionRate float64 `bson:"subscriptionRate" json:"subscriptionRate"`
+	LoggedNutrition  int64   `bson:"loggedNutrition" json:"loggedNutrition"`
+	NutritionRate    float64 `bson:"nutritionRate" json:"nutritionRate"`
+}
+
+type Cohort struct {
+	Start   time.Time      `bson:"start" json:"start"`
+	Users   int64          `bson:"users" json:"users"`
+	Periods []CohortPeriod `bson:"periods" json:"periods"` // Up to the current period
+}
+
+// CohortRetention groups the users who signed up in the range by week or month of signup. Reports are cached
+// until ExpiresAt.
+type CohortRetention struct {
+	AnalyticsRange `bson:",inline"`
+	Key            string    `bson:"_id" json:"-"`
+	Cohorts        []Cohort  `bson:"cohorts" json:"cohorts"`
+	GeneratedAt    time.Time `bson:"generatedAt" json:"generatedAt"`
+	ExpiresAt      time.Time `bson:"expiresAt" json:"-"`
+}
+
+func (r CohortRetention) CSVRecords() [][]string {
+	records := [][]string{{"cohort", "users", "offset", "exercised", "exercise_rate", "subscribed", "subscription_rate", "logged_nutrition", "nutrition_rate"}}
+	for _, cohort := range r.Cohorts {
+		for _, period := range cohort.Periods {
+			records = append(records, []string{
+				cohort.Start.Format(time.DateOnly), strconv.FormatInt(cohort.Users, 10), strconv.Itoa(period.Offset),
+				strconv.FormatInt(period.Exercised, 10), strconv.FormatFloat(period.ExerciseRate, 'f', 4, 64),
+				strconv.FormatInt(period.Subscribed, 10), strconv.FormatFloat(period.SubscriptionRate, 'f', 4, 64),
+				strconv.FormatInt(period.LoggedNutrition, 10), strconv.FormatFloat(period.NutritionRate, 'f', 4, 64),
+			})
+		}
+	}
+	return records
+}
+
+// CachedUntil tells clients how long they can keep the report.
+func (r CohortRetention) CachedUntil() (generatedAt, expiresAt time.Time) {
+	return r.GeneratedAt, r.ExpiresAt
+}
+
+// CachedReport is a report computed ahead of the request, clients can cache it until it expires.
+type CachedReport interface {
+	CachedUntil() (generatedAt, expiresAt time.Time)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog records one change an admin made. Entries are only ever inserted, never updated or deleted.
+// Before and After only hold the top level fields that changed, credentials are left out.
+type AuditLog struct {
+	ID               primitive.ObjectID `bson:"_id" json:"id"`
+	ActorID          primitive.ObjectID `bson:"actorId" json:"actorId"`
+	ActorRole        string             `bson:"actorRole" json:"actorRole"`
+	Action           string             `bson:"action" json:"action"` // "create", "update" or "delete"
+	TargetCollection string             `bson:"targetCollection" json:"targetCollection"`
+	TargetID         string             `bson:"targetId" json:"targetId"` // Hex ID, or the name for roles
+	Before           bson.M             `bson:"before,omitempty" json:"before,omitempty"`
+	After            bson.M             `bson:"after,omitempty" json:"after,omitempty"`
+	Reason           string             `bson:"reason,omitempty" json:"reason,omitempty"` // Given by the admin for changes support must be able to explain
+	RequestID        string             `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	IP               string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type AuditLogQuery struct {
+	ActorID          string     `form:"actorId" validate:"omitempty,mongodb"`
+	Action           string     `form:"action" validate:"omitempty,oneof=create update delete"`
+	TargetCollection string     `form:"targetCollection" validate:"omitempty,max=64"`
+	TargetID         string     `form:"targetId" validate:"omitempty,max=128"`
+	RequestID        string     `form:"requestId" validate:"omitempty,max=128"`
+	From             *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To               *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page             int64      `form:"page" validate:"omitempty,min=1"`
+	Limit            int64      `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type AuditLogPage struct {
+	Logs  []AuditLog `json:"logs"`
+	Page  int64      `json:"page"`
+	Limit int64      `json:"limit"`
+	Total int64      `json:"total"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Access tiers of workout plans, meal plans and meals, they match the subscription plans covering them
+const (
+	AccessTierBasic   = SubscriptionTypeBasic
+	AccessTierPremium = SubscriptionTypePremium
+)
+
+// accessTierRanks orders the tiers, a tier covers every tier ranked at or below it.
+// Content without a tier is basic.
+var accessTierRanks = map[string]int{
+	"":                1,
+	AccessTierBasic:   1,
+	AccessTierPremium: 2,
+}
+
+// AccessTier is the tier of content the user can open at the given time: the plan of the subscription while it is
+// trialing or in its period, none ("") otherwise. TrialEndsAt is only informative, older clients could set it and
+// it isn't updated when an admin overrides the trial.
+func (u User) AccessTier(now time.Time) string {
+	subscription := u.Subscription
+	if !subscription.IsActive {
+		return ""
+	}
+	switch subscription.Status {
+	case SubscriptionStatusTrialing, SubscriptionStatusCancelled:
+		// Expired lazily, the status may not have caught up with the end of the period yet
+		if subscription.EndDate == nil || !now.Before(*subscription.EndDate) {
+			return ""
+		}
+	case SubscriptionStatusExpired:
+		return ""
+	}
+
+	return subscription.Type
+}
+
+// TierCovers reports whether content of the required tier can be opened with the granted tier.
+func TierCovers(granted, required string) bool {
+	if granted == "" {
+		return false
+	}
+	return accessTierRanks[granted] >= accessTierRanks[required]
+}
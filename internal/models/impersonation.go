@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Impersonation lets a support admin use the API as a user for a short time, to see what the user sees.
+// Its ID is the ID of the token issued for it.
+type Impersonation struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	AdminID   primitive.ObjectID `bson:"adminId" json:"adminId"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	ReadOnly  bool               `bson:"readOnly" json:"readOnly"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// ImpersonationInput starts an impersonation, read-only unless write access is asked for.
+type ImpersonationInput struct {
+	WriteAccess bool   `json:"writeAccess"`
+	Reason      string `json:"reason" validate:"max=500"`
+}
+
+// ImpersonatedRequest is the audit trail of one request made with an impersonation token, refused ones included.
+type ImpersonatedRequest struct {
+	ID              primitive.ObjectID `bson:"_id" json:"id"`
+	ImpersonationID primitive.ObjectID `bson:"impersonationId" json:"impersonationId"`
+	AdminID         primitive.ObjectID `bson:"adminId" json:"adminId"`
+	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+	Method          string             `bson:"method" json:"method"`
+	Path            string             `bson:"path" json:"path"`
+	Status          int                `bson:"status" json:"status"`
+	RequestID       string             `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	IP              string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+}
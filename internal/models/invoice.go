@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// What an invoice charged for
+const (
+	InvoiceKindSubscription = "subscription"
+	InvoiceKindGift         = "gift"
+)
+
+// Invoice records a charge of a user, from the payment event that reported it. Amounts are in the smallest unit
+// of the currency (cents).
+type Invoice struct {
+	ID                primitive.ObjectID `bson:"_id" json:"id"`
+	Number            string             `bson:"number" json:"number"`
+	UserID            primitive.ObjectID `bson:"userId" json:"userId"`
+	Kind              string             `bson:"kind" json:"kind"`
+	Provider          string             `bson:"provider" json:"provider"`
+	ProviderInvoiceID string             `bson:"providerInvoiceId,omitempty" json:"-"`
+	PaymentEventID    string             `bson:"paymentEventId" json:"-"` // An event creates one invoice at most
+	Currency          string             `bson:"currency" json:"currency"`
+	Subtotal          int64              `bson:"subtotal" json:"subtotal"`
+	Tax               int64              `bson:"tax" json:"tax"`
+	Total             int64              `bson:"total" json:"total"`
+	Lines             []InvoiceLine      `bson:"lines" json:"lines"`
+	CustomerName      string             `bson:"customerName,omitempty" json:"customerName,omitempty"`
+	CustomerEmail     string             `bson:"customerEmail,omitempty" json:"customerEmail,omitempty"`
+	CustomerTaxID     string             `bson:"customerTaxId,omitempty" json:"customerTaxId,omitempty"`
+	IssuedAt          time.Time          `bson:"issuedAt" json:"issuedAt"`
+}
+
+type InvoiceLine struct {
+	Description string     `bson:"description" json:"description"`
+	Quantity    int64      `bson:"quantity" json:"quantity"`
+	Amount      int64      `bson:"amount" json:"amount"`
+	PeriodStart *time.Time `bson:"periodStart,omitempty" json:"periodStart,omitempty"`
+	PeriodEnd   *time.Time `bson:"periodEnd,omitempty" json:"periodEnd,omitempty"`
+}
+
+// InvoiceExportQuery selects the invoices issued from the start of the from day to the end of the to day, in UTC.
+type InvoiceExportQuery struct {
+	From *time.Time `form:"from" time_format:"2006-01-02" time_utc:"1" validate:"required"`
+	To   *time.Time `form:"to" time_format:"2006-01-02" time_utc:"1" validate:"required"`
+}
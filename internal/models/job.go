@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outcomes of a job run
+const (
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobLock makes sure one instance runs each occurrence of a scheduled job. The instance holding it runs the job
+// until lockedUntil, the scheduled time of the run is kept so the other instances skip it.
+type JobLock struct {
+	Name        string    `bson:"_id" json:"name"`
+	Owner       string    `bson:"owner" json:"owner"`
+	LockedUntil time.Time `bson:"lockedUntil" json:"lockedUntil"`
+	LastSlot    time.Time `bson:"lastSlot" json:"lastSlot"` // Scheduled time of the last run
+}
+
+// JobRun is the history entry of a run of a scheduled job.
+type JobRun struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Job         string             `bson:"job" json:"job"`
+	Owner       string             `bson:"owner" json:"owner"` // Instance that ran the job
+	ScheduledAt time.Time          `bson:"scheduledAt" json:"scheduledAt"`
+	StartedAt   time.Time          `bson:"startedAt" json:"startedAt"`
+	FinishedAt  time.Time          `bson:"finishedAt" json:"finishedAt"`
+	Status      string             `bson:"status" json:"status"`
+	Summary     string             `bson:"summary,omitempty" json:"summary,omitempty"` // What the run did, e.g. how many users it changed
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+type JobRunQuery struct {
+	Job    string `form:"job" validate:"omitempty,max=64"`
+	Status string `form:"status" validate:"omitempty,oneof=succeeded failed"`
+	Limit  int64  `form:"limit" validate:"omitempty,min=1,max=100"`
+}
@@ -3,39 +3,40 @@ package models
 import "time"
 
 type LoginDetails struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	LinkToken string `json:"linkToken,omitempty"` // Links the provider identity of a previous provider login
 }
 
 type AdminRegistrationInput struct {
-	Email    string `json:"email" binding:"required" validate:"required,email,endswith=@vigor.com"`
-	Password string `json:"password" binding:"required" validate:"required,min=8,max=12"`
+	Email           string `json:"email" binding:"required" validate:"required,email,endswith=@vigor.com"`
+	Password        string `json:"password" binding:"required" validate:"required,password"`
+	InvitationToken string `json:"invitationToken" validate:"omitempty"` // Only optional while bootstrapping the first super admin
+}
+
+type AdminInvitationInput struct {
+	Email string `json:"email" validate:"required,email,endswith=@vigor.com"`
+	Role  string `json:"role" validate:"required"` // Any role of the roles collection
+}
+
+type AdminUpdateInput struct {
+	Email *string `json:"email,omitempty" validate:"omitempty,email,endswith=@vigor.com"`
+	Role  *string `json:"role,omitempty" validate:"omitempty,min=1"`
 }
 
 type UserRegistrationInput struct {
 	FirstName          string                  `json:"firstName" validate:"required,alpha"`
 	LastName           string                  `json:"lastName" validate:"required,alpha"`
 	Email              string                  `json:"email" validate:"required,email"`
-	Password           string                  `json:"password" validate:"required,min=8,max=12"`
+	Password           string                  `json:"password" validate:"required,password"`
 	BirthDate          time.Time               `json:"birthDate" validate:"required"`
 	Gender             string                  `json:"gender" validate:"required,oneof=male female"`
 	Height             int                     `json:"height" validate:"required,gt=0"`
 	Weight             int                     `json:"weight" validate:"required,gt=0"`
-	Subscription       SubscriptionInput       `json:"subscription" validate:"required"`
-	TrialEndsAt        time.Time               `json:"trialEndsAt" validate:"required"`
 	ProfileInformation UserProfileInput        `json:"profileInformation" validate:"required"`
 	SystemPreferences  *SystemPreferencesInput `json:"systemPreferences,omitempty" validate:"omitempty"`
 }
 
-type SubscriptionInput struct {
-	Type            string     `json:"type" validate:"required,oneof=basic premium"`
-	Status          string     `json:"status" validate:"required,oneof=active inactive"`
-	StartDate       time.Time  `json:"startDate" validate:"required"`
-	EndDate         *time.Time `json:"endDate,omitempty" validate:"omitempty"`
-	NextRenewalDate *time.Time `json:"nextRenewalDate,omitempty" validate:"omitempty"`
-	IsActive        bool       `json:"isActive" validate:"required"`
-}
-
 type UserProfileInput struct {
 	Username         string                 `json:"username" validate:"required,alphanum"`
 	ProfilePicture   string                 `json:"profilePicture" validate:"omitempty,url"`
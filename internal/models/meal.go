@@ -35,4 +35,5 @@ type Meal struct {
 	Description       string             `bson:"description,omitempty" json:"description,omitempty" validate:"omitempty"`          // Optional.
 	NutritionalLabels []string           `bson:"nutritionalLabels" json:"nutritionalLabels" validate:"omitempty"`                  // E.g., GF, DF, etc.
 	NumberOfServings  int                `bson:"numberOfServings" json:"numberOfServings" binding:"required" validate:"required,min=1"` // Default is 1; can be updated.
+	AccessTier        string             `bson:"accessTier,omitempty" json:"accessTier,omitempty" validate:"omitempty,oneof=basic premium"` // Empty is basic
 }
\ No newline at end of file
@@ -12,6 +12,7 @@ type MealUpdateInput struct {
 	Description       *string    `json:"description" validate:"omitempty"`
 	NutritionalLabels *[]string  `json:"nutritionalLabels" validate:"omitempty"`
 	NumberOfServings  *int       `json:"numberOfServings" validate:"omitempty,min=1"`
+	AccessTier        *string    `json:"accessTier" validate:"omitempty,oneof=basic premium"`
 }
 
 type IngredientUpdateInput struct {
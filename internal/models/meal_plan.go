@@ -26,6 +26,8 @@ type MealPlan struct {
 	Name       string             `bson:"name" json:"name" validation:"required"`
 	Duration    int                `bson:"duration" json:"duration" validation:"required"`
 	WeeklyPlans []WeeklyPlan       `bson:"weeklyPlans" json:"weeklyPlans" validation:"required"`
+	AccessTier  string             `bson:"accessTier,omitempty" json:"accessTier,omitempty" validate:"omitempty,oneof=basic premium"` // Empty is basic
+	PlanPublication                `bson:",inline"` // Set by the service, new plans are drafts
 }
 
 // AI MealPlan
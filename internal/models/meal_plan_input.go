@@ -19,4 +19,5 @@ type MealPlanUpdateInput struct {
 	Name        *string `json:"name,omitempty" validate:"omitempty,min=3,max=50"`
 	Duration    *int    `json:"duration,omitempty" validate:"omitempty,gte=1,lte=10"`
 	WeeklyPlans *[]WeeklyPlanUpdateInput `json:"weeklyPlans,omitempty" validate:"omitempty,dive"`
+	AccessTier  *string `json:"accessTier,omitempty" validate:"omitempty,oneof=basic premium"`
 }
\ No newline at end of file
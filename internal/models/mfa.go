@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// MFASettings holds the TOTP enrollment of an account. Secrets and recovery code hashes never leave the server.
+type MFASettings struct {
+	Enabled            bool       `bson:"enabled" json:"enabled"`
+	Secret             string     `bson:"secret,omitempty" json:"-"`
+	PendingSecret      string     `bson:"pendingSecret,omitempty" json:"-"` // Set between enroll and confirm
+	RecoveryCodeHashes []string   `bson:"recoveryCodeHashes,omitempty" json:"-"`
+	LastUsedStep       int64      `bson:"lastUsedStep,omitempty" json:"-"` // TOTP period of the last accepted code, stops replays
+	EnrolledAt         *time.Time `bson:"enrolledAt,omitempty" json:"enrolledAt,omitempty"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+type MFALoginInput struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recoveryCode" validate:"required_without=Code,omitempty"`
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"` // Render as a QR code for authenticator apps
+}
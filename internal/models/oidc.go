@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExternalIdentity links a user to an account at an OpenID Connect provider.
+type ExternalIdentity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"-"` // Stable account ID at the provider
+	Email    string    `bson:"email,omitempty" json:"email,omitempty"`
+	LinkedAt time.Time `bson:"linkedAt" json:"linkedAt"`
+}
+
+// OIDCLoginState keeps what is needed to finish a provider login started by the client, it is used once.
+type OIDCLoginState struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	StateHash    string             `bson:"stateHash"`
+	Provider     string             `bson:"provider"`
+	Nonce        string             `bson:"nonce"`
+	CodeVerifier string             `bson:"codeVerifier"`
+	ExpiresAt    time.Time          `bson:"expiresAt"`
+}
+
+// PendingRegistration holds a verified provider identity until the user fills in the profile fields it lacks.
+type PendingRegistration struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	TokenHash      string             `bson:"tokenHash"`
+	Identity       ExternalIdentity   `bson:"identity"`
+	Email          string             `bson:"email"`
+	FirstName      string             `bson:"firstName,omitempty"`
+	LastName       string             `bson:"lastName,omitempty"`
+	ProfilePicture string             `bson:"profilePicture,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt"`
+	ExpiresAt      time.Time          `bson:"expiresAt"`
+}
+
+// PendingIdentityLink holds a verified provider identity whose email belongs to an account that never verified it,
+// until the owner of the account proves it with the password.
+type PendingIdentityLink struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	TokenHash string             `bson:"tokenHash"`
+	UserID    primitive.ObjectID `bson:"userId"`
+	Identity  ExternalIdentity   `bson:"identity"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
+type OIDCCallbackInput struct {
+	Code  string `json:"code" validate:"required,max=2048"`
+	State string `json:"state" validate:"required,max=256"`
+}
+
+// OIDCProfile is what the provider shared about the user, to prefill the registration form.
+type OIDCProfile struct {
+	Email          string `json:"email"`
+	FirstName      string `json:"firstName,omitempty"`
+	LastName       string `json:"lastName,omitempty"`
+	ProfilePicture string `json:"profilePicture,omitempty"`
+}
+
+// OIDCLoginResult either has the signed in user, a registration token when the identity has no account yet, or a
+// link token when the account with its email has to sign in with the password first.
+type OIDCLoginResult struct {
+	User              *User
+	RegistrationToken string
+	LinkToken         string
+	Profile           *OIDCProfile
+}
+
+// OIDCRegistrationInput completes a registration started with a provider, the email comes from the provider.
+type OIDCRegistrationInput struct {
+	RegistrationToken  string                  `json:"registrationToken" validate:"required,max=256"`
+	FirstName          string                  `json:"firstName" validate:"required,alpha"`
+	LastName           string                  `json:"lastName" validate:"required,alpha"`
+	BirthDate          time.Time               `json:"birthDate" validate:"required"`
+	Gender             string                  `json:"gender" validate:"required,oneof=male female"`
+	Height             int                     `json:"height" validate:"required,gt=0"`
+	Weight             int                     `json:"weight" validate:"required,gt=0"`
+	ProfileInformation UserProfileInput        `json:"profileInformation" validate:"required"`
+	SystemPreferences  *SystemPreferencesInput `json:"systemPreferences,omitempty" validate:"omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PaymentEvent records a payment provider event while it is processed and once it is, redeliveries of a done event
+// are skipped.
+type PaymentEvent struct {
+	ID          string     `bson:"_id" json:"id"` // Event ID given by the provider
+	Type        string     `bson:"type" json:"type"`
+	CreatedAt   time.Time  `bson:"createdAt" json:"createdAt"`     // When the provider created the event
+	Status      string     `bson:"status,omitempty" json:"status"` // Missing for events recorded once done
+	ClaimedAt   time.Time  `bson:"claimedAt" json:"claimedAt"`     // When the delivery processing it started
+	ProcessedAt *time.Time `bson:"processedAt,omitempty" json:"processedAt,omitempty"`
+}
+
+// Statuses of a payment event, a delivery claims it while applying it and only done events are skipped
+const (
+	PaymentEventProcessing = "processing"
+	PaymentEventDone       = "done"
+)
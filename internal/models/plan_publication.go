@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Publication statuses of workout and meal plans. Users only see published plans.
+const (
+	PlanStatusDraft     = "draft"
+	PlanStatusInReview  = "in_review"
+	PlanStatusPublished = "published"
+	PlanStatusArchived  = "archived"
+)
+
+// planTransitions lists the statuses each status can move to. A plan is published once reviewed, the review
+// sends it back to draft otherwise.
+var planTransitions = map[string][]string{
+	PlanStatusDraft:     {PlanStatusInReview},
+	PlanStatusInReview:  {PlanStatusDraft, PlanStatusPublished},
+	PlanStatusPublished: {PlanStatusArchived},
+	PlanStatusArchived:  {PlanStatusPublished},
+}
+
+// PlanPublication is where a workout or meal plan is in the review workflow.
+type PlanPublication struct {
+	Status      string     `bson:"status,omitempty" json:"status,omitempty"` // Empty is published, plans created before the workflow
+	PublishedAt *time.Time `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`
+}
+
+// PublicationStatus returns the status of the plan, published for plans created before the workflow.
+func (p PlanPublication) PublicationStatus() string {
+	if p.Status == "" {
+		return PlanStatusPublished
+	}
+	return p.Status
+}
+
+// IsLive reports whether the plan was published, its edits then go to a draft copy until the copy is published.
+func (p PlanPublication) IsLive() bool {
+	status := p.PublicationStatus()
+	return status == PlanStatusPublished || status == PlanStatusArchived
+}
+
+// CanTransitionTo reports whether the plan can move from its status to the given one.
+func (p PlanPublication) CanTransitionTo(status string) bool {
+	for _, allowed := range planTransitions[p.PublicationStatus()] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPlanStatus reports whether status is one of the publication statuses.
+func IsPlanStatus(status string) bool {
+	_, exists := planTransitions[status]
+	return exists
+}
+
+// PlanTransitionInput moves a workout or meal plan through the review workflow.
+type PlanTransitionInput struct {
+	Status string `json:"status" validate:"required,oneof=draft in_review published archived"`
+}
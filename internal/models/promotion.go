@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// What redeeming a promo code gives. Discounts are left out until the API creates the checkouts, only the checkout
+// can make the provider charge them.
+const (
+	PromoTypeTrialExtension = "trial_extension" // More days of trial
+	PromoTypeGift           = "gift"            // Prepaid months of a plan, bought by another user
+)
+
+// PromoCode is a code users redeem once each. Campaign codes are created by admins, gift codes by the user buying
+// the gift and only redeemable once paid.
+type PromoCode struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Code           string             `bson:"code" json:"code"`
+	Type           string             `bson:"type" json:"type"`
+	TrialDays      int                `bson:"trialDays,omitempty" json:"trialDays,omitempty"`
+	Plan           string             `bson:"plan,omitempty" json:"plan,omitempty"`     // Plan of a gift
+	Months         int                `bson:"months,omitempty" json:"months,omitempty"` // Length of a gift
+	MaxRedemptions int                `bson:"maxRedemptions" json:"maxRedemptions"`     // Unlimited when 0
+	Redemptions    int                `bson:"redemptions" json:"redemptions"`
+	ExpiresAt      *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	DisabledAt     *time.Time         `bson:"disabledAt,omitempty" json:"disabledAt,omitempty"`
+	CreatedBy      primitive.ObjectID `bson:"createdBy" json:"createdBy"` // Admin, or user who bought the gift
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	Gift           *GiftDetails       `bson:"gift,omitempty" json:"gift,omitempty"`
+}
+
+// GiftDetails are what the buyer of a gift code gave with it.
+type GiftDetails struct {
+	RecipientEmail string     `bson:"recipientEmail,omitempty" json:"recipientEmail,omitempty"`
+	Message        string     `bson:"message,omitempty" json:"message,omitempty"`
+	PaidAt         *time.Time `bson:"paidAt,omitempty" json:"paidAt,omitempty"` // Set by the payment webhook
+}
+
+// PromoRedemption records that a user redeemed a code, a user can redeem each code once.
+type PromoRedemption struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	CodeID     primitive.ObjectID `bson:"codeId" json:"codeId"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	RedeemedAt time.Time          `bson:"redeemedAt" json:"redeemedAt"`
+}
+
+// PromoCodeInput creates a campaign code, a code is generated when none is given. The field of the type is required.
+type PromoCodeInput struct {
+	Code           string     `json:"code,omitempty" validate:"omitempty,alphanum,min=4,max=32"`
+	Type           string     `json:"type" validate:"required,oneof=trial_extension"`
+	TrialDays      int        `json:"trialDays,omitempty" validate:"omitempty,min=1,max=365"`
+	MaxRedemptions int        `json:"maxRedemptions,omitempty" validate:"omitempty,min=1"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+}
+
+type PromoCodeRedemptionInput struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// GiftPurchaseInput creates a gift code, the app then pays it with a checkout carrying the code ID.
+type GiftPurchaseInput struct {
+	Plan           string `json:"plan" validate:"required,oneof=basic premium"`
+	Months         int    `json:"months" validate:"required,min=1,max=12"`
+	RecipientEmail string `json:"recipientEmail,omitempty" validate:"omitempty,email"`
+	Message        string `json:"message,omitempty" validate:"omitempty,max=500"`
+}
+
+// PromoCodeStats sums up the redemptions of a code.
+type PromoCodeStats struct {
+	CodeID           primitive.ObjectID   `json:"codeId"`
+	Code             string               `json:"code"`
+	Redemptions      int                  `json:"redemptions"`
+	Remaining        *int                 `json:"remaining,omitempty"` // Nil for unlimited codes
+	FirstRedeemedAt  *time.Time           `json:"firstRedeemedAt,omitempty"`
+	LastRedeemedAt   *time.Time           `json:"lastRedeemedAt,omitempty"`
+	RedemptionsByDay []PromoRedemptionDay `json:"redemptionsByDay"`
+}
+
+type PromoRedemptionDay struct {
+	Date        string `json:"date"` // YYYY-MM-DD, UTC
+	Redemptions int    `json:"redemptions"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// Admin permissions, checked per route by middlewares.RequirePermission
+const (
+	PermExercisesRead       = "exercises:read"
+	PermExercisesWrite      = "exercises:write"
+	PermWorkoutPlansRead    = "workout-plans:read"
+	PermWorkoutPlansWrite   = "workout-plans:write"
+	PermMealsRead           = "meals:read"
+	PermMealsWrite          = "meals:write"
+	PermMealPlansRead       = "meal-plans:read"
+	PermMealPlansWrite      = "meal-plans:write"
+	PermUsersRead           = "users:read"
+	PermUsersWrite          = "users:write"
+	PermUsersImpersonate    = "users:impersonate"
+	PermSubscriptionsManage = "subscriptions:manage"
+	PermAnalyticsRead       = "analytics:read"
+	PermAdminsManage        = "admins:manage"
+	PermRolesManage         = "roles:manage"
+	PermAuditRead           = "audit:read"
+	PermAPIKeysManage       = "api-keys:manage"
+	PermPromotionsManage    = "promotions:manage"
+	PermInvoicesRead        = "invoices:read"
+)
+
+var AllPermissions = []string{
+	PermExercisesRead, PermExercisesWrite,
+	PermWorkoutPlansRead, PermWorkoutPlansWrite,
+	PermMealsRead, PermMealsWrite,
+	PermMealPlansRead, PermMealPlansWrite,
+	PermUsersRead, PermUsersWrite,
+	PermUsersImpersonate,
+	PermSubscriptionsManage,
+	PermAnalyticsRead,
+	PermAdminsManage,
+	PermRolesManage,
+	PermAuditRead,
+	PermAPIKeysManage,
+	PermPromotionsManage,
+	PermInvoicesRead,
+}
+
+// Role maps an admin role name to its permission set.
+type Role struct {
+	Name        string    `bson:"_id" json:"name"`
+	Description string    `bson:"description" json:"description"`
+	Permissions []string  `bson:"permissions" json:"permissions"`
+	BuiltIn     bool      `bson:"builtIn" json:"builtIn"` // Seeded roles can be edited but not deleted
+	UpdatedAt   time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// DefaultRoles are created at startup when missing. The super admin role always has every permission.
+func DefaultRoles() []Role {
+	return []Role{
+		{
+			Name:        SuperAdminRole,
+			Description: "Full access, manages admins and roles",
+			Permissions: AllPermissions,
+			BuiltIn:     true,
+		},
+		{
+			Name:        AdminRole,
+			Description: "Manages the catalog and reads users and analytics",
+			Permissions: []string{
+				PermExercisesRead, PermExercisesWrite,
+				PermWorkoutPlansRead, PermWorkoutPlansWrite,
+				PermMealsRead, PermMealsWrite,
+				PermMealPlansRead, PermMealPlansWrite,
+				PermUsersRead,
+				PermAnalyticsRead,
+			},
+			BuiltIn: true,
+		},
+		{
+			Name:        "support",
+			Description: "Reads users, manages their subscriptions and impersonates them",
+			Permissions: []string{PermUsersRead, PermUsersWrite, PermUsersImpersonate, PermSubscriptionsManage},
+			BuiltIn:     true,
+		},
+		{
+			Name:        "finance",
+			Description: "Exports invoices and reads users",
+			Permissions: []string{PermUsersRead, PermInvoicesRead},
+			BuiltIn:     true,
+		},
+		{
+			Name:        "analyst",
+			Description: "Read only access to the catalog and analytics",
+			Permissions: []string{PermExercisesRead, PermWorkoutPlansRead, PermMealsRead, PermMealPlansRead, PermAnalyticsRead},
+			BuiltIn:     true,
+		},
+	}
+}
+
+type RoleInput struct {
+	Name        string   `json:"name" validate:"required,alphanum,lowercase,min=3,max=32"`
+	Description string   `json:"description" validate:"omitempty,max=200"`
+	Permissions []string `json:"permissions" validate:"required,dive,required"`
+}
+
+type RoleUpdateInput struct {
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=200"`
+	Permissions []string `json:"permissions,omitempty" validate:"omitempty,dive,required"`
+}
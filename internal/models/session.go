@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one login of a user on a device. Its tokens carry the session ID and stop working once it is revoked.
+type Session struct {
+	ID               primitive.ObjectID `bson:"_id" json:"id"`
+	UserID           primitive.ObjectID `bson:"userId" json:"userId"`
+	DeviceName       string             `bson:"deviceName,omitempty" json:"deviceName,omitempty"` // From the X-Device-Name header
+	UserAgent        string             `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	IP               string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	RefreshTokenHash string             `bson:"refreshTokenHash" json:"-"` // Only the latest refresh token of the session is accepted
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt       time.Time          `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt        time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt        *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
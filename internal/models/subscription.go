@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Subscription plans
+const (
+	SubscriptionTypeBasic   = "basic"
+	SubscriptionTypePremium = "premium"
+)
+
+// Subscription statuses, only the services move a subscription between them
+const (
+	SubscriptionStatusTrialing  = "trialing"
+	SubscriptionStatusActive    = "active"
+	SubscriptionStatusPastDue   = "past_due"
+	SubscriptionStatusCancelled = "cancelled"
+	SubscriptionStatusExpired   = "expired"
+)
+
+// Where subscriptions are paid
+const (
+	SubscriptionProviderStripe   = "stripe"
+	SubscriptionProviderAppStore = utils.StoreAppStore
+	SubscriptionProviderPlay     = utils.StorePlay
+	SubscriptionProviderGift     = "gift" // Prepaid months of a redeemed gift code
+)
+
+// subscriptionTransitions lists the statuses each status can move to. Active to active is a renewal.
+var subscriptionTransitions = map[string][]string{
+	SubscriptionStatusTrialing:  {SubscriptionStatusActive, SubscriptionStatusCancelled, SubscriptionStatusExpired},
+	SubscriptionStatusActive:    {SubscriptionStatusActive, SubscriptionStatusPastDue, SubscriptionStatusCancelled},
+	SubscriptionStatusPastDue:   {SubscriptionStatusActive, SubscriptionStatusCancelled, SubscriptionStatusExpired},
+	SubscriptionStatusCancelled: {SubscriptionStatusActive, SubscriptionStatusExpired},
+	SubscriptionStatusExpired:   {SubscriptionStatusActive},
+	// Status of subscriptions created by clients before the lifecycle existed
+	"inactive": {SubscriptionStatusActive, SubscriptionStatusExpired},
+}
+
+// SubscriptionChange is a plan change requested by the user, applied when the subscription is next activated
+// (payment of the upgrade, or renewal for a downgrade).
+type SubscriptionChange struct {
+	Type        string     `bson:"type" json:"type"`
+	RequestedAt time.Time  `bson:"requestedAt" json:"requestedAt"`
+	EffectiveAt *time.Time `bson:"effectiveAt,omitempty" json:"effectiveAt,omitempty"` // Known for downgrades, the end of the current period
+}
+
+// SubscriptionEvent records a change of the subscription of a user, whoever made it: the user, an admin, a payment
+// provider or a scheduled job.
+type SubscriptionEvent struct {
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	UserID    primitive.ObjectID  `bson:"userId" json:"userId"`
+	Before    UserSubscription    `bson:"before" json:"before"`
+	After     UserSubscription    `bson:"after" json:"after"`
+	ActorID   *primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"` // Admin or user of the request, none for webhooks and jobs
+	ActorRole string              `bson:"actorRole,omitempty" json:"actorRole,omitempty"`
+	RequestID string              `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	CreatedAt time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+// NewTrialSubscription starts the premium trial every new user gets.
+func NewTrialSubscription(now time.Time, trialDays int) UserSubscription {
+	trialEndsAt := now.AddDate(0, 0, trialDays)
+	return UserSubscription{
+		Type:      SubscriptionTypePremium,
+		Status:    SubscriptionStatusTrialing,
+		StartDate: now,
+		EndDate:   &trialEndsAt,
+		IsActive:  true,
+	}
+}
+
+// CanTransitionTo reports whether the subscription can move from its status to the given one.
+func (s UserSubscription) CanTransitionTo(status string) bool {
+	for _, allowed := range subscriptionTransitions[s.Status] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionChangeInput is what users can ask for, every other field is owned by the server.
+type SubscriptionChangeInput struct {
+	Type string `json:"type" validate:"required,oneof=basic premium"`
+}
+
+// SubscriptionTransitionInput moves a subscription to another status, used by admins.
+type SubscriptionTransitionInput struct {
+	Status string `json:"status" validate:"required,oneof=trialing active past_due cancelled expired"`
+}
+
+// SubscriptionOverrideInput sets the plan, status and end of a subscription regardless of the allowed transitions,
+// e.g. to compensate a user. The reason is kept in the audit log for support.
+type SubscriptionOverrideInput struct {
+	Type    string     `json:"type" validate:"required,oneof=basic premium"`
+	Status  string     `json:"status" validate:"required,oneof=trialing active past_due cancelled expired"`
+	EndDate *time.Time `json:"endDate" validate:"required_unless=Status expired"`
+	Reason  string     `json:"reason" validate:"required,max=500"`
+}
+
+// AppStorePurchaseInput is a signed transaction the app got from StoreKit after a purchase or a restore.
+type AppStorePurchaseInput struct {
+	SignedTransaction string `json:"signedTransaction" validate:"required"`
+}
+
+// PlayPurchaseInput is the purchase token the app got from Play Billing.
+type PlayPurchaseInput struct {
+	PurchaseToken string `json:"purchaseToken" validate:"required"`
+}
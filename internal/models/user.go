@@ -13,7 +13,7 @@ type User struct {
 	FirstName          string             `bson:"firstName" json:"firstName" binding:"required"`
 	LastName           string             `bson:"lastName" json:"lastName" binding:"required"`
 	Email              string             `bson:"email" json:"email" binding:"required"`
-	PasswordHash       string             `bson:"passwordHash" json:"passwordHash" binding:"required"`
+	PasswordHash       string             `bson:"passwordHash" json:"-" binding:"required"`
 	BirthDate          time.Time          `bson:"birthDate" json:"birthDate" binding:"required"`
 	Gender             string             `bson:"gender" json:"gender" binding:"required"`
 	Height             int                `bson:"height" json:"height" binding:"required"`
@@ -22,15 +22,67 @@ type User struct {
 	TrialEndsAt        time.Time          `bson:"trialEndsAt" json:"trialEndsAt" binding:"required"`
 	ProfileInformation UserProfile        `bson:"profileInformation" json:"profileInformation" binding:"required"`
 	SystemPreferences  *SystemPreferences `bson:"systemPreferences,omitempty" json:"systemPreferences,omitempty"`
+	MFA                *MFASettings       `bson:"mfa,omitempty" json:"-"`
+	Identities         []ExternalIdentity `bson:"identities,omitempty" json:"identities,omitempty"`   // Providers the user can sign in with
+	Restriction        *UserRestriction   `bson:"restriction,omitempty" json:"restriction,omitempty"` // Set by admins, blocks sign in and API access
+	PasswordResetAt    *time.Time         `bson:"passwordResetAt,omitempty" json:"-"`                 // Last password reset by an admin
+}
+
+// Account restrictions admins can put on a user
+const (
+	UserRestrictionSuspended = "suspended"
+	UserRestrictionBanned    = "banned"
+)
+
+// UserRestriction keeps a user out until a suspension ends, or for good for a ban.
+type UserRestriction struct {
+	Type         string             `bson:"type" json:"type"`
+	Reason       string             `bson:"reason" json:"reason"`
+	Until        *time.Time         `bson:"until,omitempty" json:"until,omitempty"` // End of a suspension
+	RestrictedBy primitive.ObjectID `bson:"restrictedBy" json:"restrictedBy"`       // Admin who restricted the user
+	RestrictedAt time.Time          `bson:"restrictedAt" json:"restrictedAt"`
+}
+
+// InEffect reports whether the restriction still applies, suspensions stop applying once they end.
+func (r *UserRestriction) InEffect(now time.Time) bool {
+	return r != nil && (r.Until == nil || now.Before(*r.Until))
+}
+
+// UserSuspensionInput suspends a user until the given time.
+type UserSuspensionInput struct {
+	Until  time.Time `json:"until" validate:"required"`
+	Reason string    `json:"reason" validate:"required,max=500"`
+}
+
+// UserBanInput bans a user until an admin lifts the ban.
+type UserBanInput struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// UserAdminDetail is what support sees of one user: the account, its workout plan enrollments and how its
+// subscription changed.
+type UserAdminDetail struct {
+	User                User
+	Enrollments         []UserWorkoutPlanStatus
+	SubscriptionChanges []SubscriptionEvent // Newest first
+	Invoices            []Invoice           // Payments, newest first
 }
 
 type UserSubscription struct {
-	Type            string     `bson:"type" json:"type" binding:"required"`
-	Status          string     `bson:"status" json:"status" binding:"required"`
-	StartDate       time.Time  `bson:"startDate,omitempty" json:"startDate" binding:"required"`
-	EndDate         *time.Time `bson:"endDate,omitempty" json:"endDate,omitempty"`
-	NextRenewalDate *time.Time `bson:"nextRenewalDate,omitempty" json:"nextRenewalDate,omitempty"` // Next scheduled renewal date
-	IsActive        bool       `bson:"isActive" json:"isActive" binding:"required"`                // Indicates whether the input is currently active
+	Type                   string              `bson:"type" json:"type" binding:"required"`
+	Status                 string              `bson:"status" json:"status" binding:"required"`
+	StartDate              time.Time           `bson:"startDate,omitempty" json:"startDate" binding:"required"`
+	EndDate                *time.Time          `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	NextRenewalDate        *time.Time          `bson:"nextRenewalDate,omitempty" json:"nextRenewalDate,omitempty"` // Next scheduled renewal date
+	IsActive               bool                `bson:"isActive" json:"isActive" binding:"required"`                // Indicates whether the input is currently active
+	CancelledAt            *time.Time          `bson:"cancelledAt,omitempty" json:"cancelledAt,omitempty"`         // Access remains until the end date
+	PendingChange          *SubscriptionChange `bson:"pendingChange,omitempty" json:"pendingChange,omitempty"`     // Plan change waiting for the next activation
+	Provider               string              `bson:"provider,omitempty" json:"provider,omitempty"`               // Where the subscription is paid and managed
+	ProviderCustomerID     string              `bson:"providerCustomerId,omitempty" json:"-"`                      // Customer at the payment provider
+	ProviderSubscriptionID string              `bson:"providerSubscriptionId,omitempty" json:"-"`                  // Subscription at the payment provider, matches its webhooks
+	RenewalRemindedAt      *time.Time          `bson:"renewalRemindedAt,omitempty" json:"-"`                       // Reminder of the next renewal was sent
+	ConvertedAt            *time.Time          `bson:"convertedAt,omitempty" json:"-"`                             // First activation paid by the user
+	Version                int                 `bson:"version,omitempty" json:"-"`                                 // Incremented by every write of the subscription
 }
 
 type UserProfile struct {
@@ -82,14 +134,37 @@ type SystemPreferences struct {
 	AllowReadReceipt  bool   `bson:"allowReadReceipt" json:"allowReadReceipt"` // Global setting for allowing read receipts.
 }
 
-func NewUserfromInput(input UserRegistrationInput) (User, error) {
-	hasher := &utils.DefaultHasher{}
+func NewUserfromInput(input UserRegistrationInput, hasher utils.HashPasswordService) (User, error) {
 	hashedPassword, err := hasher.HashPassword(input.Password)
 	if err != nil {
 		return User{}, err
 	}
-	userSubscription := convertSubscriptionInput(input.Subscription)
 
+	return newUser(input, hashedPassword), nil
+}
+
+// NewUserfromOIDCRegistration creates a user without password, who signs in through the linked provider.
+func NewUserfromOIDCRegistration(input OIDCRegistrationInput, pending PendingRegistration) User {
+	user := newUser(UserRegistrationInput{
+		FirstName:          input.FirstName,
+		LastName:           input.LastName,
+		Email:              pending.Email,
+		BirthDate:          input.BirthDate,
+		Gender:             input.Gender,
+		Height:             input.Height,
+		Weight:             input.Weight,
+		ProfileInformation: input.ProfileInformation,
+		SystemPreferences:  input.SystemPreferences,
+	}, "")
+	if user.ProfileInformation.ProfilePicture == "" {
+		user.ProfileInformation.ProfilePicture = pending.ProfilePicture
+	}
+	user.Identities = []ExternalIdentity{pending.Identity}
+
+	return user
+}
+
+func newUser(input UserRegistrationInput, hashedPassword string) User {
 	user := User{
 		ID:                 primitive.NewObjectID(),
 		Role:               "user",
@@ -101,24 +176,17 @@ func NewUserfromInput(input UserRegistrationInput) (User, error) {
 		Gender:             input.Gender,
 		Height:             input.Height,
 		Weight:             input.Weight,
-		Subscription:       *userSubscription,
-		TrialEndsAt:        input.TrialEndsAt,
 		ProfileInformation: convertUserProfileInput(input.ProfileInformation),
 		SystemPreferences:  convertSystemPreferencesInput(input.SystemPreferences),
 	}
 
-	return user, nil
+	return user
 }
 
-func convertSubscriptionInput(input SubscriptionInput) *UserSubscription {
-	return &UserSubscription{
-		Type:            input.Type,
-		Status:          input.Status,
-		StartDate:       input.StartDate,
-		EndDate:         input.EndDate,
-		NextRenewalDate: input.NextRenewalDate,
-		IsActive:        input.IsActive,
-	}
+// StartTrial gives the new user the premium trial, its length comes from the configuration.
+func (u *User) StartTrial(now time.Time, trialDays int) {
+	u.Subscription = NewTrialSubscription(now, trialDays)
+	u.TrialEndsAt = *u.Subscription.EndDate
 }
 
 func convertUserProfileInput(input UserProfileInput) UserProfile {
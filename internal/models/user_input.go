@@ -1,16 +1,5 @@
 package models
 
-import "time"
-
-type UserSubscriptionUpdateInput struct {
-	Type *string `json:"type" validate:"omitempty"`
-	Status *string `json:"status" validate:"omitempty"`
-	StartDate *time.Time `json:"startDate" validate:"omitempty"`
-	EndDate *time.Time `json:"endDate" validate:"omitempty"`
-	NextRenewalDate *time.Time `json:"nextRenewalDate" validate:"omitempty"`
-	IsActive *bool `json:"isActive" validate:"omitempty"`
-}
-
 type UserProfileUpdateInput struct {
 	Username *string `json:"username" validate:"omitempty"`
 	ProfilePicture *string `json:"profilePicture" validate:"omitempty,url"`
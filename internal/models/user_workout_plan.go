@@ -19,6 +19,7 @@ type UserExerciseStatus struct {
 	WorkoutPlanID primitive.ObjectID `bson:"workoutPlanId" json:"workoutPlanId" binding:"required" validate:"required"`
 	Completed     bool               `bson:"completed" json:"completed" binding:"required" validate:"omitempty"`
 	CompletedLogs []UserExerciseLogInput      `bson:"completedLogs" json:"completedLogs" binding:"required" validate:"required,dive,required"`
+	CompletedAt   *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
 }
 
 func NewUserExerciseStatus(userID, exerciseID, circuitID, workoutPlanID primitive.ObjectID) UserExerciseStatus {
@@ -79,6 +80,8 @@ type UserWorkoutWeekStatus struct {
 	WorkoutWeekID primitive.ObjectID `bson:"workoutWeekId" json:"workoutWeekId" binding:"required"`
 	WorkoutPlanID  primitive.ObjectID `bson:"workoutPlanId" json:"workoutPlanId" binding:"required"` // Reference to the WorkoutPlan
 	CompletedDays int                `bson:"completedDays" json:"completedDays"`
+	Completed     bool               `bson:"completed" json:"completed"`
+	CompletedAt   *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"` // When its last day was completed
 }
 
 func NewUserWorkoutWeekStatus(userID, workoutWeekID, workoutPlanID primitive.ObjectID) UserWorkoutWeekStatus {
@@ -99,6 +102,7 @@ type UserWorkoutPlanStatus struct {
 	Progress 	 	float64            `bson:"progress" json:"progress"`
 	CompletionDate *time.Time         `bson:"completionDate" json:"completionDate"` // nil if not completed
 	Completed      bool               `bson:"completed" json:"completed"`
+	Version        int                `bson:"version,omitempty" json:"version,omitempty"` // Version of the plan followed, none for plans never versioned
 	// More fields as necessary to track progress, such as completed workouts or weeks
 }
 
@@ -24,6 +24,7 @@ type WorkoutDay struct {
 	Workouts          []Circuit          `bson:"workouts" json:"workouts" binding:"required" validate:"required,dive"`
 	CoolDowns         []Circuit          `bson:"coolDowns" json:"coolDowns" binding:"required" validate:"required,dive"`
 	WorkoutTimeRange [2]int             `bson:"workoutTimeRange" json:"workoutTimeRange" binding:"required" validate:"required,dive,gte=1,lte=7200"` // [minTime, maxTime] in seconds.
+	TemplateID       *primitive.ObjectID `bson:"templateId,omitempty" json:"templateId,omitempty"` // Day template the day was copied from
 	// Removed TotalExercises and Equipment fields
 	// Other fields...
 }
@@ -33,6 +34,7 @@ type WorkoutWeek struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Days       []WorkoutDay       `bson:"days" json:"days" binding:"required" validate:"required,dive"`
 	WeekNumber int                `bson:"weekNumber" json:"weekNumber" binding:"required" validate:"required,gte=1"`
+	TemplateID *primitive.ObjectID `bson:"templateId,omitempty" json:"templateId,omitempty"` // Week template the days were copied from
 	//To be added later once the generative AI is integrated, program will be distinct from each user
 	// CompletedDays int          `bson:"completedDays" json:"completedDays"` // Derived by counting days with CompletionStatus true.
 }
@@ -43,6 +45,9 @@ type WorkoutPlan struct {
 	ImageURL string             `bson:"imageURL" json:"imageURL" binding:"required" validate:"required,url"`
 	Duration int                `bson:"duration" json:"duration" binding:"required" validate:"required,gt=0"`
 	Weeks    []WorkoutWeek      `bson:"weeks" json:"weeks" binding:"required" validate:"required,dive"`
+	AccessTier string           `bson:"accessTier,omitempty" json:"accessTier,omitempty" validate:"omitempty,oneof=basic premium"` // Empty is basic
+	PlanPublication             `bson:",inline"` // Set by the service, new plans are drafts
+	Version    int              `bson:"version,omitempty" json:"version,omitempty"` // Last published version, set by the service
 }
 
 // AI WorkoutPlan
@@ -6,6 +6,7 @@ type WorkoutPlanInput struct {
 	Name        *string `json:"name,omitempty" validate:"omitempty,min=3,max=50"`
 	Duration    *int    `json:"duration,omitempty" validate:"omitempty,gte=1,lte=10"`
 	Weeks       *[]WorkoutWeekInput `json:"weeks,omitempty" validate:"omitempty,dive"`
+	AccessTier  *string `json:"accessTier,omitempty" validate:"omitempty,oneof=basic premium"`
 }
 
 type WorkoutWeekInput struct {
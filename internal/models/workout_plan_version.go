@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkoutPlanVersion is the content of a workout plan as it was published, it never changes. Enrolled users follow
+// the version they joined until they are migrated to a later one.
+type WorkoutPlanVersion struct {
+	ID            primitive.ObjectID `bson:"_id" json:"id"`
+	WorkoutPlanID primitive.ObjectID `bson:"workoutPlanId" json:"workoutPlanId"`
+	Version       int                `bson:"version" json:"version"`
+	Plan          WorkoutPlan        `bson:"plan" json:"plan"`
+	PublishedAt   time.Time          `bson:"publishedAt" json:"publishedAt"`
+}
+
+// Sections of a workout day, used to locate a circuit across versions
+const (
+	WorkoutSectionWarmUps   = "warmUps"
+	WorkoutSectionWorkouts  = "workouts"
+	WorkoutSectionCoolDowns = "coolDowns"
+)
+
+// WorkoutPlanMigrationInput moves users enrolled in a workout plan from older versions to the given one.
+type WorkoutPlanMigrationInput struct {
+	ToVersion int                  `json:"toVersion" validate:"required,gte=1"`
+	UserIDs   []primitive.ObjectID `json:"userIds,omitempty" validate:"omitempty,max=1000"` // Every enrolled user of an older version when empty
+	Limit     int64                `json:"limit,omitempty" validate:"omitempty,gte=1,lte=1000"`
+	DryRun    bool                 `json:"dryRun"` // Reports what would be carried over without migrating
+}
+
+// WorkoutPlanMigrationReport tells what progress of each migrated user was carried over to the new version.
+type WorkoutPlanMigrationReport struct {
+	WorkoutPlanID primitive.ObjectID         `json:"workoutPlanId"`
+	ToVersion     int                        `json:"toVersion"`
+	DryRun        bool                       `json:"dryRun"`
+	Users         []WorkoutPlanUserMigration `json:"users"`
+	Remaining     int64                      `json:"remaining"` // Users left on older versions beyond the limit
+}
+
+// WorkoutPlanUserMigration is the migration of one enrollment. A circuit matches when it is at the same place in
+// both versions (week number, day, section and position), an exercise when its circuit matches and still has it.
+type WorkoutPlanUserMigration struct {
+	UserID           primitive.ObjectID    `json:"userId"`
+	FromVersion      int                   `json:"fromVersion"`
+	CarriedExercises int                   `json:"carriedExercises"`
+	NotCarried       []WorkoutProgressLoss `json:"notCarried"`
+}
+
+// WorkoutProgressLoss locates progress made on the old version that the new version has no place for.
+type WorkoutProgressLoss struct {
+	WeekNumber int                `json:"weekNumber"`
+	Day        int                `json:"day"` // Position in the week, from 1
+	Section    string             `json:"section"`
+	Circuit    int                `json:"circuit"` // Position in the section, from 1
+	ExerciseID primitive.ObjectID `json:"exerciseId"`
+	Reason     string             `json:"reason"`
+}
+
+// Why progress is not carried over
+const (
+	ProgressLossCircuitRemoved  = "circuit_removed"
+	ProgressLossExerciseRemoved = "exercise_removed"
+)
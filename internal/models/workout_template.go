@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of workout templates
+const (
+	WorkoutTemplateWeek = "week"
+	WorkoutTemplateDay  = "day"
+)
+
+// WorkoutTemplate is a week or a day coaches reuse across workout plans. Weeks and days of a new plan refer to it by
+// ID and get a copy of its days, later edits of the template don't change the plans built from it.
+type WorkoutTemplate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name      string             `bson:"name" json:"name" validate:"required,min=3,max=50"`
+	Kind      string             `bson:"kind" json:"kind" validate:"required,oneof=week day"`
+	Days      []WorkoutDay       `bson:"days" json:"days" validate:"required,min=1,max=7,dive"` // A single day for day templates
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// WorkoutTemplateInput creates or replaces a workout template. Its days may refer to day templates, they are
+// expanded before the input is validated.
+type WorkoutTemplateInput struct {
+	Name string       `json:"name" validate:"required,min=3,max=50"`
+	Kind string       `json:"kind" validate:"required,oneof=week day"`
+	Days []WorkoutDay `json:"days" validate:"required,min=1,max=7,dive"`
+}
+
+// PlanCloneInput names the copy of a workout or meal plan.
+type PlanCloneInput struct {
+	Name string `json:"name" validate:"required,min=5,max=50"`
+}
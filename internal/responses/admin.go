@@ -0,0 +1,74 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Admin is an admin account as listed to the admins managing it.
+type Admin struct {
+	ID         primitive.ObjectID  `json:"id"`
+	Role       string              `json:"role"`
+	Email      string              `json:"email"`
+	Disabled   bool                `json:"disabled"`
+	MFAEnabled bool                `json:"mfaEnabled"`
+	InvitedBy  *primitive.ObjectID `json:"invitedBy,omitempty"`
+	CreatedAt  time.Time           `json:"createdAt,omitempty"`
+}
+
+// AdminInvitation leaves out the token hash, the token itself is only returned once when the invitation is created.
+type AdminInvitation struct {
+	ID         primitive.ObjectID `json:"id"`
+	Email      string             `json:"email"`
+	Role       string             `json:"role"`
+	InvitedBy  primitive.ObjectID `json:"invitedBy"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	ExpiresAt  time.Time          `json:"expiresAt"`
+	AcceptedAt *time.Time         `json:"acceptedAt,omitempty"`
+	RevokedAt  *time.Time         `json:"revokedAt,omitempty"`
+}
+
+func NewAdmin(admin models.Admin) Admin {
+	return Admin{
+		ID:         admin.ID,
+		Role:       admin.Role,
+		Email:      admin.Email,
+		Disabled:   admin.Disabled,
+		MFAEnabled: admin.MFA != nil && admin.MFA.Enabled,
+		InvitedBy:  admin.InvitedBy,
+		CreatedAt:  admin.CreatedAt,
+	}
+}
+
+func NewAdmins(admins []models.Admin) []Admin {
+	views := make([]Admin, 0, len(admins))
+	for _, admin := range admins {
+		views = append(views, NewAdmin(admin))
+	}
+
+	return views
+}
+
+func NewAdminInvitation(invitation models.AdminInvitation) AdminInvitation {
+	return AdminInvitation{
+		ID:         invitation.ID,
+		Email:      invitation.Email,
+		Role:       invitation.Role,
+		InvitedBy:  invitation.InvitedBy,
+		CreatedAt:  invitation.CreatedAt,
+		ExpiresAt:  invitation.ExpiresAt,
+		AcceptedAt: invitation.AcceptedAt,
+		RevokedAt:  invitation.RevokedAt,
+	}
+}
+
+func NewAdminInvitations(invitations []models.AdminInvitation) []AdminInvitation {
+	views := make([]AdminInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		views = append(views, NewAdminInvitation(invitation))
+	}
+
+	return views
+}
@@ -0,0 +1,44 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey leaves out the key hash, the key itself is only returned once when it is created.
+type APIKey struct {
+	ID         primitive.ObjectID `json:"id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	Scopes     []string           `json:"scopes"`
+	CreatedBy  primitive.ObjectID `json:"createdBy"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	ExpiresAt  *time.Time         `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time         `json:"revokedAt,omitempty"`
+}
+
+func NewAPIKey(apiKey models.APIKey) APIKey {
+	return APIKey{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		CreatedBy:  apiKey.CreatedBy,
+		CreatedAt:  apiKey.CreatedAt,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+	}
+}
+
+func NewAPIKeys(apiKeys []models.APIKey) []APIKey {
+	views := make([]APIKey, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		views = append(views, NewAPIKey(apiKey))
+	}
+
+	return views
+}
@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a device the user is signed in on, Current marks the one making the request.
+type Session struct {
+	ID         primitive.ObjectID `json:"id"`
+	DeviceName string             `json:"deviceName,omitempty"`
+	UserAgent  string             `json:"userAgent,omitempty"`
+	IP         string             `json:"ip,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	LastUsedAt time.Time          `json:"lastUsedAt"`
+	ExpiresAt  time.Time          `json:"expiresAt"`
+	Current    bool               `json:"current"`
+}
+
+func NewSession(session models.Session, currentSessionID string) Session {
+	return Session{
+		ID:         session.ID,
+		DeviceName: session.DeviceName,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID.Hex() == currentSessionID,
+	}
+}
+
+func NewSessions(sessions []models.Session, currentSessionID string) []Session {
+	views := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, NewSession(session, currentSessionID))
+	}
+
+	return views
+}
@@ -0,0 +1,132 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserPublicProfile is what other users may see about an account.
+type UserPublicProfile struct {
+	ID             primitive.ObjectID `json:"id"`
+	Username       string             `json:"username"`
+	ProfilePicture string             `json:"profilePicture"`
+	MainGoal       string             `json:"mainGoal"`
+}
+
+// UserSelf is the account as shown to its owner.
+type UserSelf struct {
+	ID                 primitive.ObjectID        `json:"id"`
+	FirstName          string                    `json:"firstName"`
+	LastName           string                    `json:"lastName"`
+	Email              string                    `json:"email"`
+	BirthDate          time.Time                 `json:"birthDate"`
+	Gender             string                    `json:"gender"`
+	Height             int                       `json:"height"`
+	Weight             int                       `json:"weight"`
+	Subscription       models.UserSubscription   `json:"subscription"`
+	TrialEndsAt        time.Time                 `json:"trialEndsAt"`
+	ProfileInformation models.UserProfile        `json:"profileInformation"`
+	SystemPreferences  *models.SystemPreferences `json:"systemPreferences,omitempty"`
+	MFAEnabled         bool                      `json:"mfaEnabled"`
+	LinkedProviders    []string                  `json:"linkedProviders"` // Identity providers the user can sign in with
+}
+
+// UserAdminView is the account as listed to admins, without the personal profile details.
+type UserAdminView struct {
+	ID           primitive.ObjectID      `json:"id"`
+	Role         string                  `json:"role"`
+	FirstName    string                  `json:"firstName"`
+	LastName     string                  `json:"lastName"`
+	Email        string                  `json:"email"`
+	Username     string                  `json:"username"`
+	Gender       string                  `json:"gender"`
+	BirthDate    time.Time               `json:"birthDate"`
+	Subscription models.UserSubscription `json:"subscription"`
+	TrialEndsAt  time.Time               `json:"trialEndsAt"`
+	MFAEnabled   bool                    `json:"mfaEnabled"`
+	Restriction  *models.UserRestriction `json:"restriction,omitempty"` // Suspension or ban, even once ended
+}
+
+// UserAdminDetail is one user as shown to support, with what the list leaves out.
+type UserAdminDetail struct {
+	UserAdminView
+	LinkedProviders     []string                       `json:"linkedProviders"`
+	Enrollments         []models.UserWorkoutPlanStatus `json:"enrollments"`
+	SubscriptionChanges []models.SubscriptionEvent     `json:"subscriptionChanges"` // Newest first
+	Invoices            []models.Invoice               `json:"invoices"`
+}
+
+func NewUserPublicProfile(user models.User) UserPublicProfile {
+	return UserPublicProfile{
+		ID:             user.ID,
+		Username:       user.ProfileInformation.Username,
+		ProfilePicture: user.ProfileInformation.ProfilePicture,
+		MainGoal:       user.ProfileInformation.MainGoal,
+	}
+}
+
+func NewUserSelf(user models.User) UserSelf {
+	return UserSelf{
+		ID:                 user.ID,
+		FirstName:          user.FirstName,
+		LastName:           user.LastName,
+		Email:              user.Email,
+		BirthDate:          user.BirthDate,
+		Gender:             user.Gender,
+		Height:             user.Height,
+		Weight:             user.Weight,
+		Subscription:       user.Subscription,
+		TrialEndsAt:        user.TrialEndsAt,
+		ProfileInformation: user.ProfileInformation,
+		SystemPreferences:  user.SystemPreferences,
+		MFAEnabled:         user.MFA != nil && user.MFA.Enabled,
+		LinkedProviders:    linkedProviders(user.Identities),
+	}
+}
+
+func NewUserAdminView(user models.User) UserAdminView {
+	return UserAdminView{
+		ID:           user.ID,
+		Role:         user.Role,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Email:        user.Email,
+		Username:     user.ProfileInformation.Username,
+		Gender:       user.Gender,
+		BirthDate:    user.BirthDate,
+		Subscription: user.Subscription,
+		TrialEndsAt:  user.TrialEndsAt,
+		MFAEnabled:   user.MFA != nil && user.MFA.Enabled,
+		Restriction:  user.Restriction,
+	}
+}
+
+func NewUserAdminViews(users []models.User) []UserAdminView {
+	views := make([]UserAdminView, 0, len(users))
+	for _, user := range users {
+		views = append(views, NewUserAdminView(user))
+	}
+
+	return views
+}
+
+func NewUserAdminDetail(detail models.UserAdminDetail) UserAdminDetail {
+	return UserAdminDetail{
+		UserAdminView:       NewUserAdminView(detail.User),
+		LinkedProviders:     linkedProviders(detail.User.Identities),
+		Enrollments:         detail.Enrollments,
+		SubscriptionChanges: detail.SubscriptionChanges,
+		Invoices:            detail.Invoices,
+	}
+}
+
+func linkedProviders(identities []models.ExternalIdentity) []string {
+	providers := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		providers = append(providers, identity.Provider)
+	}
+
+	return providers
+}
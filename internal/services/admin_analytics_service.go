@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Subscriptions renew monthly, the MRR is the subscription revenue of this window
+const mrrWindow = 30 * 24 * time.Hour
+
+// Reports cover a few years at most, split in at most a year of days
+const (
+	maxAnalyticsRange   = 5 * 366 * 24 * time.Hour
+	maxAnalyticsPeriods = 366
+)
+
+var ErrInvalidAnalyticsRange = errors.New("invalid analytics range")
+
+// Users are matched by the timestamp of their ID, which only holds the seconds from 1970 to 2106 in 32 bits
+var (
+	minAnalyticsDate = time.Unix(0, 0).UTC()
+	maxAnalyticsDate = time.Unix(math.MaxUint32, 0).UTC()
+)
+
+// CheckAnalyticsRange refuses the ranges the reports can't cover, too long, split in too many periods of the
+// interval, or outside what the IDs of the users can be compared with.
+func CheckAnalyticsRange(from, to time.Time, interval string) error {
+	if from.Before(minAnalyticsDate) || to.After(maxAnalyticsDate) {
+		return fmt.Errorf("%w: dates must be between %s and %s", ErrInvalidAnalyticsRange, minAnalyticsDate.Format("2006-01-02"), maxAnalyticsDate.Format("2006-01-02"))
+	}
+	if to.Sub(from) > maxAnalyticsRange {
+		return fmt.Errorf("%w: the range covers %d days at most", ErrInvalidAnalyticsRange, int(maxAnalyticsRange.Hours()/24))
+	}
+	if interval != "" && len(periodsBetween(from, to, interval)) > maxAnalyticsPeriods {
+		return fmt.Errorf("%w: the range is split in %d periods at most, use a longer interval", ErrInvalidAnalyticsRange, maxAnalyticsPeriods)
+	}
+	return nil
+}
+
+// periodCount is a count grouped by the start of a period.
+type periodCount struct {
+	Period time.Time `bson:"_id"`
+	Count  int64     `bson:"count"`
+}
+
+// GetSubscriptionBreakdown counts the users who signed up in [from, to) by subscription status, and those with
+// access by plan.
+func (as *AdminService) GetSubscriptionBreakdown(ctx context.Context, from, to time.Time) (*models.SubscriptionBreakdown, error) {
+	pipeline := bson.A{
+		bson.M{"$match": signedUpBetween(from, to)},
+		bson.M{"$facet": bson.M{
+			"byStatus": bson.A{
+				bson.M{"$group": bson.M{"_id": "$subscription.status", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"byPlan": bson.A{
+				bson.M{"$match": bson.M{"subscription.isActive": true}},
+				bson.M{"$group": bson.M{"_id": "$subscription.type", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}},
+	}
+
+	var results []struct {
+		ByStatus []models.AnalyticsCount `bson:"byStatus"`
+		ByPlan   []models.AnalyticsCount `bson:"byPlan"`
+	}
+	if err := aggregateAnalytics(ctx, as.database.Collection("users"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	breakdown := &models.SubscriptionBreakdown{
+		AnalyticsRange: models.AnalyticsRange{From: from, To: to},
+		ByStatus:       []models.AnalyticsCount{},
+		ByPlan:         []models.AnalyticsCount{},
+	}
+	if len(results) > 0 {
+		breakdown.ByStatus = append(breakdown.ByStatus, results[0].ByStatus...)
+		breakdown.ByPlan = append(breakdown.ByPlan, results[0].ByPlan...)
+	}
+
+	return breakdown, nil
+}
+
+// GetSignupAnalytics counts the signups and the conversions to a paid subscription of each period of [from, to).
+// Periods without any are listed with zeros.
+func (as *AdminService) GetSignupAnalytics(ctx context.Context, from, to time.Time, interval string) (*models.SignupAnalytics, error) {
+	converted := bson.M{"subscription.convertedAt": bson.M{"$gte": from, "$lt": to}}
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"$or": bson.A{signedUpBetween(from, to), converted}}},
+		bson.M{"$facet": bson.M{
+			// Users have no creation date, their ID holds it
+			"signups": bson.A{
+				bson.M{"$match": signedUpBetween(from, to)},
+				bson.M{"$group": bson.M{"_id": truncateToPeriod(bson.M{"$toDate": "$_id"}, interval), "count": bson.M{"$sum": 1}}},
+			},
+			"conversions": bson.A{
+				bson.M{"$match": converted},
+				bson.M{"$group": bson.M{"_id": truncateToPeriod("$subscription.convertedAt", interval), "count": bson.M{"$sum": 1}}},
+			},
+		}},
+	}
+
+	var results []struct {
+		Signups     []periodCount `bson:"signups"`
+		Conversions []periodCount `bson:"conversions"`
+	}
+	if err := aggregateAnalytics(ctx, as.database.Collection("users"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	signups := map[time.Time]int64{}
+	conversions := map[time.Time]int64{}
+	if len(results) > 0 {
+		for _, count := range results[0].Signups {
+			signups[count.Period.UTC()] = count.Count
+		}
+		for _, count := range results[0].Conversions {
+			conversions[count.Period.UTC()] = count.Count
+		}
+	}
+
+	analytics := &models.SignupAnalytics{
+		AnalyticsRange: models.AnalyticsRange{From: from, To: to, Interval: interval},
+		Points:         []models.SignupPoint{},
+	}
+	for _, period := range periodsBetween(from, to, interval) {
+		analytics.Points = append(analytics.Points, models.SignupPoint{Period: period, Signups: signups[period], Conversions: conversions[period]})
+	}
+
+	return analytics, nil
+}
+
+// GetRevenueAnalytics sums the invoices of each period of [from, to) by currency, and the MRR at the end of the
+// range.
+func (as *AdminService) GetRevenueAnalytics(ctx context.Context, from, to time.Time, interval string) (*models.RevenueAnalytics, error) {
+	mrrFrom := to.Add(-mrrWindow)
+	subscriptionKind := bson.M{"$eq": bson.A{"$kind", models.InvoiceKindSubscription}}
+	giftKind := bson.M{"$eq": bson.A{"$kind", models.InvoiceKindGift}}
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"issuedAt": bson.M{"$gte": minTime(from, mrrFrom), "$lt": to}}},
+		bson.M{"$facet": bson.M{
+			"points": bson.A{
+				bson.M{"$match": bson.M{"issuedAt": bson.M{"$gte": from}}},
+				bson.M{"$group": bson.M{
+					"_id":                 bson.M{"period": truncateToPeriod("$issuedAt", interval), "currency": "$currency"},
+					"invoices":            bson.M{"$sum": 1},
+					"subscriptionRevenue": bson.M{"$sum": bson.M{"$cond": bson.A{subscriptionKind, "$subtotal", 0}}},
+					"giftRevenue":         bson.M{"$sum": bson.M{"$cond": bson.A{giftKind, "$subtotal", 0}}},
+					"tax":                 bson.M{"$sum": "$tax"},
+					"total":               bson.M{"$sum": "$total"},
+				}},
+				bson.M{"$sort": bson.D{{Key: "_id.period", Value: 1}, {Key: "_id.currency", Value: 1}}},
+			},
+			"mrr": bson.A{
+				bson.M{"$match": bson.M{"issuedAt": bson.M{"$gte": mrrFrom}, "kind": models.InvoiceKindSubscription}},
+				bson.M{"$group": bson.M{"_id": "$currency", "amount": bson.M{"$sum": "$subtotal"}}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}},
+	}
+
+	var results []struct {
+		Points []struct {
+			Key struct {
+				Period   time.Time `bson:"period"`
+				Currency string    `bson:"currency"`
+			} `bson:"_id"`
+			Invoices            int64 `bson:"invoices"`
+			SubscriptionRevenue int64 `bson:"subscriptionRevenue"`
+			GiftRevenue         int64 `bson:"giftRevenue"`
+			Tax                 int64 `bson:"tax"`
+			Total               int64 `bson:"total"`
+		} `bson:"points"`
+		MRR []models.CurrencyAmount `bson:"mrr"`
+	}
+	if err := aggregateAnalytics(ctx, as.database.Collection("invoices"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	analytics := &models.RevenueAnalytics{
+		AnalyticsRange: models.AnalyticsRange{From: from, To: to, Interval: interval},
+		Points:         []models.RevenuePoint{},
+		MRR:            []models.CurrencyAmount{},
+	}
+	if len(results) > 0 {
+		for _, point := range results[0].Points {
+			analytics.Points = append(analytics.Points, models.RevenuePoint{
+				Period:              point.Key.Period.UTC(),
+				Currency:            point.Key.Currency,
+				Invoices:            point.Invoices,
+				SubscriptionRevenue: point.SubscriptionRevenue,
+				GiftRevenue:         point.GiftRevenue,
+				Tax:                 point.Tax,
+				Total:               point.Total,
+			})
+		}
+		analytics.MRR = append(analytics.MRR, results[0].MRR...)
+	}
+
+	return analytics, nil
+}
+
+// GetChurnAnalytics compares the paid subscriptions that expired in [from, to) to the ones running at from.
+func (as *AdminService) GetChurnAnalytics(ctx context.Context, from, to time.Time) (*models.ChurnAnalytics, error) {
+	between := func(field string) bson.M {
+		return bson.M{"$and": bson.A{bson.M{"$gte": bson.A{field, from}}, bson.M{"$lt": bson.A{field, to}}}}
+	}
+	// Paying at from: converted before it and not expired yet
+	payingAtStart := bson.M{"$and": bson.A{
+		bson.M{"$lt": bson.A{"$subscription.convertedAt", from}},
+		bson.M{"$or": bson.A{
+			bson.M{"$ne": bson.A{"$subscription.status", models.SubscriptionStatusExpired}},
+			bson.M{"$gte": bson.A{"$subscription.endDate", from}},
+		}},
+	}}
+	churned := bson.M{"$and": bson.A{
+		bson.M{"$eq": bson.A{"$subscription.status", models.SubscriptionStatusExpired}},
+		between("$subscription.endDate"),
+	}}
+	pipeline := bson.A{
+		// Only users who paid at some point can churn
+		bson.M{"$match": bson.M{"subscription.convertedAt": bson.M{"$lt": to}}},
+		bson.M{"$group": bson.M{
+			"_id":           nil,
+			"payingAtStart": bson.M{"$sum": bson.M{"$cond": bson.A{payingAtStart, 1, 0}}},
+			"churned":       bson.M{"$sum": bson.M{"$cond": bson.A{churned, 1, 0}}},
+			"cancellations": bson.M{"$sum": bson.M{"$cond": bson.A{between("$subscription.cancelledAt"), 1, 0}}},
+		}},
+	}
+
+	var results []models.ChurnAnalytics
+	if err := aggregateAnalytics(ctx, as.database.Collection("users"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	analytics := models.ChurnAnalytics{}
+	if len(results) > 0 {
+		analytics = results[0]
+	}
+	analytics.AnalyticsRange = models.AnalyticsRange{From: from, To: to}
+	analytics.ChurnRate = shareOf(analytics.Churned, analytics.PayingAtStart)
+
+	return &analytics, nil
+}
+
+// GetTrialConversionAnalytics counts the trials that ended in [from, to) and the users among them who paid.
+func (as *AdminService) GetTrialConversionAnalytics(ctx context.Context, from, to time.Time) (*models.TrialConversionAnalytics, error) {
+	// Running trials haven't had their chance to convert
+	ended := minTime(to, time.Now())
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"trialEndsAt": bson.M{"$gte": from, "$lt": ended}}},
+		bson.M{"$group": bson.M{
+			"_id":         nil,
+			"trialsEnded": bson.M{"$sum": 1},
+			"converted":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$type": "$subscription.convertedAt"}, "date"}}, 1, 0}}},
+		}},
+	}
+
+	var results []models.TrialConversionAnalytics
+	if err := aggregateAnalytics(ctx, as.database.Collection("users"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	analytics := models.TrialConversionAnalytics{}
+	if len(results) > 0 {
+		analytics = results[0]
+	}
+	analytics.AnalyticsRange = models.AnalyticsRange{From: from, To: to}
+	analytics.ConversionRate = shareOf(analytics.Converted, analytics.TrialsEnded)
+
+	return &analytics, nil
+}
+
+func aggregateAnalytics(ctx context.Context, collection db.MongoCollection, pipeline bson.A, results interface{}) error {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("error aggregating analytics: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, results); err != nil {
+		return fmt.Errorf("error decoding analytics: %w", err)
+	}
+
+	return nil
+}
+
+// signedUpBetween matches the users created in [from, to), from the timestamp of their ID.
+func signedUpBetween(from, to time.Time) bson.M {
+	return bson.M{"_id": bson.M{"$gte": primitive.NewObjectIDFromTimestamp(from), "$lt": primitive.NewObjectIDFromTimestamp(to)}}
+}
+
+// truncateToPeriod is the start of the period of the date, in UTC with weeks starting on Monday.
+func truncateToPeriod(date interface{}, interval string) bson.M {
+	truncate := bson.M{"date": date, "unit": interval, "timezone": "UTC"}
+	if interval == models.AnalyticsIntervalWeek {
+		truncate["startOfWeek"] = "monday"
+	}
+	return bson.M{"$dateTrunc": truncate}
+}
+
+// periodsBetween lists the start of each period of [from, to), the way truncateToPeriod computes them.
+func periodsBetween(from, to time.Time, interval string) []time.Time {
+	from = from.UTC()
+	period := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case models.AnalyticsIntervalWeek:
+		period = period.AddDate(0, 0, -(int(period.Weekday())+6)%7)
+	case models.AnalyticsIntervalMonth:
+		period = period.AddDate(0, 0, 1-period.Day())
+	}
+
+	var periods []time.Time
+	for ; period.Before(to); period = nextPeriod(period, interval) {
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+func nextPeriod(period time.Time, interval string) time.Time {
+	switch interval {
+	case models.AnalyticsIntervalWeek:
+		return period.AddDate(0, 0, 7)
+	case models.AnalyticsIntervalMonth:
+		return period.AddDate(0, 1, 0)
+	}
+	return period.AddDate(0, 0, 1)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func shareOf(count, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// apiKeyPrefix makes keys recognizable, e.g. by secret scanners
+	apiKeyPrefix = "vigor_"
+	// Length of the start of the key kept in clear to tell keys apart
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	// Last use is only written when older than this, so each request doesn't cost a write
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrAPIKeyNotFound      = errors.New("active API key not found")
+	ErrInvalidAPIKeyScope  = errors.New("scope can't be granted to an API key")
+	ErrInvalidAPIKeyExpiry = errors.New("API key expiry must be in the future")
+)
+
+// CreateAPIKey returns the key record and the key itself. The key is only available here, it is stored hashed.
+func (as *AdminService) CreateAPIKey(ctx context.Context, createdBy primitive.ObjectID, input models.APIKeyInput) (*models.APIKey, string, error) {
+	if err := validateAPIKeyScopes(input.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, "", ErrInvalidAPIKeyExpiry
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating API key: %w", err)
+	}
+	key := apiKeyPrefix + token
+
+	apiKey := models.APIKey{
+		ID:        primitive.NewObjectID(),
+		Name:      input.Name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    input.Scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	apiKeyCollection := as.database.Collection("apiKeys")
+	if _, err := apiKeyCollection.InsertOne(ctx, apiKey); err != nil {
+		return nil, "", fmt.Errorf("error inserting API key: %w", err)
+	}
+
+	as.recordAudit(ctx, "apiKeys", auditChange{action: models.AuditActionCreate, targetID: apiKey.ID.Hex(), after: apiKey})
+
+	return &apiKey, key, nil
+}
+
+func (as *AdminService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	apiKeyCollection := as.database.Collection("apiKeys")
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetProjection(bson.M{"keyHash": 0})
+	cursor, err := apiKeyCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding API keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	apiKeys := []models.APIKey{}
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		return nil, fmt.Errorf("error decoding API keys: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (as *AdminService) RevokeAPIKey(ctx context.Context, apiKeyID primitive.ObjectID) error {
+	apiKeyCollection := as.database.Collection("apiKeys")
+
+	revokedAt := time.Now()
+	filter := bson.M{"_id": apiKeyID, "revokedAt": bson.M{"$exists": false}}
+	result, err := apiKeyCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": revokedAt}})
+	if err != nil {
+		return fmt.Errorf("error revoking API key: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	as.recordAudit(ctx, "apiKeys", auditChange{action: models.AuditActionUpdate, targetID: apiKeyID.Hex(), after: bson.M{"revokedAt": revokedAt}})
+	return nil
+}
+
+// AuthenticateAPIKey returns the key record when the key is neither revoked nor expired, nil otherwise.
+// It records the use of the key.
+func (as *AdminService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	apiKeyCollection := as.database.Collection("apiKeys")
+
+	now := time.Now()
+	filter := bson.M{
+		"keyHash":   utils.HashToken(key),
+		"revokedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}
+
+	var apiKey models.APIKey
+	opts := options.FindOne().SetProjection(bson.M{"keyHash": 0})
+	if err := apiKeyCollection.FindOne(ctx, filter, opts).Decode(&apiKey); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching API key: %w", err)
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if _, err := apiKeyCollection.UpdateOne(ctx, bson.M{"_id": apiKey.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}}); err != nil {
+			log.Printf("Error updating last use of API key %s: %v\n", apiKey.ID.Hex(), err)
+		}
+	}
+
+	return &apiKey, nil
+}
+
+func validateAPIKeyScopes(scopes []string) error {
+	allowed := make(map[string]bool, len(models.APIKeyScopes))
+	for _, scope := range models.APIKeyScopes {
+		allowed[scope] = true
+	}
+
+	for _, scope := range scopes {
+		if !allowed[scope] {
+			return fmt.Errorf("%w: %s", ErrInvalidAPIKeyScope, scope)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultAuditLogLimit = 20
+	maxAuditLogLimit     = 100
+)
+
+// Fields never copied into an audit entry. The target ID is stored on its own.
+var auditRedactedFields = []string{"_id", "passwordHash", "tokenHash", "keyHash", "mfa"}
+
+// auditChange describes one document touched by a mutation, before or after is nil on create and delete.
+type auditChange struct {
+	action   string
+	targetID string
+	before   interface{}
+	after    interface{}
+	reason   string
+}
+
+// recordAudit stores one entry per change, attributed to the actor of the request in ctx.
+// The mutation already happened at this point so a failure is logged rather than returned.
+func (as *AdminService) recordAudit(ctx context.Context, collection string, changes ...auditChange) {
+	metadata := utils.RequestMetadataFromContext(ctx)
+	now := time.Now()
+
+	var entries []interface{}
+	for _, change := range changes {
+		before, after := auditDiff(auditSnapshot(change.before), auditSnapshot(change.after))
+		if change.action == models.AuditActionUpdate && len(before) == 0 && len(after) == 0 {
+			continue
+		}
+
+		entries = append(entries, models.AuditLog{
+			ID:               primitive.NewObjectID(),
+			ActorID:          metadata.ActorID,
+			ActorRole:        metadata.ActorRole,
+			Action:           change.action,
+			TargetCollection: collection,
+			TargetID:         change.targetID,
+			Before:           before,
+			After:            after,
+			Reason:           change.reason,
+			RequestID:        metadata.RequestID,
+			IP:               metadata.IP,
+			CreatedAt:        now,
+		})
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	auditCollection := as.database.Collection("auditLogs")
+	var err error
+	if len(entries) == 1 {
+		_, err = auditCollection.InsertOne(ctx, entries[0])
+	} else {
+		_, err = auditCollection.InsertMany(ctx, entries)
+	}
+	if err != nil {
+		log.Printf("Error recording audit log for %s (request %s): %v\n", collection, metadata.RequestID, err)
+	}
+}
+
+// actingAs attributes the changes to the given admin when the request has no authenticated actor,
+// e.g. during registration or the MFA enrollment at login.
+func actingAs(ctx context.Context, adminID primitive.ObjectID, role string) context.Context {
+	metadata := utils.RequestMetadataFromContext(ctx)
+	if !metadata.ActorID.IsZero() {
+		return ctx
+	}
+
+	metadata.ActorID = adminID
+	metadata.ActorRole = role
+	return utils.WithRequestMetadata(ctx, metadata)
+}
+
+// auditSnapshot converts a document to its stored form so values compare the same way whatever their Go type.
+func auditSnapshot(document interface{}) bson.M {
+	if document == nil || (reflect.ValueOf(document).Kind() == reflect.Ptr && reflect.ValueOf(document).IsNil()) {
+		return nil
+	}
+
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		log.Printf("Error building audit snapshot: %v\n", err)
+		return nil
+	}
+
+	var snapshot bson.M
+	if err := bson.Unmarshal(raw, &snapshot); err != nil {
+		log.Printf("Error building audit snapshot: %v\n", err)
+		return nil
+	}
+
+	for _, field := range auditRedactedFields {
+		delete(snapshot, field)
+	}
+
+	return snapshot
+}
+
+// auditDiff keeps the top level fields that differ, with their old value in before and their new value in after.
+func auditDiff(before, after bson.M) (bson.M, bson.M) {
+	changedBefore, changedAfter := bson.M{}, bson.M{}
+
+	for field, oldValue := range before {
+		newValue, exists := after[field]
+		if !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changedBefore[field] = oldValue
+		}
+	}
+
+	for field, newValue := range after {
+		oldValue, exists := before[field]
+		if !exists || !reflect.DeepEqual(oldValue, newValue) {
+			changedAfter[field] = newValue
+		}
+	}
+
+	if len(changedBefore) == 0 {
+		changedBefore = nil
+	}
+	if len(changedAfter) == 0 {
+		changedAfter = nil
+	}
+
+	return changedBefore, changedAfter
+}
+
+// applySet returns the document as a top level $set of the given fields leaves it.
+func applySet(document, set bson.M) bson.M {
+	updated := make(bson.M, len(document)+len(set))
+	for field, value := range document {
+		updated[field] = value
+	}
+	for field, value := range set {
+		updated[field] = value
+	}
+
+	return updated
+}
+
+// GetAuditLogs returns the entries matching the query, newest first.
+func (as *AdminService) GetAuditLogs(ctx context.Context, query models.AuditLogQuery) (*models.AuditLogPage, error) {
+	filter := bson.M{}
+	if query.ActorID != "" {
+		actorID, err := primitive.ObjectIDFromHex(query.ActorID)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing actor ID: %w", err)
+		}
+		filter["actorId"] = actorID
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.TargetCollection != "" {
+		filter["targetCollection"] = query.TargetCollection
+	}
+	if query.TargetID != "" {
+		filter["targetId"] = query.TargetID
+	}
+	if query.RequestID != "" {
+		filter["requestId"] = query.RequestID
+	}
+	if query.From != nil || query.To != nil {
+		createdAt := bson.M{}
+		if query.From != nil {
+			createdAt["$gte"] = *query.From
+		}
+		if query.To != nil {
+			createdAt["$lte"] = *query.To
+		}
+		filter["createdAt"] = createdAt
+	}
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := query.Limit
+	if limit < 1 {
+		limit = defaultAuditLogLimit
+	}
+	if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	auditCollection := as.database.Collection("auditLogs")
+	total, err := auditCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error counting audit logs: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := auditCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding audit logs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	logs := []models.AuditLog{}
+	if err := cursor.All(ctx, &logs); err != nil {
+		return nil, fmt.Errorf("error decoding audit logs: %w", err)
+	}
+
+	return &models.AuditLogPage{Logs: logs, Page: page, Limit: limit, Total: total}, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Cohort reports scan the activity of every user of the range, they are computed once an hour at most
+const cohortReportTTL = time.Hour
+
+var ErrInvalidCohortInterval = errors.New("cohorts are grouped by week or month")
+
+// GetCohortRetention groups the users who signed up in [from, to) by week or month of signup, and reports for each
+// period since then the share of them who completed an exercise, had a paid subscription or logged their
+// nutrition. Reports are cached for an hour.
+func (as *AdminService) GetCohortRetention(ctx context.Context, from, to time.Time, interval string) (*models.CohortRetention, error) {
+	if interval != models.AnalyticsIntervalWeek && interval != models.AnalyticsIntervalMonth {
+		return nil, ErrInvalidCohortInterval
+	}
+
+	now := time.Now()
+	key := fmt.Sprintf("%s:%s:%s", interval, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+	cache := as.database.Collection("cohortReports")
+	var cached models.CohortRetention
+	err := cache.FindOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$gt": now}}).Decode(&cached)
+	if err == nil {
+		return &cached, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("error fetching cached cohort report: %w", err)
+	}
+
+	report, err := as.computeCohortRetention(ctx, from, to, interval, now)
+	if err != nil {
+		return nil, err
+	}
+	report.Key = key
+	report.GeneratedAt = now
+	report.ExpiresAt = now.Add(cohortReportTTL)
+
+	// The expired report may not be collected yet, another instance may have cached the same report meanwhile
+	if _, err := cache.DeleteOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$lte": now}}); err != nil {
+		log.Printf("Error removing expired cohort report %s: %v\n", key, err)
+	} else if _, err := cache.InsertOne(ctx, *report); err != nil && !mongo.IsDuplicateKeyError(err) {
+		log.Printf("Error caching cohort report %s: %v\n", key, err)
+	}
+
+	return report, nil
+}
+
+func (as *AdminService) computeCohortRetention(ctx context.Context, from, to time.Time, interval string, now time.Time) (*models.CohortRetention, error) {
+	// Number of periods from the start of the cohort to the date, as an int so offsets of all sources compare
+	offset := func(cohort, date interface{}) bson.M {
+		diff := bson.M{"startDate": cohort, "endDate": date, "unit": interval, "timezone": "UTC"}
+		if interval == models.AnalyticsIntervalWeek {
+			diff["startOfWeek"] = "monday"
+		}
+		return bson.M{"$toInt": bson.M{"$dateDiff": diff}}
+	}
+	// Periods of the cohort the user was active in, from the dates of an activity collection
+	activePeriods := func(collection, dateField, output string) bson.M {
+		return bson.M{"$lookup": bson.M{
+			"from": collection,
+			"let":  bson.M{"userId": "$_id", "cohort": "$cohort"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$userId", "$$userId"}},
+					bson.M{"$gte": bson.A{"$" + dateField, "$$cohort"}},
+				}}}},
+				bson.M{"$group": bson.M{"_id": offset("$$cohort", "$"+dateField)}},
+			},
+			"as": output,
+		}}
+	}
+	// A paid subscription runs from the conversion to its end, or to now while it isn't expired
+	subscriptionEnd := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{"$subscription.status", models.SubscriptionStatusExpired}},
+		bson.M{"$ifNull": bson.A{"$subscription.endDate", now}},
+		now,
+	}}
+	subscribedPeriods := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": "$subscription.convertedAt"}, "date"}},
+		bson.M{"$range": bson.A{
+			bson.M{"$max": bson.A{0, offset("$cohort", "$subscription.convertedAt")}},
+			bson.M{"$add": bson.A{offset("$cohort", subscriptionEnd), 1}},
+		}},
+		bson.A{},
+	}}
+	inPeriods := func(field string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$offset", field}}, 1, 0}}}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": signedUpBetween(from, to)},
+		bson.M{"$project": bson.M{"cohort": truncateToPeriod(bson.M{"$toDate": "$_id"}, interval), "subscription": 1}},
+		activePeriods("userExerciseStatus", "completedAt", "exercised"),
+		activePeriods("userDailyNutritionalLogs", "date", "loggedNutrition"),
+		bson.M{"$project": bson.M{
+			"cohort":          1,
+			"exercised":       "$exercised._id",
+			"loggedNutrition": "$loggedNutrition._id",
+			"subscribed":      subscribedPeriods,
+		}},
+		bson.M{"$facet": bson.M{
+			"sizes": bson.A{bson.M{"$group": bson.M{"_id": "$cohort", "users": bson.M{"$sum": 1}}}},
+			"periods": bson.A{
+				bson.M{"$set": bson.M{"offset": bson.M{"$setUnion": bson.A{"$exercised", "$loggedNutrition", "$subscribed"}}}},
+				bson.M{"$unwind": "$offset"},
+				bson.M{"$group": bson.M{
+					"_id":             bson.M{"cohort": "$cohort", "offset": "$offset"},
+					"exercised":       inPeriods("$exercised"),
+					"subscribed":      inPeriods("$subscribed"),
+					"loggedNutrition": inPeriods("$loggedNutrition"),
+				}},
+			},
+		}},
+	}
+
+	type cohortPeriodKey struct {
+		Cohort time.Time `bson:"cohort"`
+		Offset int       `bson:"offset"`
+	}
+	var results []struct {
+		Sizes []struct {
+			Cohort time.Time `bson:"_id"`
+			Users  int64     `bson:"users"`
+		} `bson:"sizes"`
+		Periods []struct {
+			Key             cohortPeriodKey `bson:"_id"`
+			Exercised       int64           `bson:"exercised"`
+			Subscribed      int64           `bson:"subscribed"`
+			LoggedNutrition int64           `bson:"loggedNutrition"`
+		} `bson:"periods"`
+	}
+	if err := aggregateAnalytics(ctx, as.database.Collection("users"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	sizes := map[time.Time]int64{}
+	periods := map[cohortPeriodKey]models.CohortPeriod{}
+	if len(results) > 0 {
+		for _, size := range results[0].Sizes {
+			sizes[size.Cohort.UTC()] = size.Users
+		}
+		for _, period := range results[0].Periods {
+			key := cohortPeriodKey{Cohort: period.Key.Cohort.UTC(), Offset: period.Key.Offset}
+			periods[key] = models.CohortPeriod{Exercised: period.Exercised, Subscribed: period.Subscribed, LoggedNutrition: period.LoggedNutrition}
+		}
+	}
+
+	report := &models.CohortRetention{
+		AnalyticsRange: models.AnalyticsRange{From: from, To: to, Interval: interval},
+		Cohorts:        []models.Cohort{},
+	}
+	for _, start := range periodsBetween(from, to, interval) {
+		cohort := models.Cohort{Start: start, Users: sizes[start], Periods: []models.CohortPeriod{}}
+		// Periods up to the current one, which is still running
+		for period, index := start, 0; !period.After(now); period, index = nextPeriod(period, interval), index+1 {
+			retention := periods[cohortPeriodKey{Cohort: start, Offset: index}]
+			retention.Offset = index
+			retention.ExerciseRate = shareOf(retention.Exercised, cohort.Users)
+			retention.SubscriptionRate = shareOf(retention.Subscribed, cohort.Users)
+			retention.NutritionRate = shareOf(retention.LoggedNutrition, cohort.Users)
+			cohort.Periods = append(cohort.Periods, retention)
+		}
+		report.Cohorts = append(report.Cohorts, cohort)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Number of skipped exercises listed in a funnel
+const skippedExerciseLimit = 10
+
+// engagementGroup sums up enrollments, grouped by plan or together.
+func engagementGroup(id interface{}) bson.M {
+	return bson.M{"$group": bson.M{
+		"_id":             id,
+		"workoutPlanName": bson.M{"$first": "$workoutPlanName"},
+		"enrollments":     bson.M{"$sum": 1},
+		"active":          bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", 0, 1}}},
+		"completed":       bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", 1, 0}}},
+	}}
+}
+
+// GetWorkoutPlanEngagement compares the workout plans users joined in [from, to), most joined first.
+func (as *AdminService) GetWorkoutPlanEngagement(ctx context.Context, from, to time.Time) (*models.WorkoutPlanEngagementList, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"startDate": bson.M{"$gte": from, "$lt": to}}},
+		engagementGroup("$workoutPlanId"),
+		bson.M{"$sort": bson.D{{Key: "enrollments", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	plans := []models.WorkoutPlanEngagement{}
+	if err := aggregateAnalytics(ctx, as.database.Collection("userWorkoutPlanStatus"), pipeline, &plans); err != nil {
+		return nil, err
+	}
+	for i := range plans {
+		plans[i].CompletionRate = shareOf(plans[i].Completed, plans[i].Enrollments)
+	}
+
+	return &models.WorkoutPlanEngagementList{AnalyticsRange: models.AnalyticsRange{From: from, To: to}, Plans: plans}, nil
+}
+
+// GetWorkoutPlanFunnel follows the users who joined the workout plan in [from, to) week by week, and lists the
+// exercises they skip the most.
+func (as *AdminService) GetWorkoutPlanFunnel(ctx context.Context, workoutPlanID primitive.ObjectID, from, to time.Time) (*models.WorkoutPlanFunnel, error) {
+	var workoutPlan models.WorkoutPlan
+	if err := as.database.Collection("workoutPlans").FindOne(ctx, bson.M{"_id": workoutPlanID}).Decode(&workoutPlan); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrWorkoutPlanNotFound
+		}
+		return nil, fmt.Errorf("error finding workout plan: %w", err)
+	}
+
+	// The weeks of each enrollment, in the order they were created
+	weeksOfEnrollment := bson.M{"$lookup": bson.M{
+		"from": "userWorkoutWeekStatus",
+		"let":  bson.M{"userId": "$userId"},
+		"pipeline": bson.A{
+			bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$userId", "$$userId"}},
+				bson.M{"$eq": bson.A{"$workoutPlanId", workoutPlanID}},
+			}}}},
+			bson.M{"$sort": bson.M{"_id": 1}},
+			bson.M{"$project": bson.M{"workoutWeekId": 1, "completed": 1, "completedAt": 1}},
+		},
+		"as": "weeks",
+	}}
+	// Circuits with a completed exercise were worked on, their incomplete exercises were skipped
+	workedCircuits := bson.M{"$lookup": bson.M{
+		"from": "userExerciseStatus",
+		"let":  bson.M{"userId": "$userId"},
+		"pipeline": bson.A{
+			bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$userId", "$$userId"}},
+				bson.M{"$eq": bson.A{"$workoutPlanId", workoutPlanID}},
+			}}}},
+			bson.M{"$group": bson.M{
+				"_id":     "$circuitId",
+				"started": bson.M{"$max": "$completed"},
+				"skipped": bson.M{"$push": bson.M{"$cond": bson.A{"$completed", nil, "$exerciseId"}}},
+			}},
+			bson.M{"$match": bson.M{"started": true}},
+		},
+		"as": "circuits",
+	}}
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"workoutPlanId": workoutPlanID, "startDate": bson.M{"$gte": from, "$lt": to}}},
+		bson.M{"$facet": bson.M{
+			"summary": bson.A{engagementGroup(nil)},
+			"weeks": bson.A{
+				weeksOfEnrollment,
+				// A week starts when the previous one is completed, the first one when the user joined
+				bson.M{"$set": bson.M{"weekStarts": bson.M{"$concatArrays": bson.A{
+					bson.A{"$startDate"},
+					bson.M{"$map": bson.M{"input": "$weeks", "in": bson.M{"$ifNull": bson.A{"$$this.completedAt", nil}}}},
+				}}}},
+				bson.M{"$unwind": bson.M{"path": "$weeks", "includeArrayIndex": "weekIndex"}},
+				bson.M{"$match": bson.M{"weeks.completed": true}},
+				bson.M{"$group": bson.M{
+					"_id":       "$weeks.workoutWeekId",
+					"completed": bson.M{"$sum": 1},
+					"durations": bson.M{"$push": bson.M{"$subtract": bson.A{
+						"$weeks.completedAt",
+						bson.M{"$arrayElemAt": bson.A{"$weekStarts", "$weekIndex"}},
+					}}},
+				}},
+			},
+			"skippedExercises": bson.A{
+				workedCircuits,
+				bson.M{"$unwind": "$circuits"},
+				bson.M{"$unwind": "$circuits.skipped"},
+				bson.M{"$match": bson.M{"circuits.skipped": bson.M{"$ne": nil}}},
+				bson.M{"$group": bson.M{"_id": "$circuits.skipped", "skipped": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "skipped", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": skippedExerciseLimit},
+				bson.M{"$lookup": bson.M{"from": "exercises", "localField": "_id", "foreignField": "_id", "as": "exercise"}},
+				bson.M{"$project": bson.M{"skipped": 1, "name": bson.M{"$first": "$exercise.name"}}},
+			},
+		}},
+	}
+
+	var results []struct {
+		Summary []models.WorkoutPlanEngagement `bson:"summary"`
+		Weeks   []struct {
+			WorkoutWeekID primitive.ObjectID `bson:"_id"`
+			Completed     int64              `bson:"completed"`
+			Durations     []interface{}      `bson:"durations"` // Milliseconds, null for completions before weeks were timed
+		} `bson:"weeks"`
+		SkippedExercises []models.SkippedExercise `bson:"skippedExercises"`
+	}
+	if err := aggregateAnalytics(ctx, as.database.Collection("userWorkoutPlanStatus"), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	funnel := &models.WorkoutPlanFunnel{
+		AnalyticsRange:   models.AnalyticsRange{From: from, To: to},
+		Weeks:            []models.WorkoutWeekFunnel{},
+		SkippedExercises: []models.SkippedExercise{},
+	}
+	completedWeeks := map[primitive.ObjectID]int64{}
+	weekDurations := map[primitive.ObjectID][]float64{}
+	if len(results) > 0 {
+		if len(results[0].Summary) > 0 {
+			funnel.WorkoutPlanEngagement = results[0].Summary[0]
+		}
+		for _, week := range results[0].Weeks {
+			completedWeeks[week.WorkoutWeekID] = week.Completed
+			for _, duration := range week.Durations {
+				if milliseconds, ok := duration.(int64); ok && milliseconds >= 0 {
+					weekDurations[week.WorkoutWeekID] = append(weekDurations[week.WorkoutWeekID], float64(milliseconds)/float64(24*time.Hour/time.Millisecond))
+				}
+			}
+		}
+		funnel.SkippedExercises = append(funnel.SkippedExercises, results[0].SkippedExercises...)
+	}
+	funnel.WorkoutPlanID = workoutPlan.ID
+	funnel.WorkoutPlanName = workoutPlan.Name
+	funnel.CompletionRate = shareOf(funnel.Completed, funnel.Enrollments)
+
+	weeks := append([]models.WorkoutWeek{}, workoutPlan.Weeks...)
+	sort.SliceStable(weeks, func(i, j int) bool { return weeks[i].WeekNumber < weeks[j].WeekNumber })
+	started := funnel.Enrollments
+	for _, week := range weeks {
+		completed := completedWeeks[week.ID]
+		funnel.Weeks = append(funnel.Weeks, models.WorkoutWeekFunnel{
+			WorkoutWeekID: week.ID,
+			WeekNumber:    week.WeekNumber,
+			Completed:     completed,
+			DropOff:       max(started-completed, 0),
+			Retention:     shareOf(completed, funnel.Enrollments),
+			MedianDays:    median(weekDurations[week.ID]),
+		})
+		started = completed
+	}
+
+	return funnel, nil
+}
+
+// median is the middle value, or the mean of the two middle ones, nil without values.
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	middle := values[len(values)/2]
+	if len(values)%2 == 0 {
+		middle = (values[len(values)/2-1] + middle) / 2
+	}
+	return &middle
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -72,6 +74,7 @@ func (as *AdminService) CreateExercise(ctx context.Context, exerciseInput models
 		return err
 	}
 
+	as.recordAudit(ctx, "exercises", auditChange{action: models.AuditActionCreate, targetID: exerciseInput.ID.Hex(), after: exerciseInput})
 	return nil
 }
 
@@ -79,10 +82,15 @@ func (as *AdminService) CreateExercises(ctx context.Context, exercises []models.
 	//Get the exercise collection
 	exerciseCollection := as.database.Collection("exercises")
 
-	// Convert the exercises to an array of interfaces
+	// Convert the exercises to an array of interfaces, IDs are set here so the audit log can reference them
 	var exercisesInterface []interface{}
+	var changes []auditChange
 	for _, exercise := range exercises {
+		if exercise.ID.IsZero() {
+			exercise.ID = primitive.NewObjectID()
+		}
 		exercisesInterface = append(exercisesInterface, exercise)
+		changes = append(changes, auditChange{action: models.AuditActionCreate, targetID: exercise.ID.Hex(), after: exercise})
 	}
 
 	// Insert the exercises into the database
@@ -91,6 +99,7 @@ func (as *AdminService) CreateExercises(ctx context.Context, exercises []models.
 		return fmt.Errorf("error inserting exercises: %w", err)
 	}
 
+	as.recordAudit(ctx, "exercises", changes...)
 	return nil
 }
 
@@ -102,17 +111,18 @@ func (as *AdminService) UpdateExercise(ctx context.Context, exerciseID primitive
 	// Convert the update input to a bson document
 	updateDoc := as.parser.StructToBson(updateInput)
 
-	// Use $set to only update the provided fields
+	// Use $set to only update the provided fields, the previous version is kept for the audit log
 	update := bson.M{"$set": updateDoc}
-	result, err := exerciseCollection.UpdateOne(ctx, filter, update)
-	if err != nil {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	var before bson.M
+	if err := exerciseCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrExerciseNotFound
+		}
 		return fmt.Errorf("error updating exercise: %w", err)
 	}
 
-	if result.MatchedCount == 0 {
-		return ErrExerciseNotFound
-	}
-
+	as.recordAudit(ctx, "exercises", auditChange{action: models.AuditActionUpdate, targetID: exerciseID.Hex(), before: before, after: applySet(before, updateDoc)})
 	return nil
 }
 
@@ -121,15 +131,15 @@ func (as *AdminService) DeleteExercise(ctx context.Context, exerciseID primitive
 	exerciseCollection := as.database.Collection("exercises")
 	filter := bson.M{"_id": exerciseID}
 
-	result, err := exerciseCollection.DeleteOne(ctx, filter)
-	if err != nil {
+	var before bson.M
+	if err := exerciseCollection.FindOneAndDelete(ctx, filter).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrExerciseNotFound
+		}
 		return fmt.Errorf("error deleting exercise: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrExerciseNotFound
-	}
-
+	as.recordAudit(ctx, "exercises", auditChange{action: models.AuditActionDelete, targetID: exerciseID.Hex(), before: before})
 	return nil
 }
 
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StartImpersonation records that the admin impersonates the user, the caller issues the token for it.
+// Suspended and banned users can be impersonated, support may need to see their account.
+func (as *AdminService) StartImpersonation(ctx context.Context, adminID, userID primitive.ObjectID, input models.ImpersonationInput) (*models.Impersonation, *models.User, error) {
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"email": 1})
+	if err := as.database.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrUserNotFound
+		}
+		return nil, nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	now := time.Now()
+	impersonation := models.Impersonation{
+		ID:        primitive.NewObjectID(),
+		AdminID:   adminID,
+		UserID:    userID,
+		ReadOnly:  !input.WriteAccess,
+		Reason:    input.Reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(utils.ImpersonationTokenLifetime),
+	}
+	if _, err := as.database.Collection("impersonations").InsertOne(ctx, impersonation); err != nil {
+		return nil, nil, fmt.Errorf("error inserting impersonation: %w", err)
+	}
+
+	as.recordAudit(ctx, "impersonations", auditChange{
+		action:   models.AuditActionCreate,
+		targetID: impersonation.ID.Hex(),
+		after:    impersonation,
+		reason:   input.Reason,
+	})
+
+	return &impersonation, &user, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var invoiceCSVHeader = []string{
+	"number", "issued_at", "user_id", "kind", "provider", "provider_invoice_id",
+	"currency", "subtotal", "tax", "total", "customer_name", "customer_email", "customer_tax_id",
+}
+
+// ExportInvoicesCSV writes the invoices issued in [from, to) as CSV, oldest first. Amounts are decimals in the
+// currency of the invoice.
+func (as *AdminService) ExportInvoicesCSV(ctx context.Context, from, to time.Time) ([]byte, error) {
+	filter := bson.M{"issuedAt": bson.M{"$gte": from, "$lt": to}}
+	opts := options.Find().SetSort(bson.D{{Key: "issuedAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := as.database.Collection("invoices").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding invoices: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var invoices []models.Invoice
+	if err := cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("error decoding invoices: %w", err)
+	}
+
+	var output bytes.Buffer
+	writer := csv.NewWriter(&output)
+	if err := writer.Write(invoiceCSVHeader); err != nil {
+		return nil, fmt.Errorf("error writing invoices: %w", err)
+	}
+	for _, invoice := range invoices {
+		record := []string{
+			invoice.Number,
+			invoice.IssuedAt.UTC().Format(time.RFC3339),
+			invoice.UserID.Hex(),
+			invoice.Kind,
+			invoice.Provider,
+			invoice.ProviderInvoiceID,
+			invoice.Currency,
+			formatAmount(invoice.Subtotal, invoice.Currency),
+			formatAmount(invoice.Tax, invoice.Currency),
+			formatAmount(invoice.Total, invoice.Currency),
+			utils.CSVSafe(invoice.CustomerName),
+			utils.CSVSafe(invoice.CustomerEmail),
+			utils.CSVSafe(invoice.CustomerTaxID),
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("error writing invoices: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("error writing invoices: %w", err)
+	}
+
+	return output.Bytes(), nil
+}
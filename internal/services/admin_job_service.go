@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultJobRunLimit = 20
+	// Invitations stay listed this long after they expired
+	expiredInvitationRetention = 30 * 24 * time.Hour
+)
+
+// GetJobRuns returns the last runs of the scheduled jobs, newest first.
+func (as *AdminService) GetJobRuns(ctx context.Context, query models.JobRunQuery) ([]models.JobRun, error) {
+	filter := bson.M{}
+	if query.Job != "" {
+		filter["job"] = query.Job
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	limit := query.Limit
+	if limit < 1 {
+		limit = defaultJobRunLimit
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetLimit(limit)
+	cursor, err := as.database.Collection("jobRuns").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding job runs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	runs := []models.JobRun{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("error decoding job runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// DeleteExpiredInvitations removes the invitations that expired without being accepted.
+func (as *AdminService) DeleteExpiredInvitations(ctx context.Context) (string, error) {
+	filter := bson.M{
+		"acceptedAt": bson.M{"$exists": false},
+		"expiresAt":  bson.M{"$lte": time.Now().Add(-expiredInvitationRetention)},
+	}
+	result, err := as.database.Collection("adminInvitations").DeleteMany(ctx, filter)
+	if err != nil {
+		return "", fmt.Errorf("error deleting expired invitations: %w", err)
+	}
+
+	return fmt.Sprintf("%d expired invitations deleted", result.DeletedCount), nil
+}
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const adminInvitationTTL = 72 * time.Hour
+
+// adminAccountProjection leaves the credentials in the database, only the MFA status is read.
+var adminAccountProjection = bson.M{"passwordHash": 0, "mfa.secret": 0, "mfa.pendingSecret": 0, "mfa.recoveryCodeHashes": 0}
+
+var (
+	ErrAdminInvitationRequired = errors.New("an invitation is required to register an admin")
+	ErrInvalidAdminInvitation  = errors.New("invitation is invalid, expired or issued for another email")
+	ErrAdminInvitationNotFound = errors.New("pending invitation not found")
+	ErrCannotModifySelf        = errors.New("admins cannot change the role or status of their own account")
+	ErrLastSuperAdmin          = errors.New("at least one active super admin is required")
+	ErrSuperAdminRequired      = errors.New("only a super admin can grant or take away the super admin role")
+)
+
+// checkAdminBootstrap allows an uninvited registration only when enabled and no admin exists yet.
+func (as *AdminService) checkAdminBootstrap(ctx context.Context) error {
+	if !as.cfg.AdminBootstrap {
+		return ErrAdminInvitationRequired
+	}
+
+	adminCollection := as.database.Collection("admins")
+	count, err := adminCollection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("error counting admins: %w", err)
+	}
+
+	if count > 0 {
+		return ErrAdminInvitationRequired
+	}
+
+	return nil
+}
+
+// acceptAdminInvitation consumes a pending invitation in a single update so it can't be used twice.
+func (as *AdminService) acceptAdminInvitation(ctx context.Context, email, token string) (*models.AdminInvitation, error) {
+	invitationCollection := as.database.Collection("adminInvitations")
+
+	now := time.Now()
+	filter := bson.M{
+		"tokenHash":  utils.HashToken(token),
+		"email":      email,
+		"acceptedAt": bson.M{"$exists": false},
+		"revokedAt":  bson.M{"$exists": false},
+		"expiresAt":  bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"acceptedAt": now}}
+
+	var invitation models.AdminInvitation
+	if err := invitationCollection.FindOneAndUpdate(ctx, filter, update).Decode(&invitation); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAdminInvitation
+		}
+
+		return nil, fmt.Errorf("error accepting admin invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// releaseAdminInvitation makes an accepted invitation usable again when the admin it was accepted for couldn't be
+// created.
+func (as *AdminService) releaseAdminInvitation(ctx context.Context, invitationID primitive.ObjectID) {
+	invitationCollection := as.database.Collection("adminInvitations")
+	if _, err := invitationCollection.UpdateOne(ctx, bson.M{"_id": invitationID}, bson.M{"$unset": bson.M{"acceptedAt": ""}}); err != nil {
+		log.Printf("Error releasing admin invitation %s: %v\n", invitationID.Hex(), err)
+	}
+}
+
+// CreateAdminInvitation returns the invitation and its token. The token is only available here, share it with the invitee.
+func (as *AdminService) CreateAdminInvitation(ctx context.Context, invitedBy primitive.ObjectID, input models.AdminInvitationInput) (*models.AdminInvitation, string, error) {
+	adminCollection := as.database.Collection("admins")
+	count, err := adminCollection.CountDocuments(ctx, bson.M{"email": input.Email})
+	if err != nil {
+		return nil, "", fmt.Errorf("error checking if admin already exists: %w", err)
+	}
+
+	if count > 0 {
+		return nil, "", ErrAdminAlreadyExists
+	}
+
+	if err := as.checkRoleExists(ctx, input.Role); err != nil {
+		return nil, "", err
+	}
+
+	if input.Role == models.SuperAdminRole {
+		if err := as.checkActorIsSuperAdmin(ctx, invitedBy); err != nil {
+			return nil, "", err
+		}
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("error generating invitation token: %w", err)
+	}
+
+	now := time.Now()
+	invitation := models.AdminInvitation{
+		ID:        primitive.NewObjectID(),
+		Email:     input.Email,
+		Role:      input.Role,
+		TokenHash: utils.HashToken(token),
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(adminInvitationTTL),
+	}
+
+	invitationCollection := as.database.Collection("adminInvitations")
+	if _, err := invitationCollection.InsertOne(ctx, invitation); err != nil {
+		return nil, "", fmt.Errorf("error inserting admin invitation: %w", err)
+	}
+
+	as.recordAudit(ctx, "adminInvitations", auditChange{action: models.AuditActionCreate, targetID: invitation.ID.Hex(), after: invitation})
+
+	return &invitation, token, nil
+}
+
+func (as *AdminService) GetAdminInvitations(ctx context.Context) ([]models.AdminInvitation, error) {
+	invitationCollection := as.database.Collection("adminInvitations")
+
+	cursor, err := invitationCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("error finding admin invitations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var invitations []models.AdminInvitation
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, fmt.Errorf("error decoding admin invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+func (as *AdminService) RevokeAdminInvitation(ctx context.Context, invitationID primitive.ObjectID) error {
+	invitationCollection := as.database.Collection("adminInvitations")
+
+	revokedAt := time.Now()
+	filter := bson.M{"_id": invitationID, "acceptedAt": bson.M{"$exists": false}, "revokedAt": bson.M{"$exists": false}}
+	result, err := invitationCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": revokedAt}})
+	if err != nil {
+		return fmt.Errorf("error revoking admin invitation: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrAdminInvitationNotFound
+	}
+
+	as.recordAudit(ctx, "adminInvitations", auditChange{action: models.AuditActionUpdate, targetID: invitationID.Hex(), after: bson.M{"revokedAt": revokedAt}})
+	return nil
+}
+
+func (as *AdminService) GetAdmins(ctx context.Context) ([]models.Admin, error) {
+	adminCollection := as.database.Collection("admins")
+
+	cursor, err := adminCollection.Find(ctx, bson.M{}, options.Find().SetProjection(adminAccountProjection))
+	if err != nil {
+		return nil, fmt.Errorf("error finding admins: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var admins []models.Admin
+	if err := cursor.All(ctx, &admins); err != nil {
+		return nil, fmt.Errorf("error decoding admins: %w", err)
+	}
+
+	return admins, nil
+}
+
+func (as *AdminService) GetAdminByID(ctx context.Context, adminID primitive.ObjectID) (*models.Admin, error) {
+	adminCollection := as.database.Collection("admins")
+
+	var admin models.Admin
+	opts := options.FindOne().SetProjection(adminAccountProjection)
+	if err := adminCollection.FindOne(ctx, bson.M{"_id": adminID}, opts).Decode(&admin); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAdminNotFound
+		}
+
+		return nil, fmt.Errorf("error fetching admin: %w", err)
+	}
+
+	return &admin, nil
+}
+
+// checkActorIsSuperAdmin keeps the super admin role in the hands of super admins, admins:manage alone isn't enough
+// to hand it out.
+func (as *AdminService) checkActorIsSuperAdmin(ctx context.Context, actorID primitive.ObjectID) error {
+	role, err := as.GetActiveAdminRole(ctx, actorID)
+	if err != nil {
+		return err
+	}
+
+	if role != models.SuperAdminRole {
+		return ErrSuperAdminRequired
+	}
+
+	return nil
+}
+
+// checkActorCanManage keeps super admin accounts in the hands of super admins: only they can change the email of
+// one, disable or delete it.
+func (as *AdminService) checkActorCanManage(ctx context.Context, actorID primitive.ObjectID, admin *models.Admin) error {
+	if admin.Role != models.SuperAdminRole {
+		return nil
+	}
+
+	return as.checkActorIsSuperAdmin(ctx, actorID)
+}
+
+// GetActiveAdminRole returns the current role of the admin, or an empty role when the admin was disabled or deleted.
+// It runs on every admin request so role changes apply without waiting for the token to expire.
+func (as *AdminService) GetActiveAdminRole(ctx context.Context, adminID primitive.ObjectID) (string, error) {
+	adminCollection := as.database.Collection("admins")
+
+	var admin models.Admin
+	opts := options.FindOne().SetProjection(bson.M{"role": 1, "disabled": 1})
+	if err := adminCollection.FindOne(ctx, bson.M{"_id": adminID}, opts).Decode(&admin); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("error checking admin status: %w", err)
+	}
+
+	if admin.Disabled {
+		return "", nil
+	}
+
+	return admin.Role, nil
+}
+
+func (as *AdminService) UpdateAdmin(ctx context.Context, actorID, adminID primitive.ObjectID, input models.AdminUpdateInput) (*models.Admin, error) {
+	adminCollection := as.database.Collection("admins")
+
+	admin, err := as.GetAdminByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{}
+	if input.Email != nil && *input.Email != admin.Email {
+		count, err := adminCollection.CountDocuments(ctx, bson.M{"email": *input.Email})
+		if err != nil {
+			return nil, fmt.Errorf("error checking if admin already exists: %w", err)
+		}
+
+		if count > 0 {
+			return nil, ErrAdminAlreadyExists
+		}
+
+		if err := as.checkActorCanManage(ctx, actorID, admin); err != nil {
+			return nil, err
+		}
+		update["email"] = *input.Email
+	}
+
+	if input.Role != nil && *input.Role != admin.Role {
+		if actorID == adminID {
+			return nil, ErrCannotModifySelf
+		}
+
+		if err := as.checkRoleExists(ctx, *input.Role); err != nil {
+			return nil, err
+		}
+
+		if *input.Role == models.SuperAdminRole || admin.Role == models.SuperAdminRole {
+			if err := as.checkActorIsSuperAdmin(ctx, actorID); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := as.ensureAnotherSuperAdmin(ctx, admin); err != nil {
+			return nil, err
+		}
+		update["role"] = *input.Role
+	}
+
+	if len(update) == 0 {
+		return admin, nil
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(adminAccountProjection)
+	var updatedAdmin models.Admin
+	if err := adminCollection.FindOneAndUpdate(ctx, bson.M{"_id": adminID}, bson.M{"$set": update}, opts).Decode(&updatedAdmin); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAdminNotFound
+		}
+
+		return nil, fmt.Errorf("error updating admin: %w", err)
+	}
+
+	as.recordAudit(ctx, "admins", auditChange{action: models.AuditActionUpdate, targetID: adminID.Hex(), before: admin, after: updatedAdmin})
+	return &updatedAdmin, nil
+}
+
+func (as *AdminService) SetAdminDisabled(ctx context.Context, actorID, adminID primitive.ObjectID, disabled bool) error {
+	if actorID == adminID {
+		return ErrCannotModifySelf
+	}
+
+	admin, err := as.GetAdminByID(ctx, adminID)
+	if err != nil {
+		return err
+	}
+
+	if err := as.checkActorCanManage(ctx, actorID, admin); err != nil {
+		return err
+	}
+
+	if disabled {
+		if err := as.ensureAnotherSuperAdmin(ctx, admin); err != nil {
+			return err
+		}
+	}
+
+	adminCollection := as.database.Collection("admins")
+	if _, err := adminCollection.UpdateOne(ctx, bson.M{"_id": adminID}, bson.M{"$set": bson.M{"disabled": disabled}}); err != nil {
+		return fmt.Errorf("error updating admin status: %w", err)
+	}
+
+	as.recordAudit(ctx, "admins", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: adminID.Hex(),
+		before:   bson.M{"disabled": admin.Disabled},
+		after:    bson.M{"disabled": disabled},
+	})
+	return nil
+}
+
+func (as *AdminService) DeleteAdmin(ctx context.Context, actorID, adminID primitive.ObjectID) error {
+	if actorID == adminID {
+		return ErrCannotModifySelf
+	}
+
+	admin, err := as.GetAdminByID(ctx, adminID)
+	if err != nil {
+		return err
+	}
+
+	if err := as.checkActorCanManage(ctx, actorID, admin); err != nil {
+		return err
+	}
+
+	if err := as.ensureAnotherSuperAdmin(ctx, admin); err != nil {
+		return err
+	}
+
+	adminCollection := as.database.Collection("admins")
+	result, err := adminCollection.DeleteOne(ctx, bson.M{"_id": adminID})
+	if err != nil {
+		return fmt.Errorf("error deleting admin: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrAdminNotFound
+	}
+
+	as.recordAudit(ctx, "admins", auditChange{action: models.AuditActionDelete, targetID: adminID.Hex(), before: admin})
+	return nil
+}
+
+// ensureAnotherSuperAdmin stops the last active super admin from being demoted, disabled or deleted.
+func (as *AdminService) ensureAnotherSuperAdmin(ctx context.Context, admin *models.Admin) error {
+	if admin.Role != models.SuperAdminRole || admin.Disabled {
+		return nil
+	}
+
+	adminCollection := as.database.Collection("admins")
+	filter := bson.M{"role": models.SuperAdminRole, "disabled": bson.M{"$ne": true}, "_id": bson.M{"$ne": admin.ID}}
+	count, err := adminCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("error counting super admins: %w", err)
+	}
+
+	if count == 0 {
+		return ErrLastSuperAdmin
+	}
+
+	return nil
+}
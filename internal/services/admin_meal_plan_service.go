@@ -3,10 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -21,16 +23,25 @@ func (as *AdminService) GetMealPlanByID(ctx context.Context, mealPlanID primitiv
 	var mealPlan models.MealPlan
 	err := mealPlanCollection.FindOne(ctx, filter).Decode(&mealPlan)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.MealPlan{}, ErrMealPlanNotFound
+		}
 		return models.MealPlan{}, fmt.Errorf("error finding meal plan: %w", err)
 	}
 
 	return mealPlan, nil
 }
 
-func (as *AdminService) GetMealPlans(ctx context.Context) ([]models.MealPlan, error) {
+// GetMealPlans returns the meal plans in the given publication status, every plan when it is empty.
+func (as *AdminService) GetMealPlans(ctx context.Context, status string) ([]models.MealPlan, error) {
 	mealPlanCollection := as.database.Collection("mealPlans")
 
-	cursor, err := mealPlanCollection.Find(ctx, bson.M{})
+	filter, err := planStatusFilter(bson.M{}, status)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := mealPlanCollection.Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error finding meal plans: %w", err)
 	}
@@ -44,10 +55,13 @@ func (as *AdminService) GetMealPlans(ctx context.Context) ([]models.MealPlan, er
 	return mealPlans, nil
 }
 
-func (as *AdminService) SearchMealPlansByName(ctx context.Context, name string) ([]models.MealPlan, error) {
+func (as *AdminService) SearchMealPlansByName(ctx context.Context, name, status string) ([]models.MealPlan, error) {
 	mealPlanCollection := as.database.Collection("mealPlans")
 
-	filter := bson.M{"name": primitive.Regex{Pattern: name, Options: "i"}}
+	filter, err := planStatusFilter(bson.M{"name": primitive.Regex{Pattern: name, Options: "i"}}, status)
+	if err != nil {
+		return nil, err
+	}
 	cursor, err := mealPlanCollection.Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error finding meal plans by name: %w", err)
@@ -62,7 +76,29 @@ func (as *AdminService) SearchMealPlansByName(ctx context.Context, name string)
 	return mealPlans, nil
 }
 
+// CreateMealPlan stores a new meal plan as a draft, users see it once it is reviewed and published.
 func (as *AdminService) CreateMealPlan(ctx context.Context, mealPlanInput models.MealPlan) error {
+	return as.insertMealPlan(ctx, &mealPlanInput)
+}
+
+// CloneMealPlan copies the meal plan under a new name as a new draft, with new IDs. The pending edits of a published
+// plan are not copied.
+func (as *AdminService) CloneMealPlan(ctx context.Context, mealPlanID primitive.ObjectID, input models.PlanCloneInput) (*models.MealPlan, error) {
+	mealPlan, err := as.GetMealPlanByID(ctx, mealPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	mealPlan.Name = input.Name
+	if err := as.insertMealPlan(ctx, &mealPlan); err != nil {
+		return nil, err
+	}
+
+	return &mealPlan, nil
+}
+
+// insertMealPlan gives the plan and every week and day in it a new ID and stores it as a draft.
+func (as *AdminService) insertMealPlan(ctx context.Context, mealPlanInput *models.MealPlan) error {
 	mealPlanCollection := as.database.Collection("mealPlans")
 
 	filter := bson.M{"name": mealPlanInput.Name}
@@ -76,6 +112,7 @@ func (as *AdminService) CreateMealPlan(ctx context.Context, mealPlanInput models
 	}
 
 	mealPlanInput.ID = primitive.NewObjectID()
+	mealPlanInput.PlanPublication = models.PlanPublication{Status: models.PlanStatusDraft}
 	for i := range mealPlanInput.WeeklyPlans {
 		mealPlanInput.WeeklyPlans[i].ID = primitive.NewObjectID()
 		for j := range mealPlanInput.WeeklyPlans[i].DailyPlans {
@@ -83,33 +120,100 @@ func (as *AdminService) CreateMealPlan(ctx context.Context, mealPlanInput models
 		}
 	}
 
-	_, err = mealPlanCollection.InsertOne(ctx, mealPlanInput)
+	_, err = mealPlanCollection.InsertOne(ctx, *mealPlanInput)
 	if err != nil {
 		return fmt.Errorf("error inserting meal plan: %w", err)
 	}
 
+	as.recordAudit(ctx, "mealPlans", auditChange{action: models.AuditActionCreate, targetID: mealPlanInput.ID.Hex(), after: *mealPlanInput})
 	return nil
 }
 
-func (as *AdminService) UpdateMealPlan(ctx context.Context, mealPlanID primitive.ObjectID, updateInput models.MealPlanUpdateInput) error {
-	mealPlanCollection := as.database.Collection("mealPlans")
-	filter := bson.M{"_id": mealPlanID}
-
+// UpdateMealPlan edits the meal plan while it is a draft, or its draft copy once it is published. Edits send a
+// plan in review back to draft. Returns the edited version.
+func (as *AdminService) UpdateMealPlan(ctx context.Context, mealPlanID primitive.ObjectID, updateInput models.MealPlanUpdateInput) (*models.MealPlan, error) {
 	var existingMealPlan models.MealPlan
-	if err := mealPlanCollection.FindOne(ctx, filter).Decode(&existingMealPlan); err != nil {
-		return fmt.Errorf("error finding meal plan: %w", err)
+	collection, created, err := as.editablePlan(ctx, mealPlanKind, mealPlanID, &existingMealPlan, &existingMealPlan.PlanPublication)
+	if err != nil {
+		return nil, err
 	}
 
 	updatedMealPlanDoc := mergeUpdatesIntoExistingMealPlan(existingMealPlan, updateInput)
+	updatedMealPlanDoc.Status = models.PlanStatusDraft
+
+	if err := as.savePlanEdit(ctx, mealPlanKind, collection, created, mealPlanID, existingMealPlan, updatedMealPlanDoc); err != nil {
+		return nil, err
+	}
+
+	return &updatedMealPlanDoc, nil
+}
+
+// GetMealPlanDraft returns the pending edits of a published meal plan.
+func (as *AdminService) GetMealPlanDraft(ctx context.Context, mealPlanID primitive.ObjectID) (models.MealPlan, error) {
+	var draft models.MealPlan
+	if err := as.getPlanDraft(ctx, mealPlanKind, mealPlanID, &draft); err != nil {
+		return models.MealPlan{}, err
+	}
 
-	update := bson.M{"$set": updatedMealPlanDoc}
-	result, err := mealPlanCollection.UpdateOne(ctx, filter, update)
+	return draft, nil
+}
+
+// TransitionMealPlan moves the meal plan, or the draft copy under review once it is published, to another status of
+// the review workflow. A plan is checked before it is published.
+func (as *AdminService) TransitionMealPlan(ctx context.Context, mealPlanID primitive.ObjectID, input models.PlanTransitionInput) (*models.MealPlan, error) {
+	var mealPlan models.MealPlan
+	collection, err := as.transitionTarget(ctx, mealPlanKind, mealPlanID, input.Status, &mealPlan)
 	if err != nil {
-		return fmt.Errorf("error updating meal plan: %w", err)
+		return nil, err
+	}
+
+	if input.Status == models.PlanStatusPublished && mealPlan.CanTransitionTo(input.Status) {
+		if err := as.validateMealPlan(ctx, mealPlan); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := as.transitionPlan(ctx, mealPlanKind, collection, mealPlanID, &mealPlan, &mealPlan.PlanPublication, input.Status); err != nil {
+		return nil, err
+	}
+
+	return &mealPlan, nil
+}
+
+// validateMealPlan checks that the meal plan can be followed: every week has days, every day its three main meals
+// and every meal exists.
+func (as *AdminService) validateMealPlan(ctx context.Context, mealPlan models.MealPlan) error {
+	if len(mealPlan.WeeklyPlans) == 0 {
+		return fmt.Errorf("%w: it has no weeks", ErrPlanNotPublishable)
+	}
+
+	mealIDs := map[primitive.ObjectID]bool{}
+	for _, weeklyPlan := range mealPlan.WeeklyPlans {
+		if len(weeklyPlan.DailyPlans) == 0 {
+			return fmt.Errorf("%w: week %d has no days", ErrPlanNotPublishable, weeklyPlan.WeekNumber)
+		}
+		for _, dailyPlan := range weeklyPlan.DailyPlans {
+			if dailyPlan.Breakfast.IsZero() || dailyPlan.Lunch.IsZero() || dailyPlan.Dinner.IsZero() {
+				return fmt.Errorf("%w: a day of week %d misses a main meal", ErrPlanNotPublishable, weeklyPlan.WeekNumber)
+			}
+			for _, mealID := range []primitive.ObjectID{dailyPlan.Breakfast, dailyPlan.MorningSnack, dailyPlan.Lunch, dailyPlan.AfternoonSnack, dailyPlan.Dinner} {
+				if !mealID.IsZero() {
+					mealIDs[mealID] = true
+				}
+			}
+		}
 	}
 
-	if result.MatchedCount == 0 {
-		return ErrMealPlanNotFound
+	ids := make([]primitive.ObjectID, 0, len(mealIDs))
+	for mealID := range mealIDs {
+		ids = append(ids, mealID)
+	}
+	count, err := as.database.Collection("meals").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return fmt.Errorf("error counting meals: %w", err)
+	}
+	if count != int64(len(ids)) {
+		return fmt.Errorf("%w: %d of its meals don't exist", ErrPlanNotPublishable, int64(len(ids))-count)
 	}
 
 	return nil
@@ -119,15 +223,20 @@ func (as *AdminService) DeleteMealPlan(ctx context.Context, mealPlanID primitive
 	mealPlanCollection := as.database.Collection("mealPlans")
 
 	filter := bson.M{"_id": mealPlanID}
-	result, err := mealPlanCollection.DeleteOne(ctx, filter)
-	if err != nil {
+	var before bson.M
+	if err := mealPlanCollection.FindOneAndDelete(ctx, filter).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrMealPlanNotFound
+		}
 		return fmt.Errorf("error deleting meal plan: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrMealPlanNotFound
-	}
+	as.recordAudit(ctx, "mealPlans", auditChange{action: models.AuditActionDelete, targetID: mealPlanID.Hex(), before: before})
 
+	// Pending edits go with the plan
+	if _, err := as.database.Collection(mealPlanKind.drafts).DeleteOne(ctx, filter); err != nil {
+		log.Printf("Error deleting draft of meal plan %s: %v\n", mealPlanID.Hex(), err)
+	}
 	return nil
 }
 
@@ -138,6 +247,9 @@ func mergeUpdatesIntoExistingMealPlan(existingPlan models.MealPlan, updateInput
 	if updateInput.Duration != nil {
 		existingPlan.Duration = *updateInput.Duration
 	}
+	if updateInput.AccessTier != nil {
+		existingPlan.AccessTier = *updateInput.AccessTier
+	}
 
 	existingWeeklyPlansMap := make(map[int]*models.WeeklyPlan)
 	for i := range existingPlan.WeeklyPlans {
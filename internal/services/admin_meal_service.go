@@ -7,6 +7,8 @@ import (
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -61,12 +63,17 @@ func (as *AdminService) CreateMeal(ctx context.Context, meal models.Meal) error
 		return ErrMealAlreadyExists
 	}
 
+	if meal.ID.IsZero() {
+		meal.ID = primitive.NewObjectID()
+	}
+
 	// Insert the new meal
 	_, err = mealCollection.InsertOne(ctx, meal)
 	if err != nil {
 		return fmt.Errorf("error inserting meal: %w", err)
 	}
 
+	as.recordAudit(ctx, "meals", auditChange{action: models.AuditActionCreate, targetID: meal.ID.Hex(), after: meal})
 	return nil
 }
 
@@ -74,8 +81,13 @@ func (as *AdminService) CreateMeals(ctx context.Context, meals []models.Meal) er
 	mealCollection := as.database.Collection("meals")
 
 	var mealInterfaces []interface{}
+	var changes []auditChange
 	for _, meal := range meals {
+		if meal.ID.IsZero() {
+			meal.ID = primitive.NewObjectID()
+		}
 		mealInterfaces = append(mealInterfaces, meal)
+		changes = append(changes, auditChange{action: models.AuditActionCreate, targetID: meal.ID.Hex(), after: meal})
 	}
 
 	_, err := mealCollection.InsertMany(ctx, mealInterfaces)
@@ -83,6 +95,7 @@ func (as *AdminService) CreateMeals(ctx context.Context, meals []models.Meal) er
 		return fmt.Errorf("error inserting meals: %w", err)
 	}
 
+	as.recordAudit(ctx, "meals", changes...)
 	return nil
 }
 
@@ -93,15 +106,16 @@ func (as *AdminService) UpdateMeal(ctx context.Context, mealID primitive.ObjectI
 	updateDoc := as.parser.StructToBson(updateInput)
 
 	update := bson.M{"$set": updateDoc}
-	result, err := mealCollection.UpdateOne(ctx, filter, update)
-	if err != nil {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	var before bson.M
+	if err := mealCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrMealNotFound
+		}
 		return fmt.Errorf("error updating meal: %w", err)
 	}
 
-	if result.MatchedCount == 0 {
-		return ErrMealNotFound
-	}
-
+	as.recordAudit(ctx, "meals", auditChange{action: models.AuditActionUpdate, targetID: mealID.Hex(), before: before, after: applySet(before, updateDoc)})
 	return nil
 }
 
@@ -109,15 +123,15 @@ func (as *AdminService) DeleteMeal(ctx context.Context, mealID primitive.ObjectI
 	mealCollection := as.database.Collection("meals")
 
 	filter := bson.M{"_id": mealID}
-	result, err := mealCollection.DeleteOne(ctx, filter)
-	if err != nil {
+	var before bson.M
+	if err := mealCollection.FindOneAndDelete(ctx, filter).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrMealNotFound
+		}
 		return fmt.Errorf("error deleting meal: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrMealNotFound
-	}
-
+	as.recordAudit(ctx, "meals", auditChange{action: models.AuditActionDelete, targetID: mealID.Hex(), before: before})
 	return nil
 }
 
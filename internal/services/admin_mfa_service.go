@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFARequired reports whether admins must complete MFA enrollment before getting an access token.
+func (as *AdminService) MFARequired() bool {
+	return as.cfg.AdminMFARequired
+}
+
+func (as *AdminService) BeginMFAEnrollment(ctx context.Context, adminID primitive.ObjectID) (*models.MFAEnrollment, error) {
+	adminCollection := as.database.Collection("admins")
+	return beginMFAEnrollment(ctx, adminCollection, adminID, as.cfg.MFAIssuer, ErrAdminNotFound)
+}
+
+func (as *AdminService) ConfirmMFAEnrollment(ctx context.Context, adminID primitive.ObjectID, code string) ([]string, error) {
+	adminCollection := as.database.Collection("admins")
+	recoveryCodes, err := confirmMFAEnrollment(ctx, adminCollection, adminID, code, ErrAdminNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	as.recordAudit(actingAs(ctx, adminID, ""), "admins", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: adminID.Hex(),
+		before:   bson.M{"mfaEnabled": false},
+		after:    bson.M{"mfaEnabled": true},
+	})
+	return recoveryCodes, nil
+}
+
+func (as *AdminService) VerifyMFA(ctx context.Context, adminID primitive.ObjectID, input models.MFALoginInput) error {
+	adminCollection := as.database.Collection("admins")
+	return verifyMFACode(ctx, adminCollection, adminID, input.Code, input.RecoveryCode, ErrAdminNotFound)
+}
+
+func (as *AdminService) DisableMFA(ctx context.Context, adminID primitive.ObjectID, code string) error {
+	if as.MFARequired() {
+		return ErrMFARequired
+	}
+
+	adminCollection := as.database.Collection("admins")
+	if err := disableMFA(ctx, adminCollection, adminID, code, ErrAdminNotFound); err != nil {
+		return err
+	}
+
+	as.recordAudit(ctx, "admins", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: adminID.Hex(),
+		before:   bson.M{"mfaEnabled": true},
+		after:    bson.M{"mfaEnabled": false},
+	})
+	return nil
+}
+
+func (as *AdminService) RegenerateRecoveryCodes(ctx context.Context, adminID primitive.ObjectID, code string) ([]string, error) {
+	adminCollection := as.database.Collection("admins")
+	recoveryCodes, err := regenerateRecoveryCodes(ctx, adminCollection, adminID, code, ErrAdminNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	// The codes themselves are secret, only the fact that they were replaced is recorded
+	as.recordAudit(ctx, "admins", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: adminID.Hex(),
+		after:    bson.M{"mfaRecoveryCodes": "regenerated"},
+	})
+	return recoveryCodes, nil
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidPlanStatus     = errors.New("invalid plan status")
+	ErrInvalidPlanTransition = errors.New("invalid plan status transition")
+	ErrPlanNotPublishable    = errors.New("plan can't be published")
+	ErrPlanDraftNotFound     = errors.New("plan draft not found")
+)
+
+// planKind names the collections of a kind of plan. Edits of a published plan go to its draft copy, kept in the
+// drafts collection under the ID of the plan until it is published.
+type planKind struct {
+	plans    string
+	drafts   string
+	notFound error
+	exists   error
+}
+
+var (
+	workoutPlanKind = planKind{plans: "workoutPlans", drafts: "workoutPlanDrafts", notFound: ErrWorkoutPlanNotFound, exists: ErrWorkoutPlanAlreadyExists}
+	mealPlanKind    = planKind{plans: "mealPlans", drafts: "mealPlanDrafts", notFound: ErrMealPlanNotFound, exists: ErrMealPlanAlreadyExists}
+)
+
+// publishedPlanFilter restricts filter to the plans users can see, plans without status predate the workflow.
+func publishedPlanFilter(filter bson.M) bson.M {
+	filter["status"] = bson.M{"$in": bson.A{models.PlanStatusPublished, nil}}
+	return filter
+}
+
+// planStatusFilter restricts filter to the plans in status, every plan when status is empty.
+func planStatusFilter(filter bson.M, status string) (bson.M, error) {
+	switch {
+	case status == "":
+		return filter, nil
+	case status == models.PlanStatusPublished:
+		return publishedPlanFilter(filter), nil
+	case models.IsPlanStatus(status):
+		filter["status"] = status
+		return filter, nil
+	default:
+		return nil, ErrInvalidPlanStatus
+	}
+}
+
+// findPlan decodes the plan with the given ID from collection into plan, found is false when there is none.
+func (as *AdminService) findPlan(ctx context.Context, collection string, planID primitive.ObjectID, plan interface{}) (bool, error) {
+	if err := as.database.Collection(collection).FindOne(ctx, bson.M{"_id": planID}).Decode(plan); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("error finding plan in %s: %w", collection, err)
+	}
+
+	return true, nil
+}
+
+// editablePlan decodes the version of the plan edits apply to into plan and returns its collection: the plan itself
+// until it is published, then its draft copy. The copy starts from the published content on the first edit, created
+// is true then.
+func (as *AdminService) editablePlan(ctx context.Context, kind planKind, planID primitive.ObjectID, plan interface{}, publication *models.PlanPublication) (collection string, created bool, err error) {
+	found, err := as.findPlan(ctx, kind.drafts, planID, plan)
+	if err != nil {
+		return "", false, err
+	}
+	if found {
+		return kind.drafts, false, nil
+	}
+
+	found, err = as.findPlan(ctx, kind.plans, planID, plan)
+	if err != nil {
+		return "", false, err
+	}
+	if !found {
+		return "", false, kind.notFound
+	}
+	if !publication.IsLive() {
+		return kind.plans, false, nil
+	}
+
+	publication.Status = models.PlanStatusDraft
+	publication.PublishedAt = nil
+	return kind.drafts, true, nil
+}
+
+// savePlanEdit stores the edited version of the plan returned by editablePlan.
+func (as *AdminService) savePlanEdit(ctx context.Context, kind planKind, collection string, created bool, planID primitive.ObjectID, before, after interface{}) error {
+	if created {
+		if _, err := as.database.Collection(collection).InsertOne(ctx, after); err != nil {
+			return fmt.Errorf("error inserting plan draft: %w", err)
+		}
+
+		as.recordAudit(ctx, collection, auditChange{action: models.AuditActionCreate, targetID: planID.Hex(), after: after})
+		return nil
+	}
+
+	result, err := as.database.Collection(collection).UpdateOne(ctx, bson.M{"_id": planID}, bson.M{"$set": after})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return kind.exists
+		}
+		return fmt.Errorf("error updating plan: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return kind.notFound
+	}
+
+	as.recordAudit(ctx, collection, auditChange{action: models.AuditActionUpdate, targetID: planID.Hex(), before: before, after: after})
+	return nil
+}
+
+// transitionTarget decodes the version of the plan a transition to status applies to into plan and returns its
+// collection. A published plan stays live during the review of its draft copy, archiving always applies to the plan.
+func (as *AdminService) transitionTarget(ctx context.Context, kind planKind, planID primitive.ObjectID, status string, plan interface{}) (string, error) {
+	if status != models.PlanStatusArchived {
+		found, err := as.findPlan(ctx, kind.drafts, planID, plan)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return kind.drafts, nil
+		}
+	}
+
+	found, err := as.findPlan(ctx, kind.plans, planID, plan)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", kind.notFound
+	}
+
+	return kind.plans, nil
+}
+
+// transitionPlan moves the version of the plan returned by transitionTarget to status. Publishing a draft copy
+// replaces the content of the plan, which keeps its ID and those of its weeks so enrolled users keep their progress.
+func (as *AdminService) transitionPlan(ctx context.Context, kind planKind, collection string, planID primitive.ObjectID, plan interface{}, publication *models.PlanPublication, status string) error {
+	if !publication.CanTransitionTo(status) {
+		return ErrInvalidPlanTransition
+	}
+
+	before := auditSnapshot(plan)
+	set := bson.M{"status": status}
+	publication.Status = status
+	if status == models.PlanStatusPublished {
+		now := time.Now()
+		publication.PublishedAt = &now
+		set["publishedAt"] = now
+	}
+
+	if collection == kind.plans {
+		result, err := as.database.Collection(collection).UpdateOne(ctx, bson.M{"_id": planID}, bson.M{"$set": set})
+		if err != nil {
+			return fmt.Errorf("error updating plan status: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return kind.notFound
+		}
+
+		as.recordAudit(ctx, collection, auditChange{action: models.AuditActionUpdate, targetID: planID.Hex(), before: before, after: plan})
+		return nil
+	}
+
+	if status != models.PlanStatusPublished {
+		return as.savePlanEdit(ctx, kind, collection, false, planID, before, plan)
+	}
+
+	var live bson.M
+	if err := as.database.Collection(kind.plans).FindOne(ctx, bson.M{"_id": planID}).Decode(&live); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return kind.notFound
+		}
+		return fmt.Errorf("error finding plan: %w", err)
+	}
+
+	content, err := planContent(plan)
+	if err != nil {
+		return err
+	}
+	result, err := as.database.Collection(kind.plans).UpdateOne(ctx, bson.M{"_id": planID}, bson.M{"$set": content})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return kind.exists
+		}
+		return fmt.Errorf("error publishing plan draft: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return kind.notFound
+	}
+
+	if _, err := as.database.Collection(kind.drafts).DeleteOne(ctx, bson.M{"_id": planID}); err != nil {
+		return fmt.Errorf("error deleting published plan draft: %w", err)
+	}
+
+	as.recordAudit(ctx, kind.plans, auditChange{action: models.AuditActionUpdate, targetID: planID.Hex(), before: live, after: applySet(live, content)})
+	as.recordAudit(ctx, kind.drafts, auditChange{action: models.AuditActionDelete, targetID: planID.Hex(), before: before})
+	return nil
+}
+
+// getPlanDraft decodes the draft copy of a published plan into plan.
+func (as *AdminService) getPlanDraft(ctx context.Context, kind planKind, planID primitive.ObjectID, plan interface{}) error {
+	found, err := as.findPlan(ctx, kind.drafts, planID, plan)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrPlanDraftNotFound
+	}
+
+	return nil
+}
+
+// planContent returns the fields of plan as stored, without its ID.
+func planContent(plan interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding plan: %w", err)
+	}
+
+	var content bson.M
+	if err := bson.Unmarshal(raw, &content); err != nil {
+		return nil, fmt.Errorf("error decoding plan: %w", err)
+	}
+	delete(content, "_id")
+
+	return content, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidPromoCode       = errors.New("promo code is missing the value of its type")
+	ErrInvalidPromoCodeExpiry = errors.New("promo code expiry must be in the future")
+	ErrPromoCodeTaken         = errors.New("promo code already exists")
+)
+
+// CreatePromoCode creates a campaign code. Codes are case insensitive, they are stored upper case.
+func (as *AdminService) CreatePromoCode(ctx context.Context, createdBy primitive.ObjectID, input models.PromoCodeInput) (*models.PromoCode, error) {
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, ErrInvalidPromoCodeExpiry
+	}
+
+	promoCode := models.PromoCode{
+		ID:             primitive.NewObjectID(),
+		Code:           normalizePromoCode(input.Code),
+		Type:           input.Type,
+		MaxRedemptions: input.MaxRedemptions,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+	}
+	// Gift codes are bought by users, admins create the other types
+	if input.Type == models.PromoTypeTrialExtension {
+		promoCode.TrialDays = input.TrialDays
+	}
+	if promoCode.TrialDays == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPromoCode, input.Type)
+	}
+
+	if promoCode.Code == "" {
+		code, err := generatePromoCode()
+		if err != nil {
+			return nil, fmt.Errorf("error generating promo code: %w", err)
+		}
+		promoCode.Code = code
+	}
+
+	promoCodeCollection := as.database.Collection("promoCodes")
+	if _, err := promoCodeCollection.InsertOne(ctx, promoCode); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPromoCodeTaken
+		}
+		return nil, fmt.Errorf("error inserting promo code: %w", err)
+	}
+
+	as.recordAudit(ctx, "promoCodes", auditChange{action: models.AuditActionCreate, targetID: promoCode.ID.Hex(), after: promoCode})
+
+	return &promoCode, nil
+}
+
+// GetPromoCodes lists the codes of a type, or all of them when codeType is empty.
+func (as *AdminService) GetPromoCodes(ctx context.Context, codeType string) ([]models.PromoCode, error) {
+	promoCodeCollection := as.database.Collection("promoCodes")
+
+	filter := bson.M{}
+	if codeType != "" {
+		filter["type"] = codeType
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := promoCodeCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding promo codes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	promoCodes := []models.PromoCode{}
+	if err := cursor.All(ctx, &promoCodes); err != nil {
+		return nil, fmt.Errorf("error decoding promo codes: %w", err)
+	}
+
+	return promoCodes, nil
+}
+
+// DisablePromoCode stops the redemptions of a code, what was redeemed is kept.
+func (as *AdminService) DisablePromoCode(ctx context.Context, promoCodeID primitive.ObjectID) error {
+	promoCodeCollection := as.database.Collection("promoCodes")
+
+	disabledAt := time.Now()
+	filter := bson.M{"_id": promoCodeID, "disabledAt": bson.M{"$exists": false}}
+	result, err := promoCodeCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"disabledAt": disabledAt}})
+	if err != nil {
+		return fmt.Errorf("error disabling promo code: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrPromoCodeNotFound
+	}
+
+	as.recordAudit(ctx, "promoCodes", auditChange{action: models.AuditActionUpdate, targetID: promoCodeID.Hex(), after: bson.M{"disabledAt": disabledAt}})
+	return nil
+}
+
+// GetPromoCodeStats counts the redemptions of a code, per UTC day.
+func (as *AdminService) GetPromoCodeStats(ctx context.Context, promoCodeID primitive.ObjectID) (*models.PromoCodeStats, error) {
+	var promoCode models.PromoCode
+	if err := as.database.Collection("promoCodes").FindOne(ctx, bson.M{"_id": promoCodeID}).Decode(&promoCode); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPromoCodeNotFound
+		}
+		return nil, fmt.Errorf("error fetching promo code: %w", err)
+	}
+
+	redemptionCollection := as.database.Collection("promoRedemptions")
+	opts := options.Find().SetSort(bson.D{{Key: "redeemedAt", Value: 1}}).SetProjection(bson.M{"redeemedAt": 1})
+	cursor, err := redemptionCollection.Find(ctx, bson.M{"codeId": promoCodeID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding promo code redemptions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var redemptions []models.PromoRedemption
+	if err := cursor.All(ctx, &redemptions); err != nil {
+		return nil, fmt.Errorf("error decoding promo code redemptions: %w", err)
+	}
+
+	stats := &models.PromoCodeStats{
+		CodeID:           promoCode.ID,
+		Code:             promoCode.Code,
+		Redemptions:      len(redemptions),
+		RedemptionsByDay: []models.PromoRedemptionDay{},
+	}
+	if promoCode.MaxRedemptions > 0 {
+		remaining := max(promoCode.MaxRedemptions-promoCode.Redemptions, 0)
+		stats.Remaining = &remaining
+	}
+	for i, redemption := range redemptions {
+		if i == 0 {
+			stats.FirstRedeemedAt = &redemptions[i].RedeemedAt
+		}
+		stats.LastRedeemedAt = &redemptions[i].RedeemedAt
+
+		// Redemptions are sorted, a day only follows itself
+		date := redemption.RedeemedAt.UTC().Format(time.DateOnly)
+		if last := len(stats.RedemptionsByDay) - 1; last >= 0 && stats.RedemptionsByDay[last].Date == date {
+			stats.RedemptionsByDay[last].Redemptions++
+		} else {
+			stats.RedemptionsByDay = append(stats.RedemptionsByDay, models.PromoRedemptionDay{Date: date, Redemptions: 1})
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Permissions are looked up on every admin request, keep them for a short while.
+// Changes made through this instance apply immediately, other instances pick them up after the TTL.
+const rolePermissionsCacheTTL = time.Minute
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleAlreadyExists  = errors.New("role already exists")
+	ErrRoleInUse          = errors.New("role is assigned to at least one admin")
+	ErrBuiltInRole        = errors.New("built-in roles cannot be deleted")
+	ErrSuperAdminRoleLock = errors.New("the super admin role always has every permission")
+	ErrUnknownPermission  = errors.New("unknown permission")
+)
+
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+type rolePermissionsCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPermissions
+}
+
+func newRolePermissionsCache() *rolePermissionsCache {
+	return &rolePermissionsCache{entries: make(map[string]cachedPermissions)}
+}
+
+func (c *rolePermissionsCache) get(role string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[role]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.permissions, true
+}
+
+func (c *rolePermissionsCache) set(role string, permissions []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[role] = cachedPermissions{permissions: permissions, expiresAt: time.Now().Add(rolePermissionsCacheTTL)}
+}
+
+func (c *rolePermissionsCache) invalidate(role string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, role)
+}
+
+// EnsureDefaultRoles creates the built-in roles that don't exist yet, existing roles keep their edited permissions.
+func (as *AdminService) EnsureDefaultRoles(ctx context.Context) error {
+	roleCollection := as.database.Collection("roles")
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	for _, role := range models.DefaultRoles() {
+		update := bson.M{"$setOnInsert": bson.M{
+			"description": role.Description,
+			"permissions": role.Permissions,
+			"builtIn":     role.BuiltIn,
+			"updatedAt":   time.Now(),
+		}}
+
+		err := roleCollection.FindOneAndUpdate(ctx, bson.M{"_id": role.Name}, update, opts).Err()
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("error seeding role %s: %w", role.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// PermissionsForRole resolves the permission set of an admin role, unknown roles have none.
+func (as *AdminService) PermissionsForRole(ctx context.Context, role string) ([]string, error) {
+	if role == models.SuperAdminRole {
+		return models.AllPermissions, nil
+	}
+
+	if permissions, ok := as.roleCache.get(role); ok {
+		return permissions, nil
+	}
+
+	roleCollection := as.database.Collection("roles")
+
+	var storedRole models.Role
+	err := roleCollection.FindOne(ctx, bson.M{"_id": role}).Decode(&storedRole)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("error fetching role %s: %w", role, err)
+	}
+
+	as.roleCache.set(role, storedRole.Permissions)
+	return storedRole.Permissions, nil
+}
+
+func (as *AdminService) GetRoles(ctx context.Context) ([]models.Role, error) {
+	roleCollection := as.database.Collection("roles")
+
+	cursor, err := roleCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("error finding roles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var roles []models.Role
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, fmt.Errorf("error decoding roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (as *AdminService) CreateRole(ctx context.Context, input models.RoleInput) (*models.Role, error) {
+	if err := validatePermissions(input.Permissions); err != nil {
+		return nil, err
+	}
+
+	roleCollection := as.database.Collection("roles")
+	count, err := roleCollection.CountDocuments(ctx, bson.M{"_id": input.Name})
+	if err != nil {
+		return nil, fmt.Errorf("error checking if role already exists: %w", err)
+	}
+
+	if count > 0 {
+		return nil, ErrRoleAlreadyExists
+	}
+
+	role := models.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+		UpdatedAt:   time.Now(),
+	}
+	if _, err := roleCollection.InsertOne(ctx, role); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrRoleAlreadyExists
+		}
+		return nil, fmt.Errorf("error inserting role: %w", err)
+	}
+
+	as.recordAudit(ctx, "roles", auditChange{action: models.AuditActionCreate, targetID: role.Name, after: role})
+	return &role, nil
+}
+
+func (as *AdminService) UpdateRole(ctx context.Context, name string, input models.RoleUpdateInput) (*models.Role, error) {
+	if name == models.SuperAdminRole && input.Permissions != nil {
+		return nil, ErrSuperAdminRoleLock
+	}
+
+	update := bson.M{"updatedAt": time.Now()}
+	if input.Description != nil {
+		update["description"] = *input.Description
+	}
+	if input.Permissions != nil {
+		if err := validatePermissions(input.Permissions); err != nil {
+			return nil, err
+		}
+		update["permissions"] = input.Permissions
+	}
+
+	roleCollection := as.database.Collection("roles")
+	// The previous version is returned for the audit log, the update is then applied to it
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var before models.Role
+	if err := roleCollection.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$set": update}, opts).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("error updating role: %w", err)
+	}
+
+	role := before
+	role.UpdatedAt = update["updatedAt"].(time.Time)
+	if input.Description != nil {
+		role.Description = *input.Description
+	}
+	if input.Permissions != nil {
+		role.Permissions = input.Permissions
+	}
+
+	as.roleCache.invalidate(name)
+	as.recordAudit(ctx, "roles", auditChange{action: models.AuditActionUpdate, targetID: name, before: before, after: role})
+	return &role, nil
+}
+
+func (as *AdminService) DeleteRole(ctx context.Context, name string) error {
+	roleCollection := as.database.Collection("roles")
+
+	var role models.Role
+	if err := roleCollection.FindOne(ctx, bson.M{"_id": name}).Decode(&role); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("error fetching role: %w", err)
+	}
+
+	if role.BuiltIn {
+		return ErrBuiltInRole
+	}
+
+	adminCollection := as.database.Collection("admins")
+	count, err := adminCollection.CountDocuments(ctx, bson.M{"role": name})
+	if err != nil {
+		return fmt.Errorf("error checking if role is in use: %w", err)
+	}
+
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if _, err := roleCollection.DeleteOne(ctx, bson.M{"_id": name}); err != nil {
+		return fmt.Errorf("error deleting role: %w", err)
+	}
+
+	as.roleCache.invalidate(name)
+	as.recordAudit(ctx, "roles", auditChange{action: models.AuditActionDelete, targetID: name, before: role})
+	return nil
+}
+
+func (as *AdminService) checkRoleExists(ctx context.Context, name string) error {
+	roleCollection := as.database.Collection("roles")
+	count, err := roleCollection.CountDocuments(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("error checking if role exists: %w", err)
+	}
+
+	if count == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
+
+func validatePermissions(permissions []string) error {
+	known := make(map[string]bool, len(models.AllPermissions))
+	for _, permission := range models.AllPermissions {
+		known[permission] = true
+	}
+
+	for _, permission := range permissions {
+		if !known[permission] {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+
+	return nil
+}
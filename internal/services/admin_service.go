@@ -5,25 +5,31 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/GhostDrew11/vigor-api/internal/config"
 	"github.com/GhostDrew11/vigor-api/internal/db"
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
+	ErrAdminNotFound = errors.New("admin not found")
 	ErrAdminAlreadyExists = errors.New("admin already exists")
 	ErrInvalidAdminCredentials = errors.New("invalid email or password")
+	ErrAdminDisabled = errors.New("admin account is disabled")
 )
 
 type AdminService struct {
 	database db.MongoDatabase
 	hasher utils.HashPasswordService
 	parser utils.ParserService
+	cfg *config.Config
+	roleCache *rolePermissionsCache
 }
 
-func NewAdminService(database db.MongoDatabase, hasher utils.HashPasswordService, parser utils.ParserService) *AdminService {
-	return &AdminService{database: database, hasher: hasher, parser: parser}
+func NewAdminService(database db.MongoDatabase, hasher utils.HashPasswordService, parser utils.ParserService, cfg *config.Config) *AdminService {
+	return &AdminService{database: database, hasher: hasher, parser: parser, cfg: cfg, roleCache: newRolePermissionsCache()}
 }
 
 func (as *AdminService) RegisterAdmin(ctx context.Context, input models.AdminRegistrationInput) error {
@@ -41,18 +47,36 @@ func (as *AdminService) RegisterAdmin(ctx context.Context, input models.AdminReg
 		return ErrAdminAlreadyExists
 	}
 
-	// Create a new admin
-	admin, err := models.NewAdminfromInput(input)
+	// Create a new admin, before the invitation is accepted so a refused password doesn't use it up
+	admin, err := models.NewAdminfromInput(input, as.hasher, models.SuperAdminRole, nil)
 	if err != nil {
 		return fmt.Errorf("error creating admin from input: %w", err)
 	}
 
+	// Admins join through an invitation, the very first one can bootstrap itself as super admin
+	var invitation *models.AdminInvitation
+	if input.InvitationToken != "" {
+		invitation, err = as.acceptAdminInvitation(ctx, input.Email, input.InvitationToken)
+		if err != nil {
+			return err
+		}
+		admin.Role = invitation.Role
+		admin.InvitedBy = &invitation.InvitedBy
+	} else if err := as.checkAdminBootstrap(ctx); err != nil {
+		return err
+	}
+
 	// Insert the admin into the database
 	_, err = adminCollection.InsertOne(ctx, admin)
 	if err != nil {
+		if invitation != nil {
+			as.releaseAdminInvitation(ctx, invitation.ID)
+		}
 		return fmt.Errorf("error inserting admin into database: %w", err)
 	}
 
+	// Nobody is signed in while registering, the new admin is recorded as the actor
+	as.recordAudit(actingAs(ctx, admin.ID, admin.Role), "admins", auditChange{action: models.AuditActionCreate, targetID: admin.ID.Hex(), after: admin})
 	return nil
 }
 
@@ -65,6 +89,10 @@ func (as *AdminService) GetAdminByEmail(ctx context.Context, email, password str
 
 	result := adminCollection.FindOne(ctx, filter)
 	if result.Err() != nil {
+		// Unknown accounts count as failed logins so they can't be told apart from a wrong password
+		if result.Err() == mongo.ErrNoDocuments {
+			return &models.Admin{}, ErrInvalidAdminCredentials
+		}
 		return &models.Admin{}, fmt.Errorf("error fetching admin with email %s: %w", email, result.Err())
 	}
 
@@ -77,6 +105,12 @@ func (as *AdminService) GetAdminByEmail(ctx context.Context, email, password str
 		return &models.Admin{}, ErrInvalidAdminCredentials
 	}
 
+	if admin.Disabled {
+		return &models.Admin{}, ErrAdminDisabled
+	}
+
+	upgradePasswordHash(ctx, adminCollection, as.hasher, admin.ID, password, admin.PasswordHash)
+
 	return &admin, nil
 }
 
@@ -2,16 +2,32 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/GhostDrew11/vigor-api/internal/db"
 	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Number of subscription changes shown in the detail of a user
+const userSubscriptionChangeLimit = 50
+
+var (
+	ErrInvalidSuspensionEnd = errors.New("suspension must end in the future")
+	ErrUserNotRestricted    = errors.New("user is neither suspended nor banned")
 )
 
 func (as *AdminService) GetUsers(ctx context.Context) ([]models.User, error) {
 	userCollection := as.database.Collection("users")
 
-	cursor, err := userCollection.Find(ctx, bson.M{})
+	cursor, err := userCollection.Find(ctx, bson.M{}, options.Find().SetProjection(userAccountProjection))
 	if err != nil {
 		return nil, fmt.Errorf("error finding users: %w", err)
 	}
@@ -23,4 +39,223 @@ func (as *AdminService) GetUsers(ctx context.Context) ([]models.User, error) {
 	}
 
 	return users, nil
-}
\ No newline at end of file
+}
+
+// TransitionUserSubscription moves the subscription of a user to another status, following the allowed transitions.
+func (as *AdminService) TransitionUserSubscription(ctx context.Context, userID primitive.ObjectID, status string) (*models.UserSubscription, error) {
+	before, after, err := updateSubscription(ctx, as.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		return transitionSubscription(subscription, status, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	as.recordAudit(ctx, "users", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: userID.Hex(),
+		before:   bson.M{"subscription": before},
+		after:    bson.M{"subscription": after},
+	})
+
+	return after, nil
+}
+
+// GetUserDetail returns the account of the user with its workout plan enrollments, the changes of its subscription
+// and its invoices.
+func (as *AdminService) GetUserDetail(ctx context.Context, userID primitive.ObjectID) (*models.UserAdminDetail, error) {
+	var detail models.UserAdminDetail
+	opts := options.FindOne().SetProjection(userAccountProjection)
+	if err := as.database.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&detail.User); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	detail.Enrollments = []models.UserWorkoutPlanStatus{}
+	enrollmentOpts := options.Find().SetSort(bson.D{{Key: "startDate", Value: -1}})
+	if err := findAll(ctx, as.database.Collection("userWorkoutPlanStatus"), bson.M{"userId": userID}, enrollmentOpts, &detail.Enrollments); err != nil {
+		return nil, fmt.Errorf("error finding workout plan enrollments: %w", err)
+	}
+
+	detail.SubscriptionChanges = []models.SubscriptionEvent{}
+	changeOpts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(userSubscriptionChangeLimit)
+	if err := findAll(ctx, as.database.Collection("subscriptionEvents"), bson.M{"userId": userID}, changeOpts, &detail.SubscriptionChanges); err != nil {
+		return nil, fmt.Errorf("error finding subscription changes: %w", err)
+	}
+
+	detail.Invoices = []models.Invoice{}
+	invoiceOpts := options.Find().SetSort(bson.D{{Key: "issuedAt", Value: -1}})
+	if err := findAll(ctx, as.database.Collection("invoices"), bson.M{"userId": userID}, invoiceOpts, &detail.Invoices); err != nil {
+		return nil, fmt.Errorf("error finding invoices: %w", err)
+	}
+
+	return &detail, nil
+}
+
+// findAll decodes every document matching the filter into results.
+func findAll(ctx context.Context, collection db.MongoCollection, filter bson.M, opts *options.FindOptions, results interface{}) error {
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
+
+// SuspendUser keeps the user out until the end of the suspension and signs them out of every device.
+func (as *AdminService) SuspendUser(ctx context.Context, actorID, userID primitive.ObjectID, input models.UserSuspensionInput) (*models.UserRestriction, error) {
+	now := time.Now()
+	if !input.Until.After(now) {
+		return nil, ErrInvalidSuspensionEnd
+	}
+
+	until := input.Until
+	restriction := models.UserRestriction{Type: models.UserRestrictionSuspended, Reason: input.Reason, Until: &until, RestrictedBy: actorID, RestrictedAt: now}
+	if err := as.restrictUser(ctx, userID, restriction); err != nil {
+		return nil, err
+	}
+
+	return &restriction, nil
+}
+
+// BanUser keeps the user out until an admin lifts the ban and signs them out of every device.
+func (as *AdminService) BanUser(ctx context.Context, actorID, userID primitive.ObjectID, input models.UserBanInput) (*models.UserRestriction, error) {
+	restriction := models.UserRestriction{Type: models.UserRestrictionBanned, Reason: input.Reason, RestrictedBy: actorID, RestrictedAt: time.Now()}
+	if err := as.restrictUser(ctx, userID, restriction); err != nil {
+		return nil, err
+	}
+
+	return &restriction, nil
+}
+
+// restrictUser replaces the restriction of the user. RequireRole refuses the tokens of the user from now on, the
+// sessions are revoked too so refresh tokens stop working.
+func (as *AdminService) restrictUser(ctx context.Context, userID primitive.ObjectID, restriction models.UserRestriction) error {
+	var user models.User
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"restriction": 1})
+	update := bson.M{"$set": bson.M{"restriction": restriction}}
+	if err := as.database.Collection("users").FindOneAndUpdate(ctx, bson.M{"_id": userID}, update, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error restricting user: %w", err)
+	}
+
+	if err := revokeUserSessions(ctx, as.database.Collection("sessions"), userID); err != nil {
+		log.Printf("Error signing out restricted user %s: %v\n", userID.Hex(), err)
+	}
+
+	as.recordAudit(ctx, "users", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: userID.Hex(),
+		before:   bson.M{"restriction": user.Restriction},
+		after:    bson.M{"restriction": restriction},
+		reason:   restriction.Reason,
+	})
+	return nil
+}
+
+// LiftUserRestriction ends the suspension or ban of the user, who can sign in again.
+func (as *AdminService) LiftUserRestriction(ctx context.Context, userID primitive.ObjectID) error {
+	var user models.User
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"restriction": 1})
+	update := bson.M{"$unset": bson.M{"restriction": ""}}
+	if err := as.database.Collection("users").FindOneAndUpdate(ctx, bson.M{"_id": userID}, update, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error lifting user restriction: %w", err)
+	}
+
+	if user.Restriction == nil {
+		return ErrUserNotRestricted
+	}
+
+	as.recordAudit(ctx, "users", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: userID.Hex(),
+		before:   bson.M{"restriction": user.Restriction},
+		after:    bson.M{},
+	})
+	return nil
+}
+
+// ResetUserPassword replaces the password of the user with a random one and signs them out of every device.
+// The new password is only available here, support hands it to the user.
+func (as *AdminService) ResetUserPassword(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	password, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("error generating password: %w", err)
+	}
+
+	passwordHash, err := as.hasher.HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"passwordHash": passwordHash, "passwordResetAt": now}}
+	result, err := as.database.Collection("users").UpdateOne(ctx, bson.M{"_id": userID}, update)
+	if err != nil {
+		return "", fmt.Errorf("error resetting password: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return "", ErrUserNotFound
+	}
+
+	if err := revokeUserSessions(ctx, as.database.Collection("sessions"), userID); err != nil {
+		log.Printf("Error signing out user %s after password reset: %v\n", userID.Hex(), err)
+	}
+
+	as.recordAudit(ctx, "users", auditChange{action: models.AuditActionUpdate, targetID: userID.Hex(), after: bson.M{"passwordResetAt": now}})
+	return password, nil
+}
+
+// OverrideUserSubscription sets the plan, status and end of the subscription of the user without following the
+// allowed transitions. The reason is recorded with the change.
+func (as *AdminService) OverrideUserSubscription(ctx context.Context, userID primitive.ObjectID, input models.SubscriptionOverrideInput) (*models.UserSubscription, error) {
+	before, after, err := updateSubscription(ctx, as.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		return overrideSubscription(subscription, input, now), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	as.recordAudit(ctx, "users", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: userID.Hex(),
+		before:   bson.M{"subscription": before},
+		after:    bson.M{"subscription": after},
+		reason:   input.Reason,
+	})
+
+	return after, nil
+}
+
+// overrideSubscription sets the subscription as the admin asked, with the dates that go with its status. Billing
+// fields are kept so the payment provider still matches its webhooks.
+func overrideSubscription(subscription models.UserSubscription, input models.SubscriptionOverrideInput, now time.Time) models.UserSubscription {
+	subscription.Type = input.Type
+	subscription.Status = input.Status
+	if input.EndDate != nil {
+		endDate := *input.EndDate
+		subscription.EndDate = &endDate
+	}
+	subscription.PendingChange = nil
+	subscription.RenewalRemindedAt = nil
+	subscription.IsActive = input.Status != models.SubscriptionStatusExpired
+
+	subscription.NextRenewalDate = nil
+	if input.Status == models.SubscriptionStatusActive {
+		subscription.NextRenewalDate = subscription.EndDate
+	}
+	if input.Status != models.SubscriptionStatusCancelled {
+		subscription.CancelledAt = nil
+	} else if subscription.CancelledAt == nil {
+		subscription.CancelledAt = &now
+	}
+
+	return subscription
+}
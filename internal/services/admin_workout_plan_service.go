@@ -3,10 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -23,16 +25,25 @@ func (as *AdminService) GetWorkoutPlanByID(ctx context.Context, workoutPlanID pr
 	var workoutPlan models.WorkoutPlan
 	err := workoutPlanCollection.FindOne(ctx, filter).Decode(&workoutPlan)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.WorkoutPlan{}, ErrWorkoutPlanNotFound
+		}
 		return models.WorkoutPlan{}, fmt.Errorf("error finding workout plan: %w", err)
 	}
 
 	return workoutPlan, nil
 }
 
-func (as *AdminService) GetWorkoutPlans(ctx context.Context) ([]models.WorkoutPlan, error) {
+// GetWorkoutPlans returns the workout plans in the given publication status, every plan when it is empty.
+func (as *AdminService) GetWorkoutPlans(ctx context.Context, status string) ([]models.WorkoutPlan, error) {
 	workoutCollection := as.database.Collection("workoutPlans")
 
-	cursor, err := workoutCollection.Find(ctx, bson.M{})
+	filter, err := planStatusFilter(bson.M{}, status)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := workoutCollection.Find(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("error finding workout plans: %w", err)
 	}
@@ -46,10 +57,13 @@ func (as *AdminService) GetWorkoutPlans(ctx context.Context) ([]models.WorkoutPl
 	return workoutPlans, nil
 }
 
-func(as *AdminService) SearchWorkoutPlansByName(ctx context.Context, name string) ([]models.WorkoutPlan, error) {
+func(as *AdminService) SearchWorkoutPlansByName(ctx context.Context, name, status string) ([]models.WorkoutPlan, error) {
     workoutPlanCollection := as.database.Collection("workoutPlans")
 
-    filter := bson.M{"name": primitive.Regex{Pattern: name, Options: "i"}}
+    filter, err := planStatusFilter(bson.M{"name": primitive.Regex{Pattern: name, Options: "i"}}, status)
+    if err != nil {
+        return nil, err
+    }
     cursor, err := workoutPlanCollection.Find(ctx, filter)
     if err != nil {
         return nil, fmt.Errorf("error finding workout plans by name: %w", err)
@@ -64,7 +78,29 @@ func(as *AdminService) SearchWorkoutPlansByName(ctx context.Context, name string
     return workoutPlans, nil
 }
 
+// CreateWorkoutPlan stores a new workout plan as a draft, users see it once it is reviewed and published.
 func (as *AdminService) CreateWorkoutPlan(ctx context.Context, workoutPlanInput models.WorkoutPlan) error {
+	return as.insertWorkoutPlan(ctx, &workoutPlanInput)
+}
+
+// CloneWorkoutPlan copies the workout plan under a new name as a new draft, with new IDs. The pending edits of a
+// published plan are not copied.
+func (as *AdminService) CloneWorkoutPlan(ctx context.Context, workoutPlanID primitive.ObjectID, input models.PlanCloneInput) (*models.WorkoutPlan, error) {
+	workoutPlan, err := as.GetWorkoutPlanByID(ctx, workoutPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	workoutPlan.Name = input.Name
+	if err := as.insertWorkoutPlan(ctx, &workoutPlan); err != nil {
+		return nil, err
+	}
+
+	return &workoutPlan, nil
+}
+
+// insertWorkoutPlan gives the plan and every week, day and circuit in it a new ID and stores it as a draft.
+func (as *AdminService) insertWorkoutPlan(ctx context.Context, workoutPlanInput *models.WorkoutPlan) error {
 	// Get the workout plan collection
 	workoutPlanCollection := as.database.Collection("workoutPlans")
 
@@ -80,6 +116,8 @@ func (as *AdminService) CreateWorkoutPlan(ctx context.Context, workoutPlanInput
 	}
 
 	workoutPlanInput.ID = primitive.NewObjectID()
+	workoutPlanInput.PlanPublication = models.PlanPublication{Status: models.PlanStatusDraft}
+	workoutPlanInput.Version = 0
 	for i := range workoutPlanInput.Weeks {
 		workoutPlanInput.Weeks[i].ID = primitive.NewObjectID()
 		for j := range workoutPlanInput.Weeks[i].Days {
@@ -97,35 +135,117 @@ func (as *AdminService) CreateWorkoutPlan(ctx context.Context, workoutPlanInput
 	}
 
 	// Insert the new workout plan
-	_, err = workoutPlanCollection.InsertOne(ctx, workoutPlanInput)
+	_, err = workoutPlanCollection.InsertOne(ctx, *workoutPlanInput)
 	if err != nil {
 		return fmt.Errorf("error inserting workout plan: %w", err)
 	}
 
+	as.recordAudit(ctx, "workoutPlans", auditChange{action: models.AuditActionCreate, targetID: workoutPlanInput.ID.Hex(), after: *workoutPlanInput})
 	return nil
 }
 
-func (as *AdminService) UpdateWorkoutPlan(ctx context.Context, workoutPlanID primitive.ObjectID, updateInput models.WorkoutPlanInput) error {
-	// Get the workout plan collection 
-	workoutPlanCollection := as.database.Collection("workoutPlans")
-
+// UpdateWorkoutPlan edits the workout plan while it is a draft, or its draft copy once it is published. Edits send a
+// plan in review back to draft. Returns the edited version.
+func (as *AdminService) UpdateWorkoutPlan(ctx context.Context, workoutPlanID primitive.ObjectID, updateInput models.WorkoutPlanInput) (*models.WorkoutPlan, error) {
 	var existingPlan models.WorkoutPlan
-    if err := workoutPlanCollection.FindOne(ctx, bson.M{"_id": workoutPlanID}).Decode(&existingPlan); err != nil {
-        return fmt.Errorf("error fetching existing workout plan: %w", err)
-    }
+	collection, created, err := as.editablePlan(ctx, workoutPlanKind, workoutPlanID, &existingPlan, &existingPlan.PlanPublication)
+	if err != nil {
+		return nil, err
+	}
 
 	updatedDoc := mergeUpdatesIntoExistingPlan(existingPlan, updateInput)
+	updatedDoc.Status = models.PlanStatusDraft
 
-	// Use $set to only update the provided fields
-	update := bson.M{"$set": updatedDoc}
-	filter := bson.M{"_id": workoutPlanID}
-	result, err := workoutPlanCollection.UpdateOne(ctx, filter, update)
+	if err := as.savePlanEdit(ctx, workoutPlanKind, collection, created, workoutPlanID, existingPlan, updatedDoc); err != nil {
+		return nil, err
+	}
+
+	return &updatedDoc, nil
+}
+
+// GetWorkoutPlanDraft returns the pending edits of a published workout plan.
+func (as *AdminService) GetWorkoutPlanDraft(ctx context.Context, workoutPlanID primitive.ObjectID) (models.WorkoutPlan, error) {
+	var draft models.WorkoutPlan
+	if err := as.getPlanDraft(ctx, workoutPlanKind, workoutPlanID, &draft); err != nil {
+		return models.WorkoutPlan{}, err
+	}
+
+	return draft, nil
+}
+
+// TransitionWorkoutPlan moves the workout plan, or the draft copy under review once it is published, to another
+// status of the review workflow. A plan is checked before it is published.
+func (as *AdminService) TransitionWorkoutPlan(ctx context.Context, workoutPlanID primitive.ObjectID, input models.PlanTransitionInput) (*models.WorkoutPlan, error) {
+	var workoutPlan models.WorkoutPlan
+	collection, err := as.transitionTarget(ctx, workoutPlanKind, workoutPlanID, input.Status, &workoutPlan)
 	if err != nil {
-		return fmt.Errorf("error updating workout plan: %w", err)
+		return nil, err
+	}
+
+	// Each publication of new content is kept as a version, enrolled users follow the version they joined
+	version := 0
+	if input.Status == models.PlanStatusPublished && workoutPlan.CanTransitionTo(input.Status) {
+		if err := as.validateWorkoutPlan(ctx, workoutPlan); err != nil {
+			return nil, err
+		}
+		if version, err = as.nextWorkoutPlanVersion(ctx, collection, workoutPlan); err != nil {
+			return nil, err
+		}
+		if version > 0 {
+			workoutPlan.Version = version
+		}
 	}
 
-	if result.MatchedCount == 0 {
-		return ErrWorkoutPlanNotFound
+	if err := as.transitionPlan(ctx, workoutPlanKind, collection, workoutPlanID, &workoutPlan, &workoutPlan.PlanPublication, input.Status); err != nil {
+		return nil, err
+	}
+
+	if version > 0 {
+		if err := as.saveWorkoutPlanVersion(ctx, workoutPlan, version); err != nil {
+			return nil, err
+		}
+	}
+
+	return &workoutPlan, nil
+}
+
+// validateWorkoutPlan checks that the workout plan can be followed: every week has days, every day a workout and
+// every circuit existing exercises.
+func (as *AdminService) validateWorkoutPlan(ctx context.Context, workoutPlan models.WorkoutPlan) error {
+	if len(workoutPlan.Weeks) == 0 {
+		return fmt.Errorf("%w: it has no weeks", ErrPlanNotPublishable)
+	}
+
+	exerciseIDs := map[primitive.ObjectID]bool{}
+	for _, week := range workoutPlan.Weeks {
+		if len(week.Days) == 0 {
+			return fmt.Errorf("%w: week %d has no days", ErrPlanNotPublishable, week.WeekNumber)
+		}
+		for _, day := range week.Days {
+			if len(day.Workouts) == 0 {
+				return fmt.Errorf("%w: a day of week %d has no workout", ErrPlanNotPublishable, week.WeekNumber)
+			}
+			for _, circuit := range append(day.WarmUps, append(day.Workouts, day.CoolDowns...)...) {
+				if len(circuit.ExerciseIDs) == 0 {
+					return fmt.Errorf("%w: a circuit of week %d has no exercises", ErrPlanNotPublishable, week.WeekNumber)
+				}
+				for _, exerciseID := range circuit.ExerciseIDs {
+					exerciseIDs[exerciseID] = true
+				}
+			}
+		}
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(exerciseIDs))
+	for exerciseID := range exerciseIDs {
+		ids = append(ids, exerciseID)
+	}
+	count, err := as.database.Collection("exercises").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return fmt.Errorf("error counting exercises: %w", err)
+	}
+	if count != int64(len(ids)) {
+		return fmt.Errorf("%w: %d of its exercises don't exist", ErrPlanNotPublishable, int64(len(ids))-count)
 	}
 
 	return nil
@@ -136,16 +256,21 @@ func (as *AdminService) DeleteWorkoutPlan(ctx context.Context, workoutPlanID pri
 	workoutPlanCollection := as.database.Collection("workoutPlans")
 	filter := bson.M{"_id": workoutPlanID}
 
-	// Delete the workout plan
-	result, err := workoutPlanCollection.DeleteOne(ctx, filter)
-	if err != nil {
+	// Delete the workout plan, keeping what was removed for the audit log
+	var before bson.M
+	if err := workoutPlanCollection.FindOneAndDelete(ctx, filter).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrWorkoutPlanNotFound
+		}
 		return fmt.Errorf("error deleting workout plan: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
-		return ErrWorkoutPlanNotFound
-	}
+	as.recordAudit(ctx, "workoutPlans", auditChange{action: models.AuditActionDelete, targetID: workoutPlanID.Hex(), before: before})
 
+	// Pending edits go with the plan
+	if _, err := as.database.Collection(workoutPlanKind.drafts).DeleteOne(ctx, filter); err != nil {
+		log.Printf("Error deleting draft of workout plan %s: %v\n", workoutPlanID.Hex(), err)
+	}
 	return nil
 }
 
@@ -157,6 +282,9 @@ func mergeUpdatesIntoExistingPlan(existingPlan models.WorkoutPlan, updateInput m
     if updateInput.Duration != nil {
         existingPlan.Duration = *updateInput.Duration
     }
+    if updateInput.AccessTier != nil {
+        existingPlan.AccessTier = *updateInput.AccessTier
+    }
 
     // Initialize a map for quick lookup of existing weeks by weekNumber
     existingWeeksMap := make(map[int]*models.WorkoutWeek)
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrWorkoutPlanVersionNotFound = errors.New("workout plan version not found")
+
+const defaultWorkoutPlanMigrationLimit = 100
+
+// nextWorkoutPlanVersion returns the version publishing the plan from collection creates, none when an archived plan
+// is published again as it was. Plans published before versions existed first keep their content as version 1, the
+// version their users are pinned to.
+func (as *AdminService) nextWorkoutPlanVersion(ctx context.Context, collection string, workoutPlan models.WorkoutPlan) (int, error) {
+	if collection == workoutPlanKind.plans {
+		if workoutPlan.Version > 0 {
+			return 0, nil
+		}
+		return 1, nil
+	}
+
+	live, err := as.GetWorkoutPlanByID(ctx, workoutPlan.ID)
+	if err != nil {
+		return 0, err
+	}
+	if live.Version > 0 {
+		return live.Version + 1, nil
+	}
+
+	if err := as.saveWorkoutPlanVersion(ctx, live, 1); err != nil {
+		return 0, err
+	}
+	return 2, nil
+}
+
+// saveWorkoutPlanVersion keeps the published content of the plan as the given version and makes it the version of
+// the plan. Users who joined the plan before it had versions are pinned to version 1.
+func (as *AdminService) saveWorkoutPlanVersion(ctx context.Context, workoutPlan models.WorkoutPlan, version int) error {
+	workoutPlan.Version = version
+	record := models.WorkoutPlanVersion{
+		ID:            primitive.NewObjectID(),
+		WorkoutPlanID: workoutPlan.ID,
+		Version:       version,
+		Plan:          workoutPlan,
+		PublishedAt:   time.Now(),
+	}
+	if workoutPlan.PublishedAt != nil {
+		record.PublishedAt = *workoutPlan.PublishedAt
+	}
+
+	// A duplicate was saved by an earlier attempt of the same publication
+	if _, err := as.database.Collection("workoutPlanVersions").InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("error inserting workout plan version: %w", err)
+	}
+
+	filter := bson.M{"_id": workoutPlan.ID}
+	if _, err := as.database.Collection("workoutPlans").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"version": version}}); err != nil {
+		return fmt.Errorf("error updating workout plan version: %w", err)
+	}
+
+	if version == 1 {
+		enrollments := bson.M{"workoutPlanId": workoutPlan.ID, "version": bson.M{"$exists": false}}
+		if _, err := as.database.Collection("userWorkoutPlanStatus").UpdateMany(ctx, enrollments, bson.M{"$set": bson.M{"version": 1}}); err != nil {
+			return fmt.Errorf("error pinning enrollments to the first workout plan version: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetWorkoutPlanVersions lists the published versions of a workout plan, latest first.
+func (as *AdminService) GetWorkoutPlanVersions(ctx context.Context, workoutPlanID primitive.ObjectID) ([]models.WorkoutPlanVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := as.database.Collection("workoutPlanVersions").Find(ctx, bson.M{"workoutPlanId": workoutPlanID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding workout plan versions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	versions := []models.WorkoutPlanVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("error decoding workout plan versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetWorkoutPlanVersion returns one published version of a workout plan.
+func (as *AdminService) GetWorkoutPlanVersion(ctx context.Context, workoutPlanID primitive.ObjectID, version int) (*models.WorkoutPlanVersion, error) {
+	var workoutPlanVersion models.WorkoutPlanVersion
+	filter := bson.M{"workoutPlanId": workoutPlanID, "version": version}
+	if err := as.database.Collection("workoutPlanVersions").FindOne(ctx, filter).Decode(&workoutPlanVersion); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrWorkoutPlanVersionNotFound
+		}
+		return nil, fmt.Errorf("error finding workout plan version: %w", err)
+	}
+
+	return &workoutPlanVersion, nil
+}
+
+// MigrateWorkoutPlanEnrollments moves the users following older versions of the plan to the given version, carrying
+// their progress over where circuits and exercises match. Users who completed the plan stay where they are.
+func (as *AdminService) MigrateWorkoutPlanEnrollments(ctx context.Context, workoutPlanID primitive.ObjectID, input models.WorkoutPlanMigrationInput) (*models.WorkoutPlanMigrationReport, error) {
+	target, err := as.GetWorkoutPlanVersion(ctx, workoutPlanID, input.ToVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workoutPlanId": workoutPlanID, "completed": false, "version": bson.M{"$lt": input.ToVersion}}
+	if len(input.UserIDs) > 0 {
+		filter["userId"] = bson.M{"$in": input.UserIDs}
+	}
+	limit := input.Limit
+	if limit < 1 {
+		limit = defaultWorkoutPlanMigrationLimit
+	}
+
+	enrollmentCollection := as.database.Collection("userWorkoutPlanStatus")
+	total, err := enrollmentCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error counting enrollments: %w", err)
+	}
+
+	var enrollments []models.UserWorkoutPlanStatus
+	opts := options.Find().SetSort(bson.D{{Key: "startDate", Value: 1}}).SetLimit(limit)
+	if err := findAll(ctx, enrollmentCollection, filter, opts, &enrollments); err != nil {
+		return nil, fmt.Errorf("error finding enrollments: %w", err)
+	}
+
+	report := &models.WorkoutPlanMigrationReport{
+		WorkoutPlanID: workoutPlanID,
+		ToVersion:     input.ToVersion,
+		DryRun:        input.DryRun,
+		Users:         []models.WorkoutPlanUserMigration{},
+		Remaining:     total - int64(len(enrollments)),
+	}
+
+	versions := map[int]*models.WorkoutPlanVersion{input.ToVersion: target}
+	for _, enrollment := range enrollments {
+		from, exists := versions[enrollment.Version]
+		if !exists {
+			if from, err = as.GetWorkoutPlanVersion(ctx, workoutPlanID, enrollment.Version); err != nil {
+				return nil, fmt.Errorf("error finding version %d followed by user %s: %w", enrollment.Version, enrollment.UserID.Hex(), err)
+			}
+			versions[enrollment.Version] = from
+		}
+
+		migration, progress, err := as.planWorkoutPlanMigration(ctx, enrollment, from.Plan, target.Plan)
+		if err != nil {
+			return nil, err
+		}
+		if !input.DryRun {
+			if err := as.applyWorkoutPlanMigration(ctx, enrollment, input.ToVersion, progress); err != nil {
+				return nil, err
+			}
+		}
+
+		report.Users = append(report.Users, migration)
+	}
+
+	return report, nil
+}
+
+// workoutCircuitKey places a circuit in a workout plan, the circuits at the same place in two versions match.
+type workoutCircuitKey struct {
+	weekNumber int
+	day        int
+	section    string
+	circuit    int
+}
+
+// forEachWorkoutCircuit calls fn with every circuit of the plan, with its place, day and week.
+func forEachWorkoutCircuit(workoutPlan models.WorkoutPlan, fn func(key workoutCircuitKey, week models.WorkoutWeek, day models.WorkoutDay, circuit models.Circuit)) {
+	for _, week := range workoutPlan.Weeks {
+		for d, day := range week.Days {
+			forEachDayCircuit(week.WeekNumber, d+1, day, func(key workoutCircuitKey, circuit models.Circuit) {
+				fn(key, week, day, circuit)
+			})
+		}
+	}
+}
+
+// forEachDayCircuit calls fn with every circuit of the given day of a week, warm-ups first and cool-downs last.
+func forEachDayCircuit(weekNumber, dayNumber int, day models.WorkoutDay, fn func(key workoutCircuitKey, circuit models.Circuit)) {
+	sections := []struct {
+		name     string
+		circuits []models.Circuit
+	}{
+		{models.WorkoutSectionWarmUps, day.WarmUps},
+		{models.WorkoutSectionWorkouts, day.Workouts},
+		{models.WorkoutSectionCoolDowns, day.CoolDowns},
+	}
+	for _, section := range sections {
+		for c, circuit := range section.circuits {
+			fn(workoutCircuitKey{weekNumber: weekNumber, day: dayNumber, section: section.name, circuit: c + 1}, circuit)
+		}
+	}
+}
+
+// workoutPlanProgress is the progress of an enrollment rebuilt for the version it moves to.
+type workoutPlanProgress struct {
+	exercises []interface{}
+	circuits  []interface{}
+	days      []interface{}
+	weeks     []interface{}
+	progress  float64
+	completed bool
+}
+
+// planWorkoutPlanMigration rebuilds the statuses of the enrollment for the target version, from the exercise
+// statuses of the circuits matching those of the version followed. Circuits, days and weeks are complete when all
+// their parts are.
+func (as *AdminService) planWorkoutPlanMigration(ctx context.Context, enrollment models.UserWorkoutPlanStatus, from, to models.WorkoutPlan) (models.WorkoutPlanUserMigration, *workoutPlanProgress, error) {
+	migration := models.WorkoutPlanUserMigration{UserID: enrollment.UserID, FromVersion: enrollment.Version, NotCarried: []models.WorkoutProgressLoss{}}
+	filter := bson.M{"userId": enrollment.UserID, "workoutPlanId": enrollment.WorkoutPlanID}
+
+	var exerciseStatuses []models.UserExerciseStatus
+	if err := findAll(ctx, as.database.Collection("userExerciseStatus"), filter, nil, &exerciseStatuses); err != nil {
+		return migration, nil, fmt.Errorf("error finding exercise statuses: %w", err)
+	}
+	var weekStatuses []models.UserWorkoutWeekStatus
+	if err := findAll(ctx, as.database.Collection("userWorkoutWeekStatus"), filter, nil, &weekStatuses); err != nil {
+		return migration, nil, fmt.Errorf("error finding week statuses: %w", err)
+	}
+
+	exercisesByCircuit := map[primitive.ObjectID]map[primitive.ObjectID]models.UserExerciseStatus{}
+	for _, status := range exerciseStatuses {
+		if exercisesByCircuit[status.CircuitID] == nil {
+			exercisesByCircuit[status.CircuitID] = map[primitive.ObjectID]models.UserExerciseStatus{}
+		}
+		exercisesByCircuit[status.CircuitID][status.ExerciseID] = status
+	}
+	weekCompletions := map[primitive.ObjectID]*time.Time{}
+	for _, status := range weekStatuses {
+		if status.Completed {
+			weekCompletions[status.WorkoutWeekID] = status.CompletedAt
+		}
+	}
+
+	fromCircuits := map[workoutCircuitKey]models.Circuit{}
+	fromWeeks := map[int]primitive.ObjectID{}
+	forEachWorkoutCircuit(from, func(key workoutCircuitKey, week models.WorkoutWeek, day models.WorkoutDay, circuit models.Circuit) {
+		fromCircuits[key] = circuit
+		fromWeeks[week.WeekNumber] = week.ID
+	})
+
+	// Progress on the old version the new one has no place for
+	toCircuits := map[workoutCircuitKey]models.Circuit{}
+	forEachWorkoutCircuit(to, func(key workoutCircuitKey, week models.WorkoutWeek, day models.WorkoutDay, circuit models.Circuit) {
+		toCircuits[key] = circuit
+	})
+	forEachWorkoutCircuit(from, func(key workoutCircuitKey, week models.WorkoutWeek, day models.WorkoutDay, circuit models.Circuit) {
+		toCircuit, matched := toCircuits[key]
+		for _, status := range exercisesByCircuit[circuit.ID] {
+			if !status.Completed && len(status.CompletedLogs) == 0 {
+				continue
+			}
+
+			loss := models.WorkoutProgressLoss{WeekNumber: key.weekNumber, Day: key.day, Section: key.section, Circuit: key.circuit, ExerciseID: status.ExerciseID}
+			switch {
+			case !matched:
+				loss.Reason = models.ProgressLossCircuitRemoved
+			case !containsObjectID(toCircuit.ExerciseIDs, status.ExerciseID):
+				loss.Reason = models.ProgressLossExerciseRemoved
+			default:
+				continue
+			}
+			migration.NotCarried = append(migration.NotCarried, loss)
+		}
+	})
+
+	progress := &workoutPlanProgress{}
+	userID, workoutPlanID := enrollment.UserID, enrollment.WorkoutPlanID
+	now := time.Now()
+	completedDays, totalDays, completedWeeks := 0, 0, 0
+	for _, week := range to.Weeks {
+		weekDone := 0
+		for d, day := range week.Days {
+			dayDone := true
+			forEachDayCircuit(week.WeekNumber, d+1, day, func(key workoutCircuitKey, circuit models.Circuit) {
+				var carried map[primitive.ObjectID]models.UserExerciseStatus
+				if fromCircuit, matched := fromCircuits[key]; matched {
+					carried = exercisesByCircuit[fromCircuit.ID]
+				}
+
+				circuitDone := true
+				seen := map[primitive.ObjectID]bool{}
+				for _, exerciseID := range circuit.ExerciseIDs {
+					if seen[exerciseID] {
+						continue
+					}
+					seen[exerciseID] = true
+
+					status := models.NewUserExerciseStatus(userID, exerciseID, circuit.ID, workoutPlanID)
+					if previous, exists := carried[exerciseID]; exists && (previous.Completed || len(previous.CompletedLogs) > 0) {
+						status.Completed = previous.Completed
+						status.CompletedLogs = previous.CompletedLogs
+						status.CompletedAt = previous.CompletedAt
+						migration.CarriedExercises++
+					}
+					circuitDone = circuitDone && status.Completed
+					progress.exercises = append(progress.exercises, status)
+				}
+
+				circuitStatus := models.NewUserCircuitStatus(userID, circuit.ID, day.ID, workoutPlanID)
+				circuitStatus.Completed = circuitDone
+				progress.circuits = append(progress.circuits, circuitStatus)
+				dayDone = dayDone && circuitDone
+			})
+
+			dayStatus := models.NewUserWorkoutDayStatus(userID, day.ID, week.ID, workoutPlanID)
+			dayStatus.Completed = dayDone
+			progress.days = append(progress.days, dayStatus)
+			totalDays++
+			if dayDone {
+				weekDone++
+				completedDays++
+			}
+		}
+
+		weekStatus := models.NewUserWorkoutWeekStatus(userID, week.ID, workoutPlanID)
+		weekStatus.CompletedDays = weekDone
+		if weekDone == len(week.Days) {
+			weekStatus.Completed = true
+			weekStatus.CompletedAt = &now
+			if completedAt, exists := weekCompletions[fromWeeks[week.WeekNumber]]; exists && completedAt != nil {
+				weekStatus.CompletedAt = completedAt
+			}
+			completedWeeks++
+		}
+		progress.weeks = append(progress.weeks, weekStatus)
+	}
+
+	if totalDays > 0 {
+		progress.progress = float64(completedDays) / float64(totalDays) * 100
+	}
+	progress.completed = completedWeeks == len(to.Weeks)
+
+	return migration, progress, nil
+}
+
+// workoutStatusKeys are the fields of the unique index of each status collection, a status rebuilt for the new version
+// replaces the one of the same circuit, day or week the old version shares with it.
+var workoutStatusKeys = map[string][]string{
+	"userExerciseStatus":    {"userId", "exerciseId", "circuitId", "workoutPlanId"},
+	"userCircuitStatus":     {"userId", "circuitId", "workoutDayId", "workoutPlanId"},
+	"userWorkoutDayStatus":  {"userId", "workoutDayId", "workoutWeekId", "workoutPlanId"},
+	"userWorkoutWeekStatus": {"userId", "workoutWeekId", "workoutPlanId"},
+}
+
+// applyWorkoutPlanMigration replaces the statuses of the enrollment by those rebuilt for the version. Without
+// transactions the new statuses are written tagged with the version before the enrollment moves to it, and the old
+// ones are only deleted afterwards: a migration failing halfway still has the statuses it plans from and can be run
+// again.
+func (as *AdminService) applyWorkoutPlanMigration(ctx context.Context, enrollment models.UserWorkoutPlanStatus, version int, progress *workoutPlanProgress) error {
+	statuses := []struct {
+		collection string
+		documents  []interface{}
+	}{
+		{"userExerciseStatus", progress.exercises},
+		{"userCircuitStatus", progress.circuits},
+		{"userWorkoutDayStatus", progress.days},
+		{"userWorkoutWeekStatus", progress.weeks},
+	}
+	for _, status := range statuses {
+		collection := as.database.Collection(status.collection)
+		for _, document := range status.documents {
+			filter, update, err := workoutStatusUpsert(document, workoutStatusKeys[status.collection], version)
+			if err != nil {
+				return fmt.Errorf("error encoding %s of user %s: %w", status.collection, enrollment.UserID.Hex(), err)
+			}
+			opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+			if err := collection.FindOneAndUpdate(ctx, filter, update, opts).Err(); err != nil {
+				return fmt.Errorf("error writing %s of user %s: %w", status.collection, enrollment.UserID.Hex(), err)
+			}
+		}
+	}
+
+	set := bson.M{"version": version, "progress": progress.progress, "completed": progress.completed}
+	if progress.completed {
+		set["completionDate"] = time.Now()
+	}
+	if _, err := as.database.Collection("userWorkoutPlanStatus").UpdateOne(ctx, bson.M{"_id": enrollment.ID}, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("error updating enrollment of user %s: %w", enrollment.UserID.Hex(), err)
+	}
+
+	// The enrollment follows the new version, the statuses of the old one left behind are only stale
+	stale := bson.M{"userId": enrollment.UserID, "workoutPlanId": enrollment.WorkoutPlanID, "version": bson.M{"$ne": version}}
+	for _, status := range statuses {
+		if _, err := as.database.Collection(status.collection).DeleteMany(ctx, stale); err != nil {
+			log.Printf("Error deleting the %s of version %d of user %s: %v\n", status.collection, enrollment.Version, enrollment.UserID.Hex(), err)
+		}
+	}
+
+	as.recordAudit(ctx, "userWorkoutPlanStatus", auditChange{
+		action:   models.AuditActionUpdate,
+		targetID: enrollment.ID.Hex(),
+		before:   bson.M{"version": enrollment.Version, "progress": enrollment.Progress, "completed": enrollment.Completed},
+		after:    set,
+	})
+	return nil
+}
+
+// workoutStatusUpsert writes the status over the one with the same keys, tagged with the version it was rebuilt for.
+// The only optional field of the statuses, completedAt, is removed when the new status has none.
+func workoutStatusUpsert(status interface{}, keys []string, version int) (filter, update bson.M, err error) {
+	data, err := bson.Marshal(status)
+	if err != nil {
+		return nil, nil, err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(data, &set); err != nil {
+		return nil, nil, err
+	}
+
+	filter = bson.M{}
+	for _, key := range keys {
+		filter[key] = set[key]
+	}
+	set["version"] = version
+	update = bson.M{"$set": set}
+	if _, exists := set["completedAt"]; !exists {
+		update["$unset"] = bson.M{"completedAt": ""}
+	}
+	return filter, update, nil
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
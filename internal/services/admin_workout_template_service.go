@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrWorkoutTemplateNotFound         = errors.New("workout template not found")
+	ErrWorkoutTemplateAlreadyExists    = errors.New("workout template already exists")
+	ErrInvalidWorkoutTemplate          = errors.New("invalid workout template")
+	ErrInvalidWorkoutTemplateReference = errors.New("invalid workout template reference")
+)
+
+// GetWorkoutTemplates lists the workout templates of the given kind, every template when it is empty.
+func (as *AdminService) GetWorkoutTemplates(ctx context.Context, kind string) ([]models.WorkoutTemplate, error) {
+	filter := bson.M{}
+	switch kind {
+	case "":
+	case models.WorkoutTemplateWeek, models.WorkoutTemplateDay:
+		filter["kind"] = kind
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidWorkoutTemplate, kind)
+	}
+
+	templates := []models.WorkoutTemplate{}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	if err := findAll(ctx, as.database.Collection("workoutTemplates"), filter, opts, &templates); err != nil {
+		return nil, fmt.Errorf("error finding workout templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+func (as *AdminService) GetWorkoutTemplateByID(ctx context.Context, templateID primitive.ObjectID) (*models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	if err := as.database.Collection("workoutTemplates").FindOne(ctx, bson.M{"_id": templateID}).Decode(&template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrWorkoutTemplateNotFound
+		}
+		return nil, fmt.Errorf("error finding workout template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// CreateWorkoutTemplate stores a week or day template, its day templates must already be expanded.
+func (as *AdminService) CreateWorkoutTemplate(ctx context.Context, input models.WorkoutTemplateInput) (*models.WorkoutTemplate, error) {
+	if err := checkWorkoutTemplate(input); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := models.WorkoutTemplate{
+		ID:        primitive.NewObjectID(),
+		Name:      input.Name,
+		Kind:      input.Kind,
+		Days:      input.Days,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := as.database.Collection("workoutTemplates").InsertOne(ctx, template); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrWorkoutTemplateAlreadyExists
+		}
+		return nil, fmt.Errorf("error inserting workout template: %w", err)
+	}
+
+	as.recordAudit(ctx, "workoutTemplates", auditChange{action: models.AuditActionCreate, targetID: template.ID.Hex(), after: template})
+	return &template, nil
+}
+
+// UpdateWorkoutTemplate replaces the content of a template. Plans already built from it keep their copy.
+func (as *AdminService) UpdateWorkoutTemplate(ctx context.Context, templateID primitive.ObjectID, input models.WorkoutTemplateInput) (*models.WorkoutTemplate, error) {
+	if err := checkWorkoutTemplate(input); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"name": input.Name, "kind": input.Kind, "days": input.Days, "updatedAt": time.Now()}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var template models.WorkoutTemplate
+	if err := as.database.Collection("workoutTemplates").FindOneAndUpdate(ctx, bson.M{"_id": templateID}, bson.M{"$set": set}, opts).Decode(&template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrWorkoutTemplateNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrWorkoutTemplateAlreadyExists
+		}
+		return nil, fmt.Errorf("error updating workout template: %w", err)
+	}
+
+	as.recordAudit(ctx, "workoutTemplates", auditChange{action: models.AuditActionUpdate, targetID: templateID.Hex(), after: template})
+	return &template, nil
+}
+
+func (as *AdminService) DeleteWorkoutTemplate(ctx context.Context, templateID primitive.ObjectID) error {
+	var before bson.M
+	if err := as.database.Collection("workoutTemplates").FindOneAndDelete(ctx, bson.M{"_id": templateID}).Decode(&before); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrWorkoutTemplateNotFound
+		}
+		return fmt.Errorf("error deleting workout template: %w", err)
+	}
+
+	as.recordAudit(ctx, "workoutTemplates", auditChange{action: models.AuditActionDelete, targetID: templateID.Hex(), before: before})
+	return nil
+}
+
+func checkWorkoutTemplate(input models.WorkoutTemplateInput) error {
+	if input.Kind == models.WorkoutTemplateDay && len(input.Days) != 1 {
+		return fmt.Errorf("%w: a day template has exactly one day", ErrInvalidWorkoutTemplate)
+	}
+	return nil
+}
+
+// ExpandWorkoutPlanTemplates copies the templates the weeks and days of the plan refer to into it: a week gets the
+// days of its week template, a day becomes a copy of its day template. Runs before the plan is validated.
+func (as *AdminService) ExpandWorkoutPlanTemplates(ctx context.Context, workoutPlan *models.WorkoutPlan) error {
+	var templateIDs []primitive.ObjectID
+	for _, week := range workoutPlan.Weeks {
+		if week.TemplateID != nil {
+			templateIDs = append(templateIDs, *week.TemplateID)
+			continue
+		}
+		templateIDs = append(templateIDs, dayTemplateIDs(week.Days)...)
+	}
+
+	templates, err := as.findWorkoutTemplates(ctx, templateIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range workoutPlan.Weeks {
+		week := &workoutPlan.Weeks[i]
+		if week.TemplateID == nil {
+			if err := expandDayTemplates(week.Days, templates); err != nil {
+				return err
+			}
+			continue
+		}
+
+		template, err := templates.lookup(*week.TemplateID, models.WorkoutTemplateWeek)
+		if err != nil {
+			return err
+		}
+		week.Days = make([]models.WorkoutDay, len(template.Days))
+		for j, day := range template.Days {
+			week.Days[j] = copyWorkoutDay(day)
+		}
+	}
+
+	return nil
+}
+
+// ExpandWorkoutDayTemplates replaces the days referring to a day template by a copy of it, so week templates can be
+// built from day templates.
+func (as *AdminService) ExpandWorkoutDayTemplates(ctx context.Context, days []models.WorkoutDay) error {
+	templates, err := as.findWorkoutTemplates(ctx, dayTemplateIDs(days))
+	if err != nil {
+		return err
+	}
+
+	return expandDayTemplates(days, templates)
+}
+
+// workoutTemplates indexes the templates referred to by a plan.
+type workoutTemplates map[primitive.ObjectID]models.WorkoutTemplate
+
+func (templates workoutTemplates) lookup(templateID primitive.ObjectID, kind string) (models.WorkoutTemplate, error) {
+	template, exists := templates[templateID]
+	if !exists {
+		return models.WorkoutTemplate{}, fmt.Errorf("%w: template %s not found", ErrInvalidWorkoutTemplateReference, templateID.Hex())
+	}
+	if template.Kind != kind {
+		return models.WorkoutTemplate{}, fmt.Errorf("%w: %s is a %s template, not a %s template", ErrInvalidWorkoutTemplateReference, templateID.Hex(), template.Kind, kind)
+	}
+
+	return template, nil
+}
+
+func (as *AdminService) findWorkoutTemplates(ctx context.Context, templateIDs []primitive.ObjectID) (workoutTemplates, error) {
+	templates := workoutTemplates{}
+	if len(templateIDs) == 0 {
+		return templates, nil
+	}
+
+	var found []models.WorkoutTemplate
+	if err := findAll(ctx, as.database.Collection("workoutTemplates"), bson.M{"_id": bson.M{"$in": templateIDs}}, nil, &found); err != nil {
+		return nil, fmt.Errorf("error finding workout templates: %w", err)
+	}
+	for _, template := range found {
+		templates[template.ID] = template
+	}
+
+	return templates, nil
+}
+
+func dayTemplateIDs(days []models.WorkoutDay) []primitive.ObjectID {
+	var templateIDs []primitive.ObjectID
+	for _, day := range days {
+		if day.TemplateID != nil {
+			templateIDs = append(templateIDs, *day.TemplateID)
+		}
+	}
+	return templateIDs
+}
+
+func expandDayTemplates(days []models.WorkoutDay, templates workoutTemplates) error {
+	for i, day := range days {
+		if day.TemplateID == nil {
+			continue
+		}
+
+		template, err := templates.lookup(*day.TemplateID, models.WorkoutTemplateDay)
+		if err != nil {
+			return err
+		}
+		days[i] = copyWorkoutDay(template.Days[0])
+		days[i].TemplateID = day.TemplateID
+	}
+
+	return nil
+}
+
+// copyWorkoutDay copies the circuits of the day too, each copy of a template gets its own IDs.
+func copyWorkoutDay(day models.WorkoutDay) models.WorkoutDay {
+	day.WarmUps = copyCircuits(day.WarmUps)
+	day.Workouts = copyCircuits(day.Workouts)
+	day.CoolDowns = copyCircuits(day.CoolDowns)
+	return day
+}
+
+func copyCircuits(circuits []models.Circuit) []models.Circuit {
+	if circuits == nil {
+		return nil
+	}
+	return append(make([]models.Circuit, 0, len(circuits)), circuits...)
+}
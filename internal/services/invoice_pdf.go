@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+)
+
+// Layout of the receipt on an A4 page, in points
+const (
+	receiptMargin      = 50.0
+	receiptRight       = utils.PDFPageWidth - receiptMargin
+	receiptQuantityX   = 400.0
+	receiptBottom      = utils.PDFPageHeight - 70
+	receiptLineSpacing = 16.0
+	receiptDateLayout  = "January 2, 2006"
+)
+
+// renderInvoicePDF lays out the receipt of a paid invoice: the company and the customer, the lines then the totals.
+func renderInvoicePDF(cfg *config.Config, invoice models.Invoice) []byte {
+	document := utils.NewPDFDocument()
+
+	document.Text(receiptMargin, 80, 22, true, "Receipt")
+	y := 80.0
+	if cfg != nil {
+		document.TextRight(receiptRight, y, 12, true, cfg.InvoiceCompanyName)
+		details := append([]string{}, cfg.InvoiceCompanyAddress...)
+		if cfg.InvoiceCompanyTaxID != "" {
+			details = append(details, "Tax ID: "+cfg.InvoiceCompanyTaxID)
+		}
+		if cfg.InvoiceCompanyEmail != "" {
+			details = append(details, cfg.InvoiceCompanyEmail)
+		}
+		for _, detail := range details {
+			y += 14
+			document.TextRight(receiptRight, y, 10, false, detail)
+		}
+	}
+
+	y = max(y, 100) + 30
+	fields := [][2]string{
+		{"Receipt number", invoice.Number},
+		{"Date paid", invoice.IssuedAt.UTC().Format(receiptDateLayout)},
+	}
+	if invoice.CustomerName != "" {
+		fields = append(fields, [2]string{"Billed to", invoice.CustomerName})
+	}
+	if invoice.CustomerEmail != "" {
+		fields = append(fields, [2]string{"Email", invoice.CustomerEmail})
+	}
+	if invoice.CustomerTaxID != "" {
+		fields = append(fields, [2]string{"Customer tax ID", invoice.CustomerTaxID})
+	}
+	for _, field := range fields {
+		document.Text(receiptMargin, y, 10, true, field[0])
+		document.Text(receiptMargin+110, y, 10, false, field[1])
+		y += receiptLineSpacing
+	}
+
+	y += 20
+	lineHeader := func() {
+		document.Text(receiptMargin, y, 10, true, "Description")
+		document.TextRight(receiptQuantityX, y, 10, true, "Qty")
+		document.TextRight(receiptRight, y, 10, true, "Amount")
+		y += 6
+		document.Line(receiptMargin, y, receiptRight, y)
+		y += receiptLineSpacing
+	}
+	lineHeader()
+	for _, line := range invoice.Lines {
+		// A line and its period stay on the same page
+		if y+2*receiptLineSpacing > receiptBottom {
+			document.AddPage()
+			y = 80
+			lineHeader()
+		}
+		document.Text(receiptMargin, y, 10, false, line.Description)
+		if line.Quantity > 0 {
+			document.TextRight(receiptQuantityX, y, 10, false, fmt.Sprintf("%d", line.Quantity))
+		}
+		document.TextRight(receiptRight, y, 10, false, formatAmount(line.Amount, invoice.Currency)+" "+invoice.Currency)
+		if line.PeriodStart != nil && line.PeriodEnd != nil {
+			y += 13
+			period := line.PeriodStart.UTC().Format(receiptDateLayout) + " - " + line.PeriodEnd.UTC().Format(receiptDateLayout)
+			document.Text(receiptMargin, y, 8, false, period)
+		}
+		y += receiptLineSpacing
+	}
+
+	if y+4*receiptLineSpacing > receiptBottom {
+		document.AddPage()
+		y = 80
+	}
+	document.Line(receiptQuantityX-100, y-8, receiptRight, y-8)
+	totals := []struct {
+		label  string
+		amount int64
+	}{
+		{"Subtotal", invoice.Subtotal},
+		{"Tax", invoice.Tax},
+		{"Total paid", invoice.Total},
+	}
+	for i, total := range totals {
+		bold := i == len(totals)-1
+		document.TextRight(receiptQuantityX, y+6, 10, bold, total.label)
+		document.TextRight(receiptRight, y+6, 10, bold, formatAmount(total.amount, invoice.Currency)+" "+invoice.Currency)
+		y += receiptLineSpacing
+	}
+
+	return document.Bytes()
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const mfaRecoveryCodeCount = 10
+
+var (
+	ErrMFAAlreadyEnabled       = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnabled           = errors.New("multi-factor authentication is not enabled")
+	ErrMFAEnrollmentNotStarted = errors.New("multi-factor authentication enrollment has not been started")
+	ErrMFARequired             = errors.New("multi-factor authentication is mandatory for this account")
+	ErrInvalidMFACode          = errors.New("invalid authentication code")
+)
+
+// The MFA helpers below are shared by admins and users, they only differ by collection and not found error.
+
+type mfaAccount struct {
+	Email string              `bson:"email"`
+	MFA   *models.MFASettings `bson:"mfa,omitempty"`
+}
+
+func findMFAAccount(ctx context.Context, collection db.MongoCollection, accountID primitive.ObjectID, errNotFound error) (*mfaAccount, error) {
+	var account mfaAccount
+	opts := options.FindOne().SetProjection(bson.M{"email": 1, "mfa": 1})
+	if err := collection.FindOne(ctx, bson.M{"_id": accountID}, opts).Decode(&account); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errNotFound
+		}
+
+		return nil, fmt.Errorf("error fetching MFA settings: %w", err)
+	}
+
+	return &account, nil
+}
+
+func beginMFAEnrollment(ctx context.Context, collection db.MongoCollection, accountID primitive.ObjectID, issuer string, errNotFound error) (*models.MFAEnrollment, error) {
+	account, err := findMFAAccount(ctx, collection, accountID, errNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.MFA != nil && account.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating TOTP secret: %w", err)
+	}
+
+	// Starting again replaces any unconfirmed secret
+	update := bson.M{"$set": bson.M{"mfa.enabled": false, "mfa.pendingSecret": secret}}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": accountID}, update); err != nil {
+		return nil, fmt.Errorf("error saving pending TOTP secret: %w", err)
+	}
+
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(issuer, account.Email, secret),
+	}, nil
+}
+
+func confirmMFAEnrollment(ctx context.Context, collection db.MongoCollection, accountID primitive.ObjectID, code string, errNotFound error) ([]string, error) {
+	account, err := findMFAAccount(ctx, collection, accountID, errNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.MFA != nil && account.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if account.MFA == nil || account.MFA.PendingSecret == "" {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+
+	step, ok := utils.ValidateTOTPCode(account.MFA.PendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enrolledAt := time.Now()
+	settings := models.MFASettings{
+		Enabled:            true,
+		Secret:             account.MFA.PendingSecret,
+		RecoveryCodeHashes: recoveryCodeHashes,
+		LastUsedStep:       step,
+		EnrolledAt:         &enrolledAt,
+	}
+
+	// Matching on the pending secret stops a concurrent enroll from swapping the secret under us
+	filter := bson.M{"_id": accountID, "mfa.pendingSecret": account.MFA.PendingSecret}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa": settings}})
+	if err != nil {
+		return nil, fmt.Errorf("error enabling MFA: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+
+	return recoveryCodes, nil
+}
+
+// verifyMFACode accepts either a TOTP code or a recovery code. Both are single use.
+func verifyMFACode(ctx context.Context, collection db.MongoCollection, accountID primitive.ObjectID, code, recoveryCode string, errNotFound error) error {
+	account, err := findMFAAccount(ctx, collection, accountID, errNotFound)
+	if err != nil {
+		return err
+	}
+
+	if account.MFA == nil || !account.MFA.Enabled {
+		return ErrMFANotEnabled
+	}
+
+	var filter, update bson.M
+	if code != "" {
+		step, ok := utils.ValidateTOTPCode(account.MFA.Secret, code, time.Now())
+		if !ok || step <= account.MFA.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+
+		filter = bson.M{"_id": accountID, "mfa.lastUsedStep": bson.M{"$not": bson.M{"$gte": step}}}
+		update = bson.M{"$set": bson.M{"mfa.lastUsedStep": step}}
+	} else {
+		codeHash := utils.HashToken(strings.ToLower(recoveryCode))
+		filter = bson.M{"_id": accountID, "mfa.recoveryCodeHashes": codeHash}
+		update = bson.M{"$pull": bson.M{"mfa.recoveryCodeHashes": codeHash}}
+	}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("error consuming MFA code: %w", err)
+	}
+
+	// Nothing matched when the code was already used, possibly by a concurrent request
+	if result.MatchedCount == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func disableMFA(ctx context.Context, collection db.MongoCollection, accountID primitive.ObjectID, code string, errNotFound error) error {
+	if err := verifyMFACode(ctx, collection, accountID, code, "", errNotFound); err != nil {
+		return err
+	}
+
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": accountID}, bson.M{"$unset": bson.M{"mfa": ""}}); err != nil {
+		return fmt.Errorf("error disabling MFA: %w", err)
+	}
+
+	return nil
+}
+
+func regenerateRecoveryCodes(ctx context.Context, collection db.MongoCollection, accountID primitive.ObjectID, code string, errNotFound error) ([]string, error) {
+	if err := verifyMFACode(ctx, collection, accountID, code, "", errNotFound); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{"mfa.recoveryCodeHashes": recoveryCodeHashes}}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": accountID}, update); err != nil {
+		return nil, fmt.Errorf("error saving recovery codes: %w", err)
+	}
+
+	return recoveryCodes, nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes, err := utils.GenerateRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating recovery codes: %w", err)
+	}
+
+	recoveryCodeHashes := make([]string, 0, len(recoveryCodes))
+	for _, recoveryCode := range recoveryCodes {
+		recoveryCodeHashes = append(recoveryCodeHashes, utils.HashToken(recoveryCode))
+	}
+
+	return recoveryCodes, recoveryCodeHashes, nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// upgradePasswordHash rehashes the password of an account that just signed in when its hash uses an outdated
+// algorithm or weaker parameters. The update only applies if the hash did not change meanwhile, and failures are
+// logged so they never block the login.
+func upgradePasswordHash(ctx context.Context, collection db.MongoCollection, hasher utils.HashPasswordService, accountID primitive.ObjectID, password, currentHash string) {
+	if !hasher.NeedsRehash(currentHash) {
+		return
+	}
+
+	newHash, err := hasher.HashPassword(password)
+	if err != nil {
+		log.Printf("Error rehashing password for account %s: %v\n", accountID.Hex(), err)
+		return
+	}
+
+	filter := bson.M{"_id": accountID, "passwordHash": currentHash}
+	update := bson.M{"$set": bson.M{"passwordHash": newHash}}
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		log.Printf("Error saving rehashed password for account %s: %v\n", accountID.Hex(), err)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRateLimitStore shares buckets and lockouts between API instances.
+// Every bucket update is a single pipeline update so concurrent requests can't overdraw a bucket.
+type MongoRateLimitStore struct {
+	database db.MongoDatabase
+}
+
+func NewMongoRateLimitStore(database db.MongoDatabase) *MongoRateLimitStore {
+	return &MongoRateLimitStore{database: database}
+}
+
+func (s *MongoRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	rateLimitCollection := s.database.Collection("rateLimits")
+
+	now := time.Now()
+	capacity := float64(rule.Limit)
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updatedAt", now}}}},
+		1000,
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", capacity}},
+				bson.M{"$multiply": bson.A{elapsedSeconds, refillRate(rule)}},
+			}}}},
+			"updatedAt": now,
+			"expiresAt": now.Add(rule.Window),
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}}}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+
+	err := rateLimitCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two instances raced to create the same bucket, the document exists now so retry once.
+		err = rateLimitCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("error updating rate limit bucket: %w", err)
+	}
+
+	return newRateLimitResult(bucket.Tokens, bucket.Allowed, rule), nil
+}
+
+func (s *MongoRateLimitStore) RegisterFailure(ctx context.Context, key string, policy LockoutPolicy) (time.Time, error) {
+	loginAttemptCollection := s.database.Collection("loginAttempts")
+
+	now := time.Now()
+	retention := policy.FailureWindow
+	if policy.MaxLockout > retention {
+		retention = policy.MaxLockout
+	}
+
+	// Failures older than the window restart the count at one
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{bson.M{"$ifNull": bson.A{"$lastFailureAt", time.Time{}}}, now.Add(-policy.FailureWindow)}},
+				bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+				1,
+			}},
+			"lastFailureAt": now,
+			"expiresAt":     now.Add(retention),
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var attempt struct {
+		Failures    int       `bson:"failures"`
+		LockedUntil time.Time `bson:"lockedUntil,omitempty"`
+	}
+
+	err := loginAttemptCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempt)
+	if mongo.IsDuplicateKeyError(err) {
+		err = loginAttemptCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempt)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error registering failed login attempt: %w", err)
+	}
+
+	lockout := policy.LockoutDuration(attempt.Failures)
+	if lockout == 0 {
+		return attempt.LockedUntil, nil
+	}
+
+	lockedUntil := now.Add(lockout)
+	if _, err := loginAttemptCollection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"lockedUntil": lockedUntil}}); err != nil {
+		return time.Time{}, fmt.Errorf("error locking account: %w", err)
+	}
+
+	return lockedUntil, nil
+}
+
+func (s *MongoRateLimitStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	loginAttemptCollection := s.database.Collection("loginAttempts")
+
+	var attempt struct {
+		LockedUntil time.Time `bson:"lockedUntil,omitempty"`
+	}
+	if err := loginAttemptCollection.FindOne(ctx, bson.M{"_id": key}).Decode(&attempt); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("error fetching login attempts: %w", err)
+	}
+
+	return attempt.LockedUntil, nil
+}
+
+func (s *MongoRateLimitStore) ResetFailures(ctx context.Context, key string) error {
+	loginAttemptCollection := s.database.Collection("loginAttempts")
+
+	if _, err := loginAttemptCollection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("error resetting login attempts: %w", err)
+	}
+
+	return nil
+}
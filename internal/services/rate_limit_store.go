@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitRule describes a token bucket: Limit tokens that fully refill over Window.
+type RateLimitRule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // Time until the bucket is full again.
+	RetryAfter time.Duration // Time until the next token is available, zero when allowed.
+}
+
+// LockoutPolicy controls the exponential lockout applied after repeated failed logins.
+type LockoutPolicy struct {
+	Threshold     int           // Failures tolerated before the first lockout.
+	BaseLockout   time.Duration // Lockout applied when the threshold is reached, doubled for every further failure.
+	MaxLockout    time.Duration
+	FailureWindow time.Duration // Failures older than this are forgotten.
+}
+
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+	RegisterFailure(ctx context.Context, key string, policy LockoutPolicy) (time.Time, error)
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// LockoutDuration returns how long an account stays locked after the given number of consecutive failures.
+func (p LockoutPolicy) LockoutDuration(failures int) time.Duration {
+	if failures < p.Threshold || p.BaseLockout <= 0 {
+		return 0
+	}
+
+	exponent := failures - p.Threshold
+	lockout := time.Duration(float64(p.BaseLockout) * math.Pow(2, float64(exponent)))
+	if p.MaxLockout > 0 && (lockout > p.MaxLockout || lockout <= 0) {
+		return p.MaxLockout
+	}
+
+	return lockout
+}
+
+func refillRate(rule RateLimitRule) float64 {
+	return float64(rule.Limit) / rule.Window.Seconds()
+}
+
+func refillTokens(tokens float64, lastRefill, now time.Time, rule RateLimitRule) float64 {
+	elapsed := now.Sub(lastRefill).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return math.Min(float64(rule.Limit), tokens+elapsed*refillRate(rule))
+}
+
+func newRateLimitResult(tokens float64, allowed bool, rule RateLimitRule) RateLimitResult {
+	rate := refillRate(rule)
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Limit:      rule.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(rule.Limit) - tokens) / rate * float64(time.Second)),
+	}
+
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return result
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+	expiresAt  time.Time
+}
+
+type loginFailures struct {
+	count         int
+	lastFailureAt time.Time
+	lockedUntil   time.Time
+	expiresAt     time.Time
+}
+
+// InMemoryRateLimitStore keeps buckets in process memory, suitable for a single instance deployment.
+type InMemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	failures  map[string]*loginFailures
+	lastSweep time.Time
+}
+
+func NewInMemoryRateLimitStore() *InMemoryRateLimitStore {
+	return &InMemoryRateLimitStore{
+		buckets:  make(map[string]*tokenBucket),
+		failures: make(map[string]*loginFailures),
+	}
+}
+
+func (s *InMemoryRateLimitStore) Take(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(rule.Limit), lastRefill: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = refillTokens(bucket.tokens, bucket.lastRefill, now, rule)
+	bucket.lastRefill = now
+	bucket.expiresAt = now.Add(rule.Window)
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	return newRateLimitResult(bucket.tokens, allowed, rule), nil
+}
+
+func (s *InMemoryRateLimitStore) RegisterFailure(ctx context.Context, key string, policy LockoutPolicy) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, exists := s.failures[key]
+	if !exists || now.Sub(entry.lastFailureAt) > policy.FailureWindow {
+		entry = &loginFailures{}
+		s.failures[key] = entry
+	}
+
+	entry.count++
+	entry.lastFailureAt = now
+	if lockout := policy.LockoutDuration(entry.count); lockout > 0 {
+		entry.lockedUntil = now.Add(lockout)
+	}
+	entry.expiresAt = now.Add(policy.FailureWindow)
+	if entry.lockedUntil.After(entry.expiresAt) {
+		entry.expiresAt = entry.lockedUntil
+	}
+
+	return entry.lockedUntil, nil
+}
+
+func (s *InMemoryRateLimitStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.failures[key]
+	if !exists {
+		return time.Time{}, nil
+	}
+
+	return entry.lockedUntil, nil
+}
+
+func (s *InMemoryRateLimitStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+// sweep drops expired entries at most once a minute so the maps don't grow without bound.
+func (s *InMemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.After(bucket.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, entry := range s.failures {
+		if now.After(entry.expiresAt) {
+			delete(s.failures, key)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrJobNotFound          = errors.New("job not found")
+	ErrJobAlreadyRegistered = errors.New("job is already registered")
+	ErrInvalidJobTimeout    = errors.New("job timeout must be positive")
+)
+
+// JobFunc runs a job, the summary says what it did and is kept in the run history.
+type JobFunc func(ctx context.Context) (summary string, err error)
+
+type scheduledJob struct {
+	name     string
+	schedule *utils.CronSchedule
+	timeout  time.Duration
+	run      JobFunc
+}
+
+// Scheduler runs the registered jobs on their cron schedule. Every instance of the API runs a scheduler, a lock per
+// job in Mongo makes sure each occurrence of a job runs on a single instance.
+type Scheduler struct {
+	database db.MongoDatabase
+	owner    string
+	jobs     map[string]*scheduledJob
+
+	mu      sync.Mutex
+	running bool
+	stop    chan struct{}
+	ctx     context.Context // Cancelled when stopping takes too long
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(database db.MongoDatabase) *Scheduler {
+	return &Scheduler{database: database, owner: schedulerOwner(), jobs: make(map[string]*scheduledJob)}
+}
+
+// schedulerOwner names the instance in the locks and the run history.
+func schedulerOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// Register adds a job. A run holds the lock of the job for at most its timeout, the run is cancelled after it.
+func (s *Scheduler) Register(name, expression string, timeout time.Duration, run JobFunc) error {
+	schedule, err := utils.ParseCron(expression)
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidJobTimeout, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobAlreadyRegistered, name)
+	}
+	s.jobs[name] = &scheduledJob{name: name, schedule: schedule, timeout: timeout, run: run}
+
+	return nil
+}
+
+// Start runs every registered job on its schedule until Stop.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+
+	s.running = true
+	s.stop = make(chan struct{})
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	log.Printf("Scheduler %s started with %d jobs\n", s.owner, len(s.jobs))
+}
+
+func (s *Scheduler) loop(job *scheduledJob) {
+	defer s.wg.Done()
+
+	for {
+		slot := job.schedule.Next(time.Now())
+		if slot.IsZero() {
+			log.Printf("Job %s is never due with %s\n", job.name, job.schedule)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := s.RunJob(s.ctx, job.name, slot); err != nil {
+			log.Printf("Error running job %s: %v\n", job.name, err)
+		}
+	}
+}
+
+// Stop waits for the running jobs to finish, they are cancelled if ctx ends first.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	close(s.stop)
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return fmt.Errorf("jobs were cancelled: %w", ctx.Err())
+	}
+}
+
+// RunJob runs the occurrence of the job scheduled at slot, unless another instance already did or is running the
+// job. It reports whether this instance ran it.
+func (s *Scheduler) RunJob(ctx context.Context, name string, slot time.Time) (bool, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	acquired, err := s.acquireLock(ctx, job, slot)
+	if err != nil || !acquired {
+		return false, err
+	}
+	defer s.releaseLock(job)
+
+	run := models.JobRun{ID: primitive.NewObjectID(), Job: job.name, Owner: s.owner, ScheduledAt: slot, StartedAt: time.Now()}
+	jobCtx, cancel := context.WithTimeout(ctx, job.timeout)
+	summary, runErr := runJobFunc(jobCtx, job)
+	cancel()
+
+	run.FinishedAt = time.Now()
+	run.Summary = summary
+	run.Status = models.JobRunSucceeded
+	if runErr != nil {
+		run.Status = models.JobRunFailed
+		run.Error = runErr.Error()
+	}
+	// The history is written even when the run was cancelled
+	if _, err := s.database.Collection("jobRuns").InsertOne(context.WithoutCancel(ctx), run); err != nil {
+		log.Printf("Error recording run of job %s: %v\n", job.name, err)
+	}
+
+	return true, runErr
+}
+
+// runJobFunc turns a panicking job into a failed run instead of taking the API down.
+func runJobFunc(ctx context.Context, job *scheduledJob) (summary string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return job.run(ctx)
+}
+
+// acquireLock takes the lock of the job for the occurrence. The lock is free once its holder released it or its
+// timeout passed, an occurrence that was already run is never taken again.
+func (s *Scheduler) acquireLock(ctx context.Context, job *scheduledJob, slot time.Time) (bool, error) {
+	now := time.Now()
+	filter := bson.M{"_id": job.name, "lockedUntil": bson.M{"$lte": now}, "lastSlot": bson.M{"$lt": slot}}
+	update := bson.M{"$set": bson.M{"owner": s.owner, "lockedUntil": now.Add(job.timeout), "lastSlot": slot}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var lock models.JobLock
+	err := s.database.Collection("jobLocks").FindOneAndUpdate(ctx, filter, update, opts).Decode(&lock)
+	if err != nil {
+		// The lock exists but is held or the occurrence was run, the upsert then collides with it
+		if mongo.IsDuplicateKeyError(err) || err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("error acquiring lock of job %s: %w", job.name, err)
+	}
+
+	return lock.Owner == s.owner, nil
+}
+
+func (s *Scheduler) releaseLock(job *scheduledJob) {
+	// Released even when the run was cancelled so the next occurrence doesn't wait for the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": job.name, "owner": s.owner}
+	if _, err := s.database.Collection("jobLocks").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lockedUntil": time.Now()}}); err != nil {
+		log.Printf("Error releasing lock of job %s: %v\n", job.name, err)
+	}
+}
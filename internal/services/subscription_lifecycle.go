@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Length of a paid period, subscriptions are billed monthly
+const subscriptionPeriod = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidSubscriptionTransition = errors.New("subscription can't move to this status")
+	ErrSubscriptionChanged           = errors.New("subscription was changed by another request")
+)
+
+// transitionSubscription moves the subscription to the status and sets the dates that go with it.
+func transitionSubscription(subscription models.UserSubscription, status string, now time.Time) (models.UserSubscription, error) {
+	if !subscription.CanTransitionTo(status) {
+		return subscription, fmt.Errorf("%w: %s to %s", ErrInvalidSubscriptionTransition, subscription.Status, status)
+	}
+
+	switch status {
+	case models.SubscriptionStatusActive:
+		// Resuming a cancelled subscription keeps the period that was paid for
+		if subscription.Status != models.SubscriptionStatusCancelled || subscription.EndDate == nil || !now.Before(*subscription.EndDate) {
+			periodEnd := now.Add(subscriptionPeriod)
+			subscription.StartDate = now
+			subscription.EndDate = &periodEnd
+		}
+		if subscription.PendingChange != nil {
+			subscription.Type = subscription.PendingChange.Type
+			subscription.PendingChange = nil
+		}
+		subscription.NextRenewalDate = subscription.EndDate
+		subscription.RenewalRemindedAt = nil
+		subscription.CancelledAt = nil
+		subscription.IsActive = true
+	case models.SubscriptionStatusPastDue:
+		// Access is kept while the payment is retried
+		subscription.IsActive = true
+	case models.SubscriptionStatusCancelled:
+		// Nothing was paid for a trial, cancelling it ends it
+		if subscription.Status == models.SubscriptionStatusTrialing {
+			subscription.EndDate = &now
+		}
+		subscription.CancelledAt = &now
+		subscription.NextRenewalDate = nil
+		subscription.PendingChange = nil
+		subscription.IsActive = true
+	case models.SubscriptionStatusExpired:
+		subscription.NextRenewalDate = nil
+		subscription.PendingChange = nil
+		subscription.IsActive = false
+	}
+
+	subscription.Status = status
+	return subscription, nil
+}
+
+// expireEndedSubscription expires trials and cancelled subscriptions whose period is over.
+func expireEndedSubscription(subscription models.UserSubscription, now time.Time) (models.UserSubscription, bool) {
+	if subscription.Status != models.SubscriptionStatusTrialing && subscription.Status != models.SubscriptionStatusCancelled {
+		return subscription, false
+	}
+	if subscription.EndDate == nil || now.Before(*subscription.EndDate) {
+		return subscription, false
+	}
+
+	expired, err := transitionSubscription(subscription, models.SubscriptionStatusExpired, now)
+	if err != nil {
+		return subscription, false
+	}
+	return expired, true
+}
+
+// updateSubscription applies change to the current subscription of the user. Every write increments the version
+// of the subscription and only succeeds if it is still the one change saw, so of two concurrent updates (a renewal
+// and a plan change, two webhooks) the second fails with ErrSubscriptionChanged instead of overwriting the first.
+func updateSubscription(ctx context.Context, database db.MongoDatabase, userID primitive.ObjectID, change func(models.UserSubscription, time.Time) (models.UserSubscription, error)) (before, after *models.UserSubscription, err error) {
+	userCollection := database.Collection("users")
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"subscription": 1, "trialEndsAt": 1})
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrUserNotFound
+		}
+		return nil, nil, fmt.Errorf("error fetching user subscription: %w", err)
+	}
+
+	now := time.Now()
+	current, _ := expireEndedSubscription(user.Subscription, now)
+	updated, err := change(current, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The first activation the user pays for converts them, analytics count conversions from it
+	if updated.ConvertedAt == nil && updated.Status == models.SubscriptionStatusActive && subscriptionIsPaid(updated) {
+		updated.ConvertedAt = &now
+	}
+
+	updated.Version = user.Subscription.Version + 1
+	set := bson.M{"subscription": updated}
+	// The trial end of the user follows the trial, which promo codes can extend, and cancellations or overrides end
+	if updated.Status == models.SubscriptionStatusTrialing && updated.EndDate != nil {
+		set["trialEndsAt"] = *updated.EndDate
+	} else if user.Subscription.Status == models.SubscriptionStatusTrialing && now.Before(user.TrialEndsAt) {
+		set["trialEndsAt"] = now
+	}
+
+	result, err := userCollection.UpdateOne(ctx, subscriptionVersionFilter(userID, user.Subscription), bson.M{"$set": set})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error updating user subscription: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, nil, ErrSubscriptionChanged
+	}
+
+	recordSubscriptionEvent(ctx, database, userID, user.Subscription, updated, now)
+	return &user.Subscription, &updated, nil
+}
+
+// recordSubscriptionEvent adds the change to the history of the subscription, with who made it when it was made in
+// a request. The subscription is already saved, failing to record the change is only logged.
+func recordSubscriptionEvent(ctx context.Context, database db.MongoDatabase, userID primitive.ObjectID, before, after models.UserSubscription, now time.Time) {
+	metadata := utils.RequestMetadataFromContext(ctx)
+	event := models.SubscriptionEvent{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Before:    before,
+		After:     after,
+		ActorRole: metadata.ActorRole,
+		RequestID: metadata.RequestID,
+		CreatedAt: now,
+	}
+	if !metadata.ActorID.IsZero() {
+		event.ActorID = &metadata.ActorID
+	}
+
+	if _, err := database.Collection("subscriptionEvents").InsertOne(ctx, event); err != nil {
+		log.Printf("Error recording subscription change of user %s: %v\n", userID.Hex(), err)
+	}
+}
+
+// subscriptionVersionFilter matches the user only while their subscription is still at the version that was read,
+// subscriptions written before versions have none.
+func subscriptionVersionFilter(userID primitive.ObjectID, subscription models.UserSubscription) bson.M {
+	if subscription.Version == 0 {
+		return bson.M{"_id": userID, "subscription.version": bson.M{"$exists": false}}
+	}
+	return bson.M{"_id": userID, "subscription.version": subscription.Version}
+}
+
+// subscriptionIsPaid reports whether the user pays the subscription, gifts are paid by someone else.
+func subscriptionIsPaid(subscription models.UserSubscription) bool {
+	switch subscription.Provider {
+	case models.SubscriptionProviderStripe, models.SubscriptionProviderAppStore, models.SubscriptionProviderPlay:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrSubscriptionRequired = errors.New("an active subscription or trial is required")
+	ErrUpgradeRequired      = errors.New("the subscription plan does not include this content")
+	// ErrExerciseNotInWorkoutPlan is returned for exercises outside of the plan the user follows
+	ErrExerciseNotInWorkoutPlan = errors.New("exercise is not part of the followed workout plan")
+)
+
+// EntitlementError tells which tier the content needs and which one the user has, so the app can offer the right plan.
+// It matches ErrSubscriptionRequired when the user has no tier at all, ErrUpgradeRequired otherwise.
+type EntitlementError struct {
+	RequiredTier string
+	CurrentTier  string
+}
+
+func (e *EntitlementError) Error() string {
+	return fmt.Sprintf("%v: requires %s, has %q", e.Unwrap(), e.RequiredTier, e.CurrentTier)
+}
+
+func (e *EntitlementError) Unwrap() error {
+	if e.CurrentTier == "" {
+		return ErrSubscriptionRequired
+	}
+	return ErrUpgradeRequired
+}
+
+// CheckEntitlement returns an *EntitlementError when the user's subscription or trial doesn't cover the tier.
+func (us *UserService) CheckEntitlement(ctx context.Context, userID primitive.ObjectID, requiredTier string) error {
+	if requiredTier == "" {
+		requiredTier = models.AccessTierBasic
+	}
+
+	currentTier, err := us.GetAccessTier(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !models.TierCovers(currentTier, requiredTier) {
+		return &EntitlementError{RequiredTier: requiredTier, CurrentTier: currentTier}
+	}
+	return nil
+}
+
+// GetAccessTier is the tier of content the subscription of the user covers right now, none ("") without one.
+func (us *UserService) GetAccessTier(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"subscription": 1})
+	if err := us.database.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("error finding user subscription: %w", err)
+	}
+
+	return user.AccessTier(time.Now()), nil
+}
+
+// enrolledWorkoutPlan is the content of the workout plan the user follows, as of the version they are enrolled in.
+func (us *UserService) enrolledWorkoutPlan(ctx context.Context, userID primitive.ObjectID) (models.WorkoutPlan, error) {
+	var status models.UserWorkoutPlanStatus
+	err := us.database.Collection("userWorkoutPlanStatus").FindOne(ctx, bson.M{"userId": userID, "completed": false}).Decode(&status)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.WorkoutPlan{}, ErrActiveWorkoutPlanNotFound
+		}
+		return models.WorkoutPlan{}, fmt.Errorf("error finding active workout plan: %w", err)
+	}
+
+	workoutPlan, err := us.GetWorkoutPlanVersion(ctx, status.WorkoutPlanID, status.Version)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, ErrWorkoutPlanNotFound) {
+			return models.WorkoutPlan{}, ErrActiveWorkoutPlanNotFound
+		}
+		return models.WorkoutPlan{}, err
+	}
+
+	return workoutPlan, nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// recordInvoice saves the charge of a payment event as an invoice of the user. A redelivered event finds its
+// invoice already there.
+func (us *UserService) recordInvoice(ctx context.Context, userID primitive.ObjectID, kind string, event *utils.PaymentWebhookEvent, defaultLine models.InvoiceLine) error {
+	charge := event.Charge
+	invoice := models.Invoice{
+		ID:                primitive.NewObjectID(),
+		Number:            charge.Number,
+		UserID:            userID,
+		Kind:              kind,
+		Provider:          models.SubscriptionProviderStripe,
+		ProviderInvoiceID: charge.InvoiceID,
+		PaymentEventID:    event.ID,
+		Currency:          charge.Currency,
+		Subtotal:          charge.Subtotal,
+		Tax:               charge.Tax,
+		Total:             charge.Total,
+		CustomerName:      charge.CustomerName,
+		CustomerEmail:     charge.CustomerEmail,
+		CustomerTaxID:     charge.CustomerTaxID,
+		IssuedAt:          event.CreatedAt,
+	}
+	// One-time checkouts aren't numbered by the provider
+	if invoice.Number == "" {
+		invoice.Number = "VG-" + strings.ToUpper(invoice.ID.Hex())
+	}
+	for _, line := range charge.Lines {
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			Amount:      line.Amount,
+			PeriodStart: line.PeriodStart,
+			PeriodEnd:   line.PeriodEnd,
+		})
+	}
+	if len(invoice.Lines) == 0 {
+		defaultLine.Amount = charge.Subtotal
+		invoice.Lines = []models.InvoiceLine{defaultLine}
+	}
+
+	if _, err := us.database.Collection("invoices").InsertOne(ctx, invoice); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("error inserting invoice: %w", err)
+	}
+
+	return nil
+}
+
+// recordGiftInvoice invoices the buyer of a gift code for its checkout.
+func (us *UserService) recordGiftInvoice(ctx context.Context, gift models.PromoCode, event *utils.PaymentWebhookEvent) error {
+	line := models.InvoiceLine{Description: fmt.Sprintf("Gift: %d months of %s", gift.Months, gift.Plan), Quantity: 1}
+	return us.recordInvoice(ctx, gift.CreatedBy, models.InvoiceKindGift, event, line)
+}
+
+// GetInvoices lists the invoices of the user, newest first.
+func (us *UserService) GetInvoices(ctx context.Context, userID primitive.ObjectID) ([]models.Invoice, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "issuedAt", Value: -1}})
+	cursor, err := us.database.Collection("invoices").Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding invoices: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	invoices := []models.Invoice{}
+	if err := cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("error decoding invoices: %w", err)
+	}
+
+	return invoices, nil
+}
+
+// GetInvoicePDF renders the receipt of an invoice of the user.
+func (us *UserService) GetInvoicePDF(ctx context.Context, userID, invoiceID primitive.ObjectID) (*models.Invoice, []byte, error) {
+	var invoice models.Invoice
+	// Invoices of other users are not found
+	filter := bson.M{"_id": invoiceID, "userId": userID}
+	if err := us.database.Collection("invoices").FindOne(ctx, filter).Decode(&invoice); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrInvoiceNotFound
+		}
+		return nil, nil, fmt.Errorf("error fetching invoice: %w", err)
+	}
+
+	return &invoice, renderInvoicePDF(us.cfg, invoice), nil
+}
+
+// zeroDecimalCurrencies have no minor unit, their amounts are whole units.
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true, "KRW": true, "MGA": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// formatAmount writes an amount in the smallest unit of the currency as a decimal, e.g. 1299 EUR is 12.99.
+func formatAmount(amount int64, currency string) string {
+	if zeroDecimalCurrencies[currency] {
+		return fmt.Sprintf("%d", amount)
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
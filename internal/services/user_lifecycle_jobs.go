@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Users a job run changes at most, the next run carries on with the rest
+	lifecycleJobBatchSize = 500
+	// Time the payment provider has to report a renewal before the subscription is ended
+	subscriptionRenewalGrace = 3 * 24 * time.Hour
+	// How long before the renewal users are reminded of it
+	renewalReminderNotice = 3 * 24 * time.Hour
+	// Gift codes that weren't paid by then were abandoned at checkout
+	unpaidGiftRetention = 7 * 24 * time.Hour
+)
+
+// errSubscriptionNotDue skips a subscription that was renewed or changed since the job selected it.
+var errSubscriptionNotDue = errors.New("subscription is not due")
+
+// SetNotifier sets where the reminders to users are delivered.
+func (us *UserService) SetNotifier(notifier utils.Notifier) {
+	us.notifier = notifier
+}
+
+// ExpireEndedTrials expires the trials that reached their end.
+func (us *UserService) ExpireEndedTrials(ctx context.Context) (string, error) {
+	filter := bson.M{"subscription.status": models.SubscriptionStatusTrialing, "subscription.endDate": bson.M{"$lte": time.Now()}}
+	expired, err := us.updateDueSubscriptions(ctx, filter, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		// updateSubscription already expired the ended trial
+		if subscription.Status != models.SubscriptionStatusExpired {
+			return subscription, errSubscriptionNotDue
+		}
+		return subscription, nil
+	})
+
+	return fmt.Sprintf("%d trials expired", expired), err
+}
+
+// ExpireEndedSubscriptions expires the cancelled subscriptions whose period is over, and the billed ones the
+// provider didn't renew within the grace period.
+func (us *UserService) ExpireEndedSubscriptions(ctx context.Context) (string, error) {
+	now := time.Now()
+	filter := bson.M{"$or": bson.A{
+		bson.M{"subscription.status": models.SubscriptionStatusCancelled, "subscription.endDate": bson.M{"$lte": now}},
+		bson.M{
+			"subscription.status":  bson.M{"$in": bson.A{models.SubscriptionStatusActive, models.SubscriptionStatusPastDue}},
+			"subscription.endDate": bson.M{"$lte": now.Add(-subscriptionRenewalGrace)},
+		},
+	}}
+	expired, err := us.updateDueSubscriptions(ctx, filter, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		switch {
+		case subscription.Status == models.SubscriptionStatusExpired:
+			// A cancelled subscription updateSubscription already expired
+			return subscription, nil
+		case (subscription.Status == models.SubscriptionStatusActive || subscription.Status == models.SubscriptionStatusPastDue) && subscription.EndDate != nil && !now.Before(subscription.EndDate.Add(subscriptionRenewalGrace)):
+			return endSubscription(subscription, now)
+		}
+		return subscription, errSubscriptionNotDue
+	})
+
+	return fmt.Sprintf("%d subscriptions expired", expired), err
+}
+
+// updateDueSubscriptions applies change to the subscriptions of the users matching filter, it counts the ones that
+// changed. Subscriptions a request changed in the meantime are left to the next run.
+func (us *UserService) updateDueSubscriptions(ctx context.Context, filter bson.M, change func(models.UserSubscription, time.Time) (models.UserSubscription, error)) (int, error) {
+	userCollection := us.database.Collection("users")
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(lifecycleJobBatchSize)
+	cursor, err := userCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("error finding due subscriptions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, fmt.Errorf("error decoding due subscriptions: %w", err)
+	}
+
+	updated := 0
+	for _, user := range users {
+		_, _, err := updateSubscription(ctx, us.database, user.ID, change)
+		if errors.Is(err, errSubscriptionNotDue) || errors.Is(err, ErrSubscriptionChanged) || errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+// SendRenewalReminders tells the users whose subscription renews soon. App stores remind their subscribers
+// themselves, each renewal is reminded once.
+func (us *UserService) SendRenewalReminders(ctx context.Context) (string, error) {
+	userCollection := us.database.Collection("users")
+
+	now := time.Now()
+	filter := bson.M{
+		"subscription.status":            models.SubscriptionStatusActive,
+		"subscription.nextRenewalDate":   bson.M{"$gt": now, "$lte": now.Add(renewalReminderNotice)},
+		"subscription.renewalRemindedAt": bson.M{"$exists": false},
+		"subscription.provider":          bson.M{"$nin": bson.A{models.SubscriptionProviderAppStore, models.SubscriptionProviderPlay}},
+	}
+	opts := options.Find().SetProjection(bson.M{"subscription": 1}).SetLimit(lifecycleJobBatchSize)
+	cursor, err := userCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return "", fmt.Errorf("error finding renewing subscriptions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return "", fmt.Errorf("error decoding renewing subscriptions: %w", err)
+	}
+
+	reminded, failed := 0, 0
+	for _, user := range users {
+		// The reminder is claimed first so concurrent runs can't send it twice
+		claimFilter := bson.M{"_id": user.ID, "subscription.status": models.SubscriptionStatusActive, "subscription.renewalRemindedAt": bson.M{"$exists": false}}
+		// Both writes bump the version, an update that read the subscription before doesn't overwrite them
+		claim := bson.M{"$set": bson.M{"subscription.renewalRemindedAt": now}, "$inc": bson.M{"subscription.version": 1}}
+		result, err := userCollection.UpdateOne(ctx, claimFilter, claim)
+		if err != nil {
+			return fmt.Sprintf("%d renewal reminders sent", reminded), fmt.Errorf("error claiming renewal reminder: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+
+		if err := us.notifier.Notify(ctx, user.ID.Hex(), renewalReminder(user.Subscription)); err != nil {
+			log.Printf("Error sending renewal reminder to user %s: %v\n", user.ID.Hex(), err)
+			// The next run tries again
+			unclaim := bson.M{"$unset": bson.M{"subscription.renewalRemindedAt": ""}, "$inc": bson.M{"subscription.version": 1}}
+			if _, err := userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, unclaim); err != nil {
+				log.Printf("Error unclaiming renewal reminder of user %s: %v\n", user.ID.Hex(), err)
+			}
+			failed++
+			continue
+		}
+		reminded++
+	}
+
+	summary := fmt.Sprintf("%d renewal reminders sent", reminded)
+	if failed > 0 {
+		return summary, fmt.Errorf("%d renewal reminders failed", failed)
+	}
+	return summary, nil
+}
+
+func renewalReminder(subscription models.UserSubscription) utils.Notification {
+	renewsAt := subscription.NextRenewalDate.UTC()
+	return utils.Notification{
+		Type:  utils.NotificationRenewalReminder,
+		Title: "Your subscription renews soon",
+		Body:  fmt.Sprintf("Your %s subscription renews on %s.", subscription.Type, renewsAt.Format("January 2, 2006")),
+		Data:  map[string]string{"plan": subscription.Type, "renewsAt": renewsAt.Format(time.RFC3339)},
+	}
+}
+
+// DeleteUnpaidGifts removes the gift codes whose checkout was abandoned.
+func (us *UserService) DeleteUnpaidGifts(ctx context.Context) (string, error) {
+	filter := bson.M{
+		"type":        models.PromoTypeGift,
+		"gift.paidAt": bson.M{"$exists": false},
+		"createdAt":   bson.M{"$lte": time.Now().Add(-unpaidGiftRetention)},
+	}
+	result, err := us.database.Collection("promoCodes").DeleteMany(ctx, filter)
+	if err != nil {
+		return "", fmt.Errorf("error deleting unpaid gift codes: %w", err)
+	}
+
+	return fmt.Sprintf("%d unpaid gift codes deleted", result.DeletedCount), nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MFA is opt-in for users, enrollment only happens from an authenticated session.
+
+func (us *UserService) BeginMFAEnrollment(ctx context.Context, userID primitive.ObjectID) (*models.MFAEnrollment, error) {
+	userCollection := us.database.Collection("users")
+	return beginMFAEnrollment(ctx, userCollection, userID, us.cfg.MFAIssuer, ErrUserNotFound)
+}
+
+func (us *UserService) ConfirmMFAEnrollment(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	userCollection := us.database.Collection("users")
+	return confirmMFAEnrollment(ctx, userCollection, userID, code, ErrUserNotFound)
+}
+
+func (us *UserService) VerifyMFA(ctx context.Context, userID primitive.ObjectID, input models.MFALoginInput) error {
+	userCollection := us.database.Collection("users")
+	return verifyMFACode(ctx, userCollection, userID, input.Code, input.RecoveryCode, ErrUserNotFound)
+}
+
+func (us *UserService) DisableMFA(ctx context.Context, userID primitive.ObjectID, code string) error {
+	userCollection := us.database.Collection("users")
+	return disableMFA(ctx, userCollection, userID, code, ErrUserNotFound)
+}
+
+func (us *UserService) RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	userCollection := us.database.Collection("users")
+	return regenerateRecoveryCodes(ctx, userCollection, userID, code, ErrUserNotFound)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	oidcLoginStateTTL      = 10 * time.Minute
+	pendingRegistrationTTL = time.Hour // Leaves time to fill in the profile
+	pendingIdentityLinkTTL = 15 * time.Minute
+)
+
+var (
+	ErrUnknownOIDCProvider      = errors.New("unknown identity provider")
+	ErrInvalidOIDCState         = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotVerified     = errors.New("the identity provider did not verify the email address")
+	ErrInvalidRegistrationToken = errors.New("invalid or expired registration token")
+	ErrInvalidLinkToken         = errors.New("invalid or expired identity link token")
+)
+
+// BeginOIDCLogin prepares a login with the provider, the client opens the returned URL and sends back the code and state.
+func (us *UserService) BeginOIDCLogin(ctx context.Context, providerName string) (*models.OIDCAuthorization, error) {
+	provider, ok := us.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("error generating login state: %w", err)
+	}
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+	codeVerifier, codeChallenge, err := utils.NewPKCEChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("error generating PKCE challenge: %w", err)
+	}
+
+	authorizationURL, err := provider.AuthorizationURL(ctx, state, nonce, codeChallenge)
+	if err != nil {
+		return nil, fmt.Errorf("error building authorization URL: %w", err)
+	}
+
+	loginState := models.OIDCLoginState{
+		ID:           primitive.NewObjectID(),
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginStateTTL),
+	}
+	if _, err := us.database.Collection("oidcLoginStates").InsertOne(ctx, loginState); err != nil {
+		return nil, fmt.Errorf("error saving login state: %w", err)
+	}
+
+	return &models.OIDCAuthorization{AuthorizationURL: authorizationURL, State: state}, nil
+}
+
+// CompleteOIDCLogin redeems the code for a verified identity and signs in the linked user. An unknown identity
+// is linked to the user with the same email when a provider already verified it for that user, waits for the user to
+// sign in with the password when it didn't, and otherwise starts a registration.
+func (us *UserService) CompleteOIDCLogin(ctx context.Context, providerName string, input models.OIDCCallbackInput) (*models.OIDCLoginResult, error) {
+	provider, ok := us.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	// The state is deleted as it is read so a code can only be redeemed once
+	var loginState models.OIDCLoginState
+	stateFilter := bson.M{"stateHash": utils.HashToken(input.State), "provider": providerName}
+	if err := us.database.Collection("oidcLoginStates").FindOneAndDelete(ctx, stateFilter).Decode(&loginState); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, fmt.Errorf("error fetching login state: %w", err)
+	}
+	if time.Now().After(loginState.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	identity, err := provider.Exchange(ctx, input.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("error signing in with %s: %w", providerName, err)
+	}
+
+	userCollection := us.database.Collection("users")
+	opts := options.FindOne().SetProjection(userAccountProjection)
+
+	var user models.User
+	identityFilter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": providerName, "subject": identity.Subject}}}
+	err = userCollection.FindOne(ctx, identityFilter, opts).Decode(&user)
+	if err == nil {
+		return &models.OIDCLoginResult{User: &user}, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("error fetching user by identity: %w", err)
+	}
+
+	// Accounts are matched by email, so the provider must vouch for it
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	externalIdentity := models.ExternalIdentity{Provider: providerName, Subject: identity.Subject, Email: identity.Email, LinkedAt: time.Now()}
+
+	err = userCollection.FindOne(ctx, bson.M{"email": identity.Email}, opts).Decode(&user)
+	if err == nil {
+		// Registering with a password doesn't verify the email, whoever registered it could be anyone
+		if !hasProviderVerifiedEmail(user) {
+			return us.startIdentityLink(ctx, user.ID, externalIdentity)
+		}
+
+		update := bson.M{"$push": bson.M{"identities": externalIdentity}}
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, update); err != nil {
+			return nil, fmt.Errorf("error linking identity: %w", err)
+		}
+		user.Identities = append(user.Identities, externalIdentity)
+		return &models.OIDCLoginResult{User: &user}, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("error fetching user with email %s: %w", identity.Email, err)
+	}
+
+	registrationToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("error generating registration token: %w", err)
+	}
+
+	now := time.Now()
+	pending := models.PendingRegistration{
+		ID:             primitive.NewObjectID(),
+		TokenHash:      utils.HashToken(registrationToken),
+		Identity:       externalIdentity,
+		Email:          identity.Email,
+		FirstName:      identity.GivenName,
+		LastName:       identity.FamilyName,
+		ProfilePicture: identity.Picture,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(pendingRegistrationTTL),
+	}
+	if _, err := us.database.Collection("pendingRegistrations").InsertOne(ctx, pending); err != nil {
+		return nil, fmt.Errorf("error saving pending registration: %w", err)
+	}
+
+	return &models.OIDCLoginResult{
+		RegistrationToken: registrationToken,
+		Profile: &models.OIDCProfile{
+			Email:          pending.Email,
+			FirstName:      pending.FirstName,
+			LastName:       pending.LastName,
+			ProfilePicture: pending.ProfilePicture,
+		},
+	}, nil
+}
+
+// hasProviderVerifiedEmail tells if one of the identities linked to the user vouched for the email of the account.
+func hasProviderVerifiedEmail(user models.User) bool {
+	for _, identity := range user.Identities {
+		if strings.EqualFold(identity.Email, user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// startIdentityLink keeps the identity until the user signs in with the password and the returned link token.
+func (us *UserService) startIdentityLink(ctx context.Context, userID primitive.ObjectID, identity models.ExternalIdentity) (*models.OIDCLoginResult, error) {
+	linkToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("error generating link token: %w", err)
+	}
+
+	now := time.Now()
+	link := models.PendingIdentityLink{
+		ID:        primitive.NewObjectID(),
+		TokenHash: utils.HashToken(linkToken),
+		UserID:    userID,
+		Identity:  identity,
+		CreatedAt: now,
+		ExpiresAt: now.Add(pendingIdentityLinkTTL),
+	}
+	if _, err := us.database.Collection("pendingIdentityLinks").InsertOne(ctx, link); err != nil {
+		return nil, fmt.Errorf("error saving pending identity link: %w", err)
+	}
+
+	return &models.OIDCLoginResult{LinkToken: linkToken, Profile: &models.OIDCProfile{Email: identity.Email}}, nil
+}
+
+// LinkPendingIdentity links the identity kept by a provider login to the user, once the user signed in with the
+// password. The link token is only valid for the account with the email of the identity.
+func (us *UserService) LinkPendingIdentity(ctx context.Context, userID primitive.ObjectID, linkToken string) error {
+	var link models.PendingIdentityLink
+	linkFilter := bson.M{"tokenHash": utils.HashToken(linkToken), "userId": userID}
+	if err := us.database.Collection("pendingIdentityLinks").FindOneAndDelete(ctx, linkFilter).Decode(&link); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrInvalidLinkToken
+		}
+		return fmt.Errorf("error fetching pending identity link: %w", err)
+	}
+	if time.Now().After(link.ExpiresAt) {
+		return ErrInvalidLinkToken
+	}
+
+	link.Identity.LinkedAt = time.Now()
+	update := bson.M{"$push": bson.M{"identities": link.Identity}}
+	if _, err := us.database.Collection("users").UpdateOne(ctx, bson.M{"_id": userID}, update); err != nil {
+		return fmt.Errorf("error linking identity: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteOIDCRegistration creates the user of a pending registration with the profile fields the provider did not have.
+func (us *UserService) CompleteOIDCRegistration(ctx context.Context, input models.OIDCRegistrationInput) (*models.User, error) {
+	pendingCollection := us.database.Collection("pendingRegistrations")
+
+	var pending models.PendingRegistration
+	if err := pendingCollection.FindOne(ctx, bson.M{"tokenHash": utils.HashToken(input.RegistrationToken)}).Decode(&pending); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidRegistrationToken
+		}
+		return nil, fmt.Errorf("error fetching pending registration: %w", err)
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		return nil, ErrInvalidRegistrationToken
+	}
+
+	userCollection := us.database.Collection("users")
+
+	// The email may have been registered with a password in the meantime
+	emailCount, err := userCollection.CountDocuments(ctx, bson.M{"email": pending.Email})
+	if err != nil {
+		return nil, fmt.Errorf("error checking if user already exists: %w", err)
+	}
+	if emailCount > 0 {
+		return nil, ErrUserAlreadyExists
+	}
+
+	usernameCount, err := userCollection.CountDocuments(ctx, bson.M{"profileInformation.username": input.ProfileInformation.Username})
+	if err != nil {
+		return nil, fmt.Errorf("error checking if username already exists: %w", err)
+	}
+	if usernameCount > 0 {
+		return nil, ErrUsernameAlreadyTaken
+	}
+
+	user := models.NewUserfromOIDCRegistration(input, pending)
+	user.StartTrial(time.Now(), us.cfg.TrialDays)
+	if _, err := userCollection.InsertOne(ctx, user); err != nil {
+		return nil, fmt.Errorf("error inserting user into database: %w", err)
+	}
+
+	// The user exists now, a leftover registration expires on its own
+	if _, err := pendingCollection.DeleteOne(ctx, bson.M{"_id": pending.ID}); err != nil {
+		log.Printf("Error deleting pending registration %s: %v\n", pending.ID.Hex(), err)
+	}
+
+	return &user, nil
+}
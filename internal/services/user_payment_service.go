@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrPaymentsNotConfigured       = errors.New("payment provider is not configured")
+	ErrPaymentEventAlreadyReceived = errors.New("payment event was already received")
+	ErrPaymentEventInProgress      = errors.New("payment event is being processed by another delivery")
+)
+
+// paymentEventClaimTimeout is longer than any delivery takes to apply an event
+const paymentEventClaimTimeout = 5 * time.Minute
+
+// ProcessPaymentWebhook verifies a webhook delivery and applies its event to the subscription it is about.
+// Every event ID is processed once, providers redeliver events until they get a success response.
+func (us *UserService) ProcessPaymentWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	if us.paymentGateway == nil {
+		return ErrPaymentsNotConfigured
+	}
+
+	event, err := us.paymentGateway.ParseWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+
+	record := models.PaymentEvent{ID: event.ID, Type: event.Type, CreatedAt: event.CreatedAt}
+	return us.processPaymentEventOnce(ctx, record, func() error {
+		return us.applyPaymentEvent(ctx, event)
+	})
+}
+
+// processPaymentEventOnce claims the event and applies it, then marks it done. A delivery finding the event done is
+// skipped, one finding it claimed by another delivery is refused so the provider delivers it again later, unless the
+// claim is older than paymentEventClaimTimeout: the delivery holding it died and the event was never applied. When
+// applying fails the claim is removed so the next delivery of the event is processed again.
+func (us *UserService) processPaymentEventOnce(ctx context.Context, record models.PaymentEvent, apply func() error) error {
+	paymentEventCollection := us.database.Collection("paymentEvents")
+	record.Status = models.PaymentEventProcessing
+	record.ClaimedAt = time.Now()
+	if _, err := paymentEventCollection.InsertOne(ctx, record); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("error recording payment event: %w", err)
+		}
+		if err := us.reclaimPaymentEvent(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	if err := apply(); err != nil {
+		claim := bson.M{"_id": record.ID, "claimedAt": record.ClaimedAt}
+		if _, deleteErr := paymentEventCollection.DeleteOne(ctx, claim); deleteErr != nil {
+			log.Printf("Error removing payment event %s: %v\n", record.ID, deleteErr)
+		}
+		return err
+	}
+
+	// The event is applied, failing to mark it only risks applying it again once the claim is stale
+	processedAt := time.Now()
+	done := bson.M{"$set": bson.M{"status": models.PaymentEventDone, "processedAt": processedAt}}
+	if _, err := paymentEventCollection.UpdateOne(ctx, bson.M{"_id": record.ID, "claimedAt": record.ClaimedAt}, done); err != nil {
+		log.Printf("Error marking payment event %s done: %v\n", record.ID, err)
+	}
+
+	return nil
+}
+
+// reclaimPaymentEvent takes over the claim of an event recorded before when the delivery holding it died.
+func (us *UserService) reclaimPaymentEvent(ctx context.Context, record models.PaymentEvent) error {
+	paymentEventCollection := us.database.Collection("paymentEvents")
+	stale := bson.M{
+		"_id":       record.ID,
+		"status":    models.PaymentEventProcessing,
+		"claimedAt": bson.M{"$lt": record.ClaimedAt.Add(-paymentEventClaimTimeout)},
+	}
+	err := paymentEventCollection.FindOneAndUpdate(ctx, stale, bson.M{"$set": bson.M{"claimedAt": record.ClaimedAt}}).Err()
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("error claiming payment event: %w", err)
+	}
+
+	// Events recorded before they had a status were done
+	var recorded models.PaymentEvent
+	if err := paymentEventCollection.FindOne(ctx, bson.M{"_id": record.ID}).Decode(&recorded); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// The delivery holding the claim just failed and gave it up
+			return ErrPaymentEventInProgress
+		}
+		return fmt.Errorf("error fetching payment event: %w", err)
+	}
+	if recorded.Status == models.PaymentEventProcessing {
+		return ErrPaymentEventInProgress
+	}
+	return ErrPaymentEventAlreadyReceived
+}
+
+// applyPaymentEvent moves the subscription as the payment says. Events no delivery could ever apply (unknown user,
+// transition not allowed) are logged and acknowledged, retrying them would not help.
+func (us *UserService) applyPaymentEvent(ctx context.Context, event *utils.PaymentWebhookEvent) error {
+	// Gifts are paid with a one-time checkout, separate from the subscription of the buyer
+	if event.Type == utils.PaymentEventCheckoutCompleted && event.GiftCodeID != "" {
+		gift, err := us.markGiftPaid(ctx, event)
+		if err != nil || gift == nil {
+			return err
+		}
+		return us.recordGiftInvoice(ctx, *gift, event)
+	}
+
+	change := paymentEventChange(event)
+	if change == nil {
+		return nil
+	}
+
+	userID, err := us.paymentEventUser(ctx, event)
+	if err == nil {
+		_, _, err = updateSubscription(ctx, us.database, userID, change)
+	}
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrInvalidSubscriptionTransition) {
+			log.Printf("Ignoring payment event %s: %v\n", event.ID, err)
+			return nil
+		}
+		return fmt.Errorf("error applying payment event %s: %w", event.ID, err)
+	}
+
+	// Paid invoices are kept for the user and the finance team
+	if event.Charge != nil {
+		line := models.InvoiceLine{Description: "Vigor subscription", Quantity: 1}
+		return us.recordInvoice(ctx, userID, models.InvoiceKindSubscription, event, line)
+	}
+
+	return nil
+}
+
+// paymentEventChange is the change the event makes to the subscription, nil for the events not followed.
+func paymentEventChange(event *utils.PaymentWebhookEvent) func(models.UserSubscription, time.Time) (models.UserSubscription, error) {
+	switch event.Type {
+	case utils.PaymentEventCheckoutCompleted:
+		return func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+			subscription.Provider = models.SubscriptionProviderStripe
+			subscription.ProviderCustomerID = event.CustomerID
+			subscription.ProviderSubscriptionID = event.SubscriptionID
+			return activatePaidSubscription(billedPlan(subscription, event.Plan, now), event.PeriodEnd, now)
+		}
+	case utils.PaymentEventInvoicePaid:
+		return func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+			return activatePaidSubscription(billedPlan(subscription, event.Plan, now), event.PeriodEnd, now)
+		}
+	case utils.PaymentEventPaymentFailed:
+		return markSubscriptionPastDue
+	case utils.PaymentEventSubscriptionDeleted:
+		return endSubscription
+	}
+	return nil
+}
+
+// billedPlan makes the plan the provider billed the one activated. A change the user requested is only applied
+// once it is billed: one the billed plan doesn't match is dropped, and so is any change when the plan is unknown.
+func billedPlan(subscription models.UserSubscription, plan string, now time.Time) models.UserSubscription {
+	if plan != models.SubscriptionTypeBasic && plan != models.SubscriptionTypePremium {
+		subscription.PendingChange = nil
+		return subscription
+	}
+
+	if subscription.PendingChange != nil && subscription.PendingChange.Type != plan {
+		log.Printf("Dropping requested change to %s, %s was billed\n", subscription.PendingChange.Type, plan)
+		subscription.PendingChange = nil
+	}
+	if plan != subscription.Type && subscription.PendingChange == nil {
+		subscription.PendingChange = &models.SubscriptionChange{Type: plan, RequestedAt: now}
+	}
+	return subscription
+}
+
+// paymentEventUser finds the user the event is about: checkouts carry the reference given when they were created,
+// the later events the provider subscription saved at checkout.
+func (us *UserService) paymentEventUser(ctx context.Context, event *utils.PaymentWebhookEvent) (primitive.ObjectID, error) {
+	if event.Type == utils.PaymentEventCheckoutCompleted {
+		userID, err := primitive.ObjectIDFromHex(event.UserID)
+		if err != nil {
+			return primitive.NilObjectID, fmt.Errorf("%w: invalid reference %q", ErrUserNotFound, event.UserID)
+		}
+		return userID, nil
+	}
+
+	return us.userByProviderSubscription(ctx, event.SubscriptionID)
+}
+
+// userByProviderSubscription finds the user whose subscription is paid with the provider or store subscription.
+func (us *UserService) userByProviderSubscription(ctx context.Context, providerSubscriptionID string) (primitive.ObjectID, error) {
+	if providerSubscriptionID == "" {
+		return primitive.NilObjectID, fmt.Errorf("%w: event has no subscription", ErrUserNotFound)
+	}
+
+	var user models.User
+	filter := bson.M{"subscription.providerSubscriptionId": providerSubscriptionID}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	if err := us.database.Collection("users").FindOne(ctx, filter, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return primitive.NilObjectID, fmt.Errorf("%w: no subscription %q", ErrUserNotFound, providerSubscriptionID)
+		}
+		return primitive.NilObjectID, fmt.Errorf("error finding user by payment subscription: %w", err)
+	}
+
+	return user.ID, nil
+}
+
+// activatePaidSubscription activates the subscription for the period the provider billed, when it says which.
+func activatePaidSubscription(subscription models.UserSubscription, periodEnd *time.Time, now time.Time) (models.UserSubscription, error) {
+	activated, err := transitionSubscription(subscription, models.SubscriptionStatusActive, now)
+	if err != nil {
+		return subscription, err
+	}
+	if periodEnd != nil && periodEnd.After(now) {
+		activated.EndDate = periodEnd
+		activated.NextRenewalDate = periodEnd
+	}
+	return activated, nil
+}
+
+// markSubscriptionPastDue keeps access while the payment is retried, every failed attempt is reported.
+func markSubscriptionPastDue(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+	if subscription.Status == models.SubscriptionStatusPastDue {
+		return subscription, nil
+	}
+	return transitionSubscription(subscription, models.SubscriptionStatusPastDue, now)
+}
+
+// endSubscription expires a subscription the provider ended, an active one is cancelled on the way.
+func endSubscription(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+	if subscription.Status == models.SubscriptionStatusExpired {
+		return subscription, nil
+	}
+	if !subscription.CanTransitionTo(models.SubscriptionStatusExpired) {
+		cancelled, err := transitionSubscription(subscription, models.SubscriptionStatusCancelled, now)
+		if err != nil {
+			return subscription, err
+		}
+		subscription = cancelled
+	}
+	return transitionSubscription(subscription, models.SubscriptionStatusExpired, now)
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Generated codes leave out the characters that are easily mistaken for others (0/O, 1/I/L)
+	promoCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	promoCodeLength   = 12
+)
+
+var (
+	ErrPromoCodeNotFound        = errors.New("promo code not found")
+	ErrPromoCodeExpired         = errors.New("promo code has expired")
+	ErrPromoCodeExhausted       = errors.New("promo code has no redemptions left")
+	ErrPromoCodeAlreadyRedeemed = errors.New("promo code was already redeemed by the user")
+	ErrPromoCodeNotApplicable   = errors.New("promo code can't be applied to the current subscription")
+)
+
+// RedeemPromoCode applies a code to the subscription of the user. Each user redeems a code once, within the limit
+// of redemptions of the code.
+func (us *UserService) RedeemPromoCode(ctx context.Context, userID primitive.ObjectID, code string) (*models.UserSubscription, error) {
+	var promoCode models.PromoCode
+	if err := us.database.Collection("promoCodes").FindOne(ctx, bson.M{"code": normalizePromoCode(code)}).Decode(&promoCode); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPromoCodeNotFound
+		}
+		return nil, fmt.Errorf("error fetching promo code: %w", err)
+	}
+
+	now := time.Now()
+	// Gift codes exist before they are paid
+	if promoCode.DisabledAt != nil || (promoCode.Type == models.PromoTypeGift && (promoCode.Gift == nil || promoCode.Gift.PaidAt == nil)) {
+		return nil, ErrPromoCodeNotFound
+	}
+	if promoCode.ExpiresAt != nil && !now.Before(*promoCode.ExpiresAt) {
+		return nil, ErrPromoCodeExpired
+	}
+
+	if err := us.claimPromoRedemption(ctx, promoCode, userID, now); err != nil {
+		return nil, err
+	}
+
+	_, subscription, err := updateSubscription(ctx, us.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		return applyPromoCode(subscription, promoCode, now)
+	})
+	if err != nil {
+		us.releasePromoRedemption(ctx, promoCode.ID, userID)
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// claimPromoRedemption records the redemption of the user then counts it on the code, the count failing undoes the
+// record.
+func (us *UserService) claimPromoRedemption(ctx context.Context, promoCode models.PromoCode, userID primitive.ObjectID, now time.Time) error {
+	redemption := models.PromoRedemption{ID: primitive.NewObjectID(), CodeID: promoCode.ID, UserID: userID, RedeemedAt: now}
+	if _, err := us.database.Collection("promoRedemptions").InsertOne(ctx, redemption); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrPromoCodeAlreadyRedeemed
+		}
+		return fmt.Errorf("error recording promo code redemption: %w", err)
+	}
+
+	filter := bson.M{"_id": promoCode.ID, "disabledAt": bson.M{"$exists": false}}
+	if promoCode.MaxRedemptions > 0 {
+		filter["redemptions"] = bson.M{"$lt": promoCode.MaxRedemptions}
+	}
+	result, err := us.database.Collection("promoCodes").UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"redemptions": 1}})
+	if err == nil && result.MatchedCount == 0 {
+		err = ErrPromoCodeExhausted
+	}
+	if err != nil {
+		if _, deleteErr := us.database.Collection("promoRedemptions").DeleteOne(ctx, bson.M{"_id": redemption.ID}); deleteErr != nil {
+			log.Printf("Error removing redemption of promo code %s: %v\n", promoCode.ID.Hex(), deleteErr)
+		}
+		if errors.Is(err, ErrPromoCodeExhausted) {
+			return err
+		}
+		return fmt.Errorf("error counting promo code redemption: %w", err)
+	}
+
+	return nil
+}
+
+// releasePromoRedemption gives the redemption back when the code couldn't be applied.
+func (us *UserService) releasePromoRedemption(ctx context.Context, promoCodeID, userID primitive.ObjectID) {
+	if _, err := us.database.Collection("promoRedemptions").DeleteOne(ctx, bson.M{"codeId": promoCodeID, "userId": userID}); err != nil {
+		log.Printf("Error removing redemption of promo code %s: %v\n", promoCodeID.Hex(), err)
+		return
+	}
+	if _, err := us.database.Collection("promoCodes").UpdateOne(ctx, bson.M{"_id": promoCodeID}, bson.M{"$inc": bson.M{"redemptions": -1}}); err != nil {
+		log.Printf("Error uncounting redemption of promo code %s: %v\n", promoCodeID.Hex(), err)
+	}
+}
+
+// applyPromoCode changes the subscription as the code gives. Gifts are prepaid periods that don't renew: the subscription is
+// activated on the gifted plan then cancelled, it expires at the end of the gift.
+func applyPromoCode(subscription models.UserSubscription, promoCode models.PromoCode, now time.Time) (models.UserSubscription, error) {
+	switch promoCode.Type {
+	case models.PromoTypeTrialExtension:
+		if subscription.Status != models.SubscriptionStatusTrialing || subscription.EndDate == nil {
+			return subscription, fmt.Errorf("%w: subscription is not in trial", ErrPromoCodeNotApplicable)
+		}
+		trialEnd := subscription.EndDate.AddDate(0, 0, promoCode.TrialDays)
+		subscription.EndDate = &trialEnd
+		return subscription, nil
+	case models.PromoTypeGift:
+		if subscription.Status == models.SubscriptionStatusActive || subscription.Status == models.SubscriptionStatusPastDue {
+			return subscription, fmt.Errorf("%w: subscription renews", ErrPromoCodeNotApplicable)
+		}
+		// The access left is kept, the gift starts after it
+		giftStart := now
+		if subscription.IsActive && subscription.EndDate != nil && subscription.EndDate.After(now) {
+			giftStart = *subscription.EndDate
+		}
+
+		subscription.PendingChange = &models.SubscriptionChange{Type: promoCode.Plan, RequestedAt: now}
+		activated, err := transitionSubscription(subscription, models.SubscriptionStatusActive, now)
+		if err != nil {
+			return subscription, err
+		}
+		gifted, err := transitionSubscription(activated, models.SubscriptionStatusCancelled, now)
+		if err != nil {
+			return subscription, err
+		}
+		giftEnd := giftStart.AddDate(0, promoCode.Months, 0)
+		gifted.EndDate = &giftEnd
+		gifted.Provider = models.SubscriptionProviderGift
+		gifted.ProviderCustomerID = ""
+		gifted.ProviderSubscriptionID = ""
+		return gifted, nil
+	}
+
+	return subscription, fmt.Errorf("%w: unknown type %s", ErrPromoCodeNotApplicable, promoCode.Type)
+}
+
+// PurchaseGift creates an unpaid gift code. The app pays it with a one-time checkout whose metadata carries the
+// code ID, the code can be redeemed once the payment webhook confirmed it.
+func (us *UserService) PurchaseGift(ctx context.Context, userID primitive.ObjectID, input models.GiftPurchaseInput) (*models.PromoCode, error) {
+	if us.paymentGateway == nil {
+		return nil, ErrPaymentsNotConfigured
+	}
+	if us.cfg.GiftMonthlyPrices[input.Plan] == 0 {
+		return nil, fmt.Errorf("%w: no gift price for %s", ErrPaymentsNotConfigured, input.Plan)
+	}
+
+	code, err := generatePromoCode()
+	if err != nil {
+		return nil, fmt.Errorf("error generating gift code: %w", err)
+	}
+
+	gift := models.PromoCode{
+		ID:             primitive.NewObjectID(),
+		Code:           code,
+		Type:           models.PromoTypeGift,
+		Plan:           input.Plan,
+		Months:         input.Months,
+		MaxRedemptions: 1,
+		CreatedBy:      userID,
+		CreatedAt:      time.Now(),
+		Gift:           &models.GiftDetails{RecipientEmail: input.RecipientEmail, Message: input.Message},
+	}
+	if _, err := us.database.Collection("promoCodes").InsertOne(ctx, gift); err != nil {
+		return nil, fmt.Errorf("error inserting gift code: %w", err)
+	}
+
+	return &gift, nil
+}
+
+// GetPurchasedGifts lists the gift codes the user bought, with their payment and redemption.
+func (us *UserService) GetPurchasedGifts(ctx context.Context, userID primitive.ObjectID) ([]models.PromoCode, error) {
+	filter := bson.M{"createdBy": userID, "type": models.PromoTypeGift}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := us.database.Collection("promoCodes").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding gift codes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	gifts := []models.PromoCode{}
+	if err := cursor.All(ctx, &gifts); err != nil {
+		return nil, fmt.Errorf("error decoding gift codes: %w", err)
+	}
+
+	return gifts, nil
+}
+
+// markGiftPaid makes a gift code redeemable once its checkout paid the price of the gift. It returns the gift the
+// checkout paid, nil when the payment is ignored.
+func (us *UserService) markGiftPaid(ctx context.Context, event *utils.PaymentWebhookEvent) (*models.PromoCode, error) {
+	id, err := primitive.ObjectIDFromHex(event.GiftCodeID)
+	if err != nil {
+		log.Printf("Ignoring payment of invalid gift code %q\n", event.GiftCodeID)
+		return nil, nil
+	}
+
+	promoCodeCollection := us.database.Collection("promoCodes")
+	var gift models.PromoCode
+	if err := promoCodeCollection.FindOne(ctx, bson.M{"_id": id, "type": models.PromoTypeGift}).Decode(&gift); err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Printf("Ignoring payment of unknown gift code %s\n", event.GiftCodeID)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching gift code: %w", err)
+	}
+
+	price := us.cfg.GiftMonthlyPrices[gift.Plan] * int64(gift.Months)
+	if event.Charge == nil || price == 0 || event.Charge.Currency != us.cfg.GiftCurrency || event.Charge.Total != price {
+		log.Printf("Ignoring payment of gift code %s: charge doesn't match the price of %d %s\n", event.GiftCodeID, price, us.cfg.GiftCurrency)
+		return nil, nil
+	}
+
+	filter := bson.M{"_id": id, "type": models.PromoTypeGift, "gift.paidAt": bson.M{"$exists": false}}
+	result, err := promoCodeCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"gift.paidAt": time.Now()}})
+	if err != nil {
+		return nil, fmt.Errorf("error marking gift code paid: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		log.Printf("Gift code %s was already paid\n", event.GiftCodeID)
+	}
+
+	return &gift, nil
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func generatePromoCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(promoCodeAlphabet)))
+	code := make([]byte, promoCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code[i] = promoCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/GhostDrew11/vigor-api/internal/config"
 	"github.com/GhostDrew11/vigor-api/internal/db"
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"github.com/GhostDrew11/vigor-api/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -19,18 +23,38 @@ var (
 	ErrInvalidUserCredentials = errors.New("invalid email or password")
 )
 
+// userAccountProjection leaves the credentials in the database, only the MFA status is read.
+var userAccountProjection = bson.M{"passwordHash": 0, "mfa.secret": 0, "mfa.pendingSecret": 0, "mfa.recoveryCodeHashes": 0}
+
 type UserService struct {
 	database db.MongoDatabase
 	hasher utils.HashPasswordService
 	parser utils.ParserService
+	cfg *config.Config
+	oidcProviders map[string]*utils.OIDCProvider
+	paymentGateway utils.PaymentGateway
+	appStore *utils.AppStoreVerifier
+	play *utils.PlayVerifier
+	notifier utils.Notifier
 }
 
 func (us *UserService) StartSession() (mongo.Session, error) {
 	return us.database.Client().StartSession()
 }
 
-func NewUserService(database db.MongoDatabase, hasher utils.HashPasswordService, parser utils.ParserService) *UserService {
-	return &UserService{database: database, hasher: hasher, parser: parser}
+func NewUserService(database db.MongoDatabase, hasher utils.HashPasswordService, parser utils.ParserService, cfg *config.Config) *UserService {
+	oidcProviders := make(map[string]*utils.OIDCProvider)
+	var paymentGateway utils.PaymentGateway
+	if cfg != nil {
+		for _, providerConfig := range cfg.OIDCProviders {
+			oidcProviders[providerConfig.Name] = utils.NewOIDCProvider(providerConfig, nil)
+		}
+		if cfg.PaymentWebhookSecret != "" {
+			paymentGateway = utils.NewStripeGateway(cfg.PaymentWebhookSecret, cfg.PaymentWebhookTolerance)
+		}
+	}
+
+	return &UserService{database: database, hasher: hasher, parser: parser, cfg: cfg, oidcProviders: oidcProviders, paymentGateway: paymentGateway, notifier: utils.LogNotifier{}}
 }
 
 func (us *UserService) RegisterUser(ctx context.Context, input models.UserRegistrationInput) error {
@@ -60,10 +84,11 @@ func (us *UserService) RegisterUser(ctx context.Context, input models.UserRegist
 	}
 
 	// Create a new user
-	user, err := models.NewUserfromInput(input)
+	user, err := models.NewUserfromInput(input, us.hasher)
 	if err != nil {
 		return fmt.Errorf("error creating user from input: %w", err)
 	}
+	user.StartTrial(time.Now(), us.cfg.TrialDays)
 
 	// Insert the user into the database
 	_, err = userCollection.InsertOne(ctx, user)
@@ -83,6 +108,10 @@ func (us *UserService) GetUserByEmail(ctx context.Context, email, password strin
 
 	result := userCollection.FindOne(ctx, filter)
 	if result.Err() != nil {
+		// Unknown accounts count as failed logins so they can't be told apart from a wrong password
+		if result.Err() == mongo.ErrNoDocuments {
+			return &models.User{}, ErrInvalidUserCredentials
+		}
 		return &models.User{}, fmt.Errorf("error fetching user with email %s: %w", email, result.Err())
 	}
 
@@ -95,5 +124,23 @@ func (us *UserService) GetUserByEmail(ctx context.Context, email, password strin
 		return &models.User{}, ErrInvalidUserCredentials
 	}
 
+	upgradePasswordHash(ctx, userCollection, us.hasher, user.ID, password, user.PasswordHash)
+
+	return &user, nil
+}
+
+func (us *UserService) GetUserByID(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	userCollection := us.database.Collection("users")
+
+	var user models.User
+	opts := options.FindOne().SetProjection(userAccountProjection)
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
 	return &user, nil
 }
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Last use is only written when older than this, so authenticated requests don't all cost a write.
+const sessionTouchInterval = 5 * time.Minute
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrUserSuspended   = errors.New("user account is suspended")
+	ErrUserBanned      = errors.New("user account is banned")
+)
+
+// activeSessionFilter matches the session only while it is neither revoked nor expired.
+func activeSessionFilter(userID, sessionID primitive.ObjectID) bson.M {
+	return bson.M{
+		"_id":       sessionID,
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+}
+
+// CreateSession records a login, the refresh token given to the client is stored hashed.
+// Suspended and banned users can't sign in.
+func (us *UserService) CreateSession(ctx context.Context, session models.Session, refreshToken string) error {
+	restriction, err := us.GetAccountRestriction(ctx, session.UserID)
+	if err != nil {
+		return err
+	}
+	if err := restrictionError(restriction); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session.RefreshTokenHash = utils.HashToken(refreshToken)
+	session.CreatedAt = now
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(utils.RefreshTokenLifetime)
+
+	if _, err := us.database.Collection("sessions").InsertOne(ctx, session); err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+
+	return nil
+}
+
+// IsSessionActive reports whether tokens of the session are still accepted, and records its use.
+func (us *UserService) IsSessionActive(ctx context.Context, userID primitive.ObjectID, sessionID string) (bool, error) {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	sessionCollection := us.database.Collection("sessions")
+	var session models.Session
+	opts := options.FindOne().SetProjection(bson.M{"lastUsedAt": 1})
+	if err := sessionCollection.FindOne(ctx, activeSessionFilter(userID, sessionObjectID), opts).Decode(&session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("error fetching session: %w", err)
+	}
+
+	if time.Since(session.LastUsedAt) > sessionTouchInterval {
+		if _, err := sessionCollection.UpdateOne(ctx, bson.M{"_id": sessionObjectID}, bson.M{"$set": bson.M{"lastUsedAt": time.Now()}}); err != nil {
+			log.Printf("Error updating last use of session %s: %v\n", sessionID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// GetAccountRestriction returns the restriction in effect on the user, suspended or banned, or an empty one.
+// It runs on every user request so suspensions and bans apply to tokens already issued.
+func (us *UserService) GetAccountRestriction(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"restriction": 1})
+	if err := us.database.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", fmt.Errorf("error checking account restriction: %w", err)
+	}
+
+	if !user.Restriction.InEffect(time.Now()) {
+		return "", nil
+	}
+
+	return user.Restriction.Type, nil
+}
+
+// restrictionError is the error a restricted user gets when signing in.
+func restrictionError(restriction string) error {
+	switch restriction {
+	case models.UserRestrictionSuspended:
+		return ErrUserSuspended
+	case models.UserRestrictionBanned:
+		return ErrUserBanned
+	}
+	return nil
+}
+
+// revokeUserSessions signs the user out of every device.
+func revokeUserSessions(ctx context.Context, sessionCollection db.MongoCollection, userID primitive.ObjectID) error {
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	if _, err := sessionCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": time.Now()}}); err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+
+	return nil
+}
+
+// CheckSessionRefreshToken accepts only the latest refresh token of an active session. An older one means the
+// token leaked and was already rotated by someone, so the session is revoked.
+func (us *UserService) CheckSessionRefreshToken(ctx context.Context, userID primitive.ObjectID, sessionID, refreshToken string) (bool, error) {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	sessionCollection := us.database.Collection("sessions")
+	var session models.Session
+	if err := sessionCollection.FindOne(ctx, activeSessionFilter(userID, sessionObjectID)).Decode(&session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("error fetching session: %w", err)
+	}
+
+	if session.RefreshTokenHash == utils.HashToken(refreshToken) {
+		return true, nil
+	}
+
+	log.Printf("Revoking session %s after reuse of a rotated refresh token\n", sessionID)
+	if err := us.RevokeSession(ctx, userID, sessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return false, err
+	}
+	return false, nil
+}
+
+// RotateSessionRefreshToken replaces the refresh token of the session and extends it. It fails when the current
+// token was rotated concurrently.
+func (us *UserService) RotateSessionRefreshToken(ctx context.Context, userID primitive.ObjectID, sessionID, currentToken, newToken string) (bool, error) {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	filter := activeSessionFilter(userID, sessionObjectID)
+	filter["refreshTokenHash"] = utils.HashToken(currentToken)
+	update := bson.M{"$set": bson.M{
+		"refreshTokenHash": utils.HashToken(newToken),
+		"lastUsedAt":       now,
+		"expiresAt":        now.Add(utils.RefreshTokenLifetime),
+	}}
+
+	result, err := us.database.Collection("sessions").UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("error rotating session refresh token: %w", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// GetSessions lists the active sessions of the user, most recently used first.
+func (us *UserService) GetSessions(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "lastUsedAt", Value: -1}}).
+		SetProjection(bson.M{"refreshTokenHash": 0})
+
+	cursor, err := us.database.Collection("sessions").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error finding sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("error decoding sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs the device out, its access token is refused from the next request on.
+func (us *UserService) RevokeSession(ctx context.Context, userID primitive.ObjectID, sessionID string) error {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	filter := bson.M{"_id": sessionObjectID, "userId": userID, "revokedAt": bson.M{"$exists": false}}
+	result, err := us.database.Collection("sessions").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RecordImpersonatedRequest adds a request made by an admin impersonating the user to the audit trail. The request
+// was already answered, a failure is logged rather than returned.
+func (us *UserService) RecordImpersonatedRequest(ctx context.Context, request models.ImpersonatedRequest) {
+	if _, err := us.database.Collection("impersonatedRequests").InsertOne(ctx, request); err != nil {
+		log.Printf("Error recording impersonated request %s of impersonation %s: %v\n", request.RequestID, request.ImpersonationID.Hex(), err)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrStoreNotConfigured   = errors.New("purchases of this store are not enabled")
+	ErrUnknownStoreProduct  = errors.New("store product is not a subscription plan")
+	ErrStorePurchaseExpired = errors.New("store purchase has expired")
+	ErrStorePurchaseOwned   = errors.New("store purchase belongs to another account")
+)
+
+// SetAppStoreVerifier enables App Store purchases, their transactions are checked against the verifier's roots.
+func (us *UserService) SetAppStoreVerifier(verifier *utils.AppStoreVerifier) {
+	us.appStore = verifier
+}
+
+// SetPlayVerifier enables Play purchases.
+func (us *UserService) SetPlayVerifier(verifier *utils.PlayVerifier) {
+	us.play = verifier
+}
+
+// VerifyAppStorePurchase links the subscription of a signed StoreKit transaction to the user.
+func (us *UserService) VerifyAppStorePurchase(ctx context.Context, userID primitive.ObjectID, signedTransaction string) (*models.UserSubscription, error) {
+	if us.appStore == nil {
+		return nil, ErrStoreNotConfigured
+	}
+
+	purchase, err := us.appStore.VerifyTransaction(signedTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.linkStorePurchase(ctx, userID, purchase)
+}
+
+// VerifyPlayPurchase links the subscription of a Play purchase token to the user and acknowledges it.
+func (us *UserService) VerifyPlayPurchase(ctx context.Context, userID primitive.ObjectID, purchaseToken string) (*models.UserSubscription, error) {
+	if us.play == nil {
+		return nil, ErrStoreNotConfigured
+	}
+
+	purchase, err := us.play.VerifyPurchase(ctx, purchaseToken)
+	if err != nil {
+		return nil, err
+	}
+	// The app passes the user ID to Play Billing as the obfuscated account ID
+	if purchase.AccountID != "" && purchase.AccountID != userID.Hex() {
+		return nil, ErrStorePurchaseOwned
+	}
+
+	subscription, err := us.linkStorePurchase(ctx, userID, purchase)
+	if err != nil {
+		return nil, err
+	}
+
+	if purchase.Unacknowledged {
+		if err := us.play.Acknowledge(ctx, purchase.ProductID, purchaseToken); err != nil {
+			// The next verification or notification of the purchase tries again
+			log.Printf("Error acknowledging Play purchase of user %s: %v\n", userID.Hex(), err)
+		}
+	}
+
+	return subscription, nil
+}
+
+func (us *UserService) linkStorePurchase(ctx context.Context, userID primitive.ObjectID, purchase *utils.StorePurchase) (*models.UserSubscription, error) {
+	if purchase.State == utils.StorePurchaseExpired {
+		return nil, ErrStorePurchaseExpired
+	}
+	plan, err := us.storePlan(purchase.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, subscription, err := updateSubscription(ctx, us.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		return syncStoreSubscription(subscription, purchase, plan, now)
+	})
+	if err != nil {
+		// The store subscription is already linked to another user
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrStorePurchaseOwned
+		}
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// ProcessAppStoreNotification applies an App Store Server Notification to the subscription it is about.
+func (us *UserService) ProcessAppStoreNotification(ctx context.Context, signedPayload string) error {
+	if us.appStore == nil {
+		return ErrStoreNotConfigured
+	}
+
+	notification, err := us.appStore.VerifyNotification(signedPayload)
+	if err != nil {
+		return err
+	}
+
+	record := models.PaymentEvent{ID: utils.StoreAppStore + ":" + notification.ID, Type: notification.Type, CreatedAt: time.Now()}
+	return us.processPaymentEventOnce(ctx, record, func() error {
+		return us.applyStorePurchase(ctx, notification.Purchase)
+	})
+}
+
+// ProcessPlayNotification applies a real-time developer notification of Play. The notification isn't signed, the
+// purchase it names is read again from the Play Developer API.
+func (us *UserService) ProcessPlayNotification(ctx context.Context, body []byte) error {
+	if us.play == nil {
+		return ErrStoreNotConfigured
+	}
+
+	messageID, purchaseToken, err := us.play.ParseNotification(body)
+	if err != nil {
+		return err
+	}
+
+	record := models.PaymentEvent{ID: utils.StorePlay + ":" + messageID, Type: "subscriptionNotification", CreatedAt: time.Now()}
+	return us.processPaymentEventOnce(ctx, record, func() error {
+		if purchaseToken == "" {
+			return nil
+		}
+
+		purchase, err := us.play.VerifyPurchase(ctx, purchaseToken)
+		if err != nil {
+			if errors.Is(err, utils.ErrStorePurchaseNotFound) || errors.Is(err, utils.ErrStorePurchasePending) {
+				log.Printf("Ignoring Play notification %s: %v\n", messageID, err)
+				return nil
+			}
+			return err
+		}
+		return us.applyStorePurchase(ctx, purchase)
+	})
+}
+
+// applyStorePurchase syncs the subscription linked to the store purchase. Purchases no user linked yet are
+// ignored, the app links them when it verifies them.
+func (us *UserService) applyStorePurchase(ctx context.Context, purchase *utils.StorePurchase) error {
+	if purchase == nil {
+		return nil
+	}
+
+	userID, err := us.userByProviderSubscription(ctx, purchase.SubscriptionID)
+	if errors.Is(err, ErrUserNotFound) && purchase.LinkedSubscriptionID != "" {
+		// A plan change on Play replaces the purchase token
+		userID, err = us.userByProviderSubscription(ctx, purchase.LinkedSubscriptionID)
+	}
+	var plan string
+	if err == nil {
+		plan, err = us.storePlan(purchase.ProductID)
+	}
+	if err == nil {
+		_, _, err = updateSubscription(ctx, us.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+			return syncStoreSubscription(subscription, purchase, plan, now)
+		})
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrUnknownStoreProduct) || errors.Is(err, ErrInvalidSubscriptionTransition) {
+			log.Printf("Ignoring %s notification for %s: %v\n", purchase.Store, purchase.SubscriptionID, err)
+			return nil
+		}
+		return fmt.Errorf("error applying %s notification: %w", purchase.Store, err)
+	}
+
+	return nil
+}
+
+func (us *UserService) storePlan(productID string) (string, error) {
+	plan, ok := us.cfg.StoreProducts[productID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownStoreProduct, productID)
+	}
+	return plan, nil
+}
+
+// syncStoreSubscription moves the subscription to the state the store reports. The store owns the plan and the
+// period, a subscription the store still bills is activated before it is cancelled or marked past due.
+func syncStoreSubscription(subscription models.UserSubscription, purchase *utils.StorePurchase, plan string, now time.Time) (models.UserSubscription, error) {
+	if purchase.State == utils.StorePurchaseExpired {
+		return endSubscription(subscription, now)
+	}
+
+	subscription.Provider = purchase.Store
+	subscription.ProviderCustomerID = ""
+	subscription.ProviderSubscriptionID = purchase.SubscriptionID
+
+	var err error
+	if purchase.State == utils.StorePurchaseActive || !subscriptionIsBilled(subscription) {
+		subscription.PendingChange = &models.SubscriptionChange{Type: plan, RequestedAt: now}
+		if subscription, err = activatePaidSubscription(subscription, purchase.ExpiresAt, now); err != nil {
+			return subscription, err
+		}
+	}
+
+	switch purchase.State {
+	case utils.StorePurchasePastDue:
+		return markSubscriptionPastDue(subscription, now)
+	case utils.StorePurchaseCancelled:
+		if subscription.Status != models.SubscriptionStatusCancelled {
+			if subscription, err = transitionSubscription(subscription, models.SubscriptionStatusCancelled, now); err != nil {
+				return subscription, err
+			}
+		}
+		// Access stays until the end of the period the store billed
+		if purchase.ExpiresAt != nil {
+			subscription.EndDate = purchase.ExpiresAt
+		}
+	}
+
+	return subscription, nil
+}
+
+func subscriptionIsBilled(subscription models.UserSubscription) bool {
+	switch subscription.Status {
+	case models.SubscriptionStatusActive, models.SubscriptionStatusPastDue, models.SubscriptionStatusCancelled:
+		return true
+	}
+	return false
+}
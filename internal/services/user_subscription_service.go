@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/GhostDrew11/vigor-api/internal/models"
@@ -27,91 +28,52 @@ func (us *UserService) GetUserSubsctiption(ctx context.Context, userID primitive
 		return nil, fmt.Errorf("error returning user subscription informations: %w", err)
 	}
 
-	return &user.Subscription, nil
-}
-
-func (us *UserService) UpdateUserSubscription(ctx context.Context, userID primitive.ObjectID, updateInput models.UserSubscriptionUpdateInput) (*models.UserSubscription, error) {	
-	userCollection := us.database.Collection("users")
-	filter := bson.M{"_id": userID}
-
-	var existingUser models.User
-	if err := userCollection.FindOne(ctx, filter).Decode(&existingUser); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, ErrUserNotFound
+	// Trials and cancelled subscriptions expire at the end of their period
+	subscription, expired := expireEndedSubscription(user.Subscription, time.Now())
+	if expired {
+		subscription.Version++
+		result, err := userCollection.UpdateOne(ctx, subscriptionVersionFilter(userID, user.Subscription), bson.M{"$set": bson.M{"subscription": subscription}})
+		if err != nil {
+			log.Printf("Error expiring subscription of user %s: %v\n", userID.Hex(), err)
+		} else if result.MatchedCount > 0 {
+			recordSubscriptionEvent(ctx, us.database, userID, user.Subscription, subscription, time.Now())
 		}
-
-		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
 
-	updatedSubscription := mergeUserSubscriptionUpdates(existingUser.Subscription, updateInput)
-	updatedDoc := bson.M{"$set": bson.M{"subscription": updatedSubscription}}
-
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var updatedUser models.User
-	if err := userCollection.FindOneAndUpdate(ctx, filter, updatedDoc, opts).Decode(&updatedUser); err != nil {
-		return nil, fmt.Errorf("error updating user subscription informations: %w", err)
-	}
-
-	return &updatedUser.Subscription, nil
+	return &subscription, nil
 }
 
-func (us *UserService) CancelUserSubscription(ctx context.Context, userID primitive.ObjectID) (*models.UserSubscription, error) {
-	userCollection := us.database.Collection("users")
-	filter := bson.M{"_id": userID}
-
-	currentTime := time.Now()
-	updateDoc := bson.M{
-		"$set": bson.M{
-			"subscription.nextRenewalDate": nil,
-		},
-	}
-
-	var user models.User
-	if err := userCollection.FindOne(ctx, filter).Decode(&user); err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, ErrUserNotFound
+// RequestSubscriptionChange records the plan the user wants. Upgrades apply once paid, downgrades at the end of
+// the current period. Requesting the current plan withdraws a pending change.
+func (us *UserService) RequestSubscriptionChange(ctx context.Context, userID primitive.ObjectID, input models.SubscriptionChangeInput) (*models.UserSubscription, error) {
+	_, subscription, err := updateSubscription(ctx, us.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		if input.Type == subscription.Type {
+			subscription.PendingChange = nil
+			return subscription, nil
 		}
-		return nil, fmt.Errorf("error fetching user for subscription cancellation: %w", err)
-	}
 
-	if user.Subscription.EndDate != nil && currentTime.After(*user.Subscription.EndDate) {
-		updateDoc["$set"].(bson.M)["subscription.isActive"] = false
-		updateDoc["$set"].(bson.M)["subscription.Status"] = "inactive"
-	} else {
-		updateDoc["$set"].(bson.M)["subscription.Status"] = "cancelled"
+		change := &models.SubscriptionChange{Type: input.Type, RequestedAt: now}
+		if input.Type == models.SubscriptionTypeBasic && subscription.IsActive {
+			change.EffectiveAt = subscription.EndDate
+		}
+		subscription.PendingChange = change
+		return subscription, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After) 
-    var updatedUser models.User
-    if err := userCollection.FindOneAndUpdate(ctx, filter, updateDoc, opts).Decode(&updatedUser); err != nil {
-        if err == mongo.ErrNoDocuments {
-            return nil, ErrUserNotFound
-        }
-        return nil, fmt.Errorf("error updating subscription: %w", err)
-    }
-
-    return &updatedUser.Subscription, nil
+	return subscription, nil
 }
 
-func mergeUserSubscriptionUpdates(existingSubscription models.UserSubscription, updateInput models.UserSubscriptionUpdateInput) models.UserSubscription {
-	if updateInput.Type != nil {
-		existingSubscription.Type = *updateInput.Type
-	}
-	if updateInput.Status != nil {
-		existingSubscription.Status = *updateInput.Status
-	}
-	if updateInput.StartDate != nil {
-		existingSubscription.StartDate = *updateInput.StartDate
-	}
-	if updateInput.EndDate != nil {
-		existingSubscription.EndDate = updateInput.EndDate
-	}
-	if updateInput.NextRenewalDate != nil {
-		existingSubscription.NextRenewalDate = updateInput.NextRenewalDate
-	}
-	if updateInput.IsActive != nil {
-		existingSubscription.IsActive = *updateInput.IsActive
+// CancelUserSubscription stops the renewals, the user keeps access until the end of the period.
+func (us *UserService) CancelUserSubscription(ctx context.Context, userID primitive.ObjectID) (*models.UserSubscription, error) {
+	_, subscription, err := updateSubscription(ctx, us.database, userID, func(subscription models.UserSubscription, now time.Time) (models.UserSubscription, error) {
+		return transitionSubscription(subscription, models.SubscriptionStatusCancelled, now)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return existingSubscription
-}
\ No newline at end of file
+	return subscription, nil
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -51,7 +52,8 @@ func (us *UserService) GetActiveWorkoutPlan(ctx context.Context, userID primitiv
 func (us *UserService) JoinWorkoutPlan(ctx context.Context, userID, workoutPlanID primitive.ObjectID) error {
 	var workoutPlan models.WorkoutPlan
 	workoutPlanCollection := us.database.Collection("workoutPlans")
-	err := workoutPlanCollection.FindOne(ctx, bson.M{"_id": workoutPlanID}).Decode(&workoutPlan)
+	// Drafts and archived plans can't be joined
+	err := workoutPlanCollection.FindOne(ctx, publishedPlanFilter(bson.M{"_id": workoutPlanID})).Decode(&workoutPlan)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return ErrWorkoutPlanNotFound
@@ -59,7 +61,12 @@ func (us *UserService) JoinWorkoutPlan(ctx context.Context, userID, workoutPlanI
 		return fmt.Errorf("error finding workout plan: %w", err)
 	}
 
+	if err := us.CheckEntitlement(ctx, userID, workoutPlan.AccessTier); err != nil {
+		return err
+	}
+
 	userWorkoutPlanStatus := models.NewUserWorkoutPlanStatus(userID, workoutPlanID, workoutPlan.Name)
+	userWorkoutPlanStatus.Version = workoutPlan.Version
 	if _, err := us.database.Collection("userWorkoutPlanStatus").InsertOne(ctx, userWorkoutPlanStatus); err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			return ErrAlreadyJoinded
@@ -128,7 +135,7 @@ func (us *UserService) MarkExerciseAsCompleted(ctx context.Context, userID, exer
     }
     
     update := bson.M{
-        "$set": bson.M{"completed": true},
+        "$set": bson.M{"completed": true, "completedAt": time.Now()},
         "$push": bson.M{"completedLogs": bson.M{"$each": logs}},
     }
 
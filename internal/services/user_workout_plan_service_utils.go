@@ -122,7 +122,7 @@ func (us *UserService) checkAndUpdateWeekStatus(ctx context.Context, userID, wor
 
 	if count == 0 {
 		filter := bson.M{"userId": userID, "workoutWeekId": workoutWeekID, "workoutPlanId": workoutPlanID}
-		update := bson.M{"$set": bson.M{"completed": true}}
+		update := bson.M{"$set": bson.M{"completed": true, "completedAt": time.Now()}}
 		if _, err := us.database.Collection("userWorkoutWeekStatus").UpdateOne(ctx, filter, update); err != nil {
 			return fmt.Errorf("error updating week status: %w", err)
 		}
@@ -7,12 +7,14 @@ import (
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func (us *UserService) GetWorkoutPlanByID(ctx context.Context, workoutPlanID primitive.ObjectID) (models.WorkoutPlan, error) {
 	workoutPlanCollection := us.database.Collection("workoutPlans")
 
-	filter := bson.M{"_id": workoutPlanID}
+	// Users who joined a plan keep following it once it is archived
+	filter := bson.M{"_id": workoutPlanID, "status": bson.M{"$nin": bson.A{models.PlanStatusDraft, models.PlanStatusInReview}}}
 	var workoutPlan models.WorkoutPlan
 	err := workoutPlanCollection.FindOne(ctx, filter).Decode(&workoutPlan)
 	if err != nil {
@@ -22,7 +24,47 @@ func (us *UserService) GetWorkoutPlanByID(ctx context.Context, workoutPlanID pri
 	return workoutPlan, nil
 }
 
-func  (uc *UserService) GetDailyExercisesByIDs(ctx context.Context, exercisesIDs []primitive.ObjectID) ([]models.Exercise, error) {
+// GetWorkoutPlanVersion returns the content of the workout plan as of the version an enrolled user follows, the
+// plan itself for enrollments that predate versions.
+func (us *UserService) GetWorkoutPlanVersion(ctx context.Context, workoutPlanID primitive.ObjectID, version int) (models.WorkoutPlan, error) {
+	if version == 0 {
+		return us.GetWorkoutPlanByID(ctx, workoutPlanID)
+	}
+
+	var workoutPlanVersion models.WorkoutPlanVersion
+	filter := bson.M{"workoutPlanId": workoutPlanID, "version": version}
+	if err := us.database.Collection("workoutPlanVersions").FindOne(ctx, filter).Decode(&workoutPlanVersion); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.WorkoutPlan{}, ErrWorkoutPlanNotFound
+		}
+		return models.WorkoutPlan{}, fmt.Errorf("error finding workout plan version: %w", err)
+	}
+
+	return workoutPlanVersion.Plan, nil
+}
+
+func  (uc *UserService) GetDailyExercisesByIDs(ctx context.Context, userID primitive.ObjectID, exercisesIDs []primitive.ObjectID) ([]models.Exercise, error) {
+	// Only the exercises of the plan version the user follows are served, it needs to be covered by their subscription
+	workoutPlan, err := uc.enrolledWorkoutPlan(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.CheckEntitlement(ctx, userID, workoutPlan.AccessTier); err != nil {
+		return nil, err
+	}
+
+	planExerciseIDs := map[primitive.ObjectID]bool{}
+	forEachWorkoutCircuit(workoutPlan, func(_ workoutCircuitKey, _ models.WorkoutWeek, _ models.WorkoutDay, circuit models.Circuit) {
+		for _, exerciseID := range circuit.ExerciseIDs {
+			planExerciseIDs[exerciseID] = true
+		}
+	})
+	for _, exerciseID := range exercisesIDs {
+		if !planExerciseIDs[exerciseID] {
+			return nil, fmt.Errorf("%w: %s", ErrExerciseNotInWorkoutPlan, exerciseID.Hex())
+		}
+	}
+
 	exerciseCollection := uc.database.Collection("exercises")
 
 	filter := bson.M{"_id": bson.M{"$in": exercisesIDs}}
@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Extensions Apple puts in the certificates signing App Store data, a chain from another Apple CA doesn't have them
+var (
+	appStoreLeafOID         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	appStoreIntermediateOID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+type appStoreJWSHeader struct {
+	Alg string   `json:"alg"`
+	X5C []string `json:"x5c"`
+}
+
+type appStoreTransaction struct {
+	TransactionID         string `json:"transactionId"`
+	OriginalTransactionID string `json:"originalTransactionId"`
+	BundleID              string `json:"bundleId"`
+	ProductID             string `json:"productId"`
+	ExpiresDate           int64  `json:"expiresDate"`
+	RevocationDate        int64  `json:"revocationDate"`
+	AppAccountToken       string `json:"appAccountToken"`
+	Environment           string `json:"environment"`
+}
+
+type appStoreNotification struct {
+	NotificationType string `json:"notificationType"`
+	Subtype          string `json:"subtype"`
+	NotificationUUID string `json:"notificationUUID"`
+	Data             struct {
+		BundleID              string `json:"bundleId"`
+		Environment           string `json:"environment"`
+		SignedTransactionInfo string `json:"signedTransactionInfo"`
+	} `json:"data"`
+}
+
+// StoreNotification is a server notification of a store about one of its subscriptions.
+type StoreNotification struct {
+	ID       string
+	Type     string
+	Purchase *StorePurchase // Nil for notifications that don't change the subscription
+}
+
+// AppStoreVerifier verifies the signed transactions and server notifications of the App Store offline, with the
+// certificate chain each of them carries.
+type AppStoreVerifier struct {
+	bundleID    string
+	environment string // "Production" or "Sandbox"
+	roots       *x509.CertPool
+	now         func() time.Time
+}
+
+func NewAppStoreVerifier(bundleID, environment string, roots *x509.CertPool) *AppStoreVerifier {
+	return &AppStoreVerifier{bundleID: bundleID, environment: environment, roots: roots, now: time.Now}
+}
+
+// LoadCertPool reads the PEM certificates of a file, e.g. the Apple root CAs.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificates: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	found := false
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate: %w", err)
+		}
+		pool.AddCert(cert)
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("no certificate in %s", path)
+	}
+
+	return pool, nil
+}
+
+// VerifyTransaction verifies a signed transaction the app got from StoreKit.
+func (v *AppStoreVerifier) VerifyTransaction(signedTransaction string) (*StorePurchase, error) {
+	var transaction appStoreTransaction
+	if err := v.verifyJWS(signedTransaction, &transaction); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStoreTransaction, err)
+	}
+	if transaction.BundleID != v.bundleID || transaction.Environment != v.environment {
+		return nil, fmt.Errorf("%w: transaction of %s in %s", ErrInvalidStoreTransaction, transaction.BundleID, transaction.Environment)
+	}
+	if transaction.OriginalTransactionID == "" || transaction.ExpiresDate == 0 {
+		return nil, fmt.Errorf("%w: not a subscription transaction", ErrInvalidStoreTransaction)
+	}
+
+	expiresAt := time.UnixMilli(transaction.ExpiresDate).UTC()
+	purchase := &StorePurchase{
+		Store:          StoreAppStore,
+		ProductID:      transaction.ProductID,
+		SubscriptionID: transaction.OriginalTransactionID,
+		State:          StorePurchaseActive,
+		ExpiresAt:      &expiresAt,
+		AccountID:      transaction.AppAccountToken,
+	}
+	if transaction.RevocationDate != 0 || !v.now().Before(expiresAt) {
+		purchase.State = StorePurchaseExpired
+	}
+
+	return purchase, nil
+}
+
+// VerifyNotification verifies an App Store Server Notification V2 and the transaction it contains.
+func (v *AppStoreVerifier) VerifyNotification(signedPayload string) (*StoreNotification, error) {
+	var payload appStoreNotification
+	if err := v.verifyJWS(signedPayload, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStoreNotification, err)
+	}
+	if payload.NotificationUUID == "" {
+		return nil, fmt.Errorf("%w: missing notification UUID", ErrInvalidStoreNotification)
+	}
+
+	notification := &StoreNotification{ID: payload.NotificationUUID, Type: payload.NotificationType}
+	state := appStoreNotificationState(payload.NotificationType, payload.Subtype)
+	if state == "" || payload.Data.SignedTransactionInfo == "" {
+		return notification, nil
+	}
+	if payload.Data.BundleID != v.bundleID || payload.Data.Environment != v.environment {
+		return nil, fmt.Errorf("%w: notification of %s in %s", ErrInvalidStoreNotification, payload.Data.BundleID, payload.Data.Environment)
+	}
+
+	purchase, err := v.VerifyTransaction(payload.Data.SignedTransactionInfo)
+	if err != nil {
+		return nil, err
+	}
+	// The transaction says when the period ends, the notification says what happened to the subscription
+	if state != stateFromTransaction {
+		purchase.State = state
+	}
+	notification.Purchase = purchase
+
+	return notification, nil
+}
+
+// stateFromTransaction keeps the state the transaction gives: active until it expires or is revoked
+const stateFromTransaction = "transaction"
+
+// appStoreNotificationState is the state a notification moves the subscription to, "" for the ones ignored.
+func appStoreNotificationState(notificationType, subtype string) string {
+	switch notificationType {
+	case "SUBSCRIBED", "DID_RENEW", "OFFER_REDEEMED", "DID_CHANGE_RENEWAL_PREF":
+		return stateFromTransaction
+	case "DID_FAIL_TO_RENEW":
+		return StorePurchasePastDue
+	case "DID_CHANGE_RENEWAL_STATUS":
+		if subtype == "AUTO_RENEW_DISABLED" {
+			return StorePurchaseCancelled
+		}
+		return stateFromTransaction
+	case "EXPIRED", "GRACE_PERIOD_EXPIRED", "REFUND", "REVOKE":
+		return StorePurchaseExpired
+	}
+	return ""
+}
+
+// verifyJWS checks the x5c chain up to a configured root and the ES256 signature made with its leaf, then decodes
+// the payload.
+func (v *AppStoreVerifier) verifyJWS(token string, payload interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed JWS")
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("malformed JWS header: %w", err)
+	}
+	var header appStoreJWSHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return fmt.Errorf("malformed JWS header: %w", err)
+	}
+	if header.Alg != jwt.SigningMethodES256.Alg() || len(header.X5C) < 2 {
+		return fmt.Errorf("unexpected JWS algorithm or certificate chain")
+	}
+
+	leaf, err := v.verifyChain(header.X5C)
+	if err != nil {
+		return err
+	}
+	publicKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("leaf certificate has no ECDSA key")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed JWS signature: %w", err)
+	}
+	if err := jwt.SigningMethodES256.Verify(parts[0]+"."+parts[1], signature, publicKey); err != nil {
+		return fmt.Errorf("invalid JWS signature: %w", err)
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("malformed JWS payload: %w", err)
+	}
+	if err := json.Unmarshal(rawPayload, payload); err != nil {
+		return fmt.Errorf("malformed JWS payload: %w", err)
+	}
+
+	return nil
+}
+
+func (v *AppStoreVerifier) verifyChain(x5c []string) (*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, len(x5c))
+	for i, encoded := range x5c {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("malformed certificate: %w", err)
+		}
+		if certs[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("malformed certificate: %w", err)
+		}
+	}
+
+	leaf, intermediate := certs[0], certs[1]
+	if !hasExtension(leaf, appStoreLeafOID) || !hasExtension(intermediate, appStoreIntermediateOID) {
+		return nil, fmt.Errorf("certificates are not App Store signing certificates")
+	}
+
+	// The root sent in the chain is ignored, only the configured ones are trusted
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("untrusted certificate chain: %w", err)
+	}
+
+	return leaf, nil
+}
+
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package utils
+
+import "strings"
+
+// CSVSafe keeps spreadsheets from running a value users or admins provided as a formula, exports escape every text
+// column with it.
+func CSVSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -14,11 +14,36 @@ var (
 	ErrTokenExpired = errors.New("token is expired")
 )
 
+// Token uses, so a token issued for one step of the auth flow can't be replayed in another.
+// Tokens issued before the claim existed have an empty TokenUse.
+const (
+	TokenUseAccess        = "access"
+	TokenUseRefresh       = "refresh"
+	TokenUseMFAChallenge  = "mfa_challenge"  // Password checked, waiting for the TOTP or recovery code
+	TokenUseMFAEnrollment = "mfa_enrollment" // Password checked, MFA is mandatory but not set up yet
+	TokenUseImpersonation = "impersonation"  // Access token of a user issued to a support admin
+)
+
+const (
+	AccessTokenLifetime        = 1 * time.Hour
+	RefreshTokenLifetime       = 168 * time.Hour // Sessions expire with their last refresh token
+	ImpersonationTokenLifetime = 15 * time.Minute
+)
+
 type Claims struct {
     jwt.RegisteredClaims
     UserId primitive.ObjectID `json:"userId"`
     Email  string             `json:"email"`
 	Role   string             `json:"role"`
+	TokenUse string           `json:"tokenUse,omitempty"`
+	SessionID string          `json:"sid,omitempty"` // Session of the login the token belongs to, empty for admins
+	ImpersonatorID *primitive.ObjectID `json:"impersonatorId,omitempty"` // Admin using the token, the impersonation ID is the token ID
+	ReadOnly       bool                `json:"readOnly,omitempty"`       // Impersonation tokens only, writes are refused
+}
+
+// IsImpersonation reports whether an admin uses the token on behalf of the user.
+func (c *Claims) IsImpersonation() bool {
+	return c.TokenUse == TokenUseImpersonation && c.ImpersonatorID != nil
 }
 
 type JWTService struct {
@@ -35,12 +60,14 @@ func NewJWTService(key string, handler JWTHandler) *JWTService {
     }
 }
 
-func (j *JWTService) GenerateAccessToken(userId primitive.ObjectID, email, role string) (string, error) {
-    accessTokenExp := time.Now().Add(1 * time.Hour) // Or use a configuration
+func (j *JWTService) GenerateAccessToken(userId primitive.ObjectID, email, role, sessionID string) (string, error) {
+    accessTokenExp := time.Now().Add(AccessTokenLifetime)
     claims := Claims{
         UserId: userId,
         Email:  email,
 		Role:   role,
+		TokenUse: TokenUseAccess,
+		SessionID: sessionID,
         RegisteredClaims: jwt.RegisteredClaims{
             ExpiresAt: jwt.NewNumericDate(accessTokenExp),
         },
@@ -48,12 +75,14 @@ func (j *JWTService) GenerateAccessToken(userId primitive.ObjectID, email, role
     return GenerateToken(j.signingMethod, claims, j.jwtSecretKey)
 }
 
-func (j *JWTService) GenerateRefreshToken(userId primitive.ObjectID, email, role string) (string, error) {
-    refreshTokenExp := time.Now().Add(168 * time.Hour) // Or use a configuration
+func (j *JWTService) GenerateRefreshToken(userId primitive.ObjectID, email, role, sessionID string) (string, error) {
+    refreshTokenExp := time.Now().Add(RefreshTokenLifetime)
     claims := Claims{
         UserId: userId,
         Email:  email,
 		Role:   role,
+		TokenUse: TokenUseRefresh,
+		SessionID: sessionID,
         RegisteredClaims: jwt.RegisteredClaims{
             ExpiresAt: jwt.NewNumericDate(refreshTokenExp),
         },
@@ -61,6 +90,38 @@ func (j *JWTService) GenerateRefreshToken(userId primitive.ObjectID, email, role
     return GenerateToken(j.signingMethod, claims, j.jwtSecretKey)
 }
 
+// GenerateChallengeToken issues a short-lived token only accepted by the MFA login steps.
+func (j *JWTService) GenerateChallengeToken(userId primitive.ObjectID, email, role, tokenUse string) (string, error) {
+	claims := Claims{
+		UserId:   userId,
+		Email:    email,
+		Role:     role,
+		TokenUse: tokenUse,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+		},
+	}
+	return GenerateToken(j.signingMethod, claims, j.jwtSecretKey)
+}
+
+// GenerateImpersonationToken issues a short-lived access token of the user for the admin, without session or
+// refresh token. Read-only tokens can't be used to change anything.
+func (j *JWTService) GenerateImpersonationToken(userId primitive.ObjectID, email string, impersonatorID primitive.ObjectID, impersonationID string, readOnly bool) (string, error) {
+	claims := Claims{
+		UserId:         userId,
+		Email:          email,
+		Role:           "user",
+		TokenUse:       TokenUseImpersonation,
+		ImpersonatorID: &impersonatorID,
+		ReadOnly:       readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        impersonationID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ImpersonationTokenLifetime)),
+		},
+	}
+	return GenerateToken(j.signingMethod, claims, j.jwtSecretKey)
+}
+
 func (j *JWTService) VerifyToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := j.handler.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"context"
+	"log"
+)
+
+// Notification types
+const (
+	NotificationRenewalReminder = "subscription.renewal_reminder"
+)
+
+// Notification is a message for a user, Data carries what the client needs to render or act on it.
+type Notification struct {
+	Type  string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Notifier delivers notifications to users.
+type Notifier interface {
+	Notify(ctx context.Context, userID string, notification Notification) error
+}
+
+// LogNotifier only logs notifications, it is used until a delivery channel (email, push) is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, userID string, notification Notification) error {
+	log.Printf("Notification %s for user %s: %s\n", notification.Type, userID, notification.Title)
+	return nil
+}
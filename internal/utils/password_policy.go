@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultPasswordMinLength = 8
+	DefaultPasswordMaxLength = 128 // Long enough for passphrases, short enough to bound the hashing cost
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooLong  = errors.New("password is too long")
+	ErrPasswordBreached = errors.New("password is too common, it appears in breached password lists")
+)
+
+//go:embed password_blocklist.txt
+var defaultPasswordBlocklist string
+
+// PasswordPolicy checks the passwords chosen at registration, lengths are counted in characters.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	blocklist map[string]struct{}
+}
+
+func NewPasswordPolicy(minLength, maxLength int, blocklist []string) *PasswordPolicy {
+	policy := &PasswordPolicy{MinLength: minLength, MaxLength: maxLength, blocklist: make(map[string]struct{}, len(blocklist))}
+	for _, password := range blocklist {
+		policy.blocklist[strings.ToLower(password)] = struct{}{}
+	}
+
+	return policy
+}
+
+// DefaultPasswordPolicy uses the default lengths and the blocklist shipped with the binary.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return NewPasswordPolicy(DefaultPasswordMinLength, DefaultPasswordMaxLength, DefaultPasswordBlocklist())
+}
+
+func (p *PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("%w, use at least %d characters", ErrPasswordTooShort, p.MinLength)
+	}
+	if length > p.MaxLength {
+		return fmt.Errorf("%w, use at most %d characters", ErrPasswordTooLong, p.MaxLength)
+	}
+	if _, breached := p.blocklist[strings.ToLower(password)]; breached {
+		return ErrPasswordBreached
+	}
+
+	return nil
+}
+
+func DefaultPasswordBlocklist() []string {
+	blocklist, _ := readPasswordBlocklist(strings.NewReader(defaultPasswordBlocklist))
+	return blocklist
+}
+
+// LoadPasswordBlocklist reads a local list of breached passwords, one per line. Empty lines and # comments are skipped.
+func LoadPasswordBlocklist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readPasswordBlocklist(file)
+}
+
+func readPasswordBlocklist(reader io.Reader) ([]string, error) {
+	var blocklist []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist = append(blocklist, line)
+	}
+
+	return blocklist, scanner.Err()
+}
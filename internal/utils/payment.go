@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrInvalidWebhookPayload   = errors.New("invalid webhook payload")
+)
+
+// Events of the payment provider the subscriptions follow, the others are acknowledged and ignored
+const (
+	PaymentEventCheckoutCompleted   = "checkout.session.completed"
+	PaymentEventInvoicePaid         = "invoice.paid"
+	PaymentEventPaymentFailed       = "invoice.payment_failed"
+	PaymentEventSubscriptionDeleted = "customer.subscription.deleted"
+)
+
+// StripeSignatureHeader carries the timestamp and signatures of a webhook delivery: t=<unix>,v1=<hex>[,v1=<hex>]
+const StripeSignatureHeader = "Stripe-Signature"
+
+// PaymentGateway is the payment provider subscriptions are billed through.
+type PaymentGateway interface {
+	// ParseWebhook verifies that a webhook delivery comes from the provider and decodes its event.
+	ParseWebhook(payload []byte, header http.Header) (*PaymentWebhookEvent, error)
+}
+
+// PaymentWebhookEvent is a provider event reduced to what the subscriptions need.
+type PaymentWebhookEvent struct {
+	ID             string
+	Type           string
+	CreatedAt      time.Time
+	UserID         string // Reference to the user passed when the checkout was created
+	CustomerID     string
+	SubscriptionID string
+	Plan           string         // Subscription plan bought at checkout or billed by an invoice, from the metadata
+	GiftCodeID     string         // Gift code paid by a one-time checkout, from the metadata
+	PeriodEnd      *time.Time     // End of the period paid by an invoice
+	Charge         *PaymentCharge // What a paid invoice or one-time checkout charged, nil for the other events
+}
+
+// PaymentCharge is a charge as the provider billed it. Amounts are in the smallest unit of the currency.
+type PaymentCharge struct {
+	InvoiceID     string // Invoice at the provider, empty for one-time checkouts
+	Number        string // Invoice number given by the provider
+	Currency      string // ISO 4217 code, upper case
+	Subtotal      int64
+	Tax           int64
+	Total         int64
+	CustomerName  string
+	CustomerEmail string
+	CustomerTaxID string
+	Lines         []PaymentChargeLine
+}
+
+type PaymentChargeLine struct {
+	Description string
+	Quantity    int64
+	Amount      int64
+	PeriodStart *time.Time
+	PeriodEnd   *time.Time
+}
+
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object stripeEventObject `json:"object"`
+	} `json:"data"`
+}
+
+type stripeEventObject struct {
+	ID                string            `json:"id"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	Customer          string            `json:"customer"`
+	Subscription      string            `json:"subscription"`
+	Metadata          map[string]string `json:"metadata"`
+	Lines             struct {
+		Data []stripeInvoiceLine `json:"data"`
+	} `json:"lines"`
+
+	// Invoices carry the metadata of the subscription they bill
+	SubscriptionDetails struct {
+		Metadata map[string]string `json:"metadata"`
+	} `json:"subscription_details"`
+
+	// Amounts of invoices
+	Number         string `json:"number"`
+	Currency       string `json:"currency"`
+	Subtotal       int64  `json:"subtotal"`
+	Tax            int64  `json:"tax"`
+	Total          int64  `json:"total"`
+	CustomerName   string `json:"customer_name"`
+	CustomerEmail  string `json:"customer_email"`
+	CustomerTaxIDs []struct {
+		Value string `json:"value"`
+	} `json:"customer_tax_ids"`
+
+	// Amounts of checkouts, only the one-time ones ("payment" mode) are charged by the checkout itself
+	Mode           string `json:"mode"`
+	AmountSubtotal int64  `json:"amount_subtotal"`
+	AmountTotal    int64  `json:"amount_total"`
+	TotalDetails   struct {
+		AmountTax int64 `json:"amount_tax"`
+	} `json:"total_details"`
+	CustomerDetails struct {
+		Name   string `json:"name"`
+		Email  string `json:"email"`
+		TaxIDs []struct {
+			Value string `json:"value"`
+		} `json:"tax_ids"`
+	} `json:"customer_details"`
+}
+
+type stripeInvoiceLine struct {
+	Description string `json:"description"`
+	Quantity    int64  `json:"quantity"`
+	Amount      int64  `json:"amount"`
+	Period      struct {
+		Start int64 `json:"start"`
+		End   int64 `json:"end"`
+	} `json:"period"`
+}
+
+// StripeGateway verifies webhooks signed the way Stripe signs them: HMAC-SHA256 of "<timestamp>.<payload>".
+type StripeGateway struct {
+	secret    []byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewStripeGateway verifies webhooks with the endpoint secret. Deliveries signed more than tolerance away from now
+// are rejected so a captured delivery can't be replayed later.
+func NewStripeGateway(secret string, tolerance time.Duration) *StripeGateway {
+	return &StripeGateway{secret: []byte(secret), tolerance: tolerance, now: time.Now}
+}
+
+func (g *StripeGateway) ParseWebhook(payload []byte, header http.Header) (*PaymentWebhookEvent, error) {
+	if err := g.verifySignature(payload, header.Get(StripeSignatureHeader)); err != nil {
+		return nil, err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, fmt.Errorf("%w: missing event ID or type", ErrInvalidWebhookPayload)
+	}
+
+	object := event.Data.Object
+	parsed := &PaymentWebhookEvent{
+		ID:             event.ID,
+		Type:           event.Type,
+		CreatedAt:      time.Unix(event.Created, 0).UTC(),
+		UserID:         object.ClientReferenceID,
+		CustomerID:     object.Customer,
+		SubscriptionID: object.Subscription,
+		Plan:           object.Metadata["plan"],
+		GiftCodeID:     object.Metadata["giftCodeId"],
+	}
+	if parsed.Plan == "" {
+		parsed.Plan = object.SubscriptionDetails.Metadata["plan"]
+	}
+	// The object of subscription events is the subscription itself
+	if event.Type == PaymentEventSubscriptionDeleted {
+		parsed.SubscriptionID = object.ID
+	}
+	for _, line := range object.Lines.Data {
+		if line.Period.End == 0 {
+			continue
+		}
+		periodEnd := time.Unix(line.Period.End, 0).UTC()
+		if parsed.PeriodEnd == nil || periodEnd.After(*parsed.PeriodEnd) {
+			parsed.PeriodEnd = &periodEnd
+		}
+	}
+	parsed.Charge = stripeCharge(event.Type, object)
+
+	return parsed, nil
+}
+
+// stripeCharge reads what a paid invoice or a one-time checkout charged. Subscription checkouts are charged by
+// their first invoice, which has its own event.
+func stripeCharge(eventType string, object stripeEventObject) *PaymentCharge {
+	if object.Currency == "" {
+		return nil
+	}
+
+	charge := &PaymentCharge{Currency: strings.ToUpper(object.Currency)}
+	switch {
+	case eventType == PaymentEventInvoicePaid:
+		charge.InvoiceID = object.ID
+		charge.Number = object.Number
+		charge.Subtotal = object.Subtotal
+		charge.Tax = object.Tax
+		charge.Total = object.Total
+		charge.CustomerName = object.CustomerName
+		charge.CustomerEmail = object.CustomerEmail
+		if len(object.CustomerTaxIDs) > 0 {
+			charge.CustomerTaxID = object.CustomerTaxIDs[0].Value
+		}
+		for _, line := range object.Lines.Data {
+			chargeLine := PaymentChargeLine{Description: line.Description, Quantity: line.Quantity, Amount: line.Amount}
+			if line.Period.Start != 0 && line.Period.End != 0 {
+				periodStart, periodEnd := time.Unix(line.Period.Start, 0).UTC(), time.Unix(line.Period.End, 0).UTC()
+				chargeLine.PeriodStart, chargeLine.PeriodEnd = &periodStart, &periodEnd
+			}
+			charge.Lines = append(charge.Lines, chargeLine)
+		}
+	case eventType == PaymentEventCheckoutCompleted && object.Mode == "payment":
+		charge.Subtotal = object.AmountSubtotal
+		charge.Tax = object.TotalDetails.AmountTax
+		charge.Total = object.AmountTotal
+		charge.CustomerName = object.CustomerDetails.Name
+		charge.CustomerEmail = object.CustomerDetails.Email
+		if len(object.CustomerDetails.TaxIDs) > 0 {
+			charge.CustomerTaxID = object.CustomerDetails.TaxIDs[0].Value
+		}
+	default:
+		return nil
+	}
+
+	return charge
+}
+
+func (g *StripeGateway) verifySignature(payload []byte, header string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: missing timestamp or signature", ErrInvalidWebhookSignature)
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidWebhookSignature)
+	}
+	if age := g.now().Sub(time.Unix(signedAt, 0)); age > g.tolerance || age < -g.tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidWebhookSignature)
+	}
+
+	expected := SignWebhookPayload(g.secret, timestamp, payload)
+	for _, signature := range signatures {
+		// Several signatures are sent while the endpoint secret is rolled
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
+
+// SignWebhookPayload is the hex HMAC-SHA256 of "<timestamp>.<payload>" with the endpoint secret.
+func SignWebhookPayload(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	playAPIScope        = "https://www.googleapis.com/auth/androidpublisher"
+	playMaxResponseSize = 1 << 20
+)
+
+// PlayServiceAccount is the part of a Google service account key file used to call the Play Developer API.
+type PlayServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// LoadPlayServiceAccount reads a service account key file downloaded from Google Cloud.
+func LoadPlayServiceAccount(path string) (*PlayServiceAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account: %w", err)
+	}
+
+	var account PlayServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("error parsing service account: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, fmt.Errorf("service account needs client_email, private_key and token_uri")
+	}
+
+	return &account, nil
+}
+
+type playSubscriptionPurchase struct {
+	SubscriptionState          string `json:"subscriptionState"`
+	LinkedPurchaseToken        string `json:"linkedPurchaseToken"`
+	AcknowledgementState       string `json:"acknowledgementState"`
+	ExternalAccountIdentifiers struct {
+		ObfuscatedExternalAccountID string `json:"obfuscatedExternalAccountId"`
+	} `json:"externalAccountIdentifiers"`
+	LineItems []struct {
+		ProductID  string    `json:"productId"`
+		ExpiryTime time.Time `json:"expiryTime"`
+	} `json:"lineItems"`
+}
+
+type playPushMessage struct {
+	Message struct {
+		Data      string `json:"data"`
+		MessageID string `json:"messageId"`
+	} `json:"message"`
+}
+
+type playDeveloperNotification struct {
+	PackageName              string `json:"packageName"`
+	SubscriptionNotification *struct {
+		NotificationType int    `json:"notificationType"`
+		PurchaseToken    string `json:"purchaseToken"`
+	} `json:"subscriptionNotification"`
+}
+
+// PlayVerifier checks Play purchase tokens with the Play Developer API, tokens can't be verified offline.
+type PlayVerifier struct {
+	packageName string
+	apiURL      string
+	account     *PlayServiceAccount
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewPlayVerifier calls the API at apiURL, https://androidpublisher.googleapis.com outside tests, as the service account.
+func NewPlayVerifier(packageName, apiURL string, account *PlayServiceAccount, client *http.Client) (*PlayVerifier, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing service account key: %w", err)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &PlayVerifier{packageName: packageName, apiURL: strings.TrimSuffix(apiURL, "/"), account: account, key: key, client: client}, nil
+}
+
+// VerifyPurchase reads the current state of the subscription bought with the purchase token.
+func (v *PlayVerifier) VerifyPurchase(ctx context.Context, purchaseToken string) (*StorePurchase, error) {
+	endpoint := fmt.Sprintf("%s/androidpublisher/v3/applications/%s/purchases/subscriptionsv2/tokens/%s", v.apiURL, url.PathEscape(v.packageName), url.PathEscape(purchaseToken))
+	var subscription playSubscriptionPurchase
+	if err := v.call(ctx, http.MethodGet, endpoint, &subscription); err != nil {
+		return nil, err
+	}
+	if len(subscription.LineItems) == 0 {
+		return nil, fmt.Errorf("%w: purchase has no subscription", ErrInvalidStoreTransaction)
+	}
+
+	item := subscription.LineItems[0]
+	expiresAt := item.ExpiryTime.UTC()
+	purchase := &StorePurchase{
+		Store:                StorePlay,
+		ProductID:            item.ProductID,
+		SubscriptionID:       purchaseToken,
+		LinkedSubscriptionID: subscription.LinkedPurchaseToken,
+		ExpiresAt:            &expiresAt,
+		AccountID:            subscription.ExternalAccountIdentifiers.ObfuscatedExternalAccountID,
+		Unacknowledged:       subscription.AcknowledgementState == "ACKNOWLEDGEMENT_STATE_PENDING",
+	}
+
+	switch subscription.SubscriptionState {
+	case "SUBSCRIPTION_STATE_ACTIVE":
+		purchase.State = StorePurchaseActive
+	case "SUBSCRIPTION_STATE_IN_GRACE_PERIOD":
+		purchase.State = StorePurchasePastDue
+	case "SUBSCRIPTION_STATE_CANCELED":
+		purchase.State = StorePurchaseCancelled
+	case "SUBSCRIPTION_STATE_PENDING":
+		return nil, ErrStorePurchasePending
+	default:
+		// Expired, on hold and paused subscriptions give no access until they are recovered
+		purchase.State = StorePurchaseExpired
+	}
+
+	return purchase, nil
+}
+
+// Acknowledge confirms the purchase to Play, which refunds the purchases left unacknowledged.
+func (v *PlayVerifier) Acknowledge(ctx context.Context, productID, purchaseToken string) error {
+	endpoint := fmt.Sprintf("%s/androidpublisher/v3/applications/%s/purchases/subscriptions/%s/tokens/%s:acknowledge", v.apiURL, url.PathEscape(v.packageName), url.PathEscape(productID), url.PathEscape(purchaseToken))
+	return v.call(ctx, http.MethodPost, endpoint, nil)
+}
+
+// ParseNotification reads a real-time developer notification pushed by Pub/Sub. The notification only says which
+// purchase changed, its state is read again with VerifyPurchase. The token is empty for other notifications.
+func (v *PlayVerifier) ParseNotification(body []byte) (messageID, purchaseToken string, err error) {
+	var push playPushMessage
+	if err := json.Unmarshal(body, &push); err != nil || push.Message.MessageID == "" {
+		return "", "", fmt.Errorf("%w: not a Pub/Sub push message", ErrInvalidStoreNotification)
+	}
+	data, err := base64.StdEncoding.DecodeString(push.Message.Data)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidStoreNotification, err)
+	}
+
+	var notification playDeveloperNotification
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrInvalidStoreNotification, err)
+	}
+	if notification.PackageName != v.packageName {
+		return "", "", fmt.Errorf("%w: notification of %s", ErrInvalidStoreNotification, notification.PackageName)
+	}
+	if notification.SubscriptionNotification == nil {
+		return push.Message.MessageID, "", nil
+	}
+
+	return push.Message.MessageID, notification.SubscriptionNotification.PurchaseToken, nil
+}
+
+func (v *PlayVerifier) call(ctx context.Context, method, endpoint string, out interface{}) error {
+	accessToken, err := v.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling Play Developer API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, playMaxResponseSize))
+	if err != nil {
+		return fmt.Errorf("error reading Play Developer API response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrStorePurchaseNotFound
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", ErrInvalidStoreTransaction, body)
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent:
+		return fmt.Errorf("Play Developer API returned %d: %s", resp.StatusCode, body)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error decoding Play Developer API response: %w", err)
+	}
+	return nil
+}
+
+// token returns an access token of the service account, a new one is requested shortly before the last expires.
+func (v *PlayVerifier) token(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.accessToken != "" && time.Now().Before(v.expiresAt) {
+		return v.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   v.account.ClientEmail,
+		"scope": playAPIScope,
+		"aud":   v.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(v.key)
+	if err != nil {
+		return "", fmt.Errorf("error signing service account assertion: %w", err)
+	}
+
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting Play access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, playMaxResponseSize)).Decode(&tokenResponse); err != nil || resp.StatusCode != http.StatusOK || tokenResponse.AccessToken == "" {
+		return "", fmt.Errorf("Play access token request failed with status %d", resp.StatusCode)
+	}
+
+	v.accessToken = tokenResponse.AccessToken
+	v.expiresAt = now.Add(time.Duration(tokenResponse.ExpiresIn)*time.Second - time.Minute)
+	return v.accessToken, nil
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequestMetadata describes who made a request and where it came from, services read it to attribute their changes.
+type RequestMetadata struct {
+	ActorID   primitive.ObjectID
+	ActorRole string
+	RequestID string
+	IP        string
+}
+
+type requestMetadataKey struct{}
+
+func WithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, metadata)
+}
+
+// RequestMetadataFromContext returns the metadata stored by the request middlewares, or an empty value outside of a request.
+func RequestMetadataFromContext(ctx context.Context) RequestMetadata {
+	metadata, _ := ctx.Value(requestMetadataKey{}).(RequestMetadata)
+	return metadata
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidStoreTransaction  = errors.New("invalid store transaction")
+	ErrInvalidStoreNotification = errors.New("invalid store notification")
+	ErrStorePurchaseNotFound    = errors.New("store purchase not found")
+	ErrStorePurchasePending     = errors.New("store purchase is not paid yet")
+)
+
+// App stores subscriptions are bought through
+const (
+	StoreAppStore = "app_store"
+	StorePlay     = "play_store"
+)
+
+// State of a store subscription, they match the subscription statuses they move to
+const (
+	StorePurchaseActive    = "active"
+	StorePurchasePastDue   = "past_due"
+	StorePurchaseCancelled = "cancelled" // Won't renew, access stays until ExpiresAt
+	StorePurchaseExpired   = "expired"   // Ended, refunded or revoked
+)
+
+// StorePurchase is a subscription bought in an app store, as verified with the store.
+type StorePurchase struct {
+	Store                string
+	ProductID            string
+	SubscriptionID       string // Stays the same across renewals: original transaction ID or purchase token
+	LinkedSubscriptionID string // Purchase token the subscription replaced after an upgrade or downgrade on Play
+	State                string
+	ExpiresAt            *time.Time
+	AccountID            string // Account reference the app attached to the purchase, when it did
+	Unacknowledged       bool   // Play refunds purchases that aren't acknowledged within three days
+}
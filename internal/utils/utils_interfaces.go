@@ -9,12 +9,15 @@ import (
 type HashPasswordService interface {
 	HashPassword(password string) (string, error)
 	CheckPasswordHash(password, hash string) bool
+	NeedsRehash(hash string) bool
 }
 
 // var _ TokenService = (*JWTService)(nil)
 type TokenService interface {
-    GenerateAccessToken(userId primitive.ObjectID, email, role string) (string, error)
-    GenerateRefreshToken(userId primitive.ObjectID, email, role string) (string, error)
+    GenerateAccessToken(userId primitive.ObjectID, email, role, sessionID string) (string, error)
+    GenerateRefreshToken(userId primitive.ObjectID, email, role, sessionID string) (string, error)
+    GenerateChallengeToken(userId primitive.ObjectID, email, role, tokenUse string) (string, error)
+    GenerateImpersonationToken(userId primitive.ObjectID, email string, impersonatorID primitive.ObjectID, impersonationID string, readOnly bool) (string, error)
     VerifyToken(tokenString string) (*Claims, error)
 }
 
@@ -532,10 +532,10 @@ func main() {
 			},
 		},
 		{
-			Name:       "Update User Subscription",
+			Name:       "Request User Subscription Change",
 			Method:     "PUT",
 			Path:       "/api/v1/user/subscription",
-			Description: "Update the user's subscription information",
+			Description: "Request an upgrade or downgrade of the user's plan",
 			Headers: []RouteHeader{
 				{
 					Key:   "Content-Type",
@@ -4,6 +4,7 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/GhostDrew11/vigor-api/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -21,9 +22,26 @@ func TestLoadConfigSuccess(t *testing.T) {
 	defer os.Unsetenv("JWT_SECRET_KEY")
 
 	want := &config.Config{
-		MongoDBURI:   "mongodb://localhost:27017",
-		DatabaseName: "Vigor_Test",
-		JWTSecretKey: "VigorSuperSecretKey",
+		MongoDBURI:       "mongodb://localhost:27017",
+		DatabaseName:     "Vigor_Test",
+		JWTSecretKey:     "VigorSuperSecretKey",
+		RateLimitStore:   "memory",
+		AdminMFARequired: true,
+		MFAIssuer:        "Vigor",
+		PasswordHashAlgorithm: "argon2id",
+		Argon2MemoryKiB:       19456,
+		Argon2Iterations:      2,
+		Argon2Parallelism:     1,
+		BcryptCost:            10,
+		PasswordMinLength:     8,
+		PasswordMaxLength:     128,
+		TrialDays:             14,
+		PaymentWebhookTolerance: 5 * time.Minute,
+		AppStoreEnvironment:     "Production",
+		PlayAPIURL:              "https://androidpublisher.googleapis.com",
+		GiftCurrency:            "EUR",
+		SchedulerEnabled:        true,
+		InvoiceCompanyName:      "Vigor",
 	}
 
 	got, err := config.LoadConfig()
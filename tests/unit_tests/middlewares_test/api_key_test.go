@@ -0,0 +1,70 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type staticAPIKeys map[string]*models.APIKey
+
+func (k staticAPIKeys) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	return k[key], nil
+}
+
+func newCatalogRouter(ts utils.TokenService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	keys := staticAPIKeys{
+		"exercises-key": {ID: primitive.NewObjectID(), Scopes: []string{models.PermExercisesRead}},
+	}
+
+	router.Use(middlewares.RequireAPIKeyOrRole(ts, nil, keys, "user"))
+	router.GET("/exercises", middlewares.RequireScope(models.PermExercisesRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	})
+	router.GET("/meals", middlewares.RequireScope(models.PermMealsRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	})
+
+	return router
+}
+
+func serveWithHeader(router *gin.Engine, path, header, value string) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set(header, value)
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRequireAPIKeyOrRoleWithAPIKey(t *testing.T) {
+	mockJWTService := new(MockJWTService)
+	router := newCatalogRouter(mockJWTService)
+
+	assert.Equal(t, http.StatusOK, serveWithHeader(router, "/exercises", middlewares.APIKeyHeader, "exercises-key"))
+	assert.Equal(t, http.StatusForbidden, serveWithHeader(router, "/meals", middlewares.APIKeyHeader, "exercises-key"), "the key has no meals scope")
+	assert.Equal(t, http.StatusUnauthorized, serveWithHeader(router, "/exercises", middlewares.APIKeyHeader, "revoked-key"))
+	mockJWTService.AssertNotCalled(t, "VerifyToken", "exercises-key")
+}
+
+func TestRequireAPIKeyOrRoleWithAccessToken(t *testing.T) {
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "userToken").Return(&utils.Claims{UserId: primitive.NewObjectID(), Role: "user"}, nil)
+	mockJWTService.On("VerifyToken", "adminToken").Return(&utils.Claims{UserId: primitive.NewObjectID(), Role: "admin"}, nil)
+	router := newCatalogRouter(mockJWTService)
+
+	// Scopes only restrict API keys
+	assert.Equal(t, http.StatusOK, serveWithHeader(router, "/meals", "Authorization", "Bearer userToken"))
+	assert.Equal(t, http.StatusForbidden, serveWithHeader(router, "/meals", "Authorization", "Bearer adminToken"))
+	assert.Equal(t, http.StatusUnauthorized, serveWithHeader(router, "/meals", "Authorization", ""))
+}
@@ -1,6 +1,7 @@
 package middlewares_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -21,14 +22,14 @@ type MockJWTService struct {
 	utils.TokenService
 }
 
-func (m *MockJWTService) GenerateAccessToken(userId primitive.ObjectID, email, role string) (string, error) {
-	args := m.Called(userId, email, role)
+func (m *MockJWTService) GenerateAccessToken(userId primitive.ObjectID, email, role, sessionID string) (string, error) {
+	args := m.Called(userId, email, role, sessionID)
 	// tokenStr, _ := args.String(0)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockJWTService) GenerateRefreshToken(userId primitive.ObjectID, email, role string ) (string, error) {
-	args := m.Called(userId, email, role)
+func (m *MockJWTService) GenerateRefreshToken(userId primitive.ObjectID, email, role, sessionID string) (string, error) {
+	args := m.Called(userId, email, role, sessionID)
 	return args.String(0), args.Error(1)
 }
 
@@ -59,7 +60,7 @@ func TestRequireRoleMiddlewareSuccess(t *testing.T) {
 
 	mockJWTService.On("VerifyToken", tokenString).Return(claims, nil)
 
-	router.Use(middlewares.RequireRole(mockJWTService, "user"))
+	router.Use(middlewares.RequireRole(mockJWTService, nil, "user"))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
 	})
@@ -80,7 +81,7 @@ func TestRequireRoleMiddlewareFailureEmptyToken(t *testing.T) {
 
 	mockJWTService := new(MockJWTService)
 
-	router.Use(middlewares.RequireRole(mockJWTService, "user"))
+	router.Use(middlewares.RequireRole(mockJWTService, nil, "user"))
 	router.GET("/test", func(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{"message": "Should not get here"})
     })
@@ -101,7 +102,7 @@ func TestRequireRoleMiddlewareFailureTokenVerification(t *testing.T) {
 
 	mockJWTService.On("VerifyToken", invalidTokenString).Return(nil, utils.ErrInvalidToken)
 
-	router.Use(middlewares.RequireRole(mockJWTService, "user"))
+	router.Use(middlewares.RequireRole(mockJWTService, nil, "user"))
 	router.GET("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "Should not reach"})
 	})
@@ -137,7 +138,7 @@ func TestRequireRoleMiddlewareFailureRoleMismatch(t *testing.T) {
 
 	mockJWTService.On("VerifyToken", tokenString).Return(claims, nil)
 	
-	router.Use(middlewares.RequireRole(mockJWTService, "user", "superuser"))
+	router.Use(middlewares.RequireRole(mockJWTService, nil, "user", "superuser"))
 	router.GET("/test", func(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{"message": "Should not get here"})
     })
@@ -161,7 +162,6 @@ func TestRefreshHandlerMiddlewareSuccess(t *testing.T) {
 
 	refreshToken := "refreshTokenDummy"
 	newAccessToken := "newAccessTokenDummy"
-	newRefreshToken := "newRefreshTokenDummy"
 
 	claims := &utils.Claims{
 		UserId: userId,
@@ -173,10 +173,9 @@ func TestRefreshHandlerMiddlewareSuccess(t *testing.T) {
 	}
 
 	mockJWTService.On("VerifyToken", refreshToken).Return(claims, nil)
-	mockJWTService.On("GenerateAccessToken", userId, email, role).Return(newAccessToken, nil)
-	mockJWTService.On("GenerateRefreshToken", userId, email, role).Return(newRefreshToken, nil)
+	mockJWTService.On("GenerateAccessToken", userId, email, role, "").Return(newAccessToken, nil)
 
-	router.POST("/refresh", middlewares.RefreshHandler(mockJWTService))
+	router.POST("/refresh", middlewares.RefreshAccessTokenHandler(mockJWTService, nil))
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/refresh", nil)
@@ -186,6 +185,42 @@ func TestRefreshHandlerMiddlewareSuccess(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), newAccessToken)
+	mockJWTService.AssertExpectations(t)
+}
+
+func TestRenewRefreshTokenHandlerSuccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockJWTService := new(MockJWTService)
+	userId := primitive.NewObjectID()
+	email := "test@example.com"
+	role := "admin"
+
+	refreshToken := "refreshTokenDummy"
+	newRefreshToken := "newRefreshTokenDummy"
+
+	claims := &utils.Claims{
+		UserId: userId,
+		Email:  email,
+		Role:  role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+		},
+	}
+
+	mockJWTService.On("VerifyToken", refreshToken).Return(claims, nil)
+	mockJWTService.On("GenerateRefreshToken", userId, email, role, "").Return(newRefreshToken, nil)
+
+	router.POST("/renew", middlewares.RenewRefreshTokenHandler(mockJWTService, nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/renew", nil)
+	req.Header.Set("Refresh-Token", refreshToken)
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), newRefreshToken)
 	mockJWTService.AssertExpectations(t)
 }
@@ -196,7 +231,7 @@ func TestRefreshHandlerMiddlewareFailureEmptyToken(t *testing.T) {
 
 	mockJWTService := new(MockJWTService)
 
-	router.POST("/refresh", middlewares.RefreshHandler(mockJWTService))
+	router.POST("/refresh", middlewares.RefreshAccessTokenHandler(mockJWTService, nil))
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/refresh", nil)
@@ -214,7 +249,7 @@ func TestRefreshHandlerMiddlewareFailureTokenVerification(t *testing.T) {
 
     mockJWTService.On("VerifyToken", invalidRefreshToken).Return(nil, utils.ErrInvalidToken)
 
-    router.POST("/refresh", middlewares.RefreshHandler(mockJWTService))
+    router.POST("/refresh", middlewares.RefreshAccessTokenHandler(mockJWTService, nil))
 
     w := httptest.NewRecorder()
     req, _ := http.NewRequest("POST", "/refresh", nil)
@@ -246,9 +281,9 @@ func TestRefreshHandlerMiddlewareFailureTokenGeneration(t *testing.T) {
 	}
 
 	mockJWTService.On("VerifyToken", refreshToken).Return(claims, nil)
-	mockJWTService.On("GenerateAccessToken", userId, email, role).Return("", errors.New("Unable to generate access token"))
+	mockJWTService.On("GenerateAccessToken", userId, email, role, "").Return("", errors.New("Unable to generate access token"))
 
-	router.POST("/refresh", middlewares.RefreshHandler(mockJWTService))
+	router.POST("/refresh", middlewares.RefreshAccessTokenHandler(mockJWTService, nil))
 
 	w := httptest.NewRecorder()
     req, _ := http.NewRequest("POST", "/refresh", nil)
@@ -260,3 +295,232 @@ func TestRefreshHandlerMiddlewareFailureTokenGeneration(t *testing.T) {
     mockJWTService.AssertExpectations(t)
 }
 
+
+func TestRequireRoleMiddlewareRejectsMFAChallengeToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockJWTService := new(MockJWTService)
+	claims := &utils.Claims{
+		UserId:   primitive.NewObjectID(),
+		Email:    "admin@vigor.com",
+		Role:     "admin",
+		TokenUse: utils.TokenUseMFAChallenge,
+	}
+	mockJWTService.On("VerifyToken", "challengeToken").Return(claims, nil)
+
+	router.Use(middlewares.RequireRole(mockJWTService, nil, "admin"))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer challengeToken")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequireChallengeTokenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "challengeToken").Return(&utils.Claims{
+		UserId:   primitive.NewObjectID(),
+		Email:    "admin@vigor.com",
+		Role:     "admin",
+		TokenUse: utils.TokenUseMFAChallenge,
+	}, nil)
+	mockJWTService.On("VerifyToken", "accessToken").Return(&utils.Claims{
+		UserId:   primitive.NewObjectID(),
+		Email:    "admin@vigor.com",
+		Role:     "admin",
+		TokenUse: utils.TokenUseAccess,
+	}, nil)
+
+	router.POST("/login/mfa", middlewares.RequireChallengeToken(mockJWTService, utils.TokenUseMFAChallenge, "admin"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"email": c.GetString("email")})
+	})
+
+	for token, want := range map[string]int{"challengeToken": http.StatusOK, "accessToken": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/login/mfa", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code, token)
+	}
+}
+
+func TestRefreshHandlerRejectsAccessToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "accessToken").Return(&utils.Claims{
+		UserId:   primitive.NewObjectID(),
+		Email:    "test@example.com",
+		Role:     "user",
+		TokenUse: utils.TokenUseAccess,
+	}, nil)
+
+	router.POST("/refresh", middlewares.RefreshAccessTokenHandler(mockJWTService, nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/refresh", nil)
+	req.Header.Set("Refresh-Token", "accessToken")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockJWTService.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+type MockSessionChecker struct {
+	mock.Mock
+}
+
+func (m *MockSessionChecker) IsSessionActive(ctx context.Context, userID primitive.ObjectID, sessionID string) (bool, error) {
+	args := m.Called(userID, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionChecker) GetAccountRestriction(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSessionChecker) CheckSessionRefreshToken(ctx context.Context, userID primitive.ObjectID, sessionID, refreshToken string) (bool, error) {
+	args := m.Called(userID, sessionID, refreshToken)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionChecker) RotateSessionRefreshToken(ctx context.Context, userID primitive.ObjectID, sessionID, currentToken, newToken string) (bool, error) {
+	args := m.Called(userID, sessionID, currentToken, newToken)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestRequireRoleMiddlewareRejectsRevokedSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := primitive.NewObjectID()
+	sessionId := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name     string
+		claims   *utils.Claims
+		active   bool
+		expected int
+	}{
+		{"active session", &utils.Claims{UserId: userId, Role: "user", SessionID: sessionId}, true, http.StatusOK},
+		{"revoked session", &utils.Claims{UserId: userId, Role: "user", SessionID: sessionId}, false, http.StatusUnauthorized},
+		{"user token without session", &utils.Claims{UserId: userId, Role: "user"}, false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockJWTService := new(MockJWTService)
+			mockJWTService.On("VerifyToken", "accessToken").Return(tt.claims, nil)
+			sessions := new(MockSessionChecker)
+			sessions.On("IsSessionActive", userId, tt.claims.SessionID).Return(tt.active, nil)
+			if tt.active {
+				sessions.On("GetAccountRestriction", userId).Return("", nil)
+			}
+
+			router := gin.New()
+			router.Use(middlewares.RequireRole(mockJWTService, sessions, "user"))
+			router.GET("/test", func(c *gin.Context) {
+				assert.Equal(t, sessionId, c.GetString("sessionId"))
+				c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer accessToken")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			sessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRequireRoleMiddlewareRejectsRestrictedUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := primitive.NewObjectID()
+	sessionId := primitive.NewObjectID().Hex()
+
+	for _, restriction := range []string{"suspended", "banned"} {
+		t.Run(restriction, func(t *testing.T) {
+			mockJWTService := new(MockJWTService)
+			mockJWTService.On("VerifyToken", "accessToken").Return(&utils.Claims{UserId: userId, Role: "user", SessionID: sessionId}, nil)
+			sessions := new(MockSessionChecker)
+			sessions.On("IsSessionActive", userId, sessionId).Return(true, nil)
+			sessions.On("GetAccountRestriction", userId).Return(restriction, nil)
+
+			router := gin.New()
+			router.Use(middlewares.RequireRole(mockJWTService, sessions, "user"))
+			router.GET("/test", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer accessToken")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "Account "+restriction)
+			sessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRenewRefreshTokenHandlerRotatesSessionToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := primitive.NewObjectID()
+	sessionId := primitive.NewObjectID().Hex()
+	claims := &utils.Claims{UserId: userId, Email: "test@example.com", Role: "user", SessionID: sessionId, TokenUse: utils.TokenUseRefresh}
+
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "refreshToken").Return(claims, nil)
+	mockJWTService.On("GenerateRefreshToken", userId, claims.Email, claims.Role, sessionId).Return("newRefreshToken", nil)
+	sessions := new(MockSessionChecker)
+	sessions.On("CheckSessionRefreshToken", userId, sessionId, "refreshToken").Return(true, nil)
+	sessions.On("RotateSessionRefreshToken", userId, sessionId, "refreshToken", "newRefreshToken").Return(true, nil)
+
+	router := gin.New()
+	router.POST("/renew", middlewares.RenewRefreshTokenHandler(mockJWTService, sessions))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/renew", nil)
+	req.Header.Set("Refresh-Token", "refreshToken")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "newRefreshToken")
+	sessions.AssertExpectations(t)
+}
+
+func TestRefreshHandlerRejectsReusedSessionToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userId := primitive.NewObjectID()
+	sessionId := primitive.NewObjectID().Hex()
+	claims := &utils.Claims{UserId: userId, Role: "user", SessionID: sessionId, TokenUse: utils.TokenUseRefresh}
+
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "rotatedToken").Return(claims, nil)
+	sessions := new(MockSessionChecker)
+	sessions.On("CheckSessionRefreshToken", userId, sessionId, "rotatedToken").Return(false, nil)
+
+	router := gin.New()
+	router.POST("/refresh", middlewares.RefreshAccessTokenHandler(mockJWTService, sessions))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/refresh", nil)
+	req.Header.Set("Refresh-Token", "rotatedToken")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockJWTService.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
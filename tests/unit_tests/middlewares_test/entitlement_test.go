@@ -0,0 +1,48 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type staticAccessTiers map[primitive.ObjectID]string
+
+func (t staticAccessTiers) GetAccessTier(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	return t[userID], nil
+}
+
+func TestLoadAccessTier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	premiumUserID := primitive.NewObjectID()
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "premiumToken").Return(&utils.Claims{UserId: premiumUserID, Role: "user"}, nil)
+	mockJWTService.On("VerifyToken", "expiredToken").Return(&utils.Claims{UserId: primitive.NewObjectID(), Role: "user"}, nil)
+	keys := staticAPIKeys{"meals-key": {ID: primitive.NewObjectID(), Scopes: []string{models.PermMealsRead}}}
+
+	var tier interface{}
+	var loaded bool
+	router := gin.New()
+	router.Use(middlewares.RequireAPIKeyOrRole(mockJWTService, nil, keys, "user"), middlewares.LoadAccessTier(staticAccessTiers{premiumUserID: models.AccessTierPremium}))
+	router.GET("/meals", func(c *gin.Context) {
+		tier, loaded = c.Get("accessTier")
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	})
+
+	assert.Equal(t, http.StatusOK, serveWithHeader(router, "/meals", "Authorization", "Bearer premiumToken"))
+	assert.Equal(t, models.AccessTierPremium, tier)
+
+	assert.Equal(t, http.StatusOK, serveWithHeader(router, "/meals", "Authorization", "Bearer expiredToken"))
+	assert.Equal(t, "", tier, "users without a subscription get no tier")
+
+	// Integrations aren't limited by a subscription
+	assert.Equal(t, http.StatusOK, serveWithHeader(router, "/meals", middlewares.APIKeyHeader, "meals-key"))
+	assert.False(t, loaded)
+}
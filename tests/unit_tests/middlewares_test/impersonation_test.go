@@ -0,0 +1,112 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type impersonationRecorder struct {
+	requests []models.ImpersonatedRequest
+}
+
+func (r *impersonationRecorder) RecordImpersonatedRequest(ctx context.Context, request models.ImpersonatedRequest) {
+	r.requests = append(r.requests, request)
+}
+
+func impersonationClaims(userID, adminID, impersonationID primitive.ObjectID, readOnly bool) *utils.Claims {
+	return &utils.Claims{
+		UserId:           userID,
+		Role:             "user",
+		TokenUse:         utils.TokenUseImpersonation,
+		ImpersonatorID:   &adminID,
+		ReadOnly:         readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{ID: impersonationID.Hex()},
+	}
+}
+
+// newImpersonationRouter has no session checker, an impersonation token must not need one.
+func newImpersonationRouter(claims *utils.Claims, recorder *impersonationRecorder) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	mockJWTService := new(MockJWTService)
+	mockJWTService.On("VerifyToken", "accessToken").Return(claims, nil)
+
+	router := gin.New()
+	user := router.Group("/user")
+	user.Use(middlewares.RequireRole(mockJWTService, nil, "user"), middlewares.GuardImpersonation(recorder, "/user/daily-exercises"))
+	passed := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	}
+	user.GET("/profile", passed)
+	user.PUT("/profile", passed)
+	user.POST("/daily-exercises", passed)
+
+	admin := router.Group("/admin")
+	admin.Use(middlewares.Authenticate(mockJWTService))
+	admin.GET("/users", passed)
+
+	return router
+}
+
+func serveWithToken(router *gin.Engine, method, path string) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer accessToken")
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestGuardImpersonationReadOnly(t *testing.T) {
+	userID, adminID, impersonationID := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	recorder := &impersonationRecorder{}
+	router := newImpersonationRouter(impersonationClaims(userID, adminID, impersonationID, true), recorder)
+
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodGet, "/user/profile"))
+	assert.Equal(t, http.StatusForbidden, serveWithToken(router, http.MethodPut, "/user/profile"))
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodPost, "/user/daily-exercises"))
+
+	// Refused requests are recorded too
+	if assert.Len(t, recorder.requests, 3) {
+		refused := recorder.requests[1]
+		assert.Equal(t, http.MethodPut, refused.Method)
+		assert.Equal(t, "/user/profile", refused.Path)
+		assert.Equal(t, http.StatusForbidden, refused.Status)
+		assert.Equal(t, adminID, refused.AdminID)
+		assert.Equal(t, userID, refused.UserID)
+		assert.Equal(t, impersonationID, refused.ImpersonationID)
+	}
+}
+
+func TestGuardImpersonationWriteAccess(t *testing.T) {
+	recorder := &impersonationRecorder{}
+	router := newImpersonationRouter(impersonationClaims(primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), false), recorder)
+
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodPut, "/user/profile"))
+	assert.Len(t, recorder.requests, 1)
+}
+
+func TestGuardImpersonationIgnoresUserTokens(t *testing.T) {
+	recorder := &impersonationRecorder{}
+	router := newImpersonationRouter(&utils.Claims{UserId: primitive.NewObjectID(), Role: "user"}, recorder)
+
+	assert.Equal(t, http.StatusOK, serveWithToken(router, http.MethodPut, "/user/profile"))
+	assert.Empty(t, recorder.requests)
+}
+
+func TestAuthenticateRejectsImpersonationToken(t *testing.T) {
+	recorder := &impersonationRecorder{}
+	claims := impersonationClaims(primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), true)
+	claims.Role = "admin"
+	router := newImpersonationRouter(claims, recorder)
+
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(router, http.MethodGet, "/admin/users"))
+}
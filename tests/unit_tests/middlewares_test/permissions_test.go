@@ -0,0 +1,79 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type staticPermissions map[string][]string
+
+func (p staticPermissions) PermissionsForRole(ctx context.Context, role string) ([]string, error) {
+	return p[role], nil
+}
+
+type staticAdminRoles map[primitive.ObjectID]string
+
+func (r staticAdminRoles) GetActiveAdminRole(ctx context.Context, adminID primitive.ObjectID) (string, error) {
+	return r[adminID], nil
+}
+
+func newPermissionRouter(adminID primitive.ObjectID, roles staticAdminRoles) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	resolver := staticPermissions{
+		"admin":   {models.PermExercisesRead, models.PermExercisesWrite},
+		"analyst": {models.PermExercisesRead},
+	}
+
+	router.Use(func(c *gin.Context) {
+		c.Set("userId", adminID)
+		c.Set("role", "admin") // Role from the token, may be stale
+		c.Next()
+	}, middlewares.RequireActiveAdmin(roles))
+	router.GET("/exercises", middlewares.RequirePermission(resolver, models.PermExercisesRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	})
+	router.POST("/exercises", middlewares.RequirePermission(resolver, models.PermExercisesWrite), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Passed"})
+	})
+
+	return router
+}
+
+func serve(router *gin.Engine, method, path string) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRequirePermissionUsesCurrentAdminRole(t *testing.T) {
+	adminID := primitive.NewObjectID()
+	router := newPermissionRouter(adminID, staticAdminRoles{adminID: "analyst"})
+
+	assert.Equal(t, http.StatusOK, serve(router, "GET", "/exercises"))
+	assert.Equal(t, http.StatusForbidden, serve(router, "POST", "/exercises"))
+}
+
+func TestRequirePermissionGranted(t *testing.T) {
+	adminID := primitive.NewObjectID()
+	router := newPermissionRouter(adminID, staticAdminRoles{adminID: "admin"})
+
+	assert.Equal(t, http.StatusOK, serve(router, "POST", "/exercises"))
+}
+
+func TestRequireActiveAdminRejectsDisabledAdmin(t *testing.T) {
+	// Disabled and unknown accounts resolve to an empty role
+	router := newPermissionRouter(primitive.NewObjectID(), staticAdminRoles{})
+
+	assert.Equal(t, http.StatusForbidden, serve(router, "GET", "/exercises"))
+}
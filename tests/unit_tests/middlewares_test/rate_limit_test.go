@@ -0,0 +1,159 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoginRouter(store services.RateLimitStore, cfg middlewares.RateLimitConfig, password string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	router.POST("/login", middlewares.RateLimit(store, cfg), func(c *gin.Context) {
+		var body struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not parse request body"})
+			return
+		}
+
+		if body.Password != password {
+			c.Error(services.ErrInvalidUserCredentials)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged in"})
+	})
+
+	return router
+}
+
+func login(router *gin.Engine, email, password string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := `{"email":"` + email + `","password":"` + password + `"}`
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitPerIP(t *testing.T) {
+	store := services.NewInMemoryRateLimitStore()
+	router := newLoginRouter(store, middlewares.RateLimitConfig{
+		Name:  "test",
+		PerIP: services.RateLimitRule{Limit: 2, Window: time.Minute},
+	}, "secret")
+
+	first := login(router, "a@example.com", "secret")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+
+	second := login(router, "b@example.com", "secret")
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "0", second.Header().Get("RateLimit-Remaining"))
+
+	third := login(router, "c@example.com", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, third.Code)
+	assert.NotEmpty(t, third.Header().Get("Retry-After"))
+}
+
+func TestRateLimitPerAccount(t *testing.T) {
+	store := services.NewInMemoryRateLimitStore()
+	router := newLoginRouter(store, middlewares.RateLimitConfig{
+		Name:       "test",
+		PerIP:      services.RateLimitRule{Limit: 100, Window: time.Minute},
+		PerAccount: &services.RateLimitRule{Limit: 1, Window: time.Minute},
+	}, "secret")
+
+	assert.Equal(t, http.StatusOK, login(router, "a@example.com", "secret").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login(router, "A@example.com", "secret").Code)
+	assert.Equal(t, http.StatusOK, login(router, "b@example.com", "secret").Code)
+}
+
+func TestRateLimitLockoutAfterFailedLogins(t *testing.T) {
+	store := services.NewInMemoryRateLimitStore()
+	router := newLoginRouter(store, middlewares.RateLimitConfig{
+		Name:  "test",
+		PerIP: services.RateLimitRule{Limit: 100, Window: time.Minute},
+		Lockout: &services.LockoutPolicy{
+			Threshold:     2,
+			BaseLockout:   time.Minute,
+			MaxLockout:    time.Hour,
+			FailureWindow: time.Hour,
+		},
+	}, "secret")
+
+	assert.Equal(t, http.StatusUnauthorized, login(router, "a@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(router, "a@example.com", "wrong").Code)
+
+	locked := login(router, "a@example.com", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, locked.Code)
+	assert.NotEmpty(t, locked.Header().Get("Retry-After"))
+
+	// Other accounts behind the same IP are not affected
+	assert.Equal(t, http.StatusOK, login(router, "b@example.com", "secret").Code)
+}
+
+func TestRateLimitSuccessfulLoginResetsFailures(t *testing.T) {
+	store := services.NewInMemoryRateLimitStore()
+	router := newLoginRouter(store, middlewares.RateLimitConfig{
+		Name:  "test",
+		PerIP: services.RateLimitRule{Limit: 100, Window: time.Minute},
+		Lockout: &services.LockoutPolicy{
+			Threshold:     2,
+			BaseLockout:   time.Minute,
+			MaxLockout:    time.Hour,
+			FailureWindow: time.Hour,
+		},
+	}, "secret")
+
+	assert.Equal(t, http.StatusUnauthorized, login(router, "a@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusOK, login(router, "a@example.com", "secret").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(router, "a@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusOK, login(router, "a@example.com", "secret").Code)
+}
+
+func TestLockoutDurationGrowsExponentially(t *testing.T) {
+	policy := services.LockoutPolicy{Threshold: 3, BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+
+	assert.Equal(t, time.Duration(0), policy.LockoutDuration(2))
+	assert.Equal(t, time.Minute, policy.LockoutDuration(3))
+	assert.Equal(t, 2*time.Minute, policy.LockoutDuration(4))
+	assert.Equal(t, 8*time.Minute, policy.LockoutDuration(6))
+	assert.Equal(t, 10*time.Minute, policy.LockoutDuration(7))
+}
+
+func TestRateLimitPerIPIgnoresForwardedForOfUntrustedClients(t *testing.T) {
+	store := services.NewInMemoryRateLimitStore()
+	router := newLoginRouter(store, middlewares.RateLimitConfig{
+		Name:  "test",
+		PerIP: services.RateLimitRule{Limit: 1, Window: time.Minute},
+	}, "secret")
+	// As set up in main, no proxy is trusted by default
+	assert.NoError(t, router.SetTrustedProxies(nil))
+
+	loginFrom := func(forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"email":"a@example.com","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "203.0.113.7:41000"
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, loginFrom("198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, loginFrom("198.51.100.2"), "a new forwarded IP doesn't get a new bucket")
+}
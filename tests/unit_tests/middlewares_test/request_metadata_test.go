@@ -0,0 +1,54 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/middlewares"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequestMetadataMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminID := primitive.NewObjectID()
+
+	var metadata utils.RequestMetadata
+	router := gin.New()
+	router.Use(middlewares.RequestMetadata())
+	router.Use(func(c *gin.Context) {
+		c.Set("userId", adminID)
+		c.Next()
+	})
+	router.GET("/admin", middlewares.RequireActiveAdmin(staticAdminRoles{adminID: "support"}), func(c *gin.Context) {
+		metadata = utils.RequestMetadataFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	// An ID forwarded by a proxy is kept
+	req, _ := http.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set(middlewares.RequestIDHeader, "proxy-request-id")
+	req.RemoteAddr = "203.0.113.7:41000"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "proxy-request-id", w.Header().Get(middlewares.RequestIDHeader))
+	assert.Equal(t, "proxy-request-id", metadata.RequestID)
+	assert.Equal(t, adminID, metadata.ActorID)
+	assert.Equal(t, "support", metadata.ActorRole)
+	assert.Equal(t, "203.0.113.7", metadata.IP)
+
+	// A malformed ID is replaced by a generated one
+	req, _ = http.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set(middlewares.RequestIDHeader, "bad id\twith spaces")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, w.Header().Get(middlewares.RequestIDHeader), 32)
+	assert.Equal(t, w.Header().Get(middlewares.RequestIDHeader), metadata.RequestID)
+}
@@ -0,0 +1,141 @@
+// Package paymentstub plays the payment provider for tests: it builds provider events and signs their deliveries.
+package paymentstub
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+)
+
+const Secret = "whsec_vigor_test"
+
+type Provider struct {
+	Secret string
+	Now    func() time.Time
+}
+
+func NewProvider() *Provider {
+	return &Provider{Secret: Secret, Now: time.Now}
+}
+
+// Event builds the payload of an event with a new ID.
+func (p *Provider) Event(eventType string, object map[string]interface{}) []byte {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":      "evt_" + randomHex(),
+		"object":  "event",
+		"type":    eventType,
+		"created": p.Now().Unix(),
+		"data":    map[string]interface{}{"object": object},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return payload
+}
+
+// Sign returns the headers of a delivery of the payload signed at the given time.
+func (p *Provider) Sign(payload []byte, signedAt time.Time) http.Header {
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	header := http.Header{}
+	header.Set(utils.StripeSignatureHeader, "t="+timestamp+",v1="+utils.SignWebhookPayload([]byte(p.Secret), timestamp, payload))
+	return header
+}
+
+// Deliver builds the event and signs it now.
+func (p *Provider) Deliver(eventType string, object map[string]interface{}) ([]byte, http.Header) {
+	payload := p.Event(eventType, object)
+	return payload, p.Sign(payload, p.Now())
+}
+
+// CheckoutCompleted is the object of a completed checkout of the plan by the user.
+func CheckoutCompleted(userID, plan, customerID, subscriptionID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                  "cs_" + randomHex(),
+		"object":              "checkout.session",
+		"client_reference_id": userID,
+		"customer":            customerID,
+		"subscription":        subscriptionID,
+		"metadata":            map[string]string{"plan": plan},
+	}
+}
+
+// Invoice is the object of an invoice of the subscription for the period ending at periodEnd.
+func Invoice(subscriptionID string, periodEnd time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           "in_" + randomHex(),
+		"object":       "invoice",
+		"subscription": subscriptionID,
+		"lines": map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"period": map[string]int64{"start": periodEnd.AddDate(0, -1, 0).Unix(), "end": periodEnd.Unix()}},
+			},
+		},
+	}
+}
+
+// PlanInvoice is an invoice of the subscription billing the plan.
+func PlanInvoice(subscriptionID, plan string, periodEnd time.Time) map[string]interface{} {
+	invoice := Invoice(subscriptionID, periodEnd)
+	invoice["subscription_details"] = map[string]interface{}{"metadata": map[string]string{"plan": plan}}
+	return invoice
+}
+
+// PaidInvoice is an invoice of the subscription with its amounts, in cents of the currency.
+func PaidInvoice(subscriptionID string, periodEnd time.Time, currency string, subtotal, tax int64) map[string]interface{} {
+	invoice := Invoice(subscriptionID, periodEnd)
+	invoice["number"] = "VIGOR-0001"
+	invoice["currency"] = currency
+	invoice["subtotal"] = subtotal
+	invoice["tax"] = tax
+	invoice["total"] = subtotal + tax
+	invoice["customer_name"] = "Jane Doe"
+	invoice["customer_email"] = "jane@example.com"
+	invoice["lines"] = map[string]interface{}{
+		"data": []map[string]interface{}{{
+			"description": "1 x Vigor Premium (at 12.99 / month)",
+			"quantity":    1,
+			"amount":      subtotal,
+			"period":      map[string]int64{"start": periodEnd.AddDate(0, -1, 0).Unix(), "end": periodEnd.Unix()},
+		}},
+	}
+	return invoice
+}
+
+// GiftCheckout is a completed one-time checkout paying the gift code.
+func GiftCheckout(giftCodeID, currency string, subtotal, tax int64) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              "cs_" + randomHex(),
+		"object":          "checkout.session",
+		"mode":            "payment",
+		"metadata":        map[string]string{"giftCodeId": giftCodeID},
+		"currency":        currency,
+		"amount_subtotal": subtotal,
+		"amount_total":    subtotal + tax,
+		"total_details":   map[string]int64{"amount_tax": tax},
+		"customer_details": map[string]interface{}{
+			"name":  "Jane Doe",
+			"email": "jane@example.com",
+		},
+	}
+}
+
+// Subscription is the object of a subscription event.
+func Subscription(subscriptionID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     subscriptionID,
+		"object": "subscription",
+	}
+}
+
+func randomHex() string {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,129 @@
+package responses_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/responses"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Every type of the responses package, a new type must be added here to be checked.
+var responseTypes = map[string]reflect.Type{
+	"UserPublicProfile": reflect.TypeOf(responses.UserPublicProfile{}),
+	"UserSelf":          reflect.TypeOf(responses.UserSelf{}),
+	"UserAdminView":     reflect.TypeOf(responses.UserAdminView{}),
+	"UserAdminDetail":   reflect.TypeOf(responses.UserAdminDetail{}),
+	"Admin":             reflect.TypeOf(responses.Admin{}),
+	"AdminInvitation":   reflect.TypeOf(responses.AdminInvitation{}),
+	"Session":           reflect.TypeOf(responses.Session{}),
+	"APIKey":            reflect.TypeOf(responses.APIKey{}),
+}
+
+var credentialNameFragments = []string{"password", "secret", "tokenhash", "keyhash", "recoverycode"}
+
+// Types holding credentials, a response must never embed them.
+var credentialTypes = []reflect.Type{
+	reflect.TypeOf(models.User{}),
+	reflect.TypeOf(models.Admin{}),
+	reflect.TypeOf(models.AdminInvitation{}),
+	reflect.TypeOf(models.MFASettings{}),
+	reflect.TypeOf(models.Session{}),
+	reflect.TypeOf(models.APIKey{}),
+}
+
+func TestEveryResponseTypeIsChecked(t *testing.T) {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, "../../../internal/responses", nil, 0)
+	assert.NoError(t, err)
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					name := spec.(*ast.TypeSpec).Name.Name
+					if ast.IsExported(name) {
+						assert.Contains(t, responseTypes, name, "response type %s is not covered by the credential checks", name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestResponseTypesHaveNoCredentialFields(t *testing.T) {
+	for name, typ := range responseTypes {
+		for _, problem := range credentialFields(typ, name, map[reflect.Type]bool{}) {
+			t.Errorf("credential field in response: %s", problem)
+		}
+	}
+}
+
+func TestModelsDoNotSerializeCredentials(t *testing.T) {
+	mfa := &models.MFASettings{Enabled: true, Secret: "TOTPSECRET", RecoveryCodeHashes: []string{"recovery-hash"}}
+	user := models.User{ID: primitive.NewObjectID(), Email: "user@vigor.com", PasswordHash: "password-hash", MFA: mfa}
+	admin := models.Admin{ID: primitive.NewObjectID(), Email: "admin@vigor.com", PasswordHash: "password-hash", MFA: mfa}
+
+	for _, value := range []interface{}{
+		user, admin,
+		responses.NewUserSelf(user), responses.NewUserAdminView(user), responses.NewUserPublicProfile(user),
+		responses.NewAdmin(admin),
+	} {
+		body, err := json.Marshal(value)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(body), "password-hash")
+		assert.NotContains(t, string(body), "TOTPSECRET")
+		assert.NotContains(t, string(body), "recovery-hash")
+	}
+
+	assert.True(t, responses.NewUserSelf(user).MFAEnabled)
+}
+
+// credentialFields walks the fields reachable from typ and lists the ones that look like credentials.
+func credentialFields(typ reflect.Type, path string, seen map[reflect.Type]bool) []string {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+
+	for _, credentialType := range credentialTypes {
+		if typ == credentialType {
+			return []string{path + " (" + typ.String() + ")"}
+		}
+	}
+
+	if typ.Kind() != reflect.Struct || seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+
+	var problems []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" || !field.IsExported() {
+			continue
+		}
+
+		fieldPath := path + "." + field.Name
+		lowerName := strings.ToLower(field.Name + " " + jsonName)
+		for _, fragment := range credentialNameFragments {
+			if strings.Contains(lowerName, fragment) {
+				problems = append(problems, fieldPath)
+			}
+		}
+		problems = append(problems, credentialFields(field.Type, fieldPath, seen)...)
+	}
+
+	return problems
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/GhostDrew11/vigor-api/internal/config"
 	"github.com/GhostDrew11/vigor-api/internal/db"
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"github.com/GhostDrew11/vigor-api/internal/services"
@@ -12,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 
@@ -23,7 +26,7 @@ func TestRegisterAdminSuccess(t *testing.T){
 	mockCollection := new(MockMongoCollection)
 	hasher := &utils.DefaultHasher{}
 	parser := &utils.DefaultParser{}
-	adminService := services.NewAdminService(mockDB, hasher, parser)
+	adminService := services.NewAdminService(mockDB, hasher, parser, &config.Config{AdminBootstrap: true})
 	input := models.AdminRegistrationInput{
 		Email: "admin@vigor.com",
 		Password: "securepassword",
@@ -33,7 +36,16 @@ func TestRegisterAdminSuccess(t *testing.T){
 	
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(0), nil)
-	mockCollection.On("InsertOne", ctx, mock.AnythingOfType("models.Admin")).Return(*mockInsertOneResult, nil)
+	mockCollection.On("CountDocuments", ctx, bson.M{}).Return(int64(0), nil)
+	mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(admin models.Admin) bool {
+		return admin.Role == models.SuperAdminRole
+	})).Return(*mockInsertOneResult, nil)
+	// The new admin is recorded as the actor of its own creation, without its password hash
+	mockCollection.On("InsertOne", mock.Anything, mock.MatchedBy(func(entry models.AuditLog) bool {
+		_, hasPasswordHash := entry.After["passwordHash"]
+		return entry.Action == models.AuditActionCreate && entry.TargetCollection == "admins" &&
+			entry.TargetID == entry.ActorID.Hex() && entry.After["email"] == input.Email && !hasPasswordHash
+	})).Return(*mockInsertOneResult, nil)
 
 	err := adminService.RegisterAdmin(ctx, input)
 
@@ -53,7 +65,7 @@ func TestRegisterAdminFailure_CheckingAdmin(t *testing.T){
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
 
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(0), errors.New("error checking if admin already exists"))
@@ -76,7 +88,7 @@ func TestRegisterAdminFailure_AdminAlreadyExists(t *testing.T){
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
 
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(1), nil)
@@ -99,10 +111,11 @@ func TestRegisterAdminFailure_InsertingOne(t *testing.T){
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
 
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{AdminBootstrap: true})
 
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(0), nil)
+	mockCollection.On("CountDocuments", ctx, bson.M{}).Return(int64(0), nil)
 	mockCollection.On("InsertOne", ctx, mock.AnythingOfType("models.Admin")).Return(*new(db.MongoInsertOneResult), errors.New("error inserting admin"))
 
 	err := adminService.RegisterAdmin(ctx, input)
@@ -118,7 +131,7 @@ func TestGetAdminByEmailSuccess(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testadmin@vigor.com"
 	password := "securepassword"
@@ -129,6 +142,7 @@ func TestGetAdminByEmailSuccess(t *testing.T){
 	mockMongoSingleResult.On("Err").Return(nil)
 	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.Admin")).Return(nil)
 	mockHasher.On("CheckPasswordHash", password, mock.AnythingOfType("string")).Return(true)
+	mockHasher.On("NeedsRehash", mock.AnythingOfType("string")).Return(false)
 
 
 	_, err := adminService.GetAdminByEmail(ctx, email, password)
@@ -147,7 +161,7 @@ func TestGetAdminByEmailFailure_FindingAdmin(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testadmin@vigor.com"
 	password := "securepassword"
@@ -173,7 +187,7 @@ func TestGetAdminByEmailFailure_DecodingResult(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testadmin@vigor.com"
 	password := "securepassword"
@@ -200,7 +214,7 @@ func TestGetAdminByEmailFailure_CheckingPassword(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	adminService := services.NewAdminService(mockDB, mockHasher, mockParser)
+	adminService := services.NewAdminService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testadmin@vigor.com"
 	password := "securepassword"
@@ -219,4 +233,256 @@ func TestGetAdminByEmailFailure_CheckingPassword(t *testing.T){
 	mockCollection.AssertExpectations(t)
 	mockMongoSingleResult.AssertExpectations(t)
 	mockHasher.AssertExpectations(t)
-}
\ No newline at end of file
+}
+func TestVerifyAdminMFARejectsReplayedCode(t *testing.T){
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	adminID := primitive.NewObjectID()
+	secret, _ := utils.GenerateTOTPSecret()
+	now := time.Now()
+	code, _ := utils.GenerateTOTPCode(secret, now)
+	step, _ := utils.ValidateTOTPCode(secret, code, now)
+
+	mockDB.On("Collection", "admins").Return(mockCollection)
+	mockCollection.On("FindOne", ctx, bson.M{"_id": adminID}, mock.AnythingOfType("[]*options.FindOneOptions")).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		raw, _ := bson.Marshal(bson.M{
+			"email": "admin@vigor.com",
+			"mfa":   bson.M{"enabled": true, "secret": secret, "lastUsedStep": step},
+		})
+		bson.Unmarshal(raw, args.Get(0))
+	}).Return(nil)
+
+	err := adminService.VerifyMFA(ctx, adminID, models.MFALoginInput{Code: code})
+	assert.Equal(t, services.ErrInvalidMFACode, err)
+	mockCollection.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRegisterAdminFailure_InvitationRequired(t *testing.T){
+	ctx := context.Background()
+	input := models.AdminRegistrationInput{
+		Email: "admin@vigor.com",
+		Password: "securepassword",
+	}
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+
+	mockDB.On("Collection", "admins").Return(mockCollection)
+	mockCollection.On("CountDocuments", ctx, bson.M{"email": input.Email}).Return(int64(0), nil)
+	mockCollection.On("CountDocuments", ctx, bson.M{}).Return(int64(1), nil)
+
+	// Bootstrap disabled
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+	assert.Equal(t, services.ErrAdminInvitationRequired, adminService.RegisterAdmin(ctx, input))
+
+	// Bootstrap enabled but an admin already exists
+	adminService = services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{AdminBootstrap: true})
+	assert.Equal(t, services.ErrAdminInvitationRequired, adminService.RegisterAdmin(ctx, input))
+	mockCollection.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestRegisterAdminWithInvitation(t *testing.T){
+	ctx := context.Background()
+	input := models.AdminRegistrationInput{
+		Email: "admin@vigor.com",
+		Password: "securepassword",
+		InvitationToken: "invitation-token",
+	}
+	inviterID := primitive.NewObjectID()
+	mockDB := new(MockMongoDatabase)
+	mockAdminCollection := new(MockMongoCollection)
+	mockInvitationCollection := new(MockMongoCollection)
+	mockAuditCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	mockDB.On("Collection", "admins").Return(mockAdminCollection)
+	mockDB.On("Collection", "adminInvitations").Return(mockInvitationCollection)
+	mockDB.On("Collection", "auditLogs").Return(mockAuditCollection)
+	mockAdminCollection.On("CountDocuments", ctx, bson.M{"email": input.Email}).Return(int64(0), nil)
+	mockInvitationCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["tokenHash"] == utils.HashToken(input.InvitationToken) && filter["email"] == input.Email
+	}), mock.Anything, mock.Anything).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.AdminInvitation")).Run(func(args mock.Arguments) {
+		invitation := args.Get(0).(*models.AdminInvitation)
+		invitation.Role = models.AdminRole
+		invitation.InvitedBy = inviterID
+	}).Return(nil)
+	mockAdminCollection.On("InsertOne", ctx, mock.MatchedBy(func(admin models.Admin) bool {
+		return admin.Role == models.AdminRole && admin.InvitedBy != nil && *admin.InvitedBy == inviterID
+	})).Return(*new(db.MongoInsertOneResult), nil)
+	mockAuditCollection.On("InsertOne", mock.Anything, mock.AnythingOfType("models.AuditLog")).Return(*new(db.MongoInsertOneResult), nil)
+
+	err := adminService.RegisterAdmin(ctx, input)
+	assert.NoError(t, err)
+	mockAdminCollection.AssertExpectations(t)
+	mockInvitationCollection.AssertExpectations(t)
+	mockAuditCollection.AssertExpectations(t)
+}
+
+func TestRegisterAdminWithInvitationFailure_InsertReleasesInvitation(t *testing.T){
+	ctx := context.Background()
+	input := models.AdminRegistrationInput{
+		Email: "admin@vigor.com",
+		Password: "securepassword",
+		InvitationToken: "invitation-token",
+	}
+	invitationID := primitive.NewObjectID()
+	mockDB := new(MockMongoDatabase)
+	mockAdminCollection := new(MockMongoCollection)
+	mockInvitationCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	mockDB.On("Collection", "admins").Return(mockAdminCollection)
+	mockDB.On("Collection", "adminInvitations").Return(mockInvitationCollection)
+	mockAdminCollection.On("CountDocuments", ctx, bson.M{"email": input.Email}).Return(int64(0), nil)
+	mockInvitationCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.Anything, mock.Anything).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.AdminInvitation")).Run(func(args mock.Arguments) {
+		invitation := args.Get(0).(*models.AdminInvitation)
+		invitation.ID = invitationID
+		invitation.Role = models.AdminRole
+	}).Return(nil)
+	mockAdminCollection.On("InsertOne", ctx, mock.AnythingOfType("models.Admin")).Return(*new(db.MongoInsertOneResult), errors.New("insert failed"))
+	mockInvitationCollection.On("UpdateOne", ctx, bson.M{"_id": invitationID}, bson.M{"$unset": bson.M{"acceptedAt": ""}}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+
+	err := adminService.RegisterAdmin(ctx, input)
+	assert.Error(t, err)
+	mockInvitationCollection.AssertExpectations(t)
+}
+
+func TestDeleteAdminFailure_Self(t *testing.T){
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	adminID := primitive.NewObjectID()
+	err := adminService.DeleteAdmin(ctx, adminID, adminID)
+	assert.Equal(t, services.ErrCannotModifySelf, err)
+	mockDB.AssertNotCalled(t, "Collection", mock.Anything)
+}
+
+func TestDisableAdminFailure_LastSuperAdmin(t *testing.T){
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	actorID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	mockDB.On("Collection", "admins").Return(mockCollection)
+	expectAdminRole(mockCollection, actorID, models.SuperAdminRole)
+	mockCollection.On("FindOne", ctx, bson.M{"_id": adminID}, mock.Anything).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.Admin")).Run(func(args mock.Arguments) {
+		admin := args.Get(0).(*models.Admin)
+		admin.ID = adminID
+		admin.Role = models.SuperAdminRole
+	}).Return(nil)
+	mockCollection.On("CountDocuments", ctx, mock.Anything).Return(int64(0), nil)
+
+	err := adminService.SetAdminDisabled(ctx, actorID, adminID, true)
+	assert.Equal(t, services.ErrLastSuperAdmin, err)
+	mockCollection.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// expectAdminRole serves the admin with the given role to both GetAdminByID and GetActiveAdminRole.
+func expectAdminRole(mockCollection *MockMongoCollection, adminID primitive.ObjectID, role string) {
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.Admin")).Run(func(args mock.Arguments) {
+		admin := args.Get(0).(*models.Admin)
+		admin.ID = adminID
+		admin.Role = role
+	}).Return(nil)
+	mockCollection.On("FindOne", mock.Anything, bson.M{"_id": adminID}, mock.Anything).Return(mockMongoSingleResult)
+}
+
+func TestCreateAdminInvitationFailure_SuperAdminInvitedByAdmin(t *testing.T){
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	mockAdminCollection := new(MockMongoCollection)
+	mockRoleCollection := new(MockMongoCollection)
+	mockInvitationCollection := new(MockMongoCollection)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	actorID := primitive.NewObjectID()
+	input := models.AdminInvitationInput{Email: "new-admin@vigor.com", Role: models.SuperAdminRole}
+	mockDB.On("Collection", "admins").Return(mockAdminCollection)
+	mockDB.On("Collection", "roles").Return(mockRoleCollection)
+	mockDB.On("Collection", "adminInvitations").Return(mockInvitationCollection)
+	mockAdminCollection.On("CountDocuments", ctx, bson.M{"email": input.Email}).Return(int64(0), nil)
+	mockRoleCollection.On("CountDocuments", ctx, bson.M{"_id": models.SuperAdminRole}).Return(int64(1), nil)
+	expectAdminRole(mockAdminCollection, actorID, models.AdminRole)
+
+	_, _, err := adminService.CreateAdminInvitation(ctx, actorID, input)
+	assert.Equal(t, services.ErrSuperAdminRequired, err)
+	mockInvitationCollection.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestUpdateAdminFailure_PromotedToSuperAdminByAdmin(t *testing.T){
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	mockAdminCollection := new(MockMongoCollection)
+	mockRoleCollection := new(MockMongoCollection)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	actorID := primitive.NewObjectID()
+	adminID := primitive.NewObjectID()
+	role := models.SuperAdminRole
+	mockDB.On("Collection", "admins").Return(mockAdminCollection)
+	mockDB.On("Collection", "roles").Return(mockRoleCollection)
+	mockRoleCollection.On("CountDocuments", ctx, bson.M{"_id": models.SuperAdminRole}).Return(int64(1), nil)
+	expectAdminRole(mockAdminCollection, adminID, models.AdminRole)
+	expectAdminRole(mockAdminCollection, actorID, models.AdminRole)
+
+	_, err := adminService.UpdateAdmin(ctx, actorID, adminID, models.AdminUpdateInput{Role: &role})
+	assert.Equal(t, services.ErrSuperAdminRequired, err)
+	mockAdminCollection.AssertNotCalled(t, "FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSuperAdminAccountFailure_ManagedByAdmin(t *testing.T){
+	email := "other@vigor.com"
+	tests := []struct {
+		name   string
+		manage func(adminService *services.AdminService, actorID, adminID primitive.ObjectID) error
+	}{
+		{"update email", func(adminService *services.AdminService, actorID, adminID primitive.ObjectID) error {
+			_, err := adminService.UpdateAdmin(context.Background(), actorID, adminID, models.AdminUpdateInput{Email: &email})
+			return err
+		}},
+		{"disable", func(adminService *services.AdminService, actorID, adminID primitive.ObjectID) error {
+			return adminService.SetAdminDisabled(context.Background(), actorID, adminID, true)
+		}},
+		{"enable", func(adminService *services.AdminService, actorID, adminID primitive.ObjectID) error {
+			return adminService.SetAdminDisabled(context.Background(), actorID, adminID, false)
+		}},
+		{"delete", func(adminService *services.AdminService, actorID, adminID primitive.ObjectID) error {
+			return adminService.DeleteAdmin(context.Background(), actorID, adminID)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T){
+			mockDB := new(MockMongoDatabase)
+			mockAdminCollection := new(MockMongoCollection)
+			adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+			actorID := primitive.NewObjectID()
+			adminID := primitive.NewObjectID()
+			mockDB.On("Collection", "admins").Return(mockAdminCollection)
+			mockAdminCollection.On("CountDocuments", mock.Anything, bson.M{"email": email}).Return(int64(0), nil)
+			expectAdminRole(mockAdminCollection, adminID, models.SuperAdminRole)
+			expectAdminRole(mockAdminCollection, actorID, models.AdminRole)
+
+			err := tt.manage(adminService, actorID, adminID)
+			assert.Equal(t, services.ErrSuperAdminRequired, err)
+			mockAdminCollection.AssertNotCalled(t, "FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mockAdminCollection.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			mockAdminCollection.AssertNotCalled(t, "DeleteOne", mock.Anything, mock.Anything)
+		})
+	}
+}
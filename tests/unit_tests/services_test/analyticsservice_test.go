@@ -0,0 +1,373 @@
+package s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newAnalyticsTestService() (*services.AdminService, *MockMongoCollection, *MockMongoCollection) {
+	users := new(MockMongoCollection)
+	invoices := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "invoices").Return(invoices)
+	return services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{}), users, invoices
+}
+
+// expectAggregate serves the rows as the result of the pipeline, decoded the way the driver would, and captures
+// the pipeline.
+func expectAggregate(collection *MockMongoCollection, rows ...bson.M) *bson.A {
+	pipeline := new(bson.A)
+	cursor := new(MockMongoCursor)
+	cursor.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		results := reflect.ValueOf(args.Get(1)).Elem()
+		for _, row := range rows {
+			raw, _ := bson.Marshal(row)
+			item := reflect.New(results.Type().Elem())
+			_ = bson.Unmarshal(raw, item.Interface())
+			results.Set(reflect.Append(results, item.Elem()))
+		}
+	}).Return(nil)
+	cursor.On("Close", mock.Anything).Return(nil)
+	collection.On("Aggregate", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*pipeline = args.Get(1).(bson.A)
+	}).Return(cursor, nil)
+	return pipeline
+}
+
+var (
+	analyticsFrom = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	analyticsTo   = time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestSubscriptionBreakdownCountsStatusesAndPlans(t *testing.T) {
+	adminService, users, _ := newAnalyticsTestService()
+	expectAggregate(users, bson.M{
+		"byStatus": bson.A{bson.M{"_id": "active", "count": 12}, bson.M{"_id": "trialing", "count": 30}},
+		"byPlan":   bson.A{bson.M{"_id": "premium", "count": 40}},
+	})
+
+	breakdown, err := adminService.GetSubscriptionBreakdown(context.Background(), analyticsFrom, analyticsTo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AnalyticsCount{{Key: "active", Count: 12}, {Key: "trialing", Count: 30}}, breakdown.ByStatus)
+	assert.Equal(t, [][]string{
+		{"group", "key", "count"},
+		{"status", "active", "12"},
+		{"status", "trialing", "30"},
+		{"plan", "premium", "40"},
+	}, breakdown.CSVRecords())
+}
+
+func TestSignupAnalyticsFillsEmptyPeriods(t *testing.T) {
+	adminService, users, _ := newAnalyticsTestService()
+	expectAggregate(users, bson.M{
+		"signups":     bson.A{bson.M{"_id": time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC), "count": 5}},
+		"conversions": bson.A{bson.M{"_id": time.Date(2026, time.March, 23, 0, 0, 0, 0, time.UTC), "count": 2}},
+	})
+
+	analytics, err := adminService.GetSignupAnalytics(context.Background(), analyticsFrom, analyticsTo, models.AnalyticsIntervalWeek)
+
+	assert.NoError(t, err)
+	// Weeks start on Monday, the first one starts before the range
+	if assert.Len(t, analytics.Points, 6) {
+		assert.Equal(t, time.Date(2026, time.February, 23, 0, 0, 0, 0, time.UTC), analytics.Points[0].Period)
+		assert.Equal(t, models.SignupPoint{Period: time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC), Signups: 5}, analytics.Points[2])
+		assert.Equal(t, models.SignupPoint{Period: time.Date(2026, time.March, 23, 0, 0, 0, 0, time.UTC), Conversions: 2}, analytics.Points[4])
+	}
+}
+
+func TestCheckAnalyticsRange(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		interval string
+		valid    bool
+	}{
+		{"a month by day", analyticsFrom, analyticsTo, models.AnalyticsIntervalDay, true},
+		{"five years by month", day(2021, time.January, 1), day(2026, time.January, 1), models.AnalyticsIntervalMonth, true},
+		{"before the epoch", day(1969, time.December, 1), day(1970, time.February, 1), "", false},
+		{"after IDs overflow", day(2106, time.January, 1), day(2106, time.March, 1), "", false},
+		{"too long", day(2010, time.January, 1), day(2026, time.January, 1), models.AnalyticsIntervalMonth, false},
+		{"too many points", day(2024, time.January, 1), day(2026, time.January, 1), models.AnalyticsIntervalDay, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := services.CheckAnalyticsRange(test.from, test.to, test.interval)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, services.ErrInvalidAnalyticsRange)
+			}
+		})
+	}
+}
+
+func TestSignupAnalyticsGroupsByMonth(t *testing.T) {
+	adminService, users, _ := newAnalyticsTestService()
+	pipeline := expectAggregate(users, bson.M{"signups": bson.A{}, "conversions": bson.A{}})
+
+	analytics, err := adminService.GetSignupAnalytics(context.Background(), time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC), analyticsTo, models.AnalyticsIntervalMonth)
+
+	assert.NoError(t, err)
+	if assert.Len(t, analytics.Points, 3) {
+		assert.Equal(t, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), analytics.Points[0].Period)
+		assert.Equal(t, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), analytics.Points[2].Period)
+	}
+	assert.Contains(t, (*pipeline)[1].(bson.M)["$facet"].(bson.M)["conversions"].(bson.A)[1].(bson.M)["$group"].(bson.M)["_id"], "$dateTrunc")
+}
+
+func TestRevenueAnalyticsSumsInvoicesAndMRR(t *testing.T) {
+	adminService, _, invoices := newAnalyticsTestService()
+	period := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	pipeline := expectAggregate(invoices, bson.M{
+		"points": bson.A{bson.M{
+			"_id":                 bson.M{"period": period, "currency": "EUR"},
+			"invoices":            3,
+			"subscriptionRevenue": int64(2598),
+			"giftRevenue":         int64(5000),
+			"tax":                 int64(1520),
+			"total":               int64(9118),
+		}},
+		"mrr": bson.A{bson.M{"_id": "EUR", "amount": int64(2598)}},
+	})
+
+	analytics, err := adminService.GetRevenueAnalytics(context.Background(), analyticsFrom, analyticsTo, models.AnalyticsIntervalMonth)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.RevenuePoint{{Period: period, Currency: "EUR", Invoices: 3, SubscriptionRevenue: 2598, GiftRevenue: 5000, Tax: 1520, Total: 9118}}, analytics.Points)
+	assert.Equal(t, []models.CurrencyAmount{{Currency: "EUR", Amount: 2598}}, analytics.MRR)
+	assert.Equal(t, []string{"mrr", "EUR", "", "2598", "", "", ""}, analytics.CSVRecords()[2])
+	// The MRR window starts before a range shorter than a month
+	shortFrom := analyticsTo.AddDate(0, 0, -7)
+	_, err = adminService.GetRevenueAnalytics(context.Background(), shortFrom, analyticsTo, models.AnalyticsIntervalDay)
+	assert.NoError(t, err)
+	issuedAt := (*pipeline)[0].(bson.M)["$match"].(bson.M)["issuedAt"].(bson.M)
+	assert.Equal(t, analyticsTo.Add(-30*24*time.Hour), issuedAt["$gte"])
+}
+
+func TestChurnAnalyticsComputesRate(t *testing.T) {
+	adminService, users, _ := newAnalyticsTestService()
+	expectAggregate(users, bson.M{"_id": nil, "payingAtStart": 200, "churned": 10, "cancellations": 14})
+
+	analytics, err := adminService.GetChurnAnalytics(context.Background(), analyticsFrom, analyticsTo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), analytics.PayingAtStart)
+	assert.Equal(t, int64(14), analytics.Cancellations)
+	assert.InDelta(t, 0.05, analytics.ChurnRate, 1e-9)
+	assert.Equal(t, []string{"2026-03-01", "2026-04-01", "200", "10", "14", "0.0500"}, analytics.CSVRecords()[1])
+}
+
+func TestChurnAnalyticsWithoutPayingUsers(t *testing.T) {
+	adminService, users, _ := newAnalyticsTestService()
+	expectAggregate(users)
+
+	analytics, err := adminService.GetChurnAnalytics(context.Background(), analyticsFrom, analyticsTo)
+
+	assert.NoError(t, err)
+	assert.Zero(t, analytics.ChurnRate)
+	assert.Equal(t, analyticsFrom, analytics.From)
+}
+
+func TestTrialConversionOnlyCountsEndedTrials(t *testing.T) {
+	adminService, users, _ := newAnalyticsTestService()
+	pipeline := expectAggregate(users, bson.M{"_id": nil, "trialsEnded": 40, "converted": 10})
+	to := time.Now().AddDate(0, 0, 7)
+
+	analytics, err := adminService.GetTrialConversionAnalytics(context.Background(), analyticsFrom, to)
+
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.25, analytics.ConversionRate, 1e-9)
+	trialEndsAt := (*pipeline)[0].(bson.M)["$match"].(bson.M)["trialEndsAt"].(bson.M)
+	assert.True(t, trialEndsAt["$lt"].(time.Time).Before(to))
+}
+
+func TestAnalyticsCSVEscapesTextColumns(t *testing.T) {
+	planID, exerciseID := primitive.NewObjectID(), primitive.NewObjectID()
+	funnel := models.WorkoutPlanFunnel{
+		WorkoutPlanEngagement: models.WorkoutPlanEngagement{WorkoutPlanID: planID, WorkoutPlanName: "=HYPERLINK(\"evil\")"},
+		SkippedExercises:      []models.SkippedExercise{{ExerciseID: exerciseID, Name: "@SUM(A1)", Skipped: 2}},
+	}
+
+	records := funnel.CSVRecords()
+
+	assert.Equal(t, "'=HYPERLINK(\"evil\")", records[1][1])
+	assert.Equal(t, []string{exerciseID.Hex(), "'@SUM(A1)", "2"}, records[len(records)-1])
+}
+
+func newEngagementTestService(workoutPlan *models.WorkoutPlan) (*services.AdminService, *MockMongoCollection) {
+	planStatuses := new(MockMongoCollection)
+	workoutPlans := new(MockMongoCollection)
+	result := new(MockMongoSingleResult)
+	if workoutPlan != nil {
+		result.On("Decode", mock.AnythingOfType("*models.WorkoutPlan")).Run(func(args mock.Arguments) {
+			*args.Get(0).(*models.WorkoutPlan) = *workoutPlan
+		}).Return(nil)
+	} else {
+		result.On("Decode", mock.AnythingOfType("*models.WorkoutPlan")).Return(mongo.ErrNoDocuments)
+	}
+	workoutPlans.On("FindOne", mock.Anything, mock.Anything, mock.Anything).Return(result)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(planStatuses)
+	mockDB.On("Collection", "workoutPlans").Return(workoutPlans)
+	return services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{}), planStatuses
+}
+
+func TestWorkoutPlanEngagementComputesCompletionRate(t *testing.T) {
+	adminService, planStatuses := newEngagementTestService(nil)
+	planID := primitive.NewObjectID()
+	expectAggregate(planStatuses, bson.M{"_id": planID, "workoutPlanName": "Full Body Starter", "enrollments": 40, "active": 30, "completed": 10})
+
+	engagement, err := adminService.GetWorkoutPlanEngagement(context.Background(), analyticsFrom, analyticsTo)
+
+	assert.NoError(t, err)
+	if assert.Len(t, engagement.Plans, 1) {
+		assert.Equal(t, planID, engagement.Plans[0].WorkoutPlanID)
+		assert.Equal(t, int64(30), engagement.Plans[0].Active)
+		assert.InDelta(t, 0.25, engagement.Plans[0].CompletionRate, 1e-9)
+	}
+}
+
+func TestWorkoutPlanFunnelFollowsWeeksInOrder(t *testing.T) {
+	week1, week2, week3 := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	workoutPlan := &models.WorkoutPlan{
+		ID:    primitive.NewObjectID(),
+		Name:  "Full Body Starter",
+		Weeks: []models.WorkoutWeek{{ID: week2, WeekNumber: 2}, {ID: week1, WeekNumber: 1}, {ID: week3, WeekNumber: 3}},
+	}
+	adminService, planStatuses := newEngagementTestService(workoutPlan)
+	day := int64(24 * time.Hour / time.Millisecond)
+	squatID := primitive.NewObjectID()
+	expectAggregate(planStatuses, bson.M{
+		"summary": bson.A{bson.M{"_id": nil, "workoutPlanName": "Full Body Starter", "enrollments": 10, "active": 8, "completed": 2}},
+		"weeks": bson.A{
+			// Completions from before weeks were timed have no duration
+			bson.M{"_id": week1, "completed": 6, "durations": bson.A{5 * day, 7 * day, 9 * day, nil}},
+			bson.M{"_id": week2, "completed": 3, "durations": bson.A{6 * day, 8 * day}},
+		},
+		"skippedExercises": bson.A{bson.M{"_id": squatID, "name": "Barbell Squat", "skipped": 7}},
+	})
+
+	funnel, err := adminService.GetWorkoutPlanFunnel(context.Background(), workoutPlan.ID, analyticsFrom, analyticsTo)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Full Body Starter", funnel.WorkoutPlanName)
+	assert.InDelta(t, 0.2, funnel.CompletionRate, 1e-9)
+	if assert.Len(t, funnel.Weeks, 3) {
+		assert.Equal(t, week1, funnel.Weeks[0].WorkoutWeekID)
+		assert.Equal(t, int64(4), funnel.Weeks[0].DropOff)
+		assert.Equal(t, int64(3), funnel.Weeks[1].DropOff)
+		assert.InDelta(t, 0.3, funnel.Weeks[1].Retention, 1e-9)
+		if assert.NotNil(t, funnel.Weeks[0].MedianDays) && assert.NotNil(t, funnel.Weeks[1].MedianDays) {
+			assert.InDelta(t, 7, *funnel.Weeks[0].MedianDays, 1e-9)
+			assert.InDelta(t, 7, *funnel.Weeks[1].MedianDays, 1e-9)
+		}
+		assert.Nil(t, funnel.Weeks[2].MedianDays)
+		assert.Equal(t, int64(3), funnel.Weeks[2].DropOff)
+	}
+	assert.Equal(t, []models.SkippedExercise{{ExerciseID: squatID, Name: "Barbell Squat", Skipped: 7}}, funnel.SkippedExercises)
+}
+
+func TestWorkoutPlanFunnelOfUnknownPlan(t *testing.T) {
+	adminService, planStatuses := newEngagementTestService(nil)
+
+	_, err := adminService.GetWorkoutPlanFunnel(context.Background(), primitive.NewObjectID(), analyticsFrom, analyticsTo)
+
+	assert.ErrorIs(t, err, services.ErrWorkoutPlanNotFound)
+	planStatuses.AssertNotCalled(t, "Aggregate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func newCohortTestService() (*services.AdminService, *MockMongoCollection, *MockMongoCollection) {
+	users := new(MockMongoCollection)
+	cohortReports := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "cohortReports").Return(cohortReports)
+	return services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{}), users, cohortReports
+}
+
+func expectCachedCohortReport(cohortReports *MockMongoCollection, cached *models.CohortRetention) {
+	result := new(MockMongoSingleResult)
+	if cached != nil {
+		result.On("Decode", mock.AnythingOfType("*models.CohortRetention")).Run(func(args mock.Arguments) {
+			*args.Get(0).(*models.CohortRetention) = *cached
+		}).Return(nil)
+	} else {
+		result.On("Decode", mock.AnythingOfType("*models.CohortRetention")).Return(mongo.ErrNoDocuments)
+	}
+	cohortReports.On("FindOne", mock.Anything, mock.Anything, mock.Anything).Return(result)
+}
+
+func TestCohortRetentionReportsEachPeriodSinceSignup(t *testing.T) {
+	adminService, users, cohortReports := newCohortTestService()
+	expectCachedCohortReport(cohortReports, nil)
+	cohortReports.On("DeleteOne", mock.Anything, mock.Anything).Return(db.MongoDeleteResult{}, nil)
+	var inserted models.CohortRetention
+	cohortReports.On("InsertOne", mock.Anything, mock.AnythingOfType("models.CohortRetention")).Run(func(args mock.Arguments) {
+		inserted = args.Get(1).(models.CohortRetention)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	march := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	expectAggregate(users, bson.M{
+		"sizes": bson.A{bson.M{"_id": march, "users": 20}},
+		"periods": bson.A{
+			bson.M{"_id": bson.M{"cohort": march, "offset": 0}, "exercised": 15, "subscribed": 4, "loggedNutrition": 10},
+			bson.M{"_id": bson.M{"cohort": march, "offset": 2}, "exercised": 5, "subscribed": 6, "loggedNutrition": 0},
+		},
+	})
+
+	report, err := adminService.GetCohortRetention(context.Background(), march, time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC), models.AnalyticsIntervalMonth)
+
+	assert.NoError(t, err)
+	if assert.Len(t, report.Cohorts, 2) {
+		cohort := report.Cohorts[0]
+		assert.Equal(t, int64(20), cohort.Users)
+		// Every month from the signup month to the current one is reported
+		now := time.Now().UTC()
+		assert.Len(t, cohort.Periods, (now.Year()-2026)*12+int(now.Month()-time.March)+1)
+		assert.InDelta(t, 0.75, cohort.Periods[0].ExerciseRate, 1e-9)
+		assert.Equal(t, models.CohortPeriod{Offset: 1}, cohort.Periods[1])
+		assert.InDelta(t, 0.3, cohort.Periods[2].SubscriptionRate, 1e-9)
+		// An empty cohort still lists its periods
+		assert.Zero(t, report.Cohorts[1].Users)
+		assert.Len(t, report.Cohorts[1].Periods, len(cohort.Periods)-1)
+	}
+	assert.Equal(t, report.Key, inserted.Key)
+	assert.True(t, inserted.ExpiresAt.After(time.Now()))
+}
+
+func TestCohortRetentionServesCachedReport(t *testing.T) {
+	adminService, users, cohortReports := newCohortTestService()
+	cached := &models.CohortRetention{Key: "week", GeneratedAt: time.Now().Add(-time.Minute), Cohorts: []models.Cohort{{Users: 3}}}
+	expectCachedCohortReport(cohortReports, cached)
+
+	report, err := adminService.GetCohortRetention(context.Background(), analyticsFrom, analyticsTo, models.AnalyticsIntervalWeek)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), report.Cohorts[0].Users)
+	users.AssertNotCalled(t, "Aggregate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCohortRetentionRejectsDailyCohorts(t *testing.T) {
+	adminService, _, _ := newCohortTestService()
+
+	_, err := adminService.GetCohortRetention(context.Background(), analyticsFrom, analyticsTo, models.AnalyticsIntervalDay)
+
+	assert.ErrorIs(t, err, services.ErrInvalidCohortInterval)
+}
@@ -0,0 +1,132 @@
+package s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newAPIKeyTestService() (*services.AdminService, *MockMongoCollection, *MockMongoCollection) {
+	apiKeys := new(MockMongoCollection)
+	auditLogs := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "apiKeys").Return(apiKeys)
+	mockDB.On("Collection", "auditLogs").Return(auditLogs)
+	return services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{}), apiKeys, auditLogs
+}
+
+func TestCreateAPIKeyStoresOnlyTheHash(t *testing.T) {
+	ctx := context.Background()
+	adminService, apiKeys, auditLogs := newAPIKeyTestService()
+	creatorID := primitive.NewObjectID()
+
+	var stored models.APIKey
+	apiKeys.On("InsertOne", ctx, mock.AnythingOfType("models.APIKey")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.APIKey)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	auditLogs.On("InsertOne", ctx, mock.MatchedBy(func(entry models.AuditLog) bool {
+		_, leaked := entry.After["keyHash"]
+		return entry.TargetCollection == "apiKeys" && !leaked
+	})).Return(*new(db.MongoInsertOneResult), nil)
+
+	apiKey, key, err := adminService.CreateAPIKey(ctx, creatorID, models.APIKeyInput{Name: "Gym partner", Scopes: []string{models.PermExercisesRead}})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, stored.Prefix), "the prefix should be the start of the key")
+	assert.Equal(t, utils.HashToken(key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, key)
+	assert.Equal(t, creatorID, apiKey.CreatedBy)
+	auditLogs.AssertExpectations(t)
+}
+
+func TestCreateAPIKeyRejectsInvalidInput(t *testing.T) {
+	ctx := context.Background()
+	adminService, apiKeys, _ := newAPIKeyTestService()
+	past := time.Now().Add(-time.Hour)
+
+	_, _, err := adminService.CreateAPIKey(ctx, primitive.NewObjectID(), models.APIKeyInput{Name: "Partner", Scopes: []string{models.PermUsersRead}})
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKeyScope, "keys should only read the catalog")
+
+	_, _, err = adminService.CreateAPIKey(ctx, primitive.NewObjectID(), models.APIKeyInput{Name: "Partner", Scopes: []string{models.PermMealsRead}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, services.ErrInvalidAPIKeyExpiry)
+
+	apiKeys.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	apiKeyID := primitive.NewObjectID()
+
+	expectLookup := func(apiKeys *MockMongoCollection, apiKey *models.APIKey) {
+		result := new(MockMongoSingleResult)
+		if apiKey == nil {
+			result.On("Decode", mock.AnythingOfType("*models.APIKey")).Return(mongo.ErrNoDocuments)
+		} else {
+			result.On("Decode", mock.AnythingOfType("*models.APIKey")).Run(func(args mock.Arguments) {
+				*args.Get(0).(*models.APIKey) = *apiKey
+			}).Return(nil)
+		}
+		apiKeys.On("FindOne", ctx, mock.MatchedBy(func(filter bson.M) bool {
+			return filter["keyHash"] == utils.HashToken("vigor_key") && filter["revokedAt"] != nil
+		}), mock.Anything).Return(result).Once()
+	}
+
+	t.Run("unknown, revoked or expired key", func(t *testing.T) {
+		adminService, apiKeys, _ := newAPIKeyTestService()
+		expectLookup(apiKeys, nil)
+
+		apiKey, err := adminService.AuthenticateAPIKey(ctx, "vigor_key")
+
+		assert.NoError(t, err)
+		assert.Nil(t, apiKey)
+	})
+
+	t.Run("first use is recorded", func(t *testing.T) {
+		adminService, apiKeys, _ := newAPIKeyTestService()
+		expectLookup(apiKeys, &models.APIKey{ID: apiKeyID, Scopes: []string{models.PermExercisesRead}})
+		apiKeys.On("UpdateOne", ctx, bson.M{"_id": apiKeyID}, mock.Anything).Return(*new(db.MongoUpdateResult), nil).Once()
+
+		apiKey, err := adminService.AuthenticateAPIKey(ctx, "vigor_key")
+
+		assert.NoError(t, err)
+		assert.True(t, apiKey.HasScope(models.PermExercisesRead))
+		apiKeys.AssertExpectations(t)
+	})
+
+	t.Run("recent use is not written again", func(t *testing.T) {
+		adminService, apiKeys, _ := newAPIKeyTestService()
+		lastUsedAt := time.Now()
+		expectLookup(apiKeys, &models.APIKey{ID: apiKeyID, LastUsedAt: &lastUsedAt})
+
+		apiKey, err := adminService.AuthenticateAPIKey(ctx, "vigor_key")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, apiKey)
+		apiKeys.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRevokeAPIKeyNotFound(t *testing.T) {
+	ctx := context.Background()
+	adminService, apiKeys, auditLogs := newAPIKeyTestService()
+	apiKeyID := primitive.NewObjectID()
+	apiKeys.On("UpdateOne", ctx, bson.M{"_id": apiKeyID, "revokedAt": bson.M{"$exists": false}}, mock.Anything).
+		Return(db.MongoUpdateResult{MatchedCount: 0}, nil)
+
+	err := adminService.RevokeAPIKey(ctx, apiKeyID)
+
+	assert.ErrorIs(t, err, services.ErrAPIKeyNotFound)
+	auditLogs.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
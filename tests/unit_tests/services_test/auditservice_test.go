@@ -0,0 +1,102 @@
+package s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUpdateExerciseRecordsAuditDiff(t *testing.T) {
+	actorID := primitive.NewObjectID()
+	exerciseID := primitive.NewObjectID()
+	ctx := utils.WithRequestMetadata(context.Background(), utils.RequestMetadata{
+		ActorID:   actorID,
+		ActorRole: models.AdminRole,
+		RequestID: "request-1",
+		IP:        "203.0.113.7",
+	})
+
+	mockDB := new(MockMongoDatabase)
+	mockExerciseCollection := new(MockMongoCollection)
+	mockAuditCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	mockDB.On("Collection", "exercises").Return(mockExerciseCollection)
+	mockDB.On("Collection", "auditLogs").Return(mockAuditCollection)
+	mockExerciseCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": exerciseID}, mock.Anything, mock.Anything).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*primitive.M")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*bson.M) = bson.M{"_id": exerciseID, "name": "Push ups", "time": int32(60)}
+	}).Return(nil)
+	mockAuditCollection.On("InsertOne", ctx, mock.MatchedBy(func(entry models.AuditLog) bool {
+		return entry.ActorID == actorID && entry.ActorRole == models.AdminRole &&
+			entry.RequestID == "request-1" && entry.IP == "203.0.113.7" &&
+			entry.Action == models.AuditActionUpdate && entry.TargetCollection == "exercises" && entry.TargetID == exerciseID.Hex() &&
+			assert.ObjectsAreEqual(bson.M{"name": "Push ups"}, entry.Before) &&
+			assert.ObjectsAreEqual(bson.M{"name": "Diamond push ups"}, entry.After)
+	})).Return(*new(db.MongoInsertOneResult), nil)
+
+	name := "Diamond push ups"
+	err := adminService.UpdateExercise(ctx, exerciseID, models.ExerciseUpdateInput{Name: &name})
+	assert.NoError(t, err)
+	mockAuditCollection.AssertExpectations(t)
+}
+
+func TestUpdateExerciseFailure_NotFoundIsNotAudited(t *testing.T) {
+	ctx := context.Background()
+	exerciseID := primitive.NewObjectID()
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	mockDB.On("Collection", "exercises").Return(mockCollection)
+	mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": exerciseID}, mock.Anything, mock.Anything).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Decode", mock.Anything).Return(mongo.ErrNoDocuments)
+
+	name := "Diamond push ups"
+	err := adminService.UpdateExercise(ctx, exerciseID, models.ExerciseUpdateInput{Name: &name})
+	assert.Equal(t, services.ErrExerciseNotFound, err)
+	mockDB.AssertNotCalled(t, "Collection", "auditLogs")
+}
+
+func TestGetAuditLogsAppliesFiltersAndPagination(t *testing.T) {
+	ctx := context.Background()
+	actorID := primitive.NewObjectID()
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockCursor := new(MockMongoCursor)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	filter := bson.M{"actorId": actorID, "action": models.AuditActionDelete, "targetCollection": "meals"}
+	mockDB.On("Collection", "auditLogs").Return(mockCollection)
+	mockCollection.On("CountDocuments", ctx, filter).Return(int64(45), nil)
+	mockCollection.On("Find", ctx, filter, mock.MatchedBy(func(opts []*options.FindOptions) bool {
+		return len(opts) == 1 && *opts[0].Skip == 40 && *opts[0].Limit == 20
+	})).Return(mockCursor, nil)
+	mockCursor.On("All", ctx, mock.AnythingOfType("*[]models.AuditLog")).Return(nil)
+	mockCursor.On("Close", ctx).Return(nil)
+
+	page, err := adminService.GetAuditLogs(ctx, models.AuditLogQuery{
+		ActorID:          actorID.Hex(),
+		Action:           models.AuditActionDelete,
+		TargetCollection: "meals",
+		Page:             3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(45), page.Total)
+	assert.Equal(t, int64(3), page.Page)
+	assert.Equal(t, int64(20), page.Limit)
+	mockCollection.AssertExpectations(t)
+}
@@ -0,0 +1,200 @@
+package s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// expectEntitlementUser serves the subscription and trial end of the user checked for entitlement.
+func expectEntitlementUser(users *MockMongoCollection, userID primitive.ObjectID, subscription models.UserSubscription, trialEndsAt time.Time) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		user := args.Get(0).(*models.User)
+		user.Subscription = subscription
+		user.TrialEndsAt = trialEndsAt
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, bson.M{"_id": userID}, mock.Anything).Return(result)
+}
+
+func TestCheckEntitlement(t *testing.T) {
+	now := time.Now()
+	periodEnd := now.AddDate(0, 0, 10)
+	basic := models.UserSubscription{Type: models.SubscriptionTypeBasic, Status: models.SubscriptionStatusActive, EndDate: &periodEnd, IsActive: true}
+	premium := models.UserSubscription{Type: models.SubscriptionTypePremium, Status: models.SubscriptionStatusActive, EndDate: &periodEnd, IsActive: true}
+	cancelled := premium
+	cancelled.Status = models.SubscriptionStatusCancelled
+	trial := subscriptionWithStatus(models.SubscriptionStatusTrialing, now.Add(time.Hour))
+	endedTrial := subscriptionWithStatus(models.SubscriptionStatusTrialing, now.Add(-time.Hour))
+	expired := models.UserSubscription{Type: models.SubscriptionTypePremium, Status: models.SubscriptionStatusExpired}
+
+	tests := []struct {
+		name         string
+		subscription models.UserSubscription
+		trialEndsAt  time.Time
+		requiredTier string
+		wantErr      error
+		currentTier  string
+	}{
+		{name: "trial covers premium", subscription: trial, trialEndsAt: now.Add(time.Hour), requiredTier: models.AccessTierPremium},
+		{name: "untiered content is basic", subscription: basic, requiredTier: ""},
+		{name: "basic plan covers basic", subscription: basic, requiredTier: models.AccessTierBasic},
+		{name: "premium plan covers basic", subscription: premium, requiredTier: models.AccessTierBasic},
+		{name: "cancelled plan keeps access until period end", subscription: cancelled, requiredTier: models.AccessTierPremium},
+		{name: "basic plan needs an upgrade", subscription: basic, requiredTier: models.AccessTierPremium, wantErr: services.ErrUpgradeRequired, currentTier: models.AccessTierBasic},
+		{name: "ended trial needs a subscription", subscription: endedTrial, trialEndsAt: now.Add(-time.Hour), requiredTier: models.AccessTierBasic, wantErr: services.ErrSubscriptionRequired},
+		{name: "expired subscription needs a subscription", subscription: expired, requiredTier: models.AccessTierPremium, wantErr: services.ErrSubscriptionRequired},
+		{name: "trial end alone gives no access", subscription: expired, trialEndsAt: now.Add(time.Hour), requiredTier: models.AccessTierBasic, wantErr: services.ErrSubscriptionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService, _, users := newSubscriptionTestServices()
+			userID := primitive.NewObjectID()
+			expectEntitlementUser(users, userID, tt.subscription, tt.trialEndsAt)
+
+			err := userService.CheckEntitlement(context.Background(), userID, tt.requiredTier)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			var entitlementErr *services.EntitlementError
+			if assert.True(t, errors.As(err, &entitlementErr)) {
+				assert.Equal(t, tt.requiredTier, entitlementErr.RequiredTier)
+				assert.Equal(t, tt.currentTier, entitlementErr.CurrentTier)
+			}
+		})
+	}
+}
+
+func TestJoinPremiumWorkoutPlanRequiresPremium(t *testing.T) {
+	ctx := context.Background()
+	userID, workoutPlanID := primitive.NewObjectID(), primitive.NewObjectID()
+	periodEnd := time.Now().AddDate(0, 0, 10)
+
+	users := new(MockMongoCollection)
+	workoutPlans := new(MockMongoCollection)
+	statuses := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "workoutPlans").Return(workoutPlans)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(statuses)
+	userService := services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{TrialDays: 14})
+
+	plan := new(MockMongoSingleResult)
+	plan.On("Decode", mock.AnythingOfType("*models.WorkoutPlan")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.WorkoutPlan) = models.WorkoutPlan{ID: workoutPlanID, Name: "Strength", AccessTier: models.AccessTierPremium}
+	}).Return(nil)
+	workoutPlans.On("FindOne", ctx, bson.M{"_id": workoutPlanID, "status": bson.M{"$in": bson.A{models.PlanStatusPublished, nil}}}, mock.Anything).Return(plan)
+	expectEntitlementUser(users, userID, models.UserSubscription{Type: models.SubscriptionTypeBasic, Status: models.SubscriptionStatusActive, EndDate: &periodEnd, IsActive: true}, time.Time{})
+
+	err := userService.JoinWorkoutPlan(ctx, userID, workoutPlanID)
+
+	assert.ErrorIs(t, err, services.ErrUpgradeRequired)
+	statuses.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestGetDailyExercisesChecksActiveWorkoutPlanTier(t *testing.T) {
+	ctx := context.Background()
+	userID, workoutPlanID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	users := new(MockMongoCollection)
+	workoutPlans := new(MockMongoCollection)
+	statuses := new(MockMongoCollection)
+	exercises := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "workoutPlans").Return(workoutPlans)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(statuses)
+	mockDB.On("Collection", "exercises").Return(exercises)
+	userService := services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{TrialDays: 14})
+
+	status := new(MockMongoSingleResult)
+	status.On("Decode", mock.AnythingOfType("*models.UserWorkoutPlanStatus")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.UserWorkoutPlanStatus).WorkoutPlanID = workoutPlanID
+	}).Return(nil)
+	statuses.On("FindOne", ctx, bson.M{"userId": userID, "completed": false}, mock.Anything).Return(status)
+	plan := new(MockMongoSingleResult)
+	plan.On("Decode", mock.AnythingOfType("*models.WorkoutPlan")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.WorkoutPlan).AccessTier = models.AccessTierPremium
+	}).Return(nil)
+	workoutPlans.On("FindOne", ctx, bson.M{"_id": workoutPlanID, "status": bson.M{"$nin": bson.A{models.PlanStatusDraft, models.PlanStatusInReview}}}, mock.Anything).Return(plan)
+	expectEntitlementUser(users, userID, models.UserSubscription{Type: models.SubscriptionTypePremium, Status: models.SubscriptionStatusExpired}, time.Time{})
+
+	_, err := userService.GetDailyExercisesByIDs(ctx, userID, []primitive.ObjectID{primitive.NewObjectID()})
+
+	var entitlementErr *services.EntitlementError
+	assert.ErrorIs(t, err, services.ErrSubscriptionRequired)
+	if assert.True(t, errors.As(err, &entitlementErr)) {
+		assert.Equal(t, models.AccessTierPremium, entitlementErr.RequiredTier)
+	}
+	exercises.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetDailyExercisesFailure_ExerciseNotInEnrolledVersion(t *testing.T) {
+	ctx := context.Background()
+	userID, workoutPlanID := primitive.NewObjectID(), primitive.NewObjectID()
+	planExerciseID, otherExerciseID := primitive.NewObjectID(), primitive.NewObjectID()
+	periodEnd := time.Now().AddDate(0, 0, 10)
+
+	users := new(MockMongoCollection)
+	versions := new(MockMongoCollection)
+	statuses := new(MockMongoCollection)
+	exercises := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "workoutPlanVersions").Return(versions)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(statuses)
+	mockDB.On("Collection", "exercises").Return(exercises)
+	userService := services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{TrialDays: 14})
+
+	status := new(MockMongoSingleResult)
+	status.On("Decode", mock.AnythingOfType("*models.UserWorkoutPlanStatus")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.UserWorkoutPlanStatus) = models.UserWorkoutPlanStatus{WorkoutPlanID: workoutPlanID, Version: 2}
+	}).Return(nil)
+	statuses.On("FindOne", ctx, bson.M{"userId": userID, "completed": false}, mock.Anything).Return(status)
+	version := new(MockMongoSingleResult)
+	version.On("Decode", mock.AnythingOfType("*models.WorkoutPlanVersion")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.WorkoutPlanVersion).Plan = *workoutPlanWithStatus(workoutPlanID, models.PlanStatusPublished, planExerciseID)
+	}).Return(nil)
+	versions.On("FindOne", ctx, bson.M{"workoutPlanId": workoutPlanID, "version": 2}, mock.Anything).Return(version)
+	expectEntitlementUser(users, userID, models.UserSubscription{Type: models.SubscriptionTypeBasic, Status: models.SubscriptionStatusActive, EndDate: &periodEnd, IsActive: true}, time.Time{})
+
+	_, err := userService.GetDailyExercisesByIDs(ctx, userID, []primitive.ObjectID{planExerciseID, otherExerciseID})
+
+	assert.ErrorIs(t, err, services.ErrExerciseNotInWorkoutPlan)
+	exercises.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetDailyExercisesFailure_NoActiveWorkoutPlan(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	statuses := new(MockMongoCollection)
+	exercises := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(statuses)
+	mockDB.On("Collection", "exercises").Return(exercises)
+	userService := services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{TrialDays: 14})
+
+	status := new(MockMongoSingleResult)
+	status.On("Decode", mock.AnythingOfType("*models.UserWorkoutPlanStatus")).Return(mongo.ErrNoDocuments)
+	statuses.On("FindOne", ctx, bson.M{"userId": userID, "completed": false}, mock.Anything).Return(status)
+
+	_, err := userService.GetDailyExercisesByIDs(ctx, userID, []primitive.ObjectID{primitive.NewObjectID()})
+
+	assert.ErrorIs(t, err, services.ErrActiveWorkoutPlanNotFound)
+	exercises.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,218 @@
+package s
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/paymentstub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type invoiceTestCollections struct {
+	users, paymentEvents, promoCodes, invoices *MockMongoCollection
+}
+
+func newInvoiceTestServices() (*services.UserService, *services.AdminService, invoiceTestCollections) {
+	collections := invoiceTestCollections{
+		users:         new(MockMongoCollection),
+		paymentEvents: new(MockMongoCollection),
+		promoCodes:    new(MockMongoCollection),
+		invoices:      new(MockMongoCollection),
+	}
+	collections.paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectPaymentEventDone(collections.paymentEvents)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(collections.users)
+	expectSubscriptionEvents(mockDB)
+	mockDB.On("Collection", "paymentEvents").Return(collections.paymentEvents)
+	mockDB.On("Collection", "promoCodes").Return(collections.promoCodes)
+	mockDB.On("Collection", "invoices").Return(collections.invoices)
+	cfg := &config.Config{
+		TrialDays:               14,
+		PaymentWebhookSecret:    paymentstub.Secret,
+		PaymentWebhookTolerance: 5 * time.Minute,
+		InvoiceCompanyName:      "Vigor",
+		InvoiceCompanyAddress:   []string{"1 Main Street", "75001 Paris"},
+		InvoiceCompanyTaxID:     "FR12345678901",
+		GiftMonthlyPrices:       map[string]int64{models.SubscriptionTypePremium: 900},
+		GiftCurrency:            "USD",
+	}
+	return services.NewUserService(mockDB, new(MockHasher), new(MockParser), cfg), services.NewAdminService(mockDB, new(MockHasher), new(MockParser), cfg), collections
+}
+
+// expectInvoiceInsert captures the invoice recorded from a payment event.
+func expectInvoiceInsert(invoices *MockMongoCollection, err error) *models.Invoice {
+	recorded := new(models.Invoice)
+	invoices.On("InsertOne", mock.Anything, mock.AnythingOfType("models.Invoice")).Run(func(args mock.Arguments) {
+		*recorded = args.Get(1).(models.Invoice)
+	}).Return(*new(db.MongoInsertOneResult), err)
+	return recorded
+}
+
+func TestPaidInvoiceIsRecorded(t *testing.T) {
+	userService, _, collections := newInvoiceTestServices()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	periodEnd := time.Now().AddDate(0, 1, 0).Truncate(time.Second).UTC()
+	expectProviderSubscription(collections.users, "sub_1", userID)
+	expectSubscription(collections.users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now()), 1)
+	recorded := expectInvoiceInsert(collections.invoices, nil)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.PaidInvoice("sub_1", periodEnd, "eur", 1299, 260))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, userID, recorded.UserID)
+	assert.Equal(t, models.InvoiceKindSubscription, recorded.Kind)
+	assert.Equal(t, "VIGOR-0001", recorded.Number)
+	assert.Equal(t, "EUR", recorded.Currency)
+	assert.Equal(t, int64(1559), recorded.Total)
+	assert.NotEmpty(t, recorded.PaymentEventID)
+	if assert.Len(t, recorded.Lines, 1) {
+		assert.Equal(t, int64(1299), recorded.Lines[0].Amount)
+	}
+}
+
+func TestRedeliveredInvoiceIsNotRecordedTwice(t *testing.T) {
+	userService, _, collections := newInvoiceTestServices()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	expectProviderSubscription(collections.users, "sub_1", userID)
+	expectSubscription(collections.users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now()), 1)
+	// The payment event record expired, the invoice of the event is still there
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	expectInvoiceInsert(collections.invoices, duplicate)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.PaidInvoice("sub_1", time.Now().AddDate(0, 1, 0), "eur", 1299, 0))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	collections.paymentEvents.AssertNotCalled(t, "DeleteOne", mock.Anything, mock.Anything)
+}
+
+func TestUnchargedInvoiceEventRecordsNoInvoice(t *testing.T) {
+	userService, _, collections := newInvoiceTestServices()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	expectProviderSubscription(collections.users, "sub_1", userID)
+	expectSubscription(collections.users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now()), 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", time.Now().AddDate(0, 1, 0)))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	collections.invoices.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestGiftCheckoutInvoicesBuyer(t *testing.T) {
+	userService, _, collections := newInvoiceTestServices()
+	stub := paymentstub.NewProvider()
+	buyerID := primitive.NewObjectID()
+	gift := models.PromoCode{ID: primitive.NewObjectID(), Type: models.PromoTypeGift, Plan: models.SubscriptionTypePremium, Months: 6, CreatedBy: buyerID}
+	collections.promoCodes.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.PromoCode")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PromoCode) = gift
+	}).Return(nil)
+	collections.promoCodes.On("FindOne", mock.Anything, bson.M{"_id": gift.ID, "type": models.PromoTypeGift}, mock.Anything).Return(result)
+	recorded := expectInvoiceInsert(collections.invoices, nil)
+
+	payload, header := stub.Deliver(utils.PaymentEventCheckoutCompleted, paymentstub.GiftCheckout(gift.ID.Hex(), "usd", 5000, 400))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, buyerID, recorded.UserID)
+	assert.Equal(t, models.InvoiceKindGift, recorded.Kind)
+	assert.True(t, strings.HasPrefix(recorded.Number, "VG-"))
+	assert.Equal(t, int64(5400), recorded.Total)
+	if assert.Len(t, recorded.Lines, 1) {
+		assert.Equal(t, "Gift: 6 months of premium", recorded.Lines[0].Description)
+		assert.Equal(t, int64(5000), recorded.Lines[0].Amount)
+	}
+}
+
+func newStoredInvoice(userID primitive.ObjectID) models.Invoice {
+	periodStart := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	return models.Invoice{
+		ID:            primitive.NewObjectID(),
+		Number:        "VIGOR-0001",
+		UserID:        userID,
+		Kind:          models.InvoiceKindSubscription,
+		Provider:      models.SubscriptionProviderStripe,
+		Currency:      "EUR",
+		Subtotal:      1299,
+		Tax:           260,
+		Total:         1559,
+		Lines:         []models.InvoiceLine{{Description: "Vigor Premium", Quantity: 1, Amount: 1299, PeriodStart: &periodStart, PeriodEnd: &periodEnd}},
+		CustomerName:  "=HYPERLINK(\"evil\")",
+		CustomerEmail: "jane@example.com",
+		IssuedAt:      periodStart,
+	}
+}
+
+func TestGetInvoicePDFRendersReceipt(t *testing.T) {
+	userService, _, collections := newInvoiceTestServices()
+	userID := primitive.NewObjectID()
+	invoice := newStoredInvoice(userID)
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.Invoice")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.Invoice) = invoice
+	}).Return(nil)
+	collections.invoices.On("FindOne", mock.Anything, bson.M{"_id": invoice.ID, "userId": userID}, mock.Anything).Return(result)
+
+	found, pdf, err := userService.GetInvoicePDF(context.Background(), userID, invoice.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "VIGOR-0001", found.Number)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+	for _, text := range []string{"(Receipt)", "(VIGOR-0001)", "(Tax ID: FR12345678901)", "(12.99 EUR)", "(2.60 EUR)", "(15.59 EUR)", "(March 2, 2026 - April 2, 2026)"} {
+		assert.Contains(t, string(pdf), text)
+	}
+}
+
+func TestGetInvoicePDFOfAnotherUserIsNotFound(t *testing.T) {
+	userService, _, collections := newInvoiceTestServices()
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.Invoice")).Return(mongo.ErrNoDocuments)
+	collections.invoices.On("FindOne", mock.Anything, mock.Anything, mock.Anything).Return(result)
+
+	_, _, err := userService.GetInvoicePDF(context.Background(), primitive.NewObjectID(), primitive.NewObjectID())
+
+	assert.ErrorIs(t, err, services.ErrInvoiceNotFound)
+}
+
+func TestExportInvoicesCSV(t *testing.T) {
+	_, adminService, collections := newInvoiceTestServices()
+	userID := primitive.NewObjectID()
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	cursor := new(MockMongoCursor)
+	cursor.On("All", mock.Anything, mock.AnythingOfType("*[]models.Invoice")).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]models.Invoice) = []models.Invoice{newStoredInvoice(userID)}
+	}).Return(nil)
+	cursor.On("Close", mock.Anything).Return(nil)
+	collections.invoices.On("Find", mock.Anything, bson.M{"issuedAt": bson.M{"$gte": from, "$lt": to}}, mock.Anything).Return(cursor, nil)
+
+	export, err := adminService.ExportInvoicesCSV(context.Background(), from, to)
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(export)), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "number,issued_at,user_id,kind,provider,provider_invoice_id,currency,subtotal,tax,total,customer_name,customer_email,customer_tax_id", lines[0])
+		// Formulas are neutralized
+		assert.Equal(t, "VIGOR-0001,2026-03-02T00:00:00Z,"+userID.Hex()+",subscription,stripe,,EUR,12.99,2.60,15.59,\"'=HYPERLINK(\"\"evil\"\")\",jane@example.com,", lines[1])
+	}
+}
@@ -0,0 +1,329 @@
+package s
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/oidcstub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// oidcTestSetup wires a user service to a stub provider named "stub" and mocked collections.
+type oidcTestSetup struct {
+	stub        *oidcstub.Provider
+	userService *services.UserService
+	states      *MockMongoCollection
+	users       *MockMongoCollection
+	pending     *MockMongoCollection
+	links       *MockMongoCollection
+}
+
+func newOIDCTestSetup(t *testing.T) *oidcTestSetup {
+	stub := oidcstub.NewProvider()
+	t.Cleanup(stub.Close)
+
+	setup := &oidcTestSetup{
+		stub:    stub,
+		states:  new(MockMongoCollection),
+		users:   new(MockMongoCollection),
+		pending: new(MockMongoCollection),
+		links:   new(MockMongoCollection),
+	}
+
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "oidcLoginStates").Return(setup.states)
+	mockDB.On("Collection", "users").Return(setup.users)
+	mockDB.On("Collection", "pendingRegistrations").Return(setup.pending)
+	mockDB.On("Collection", "pendingIdentityLinks").Return(setup.links)
+
+	setup.userService = services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{
+		OIDCProviders: []utils.OIDCProviderConfig{{
+			Name:        "stub",
+			Issuer:      stub.Issuer(),
+			ClientID:    oidcstub.ClientID,
+			RedirectURI: oidcstub.RedirectURI,
+		}},
+	})
+	return setup
+}
+
+// signIn starts a login, signs the account in at the stub provider and returns the callback input with the stored state.
+func (s *oidcTestSetup) signIn(t *testing.T, ctx context.Context, account oidcstub.Account) (models.OIDCCallbackInput, models.OIDCLoginState) {
+	var stored models.OIDCLoginState
+	s.states.On("InsertOne", ctx, mock.AnythingOfType("models.OIDCLoginState")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.OIDCLoginState)
+	}).Return(*new(db.MongoInsertOneResult), nil).Once()
+
+	authorization, err := s.userService.BeginOIDCLogin(ctx, "stub")
+	assert.NoError(t, err)
+
+	authorizationURL, err := url.Parse(authorization.AuthorizationURL)
+	assert.NoError(t, err)
+	query := authorizationURL.Query()
+	assert.Equal(t, stored.Nonce, query.Get("nonce"))
+	assert.Equal(t, utils.HashToken(authorization.State), stored.StateHash, "only the hash of the state should be stored")
+
+	code := s.stub.Authorize(account, query.Get("nonce"), query.Get("code_challenge"))
+	return models.OIDCCallbackInput{Code: code, State: authorization.State}, stored
+}
+
+func (s *oidcTestSetup) expectStateLookup(ctx context.Context, stored models.OIDCLoginState) {
+	stateResult := new(MockMongoSingleResult)
+	stateResult.On("Decode", mock.AnythingOfType("*models.OIDCLoginState")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.OIDCLoginState) = stored
+	}).Return(nil)
+	s.states.On("FindOneAndDelete", ctx, bson.M{"stateHash": stored.StateHash, "provider": "stub"}).Return(stateResult).Once()
+}
+
+func (s *oidcTestSetup) expectUserLookup(ctx context.Context, filter bson.M, user *models.User) {
+	result := new(MockMongoSingleResult)
+	if user == nil {
+		result.On("Decode", mock.AnythingOfType("*models.User")).Return(mongo.ErrNoDocuments)
+	} else {
+		result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+			*args.Get(0).(*models.User) = *user
+		}).Return(nil)
+	}
+	s.users.On("FindOne", ctx, filter, mock.AnythingOfType("[]*options.FindOneOptions")).Return(result).Once()
+}
+
+func identityFilter(subject string) bson.M {
+	return bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": "stub", "subject": subject}}}
+}
+
+var verifiedAccount = oidcstub.Account{Subject: "stub-42", Email: "jane@example.com", EmailVerified: true, GivenName: "Jane", FamilyName: "Doe"}
+
+func TestCompleteOIDCLoginSignsInLinkedUser(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	input, stored := setup.signIn(t, ctx, verifiedAccount)
+
+	linked := &models.User{ID: primitive.NewObjectID(), Role: "user", Email: "jane@example.com"}
+	setup.expectStateLookup(ctx, stored)
+	setup.expectUserLookup(ctx, identityFilter("stub-42"), linked)
+
+	result, err := setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, linked.ID, result.User.ID)
+	assert.Empty(t, result.RegistrationToken)
+	setup.users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCompleteOIDCLoginLinksUserWithVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	input, stored := setup.signIn(t, ctx, verifiedAccount)
+
+	// Another provider verified the email of the account when it was registered
+	existing := &models.User{ID: primitive.NewObjectID(), Role: "user", Email: "jane@example.com", Identities: []models.ExternalIdentity{
+		{Provider: "other", Subject: "other-7", Email: "Jane@example.com"},
+	}}
+	setup.expectStateLookup(ctx, stored)
+	setup.expectUserLookup(ctx, identityFilter("stub-42"), nil)
+	setup.expectUserLookup(ctx, bson.M{"email": "jane@example.com"}, existing)
+	setup.users.On("UpdateOne", ctx, bson.M{"_id": existing.ID}, mock.MatchedBy(func(update bson.M) bool {
+		identity, ok := update["$push"].(bson.M)["identities"].(models.ExternalIdentity)
+		return ok && identity.Provider == "stub" && identity.Subject == "stub-42" && identity.Email == "jane@example.com"
+	})).Return(*new(db.MongoUpdateResult), nil)
+
+	result, err := setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, result.User.ID)
+	setup.users.AssertExpectations(t)
+}
+
+func TestCompleteOIDCLoginWaitsForPasswordToLinkUnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	input, stored := setup.signIn(t, ctx, verifiedAccount)
+
+	// Registered with a password, nothing proves the email is the registrant's
+	existing := &models.User{ID: primitive.NewObjectID(), Role: "user", Email: "jane@example.com"}
+	setup.expectStateLookup(ctx, stored)
+	setup.expectUserLookup(ctx, identityFilter("stub-42"), nil)
+	setup.expectUserLookup(ctx, bson.M{"email": "jane@example.com"}, existing)
+	var link models.PendingIdentityLink
+	setup.links.On("InsertOne", ctx, mock.AnythingOfType("models.PendingIdentityLink")).Run(func(args mock.Arguments) {
+		link = args.Get(1).(models.PendingIdentityLink)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	result, err := setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result.User)
+	assert.NotEmpty(t, result.LinkToken)
+	assert.Equal(t, utils.HashToken(result.LinkToken), link.TokenHash)
+	assert.Equal(t, existing.ID, link.UserID)
+	assert.Equal(t, "stub-42", link.Identity.Subject)
+	setup.users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLinkPendingIdentityLinksAfterPasswordSignIn(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	userID := primitive.NewObjectID()
+
+	linkResult := new(MockMongoSingleResult)
+	linkResult.On("Decode", mock.AnythingOfType("*models.PendingIdentityLink")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PendingIdentityLink) = models.PendingIdentityLink{
+			UserID:    userID,
+			Identity:  models.ExternalIdentity{Provider: "stub", Subject: "stub-42", Email: "jane@example.com"},
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+	}).Return(nil)
+	setup.links.On("FindOneAndDelete", ctx, bson.M{"tokenHash": utils.HashToken("link-token"), "userId": userID}).Return(linkResult)
+	setup.users.On("UpdateOne", ctx, bson.M{"_id": userID}, mock.MatchedBy(func(update bson.M) bool {
+		identity, ok := update["$push"].(bson.M)["identities"].(models.ExternalIdentity)
+		return ok && identity.Subject == "stub-42" && !identity.LinkedAt.IsZero()
+	})).Return(*new(db.MongoUpdateResult), nil)
+
+	err := setup.userService.LinkPendingIdentity(ctx, userID, "link-token")
+
+	assert.NoError(t, err)
+	setup.users.AssertExpectations(t)
+}
+
+func TestLinkPendingIdentityFailure_TokenOfAnotherUser(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+
+	missing := new(MockMongoSingleResult)
+	missing.On("Decode", mock.AnythingOfType("*models.PendingIdentityLink")).Return(mongo.ErrNoDocuments)
+	setup.links.On("FindOneAndDelete", ctx, mock.Anything).Return(missing)
+
+	err := setup.userService.LinkPendingIdentity(ctx, primitive.NewObjectID(), "link-token")
+
+	assert.ErrorIs(t, err, services.ErrInvalidLinkToken)
+	setup.users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCompleteOIDCLoginStartsRegistrationForNewIdentity(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	input, stored := setup.signIn(t, ctx, verifiedAccount)
+
+	var pending models.PendingRegistration
+	setup.expectStateLookup(ctx, stored)
+	setup.expectUserLookup(ctx, identityFilter("stub-42"), nil)
+	setup.expectUserLookup(ctx, bson.M{"email": "jane@example.com"}, nil)
+	setup.pending.On("InsertOne", ctx, mock.AnythingOfType("models.PendingRegistration")).Run(func(args mock.Arguments) {
+		pending = args.Get(1).(models.PendingRegistration)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	result, err := setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result.User)
+	assert.NotEmpty(t, result.RegistrationToken)
+	assert.Equal(t, &models.OIDCProfile{Email: "jane@example.com", FirstName: "Jane", LastName: "Doe"}, result.Profile)
+	assert.Equal(t, utils.HashToken(result.RegistrationToken), pending.TokenHash)
+	assert.Equal(t, "stub-42", pending.Identity.Subject)
+	assert.True(t, pending.ExpiresAt.After(time.Now()))
+}
+
+func TestCompleteOIDCLoginFailure_UnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	account := verifiedAccount
+	account.EmailVerified = false
+	input, stored := setup.signIn(t, ctx, account)
+
+	setup.expectStateLookup(ctx, stored)
+	setup.expectUserLookup(ctx, identityFilter("stub-42"), nil)
+
+	_, err := setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+
+	assert.ErrorIs(t, err, services.ErrOIDCEmailNotVerified)
+	setup.pending.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestCompleteOIDCLoginFailure_InvalidState(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+	input, stored := setup.signIn(t, ctx, verifiedAccount)
+
+	// Unknown or already used state
+	missing := new(MockMongoSingleResult)
+	missing.On("Decode", mock.AnythingOfType("*models.OIDCLoginState")).Return(mongo.ErrNoDocuments)
+	setup.states.On("FindOneAndDelete", ctx, bson.M{"stateHash": stored.StateHash, "provider": "stub"}).Return(missing).Once()
+	_, err := setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+	assert.ErrorIs(t, err, services.ErrInvalidOIDCState)
+
+	// Expired state not yet removed by the TTL index
+	stored.ExpiresAt = time.Now().Add(-time.Second)
+	setup.expectStateLookup(ctx, stored)
+	_, err = setup.userService.CompleteOIDCLogin(ctx, "stub", input)
+	assert.ErrorIs(t, err, services.ErrInvalidOIDCState)
+
+	assert.Equal(t, 0, setup.stub.TokenRequests, "the code should not be redeemed without a valid state")
+	_, err = setup.userService.CompleteOIDCLogin(ctx, "unknown", input)
+	assert.ErrorIs(t, err, services.ErrUnknownOIDCProvider)
+}
+
+func TestCompleteOIDCRegistrationCreatesPasswordlessUser(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+
+	pending := models.PendingRegistration{
+		ID:             primitive.NewObjectID(),
+		TokenHash:      utils.HashToken("registration-token"),
+		Identity:       models.ExternalIdentity{Provider: "stub", Subject: "stub-42", Email: "jane@example.com", LinkedAt: time.Now()},
+		Email:          "jane@example.com",
+		ProfilePicture: "https://example.com/jane.png",
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	pendingResult := new(MockMongoSingleResult)
+	pendingResult.On("Decode", mock.AnythingOfType("*models.PendingRegistration")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PendingRegistration) = pending
+	}).Return(nil)
+	setup.pending.On("FindOne", ctx, bson.M{"tokenHash": pending.TokenHash}, mock.AnythingOfType("[]*options.FindOneOptions")).Return(pendingResult)
+	setup.users.On("CountDocuments", ctx, bson.M{"email": "jane@example.com"}).Return(int64(0), nil)
+	setup.users.On("CountDocuments", ctx, bson.M{"profileInformation.username": "janedoe"}).Return(int64(0), nil)
+	setup.users.On("InsertOne", ctx, mock.MatchedBy(func(user models.User) bool {
+		return user.Email == "jane@example.com" && user.PasswordHash == "" && user.FirstName == "Jane" &&
+			user.ProfileInformation.ProfilePicture == "https://example.com/jane.png" &&
+			len(user.Identities) == 1 && user.Identities[0].Subject == "stub-42"
+	})).Return(*new(db.MongoInsertOneResult), nil)
+	setup.pending.On("DeleteOne", ctx, bson.M{"_id": pending.ID}).Return(*new(db.MongoDeleteResult), nil)
+
+	user, err := setup.userService.CompleteOIDCRegistration(ctx, models.OIDCRegistrationInput{
+		RegistrationToken:  "registration-token",
+		FirstName:          "Jane",
+		LastName:           "Doe",
+		ProfileInformation: models.UserProfileInput{Username: "janedoe"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user", user.Role)
+	setup.users.AssertExpectations(t)
+	setup.pending.AssertExpectations(t)
+}
+
+func TestCompleteOIDCRegistrationFailure_ExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	setup := newOIDCTestSetup(t)
+
+	pendingResult := new(MockMongoSingleResult)
+	pendingResult.On("Decode", mock.AnythingOfType("*models.PendingRegistration")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PendingRegistration) = models.PendingRegistration{ExpiresAt: time.Now().Add(-time.Minute)}
+	}).Return(nil)
+	setup.pending.On("FindOne", ctx, mock.Anything, mock.Anything).Return(pendingResult)
+
+	_, err := setup.userService.CompleteOIDCRegistration(ctx, models.OIDCRegistrationInput{RegistrationToken: "stale"})
+
+	assert.ErrorIs(t, err, services.ErrInvalidRegistrationToken)
+	setup.users.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
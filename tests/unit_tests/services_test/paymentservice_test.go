@@ -0,0 +1,262 @@
+package s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/paymentstub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newPaymentTestService() (*services.UserService, *MockMongoCollection, *MockMongoCollection) {
+	users := new(MockMongoCollection)
+	paymentEvents := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	expectSubscriptionEvents(mockDB)
+	mockDB.On("Collection", "paymentEvents").Return(paymentEvents)
+	expectPaymentEventDone(paymentEvents)
+	cfg := &config.Config{TrialDays: 14, PaymentWebhookSecret: paymentstub.Secret, PaymentWebhookTolerance: 5 * time.Minute}
+	return services.NewUserService(mockDB, new(MockHasher), new(MockParser), cfg), users, paymentEvents
+}
+
+// expectPaymentEventDone accepts marking the claimed payment events done.
+func expectPaymentEventDone(paymentEvents *MockMongoCollection) {
+	paymentEvents.On("UpdateOne", mock.Anything, mock.Anything, mock.MatchedBy(func(update bson.M) bool {
+		return update["$set"].(bson.M)["status"] == models.PaymentEventDone
+	})).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+}
+
+// expectRecordedPaymentEvent makes the event already recorded with the status, claimed now so it can't be taken over.
+func expectRecordedPaymentEvent(paymentEvents *MockMongoCollection, status string) {
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), duplicate)
+	notStale := new(MockMongoSingleResult)
+	notStale.On("Err").Return(mongo.ErrNoDocuments)
+	paymentEvents.On("FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(notStale)
+	recorded := new(MockMongoSingleResult)
+	recorded.On("Decode", mock.AnythingOfType("*models.PaymentEvent")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.PaymentEvent).Status = status
+	}).Return(nil)
+	paymentEvents.On("FindOne", mock.Anything, mock.Anything, mock.Anything).Return(recorded)
+}
+
+// expectProviderSubscription serves the user owning the provider subscription.
+func expectProviderSubscription(users *MockMongoCollection, providerSubscriptionID string, userID primitive.ObjectID) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = userID
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, bson.M{"subscription.providerSubscriptionId": providerSubscriptionID}, mock.Anything).Return(result)
+}
+
+func TestCheckoutCompletedActivatesPaidPlan(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	stored := subscriptionWithStatus(models.SubscriptionStatusExpired, time.Now().Add(-time.Hour))
+	stored.Type = models.SubscriptionTypeBasic
+	written := expectSubscription(users, userID, stored, 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventCheckoutCompleted, paymentstub.CheckoutCompleted(userID.Hex(), models.SubscriptionTypePremium, "cus_1", "sub_1"))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, written.Status)
+	assert.Equal(t, models.SubscriptionTypePremium, written.Type)
+	assert.Nil(t, written.PendingChange)
+	assert.True(t, written.IsActive)
+	assert.Equal(t, "cus_1", written.ProviderCustomerID)
+	assert.Equal(t, "sub_1", written.ProviderSubscriptionID)
+	// The first paid activation converts the user
+	assert.NotNil(t, written.ConvertedAt)
+}
+
+func TestInvoicePaidRenewsForBilledPeriod(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	periodEnd := time.Now().AddDate(0, 1, 0).Truncate(time.Second).UTC()
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "sub_1", userID)
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusPastDue, time.Now().Add(-time.Hour)), 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", periodEnd))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, written.Status)
+	if assert.NotNil(t, written.EndDate) {
+		assert.Equal(t, periodEnd, *written.EndDate)
+	}
+	assert.Equal(t, written.EndDate, written.NextRenewalDate)
+}
+
+func TestInvoicePaidDropsRequestedChangeNotBilled(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "sub_1", userID)
+	stored := subscriptionWithStatus(models.SubscriptionStatusPastDue, time.Now().Add(-time.Hour))
+	stored.Type = models.SubscriptionTypeBasic
+	stored.PendingChange = &models.SubscriptionChange{Type: models.SubscriptionTypePremium, RequestedAt: time.Now().Add(-time.Hour)}
+	written := expectSubscription(users, userID, stored, 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.PlanInvoice("sub_1", models.SubscriptionTypeBasic, time.Now().AddDate(0, 1, 0)))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, written.Status)
+	// The upgrade was requested but the provider billed the basic plan
+	assert.Equal(t, models.SubscriptionTypeBasic, written.Type)
+	assert.Nil(t, written.PendingChange)
+}
+
+func TestPaymentFailedMovesToPastDue(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "sub_1", userID)
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().Add(time.Hour)), 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventPaymentFailed, paymentstub.Invoice("sub_1", time.Now()))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusPastDue, written.Status)
+	assert.True(t, written.IsActive)
+}
+
+func TestSubscriptionDeletedExpiresActiveSubscription(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "sub_1", userID)
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().Add(time.Hour)), 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventSubscriptionDeleted, paymentstub.Subscription("sub_1"))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusExpired, written.Status)
+	assert.False(t, written.IsActive)
+	assert.NotNil(t, written.CancelledAt)
+}
+
+func TestRedeliveredPaymentEventIsSkipped(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	expectRecordedPaymentEvent(paymentEvents, models.PaymentEventDone)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", time.Now().AddDate(0, 1, 0)))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.ErrorIs(t, err, services.ErrPaymentEventAlreadyReceived)
+	users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedeliveredPaymentEventFailure_StillProcessing(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	expectRecordedPaymentEvent(paymentEvents, models.PaymentEventProcessing)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", time.Now().AddDate(0, 1, 0)))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	// Not acknowledged, the provider delivers it again in case the delivery processing it fails
+	assert.ErrorIs(t, err, services.ErrPaymentEventInProgress)
+	users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedeliveredPaymentEventTakesOverStaleClaim(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), duplicate)
+	reclaimed := new(MockMongoSingleResult)
+	reclaimed.On("Err").Return(nil)
+	paymentEvents.On("FindOneAndUpdate", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		claimedAt, ok := filter["claimedAt"].(bson.M)["$lt"].(time.Time)
+		return ok && filter["status"] == models.PaymentEventProcessing && claimedAt.Before(time.Now().Add(-4*time.Minute))
+	}), mock.Anything, mock.Anything).Return(reclaimed)
+	expectProviderSubscription(users, "sub_1", userID)
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().Add(time.Hour)), 1)
+
+	payload, header := stub.Deliver(utils.PaymentEventSubscriptionDeleted, paymentstub.Subscription("sub_1"))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusExpired, written.Status)
+	paymentEvents.AssertCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFailedPaymentEventIsForgottenForRetry(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	userID := primitive.NewObjectID()
+	var recorded models.PaymentEvent
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(models.PaymentEvent)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "sub_1", userID)
+	// Another request changed the subscription in between
+	expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().Add(time.Hour)), 0)
+	paymentEvents.On("DeleteOne", mock.Anything, mock.Anything).Return(db.MongoDeleteResult{DeletedCount: 1}, nil)
+
+	payload, header := stub.Deliver(utils.PaymentEventPaymentFailed, paymentstub.Invoice("sub_1", time.Now()))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.ErrorIs(t, err, services.ErrSubscriptionChanged)
+	paymentEvents.AssertCalled(t, "DeleteOne", mock.Anything, bson.M{"_id": recorded.ID, "claimedAt": recorded.ClaimedAt})
+	paymentEvents.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUnknownPaymentSubscriptionIsAcknowledged(t *testing.T) {
+	userService, users, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.Anything).Return(mongo.ErrNoDocuments)
+	users.On("FindOne", mock.Anything, bson.M{"subscription.providerSubscriptionId": "sub_unknown"}, mock.Anything).Return(result)
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_unknown", time.Now().AddDate(0, 1, 0)))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	paymentEvents.AssertNotCalled(t, "DeleteOne", mock.Anything, mock.Anything)
+}
+
+func TestPaymentWebhookRejectsBadSignatureBeforeRecording(t *testing.T) {
+	userService, _, paymentEvents := newPaymentTestService()
+	stub := paymentstub.NewProvider()
+	payload := stub.Event(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", time.Now()))
+
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, stub.Sign(payload, time.Now().Add(-time.Hour)))
+
+	assert.ErrorIs(t, err, utils.ErrInvalidWebhookSignature)
+	paymentEvents.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestPaymentWebhookNeedsConfiguredProvider(t *testing.T) {
+	userService := services.NewUserService(new(MockMongoDatabase), new(MockHasher), new(MockParser), &config.Config{TrialDays: 14})
+	payload, header := paymentstub.NewProvider().Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", time.Now()))
+
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.ErrorIs(t, err, services.ErrPaymentsNotConfigured)
+}
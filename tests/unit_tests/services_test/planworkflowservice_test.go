@@ -0,0 +1,169 @@
+package s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type planWorkflowCollections struct {
+	plans       *MockMongoCollection
+	drafts      *MockMongoCollection
+	exercises   *MockMongoCollection
+	versions    *MockMongoCollection
+	enrollments *MockMongoCollection
+}
+
+func newPlanWorkflowTestService() (*services.AdminService, *planWorkflowCollections) {
+	collections := &planWorkflowCollections{
+		plans:       new(MockMongoCollection),
+		drafts:      new(MockMongoCollection),
+		exercises:   new(MockMongoCollection),
+		versions:    new(MockMongoCollection),
+		enrollments: new(MockMongoCollection),
+	}
+	auditLogs := new(MockMongoCollection)
+	auditLogs.On("InsertOne", mock.Anything, mock.Anything).Return(*new(db.MongoInsertOneResult), nil)
+
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "workoutPlans").Return(collections.plans)
+	mockDB.On("Collection", "workoutPlanDrafts").Return(collections.drafts)
+	mockDB.On("Collection", "exercises").Return(collections.exercises)
+	mockDB.On("Collection", "workoutPlanVersions").Return(collections.versions)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(collections.enrollments)
+	mockDB.On("Collection", "auditLogs").Return(auditLogs)
+	return services.NewAdminService(mockDB, &utils.DefaultHasher{}, new(MockParser), &config.Config{}), collections
+}
+
+// expectPlan serves plan from collection, no plan is found when it is nil.
+func expectPlan(collection *MockMongoCollection, planID primitive.ObjectID, plan *models.WorkoutPlan) {
+	result := new(MockMongoSingleResult)
+	if plan == nil {
+		result.On("Decode", mock.Anything).Return(mongo.ErrNoDocuments)
+	} else {
+		result.On("Decode", mock.AnythingOfType("*models.WorkoutPlan")).Run(func(args mock.Arguments) {
+			*args.Get(0).(*models.WorkoutPlan) = *plan
+		}).Return(nil)
+		result.On("Decode", mock.AnythingOfType("*primitive.M")).Run(func(args mock.Arguments) {
+			*args.Get(0).(*bson.M) = bson.M{"_id": planID, "name": plan.Name}
+		}).Return(nil)
+	}
+	collection.On("FindOne", mock.Anything, bson.M{"_id": planID}, mock.Anything).Return(result)
+}
+
+func workoutPlanWithStatus(planID primitive.ObjectID, status string, exerciseID primitive.ObjectID) *models.WorkoutPlan {
+	return &models.WorkoutPlan{
+		ID:   planID,
+		Name: "Full body",
+		Weeks: []models.WorkoutWeek{{
+			ID:         primitive.NewObjectID(),
+			WeekNumber: 1,
+			Days: []models.WorkoutDay{{
+				ID:       primitive.NewObjectID(),
+				Workouts: []models.Circuit{{ID: primitive.NewObjectID(), ExerciseIDs: []primitive.ObjectID{exerciseID}, ProposedLaps: 3}},
+			}},
+		}},
+		PlanPublication: models.PlanPublication{Status: status},
+	}
+}
+
+func TestUpdatePublishedWorkoutPlanEditsDraftCopy(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newPlanWorkflowTestService()
+	planID := primitive.NewObjectID()
+	published := workoutPlanWithStatus(planID, "", primitive.NewObjectID()) // Created before the workflow
+
+	expectPlan(collections.drafts, planID, nil)
+	expectPlan(collections.plans, planID, published)
+	var inserted models.WorkoutPlan
+	collections.drafts.On("InsertOne", ctx, mock.AnythingOfType("models.WorkoutPlan")).Run(func(args mock.Arguments) {
+		inserted = args.Get(1).(models.WorkoutPlan)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	name := "Full body, revised"
+	draft, err := adminService.UpdateWorkoutPlan(ctx, planID, models.WorkoutPlanInput{Name: &name})
+
+	assert.NoError(t, err)
+	assert.Equal(t, planID, inserted.ID)
+	assert.Equal(t, name, inserted.Name)
+	assert.Equal(t, models.PlanStatusDraft, inserted.Status)
+	assert.Equal(t, published.Weeks[0].ID, inserted.Weeks[0].ID)
+	assert.Equal(t, inserted, *draft)
+	collections.plans.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransitionWorkoutPlanFailure_PublishWithoutReview(t *testing.T) {
+	adminService, collections := newPlanWorkflowTestService()
+	planID := primitive.NewObjectID()
+
+	expectPlan(collections.drafts, planID, nil)
+	expectPlan(collections.plans, planID, workoutPlanWithStatus(planID, models.PlanStatusDraft, primitive.NewObjectID()))
+
+	_, err := adminService.TransitionWorkoutPlan(context.Background(), planID, models.PlanTransitionInput{Status: models.PlanStatusPublished})
+
+	assert.ErrorIs(t, err, services.ErrInvalidPlanTransition)
+	collections.plans.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransitionWorkoutPlanFailure_MissingExercises(t *testing.T) {
+	adminService, collections := newPlanWorkflowTestService()
+	planID := primitive.NewObjectID()
+
+	expectPlan(collections.drafts, planID, nil)
+	expectPlan(collections.plans, planID, workoutPlanWithStatus(planID, models.PlanStatusInReview, primitive.NewObjectID()))
+	collections.exercises.On("CountDocuments", mock.Anything, mock.Anything).Return(int64(0), nil)
+
+	_, err := adminService.TransitionWorkoutPlan(context.Background(), planID, models.PlanTransitionInput{Status: models.PlanStatusPublished})
+
+	assert.ErrorIs(t, err, services.ErrPlanNotPublishable)
+	collections.plans.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPublishWorkoutPlanDraftReplacesPublishedContent(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newPlanWorkflowTestService()
+	planID, exerciseID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	reviewed := workoutPlanWithStatus(planID, models.PlanStatusInReview, exerciseID)
+	reviewed.Name = "Full body, revised"
+	expectPlan(collections.drafts, planID, reviewed)
+	live := workoutPlanWithStatus(planID, models.PlanStatusPublished, exerciseID)
+	live.Version = 1
+	expectPlan(collections.plans, planID, live)
+	collections.exercises.On("CountDocuments", ctx, bson.M{"_id": bson.M{"$in": []primitive.ObjectID{exerciseID}}}).Return(int64(1), nil)
+
+	var set bson.M
+	collections.plans.On("UpdateOne", ctx, bson.M{"_id": planID}, mock.Anything).Run(func(args mock.Arguments) {
+		set = args.Get(2).(bson.M)["$set"].(bson.M)
+	}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil).Once()
+	collections.plans.On("UpdateOne", ctx, bson.M{"_id": planID}, bson.M{"$set": bson.M{"version": 2}}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+	collections.drafts.On("DeleteOne", ctx, bson.M{"_id": planID}).Return(db.MongoDeleteResult{DeletedCount: 1}, nil)
+	var version models.WorkoutPlanVersion
+	collections.versions.On("InsertOne", ctx, mock.AnythingOfType("models.WorkoutPlanVersion")).Run(func(args mock.Arguments) {
+		version = args.Get(1).(models.WorkoutPlanVersion)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	workoutPlan, err := adminService.TransitionWorkoutPlan(ctx, planID, models.PlanTransitionInput{Status: models.PlanStatusPublished})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlanStatusPublished, workoutPlan.Status)
+	assert.NotNil(t, workoutPlan.PublishedAt)
+	assert.Equal(t, reviewed.Name, set["name"])
+	assert.Equal(t, models.PlanStatusPublished, set["status"])
+	assert.NotContains(t, set, "_id")
+	assert.EqualValues(t, 2, set["version"])
+	assert.Equal(t, 2, version.Version)
+	assert.Equal(t, reviewed.Name, version.Plan.Name)
+	collections.drafts.AssertExpectations(t)
+	collections.enrollments.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,351 @@
+package s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/paymentstub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type promoCodeTestCollections struct {
+	users, promoCodes, redemptions, paymentEvents, invoices, auditLogs *MockMongoCollection
+}
+
+func newPromoCodeTestServices() (*services.UserService, *services.AdminService, promoCodeTestCollections) {
+	collections := promoCodeTestCollections{
+		users:         new(MockMongoCollection),
+		promoCodes:    new(MockMongoCollection),
+		redemptions:   new(MockMongoCollection),
+		paymentEvents: new(MockMongoCollection),
+		invoices:      new(MockMongoCollection),
+		auditLogs:     new(MockMongoCollection),
+	}
+	collections.auditLogs.On("InsertOne", mock.Anything, mock.AnythingOfType("models.AuditLog")).Return(*new(db.MongoInsertOneResult), nil)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(collections.users)
+	expectSubscriptionEvents(mockDB)
+	mockDB.On("Collection", "promoCodes").Return(collections.promoCodes)
+	mockDB.On("Collection", "promoRedemptions").Return(collections.redemptions)
+	mockDB.On("Collection", "paymentEvents").Return(collections.paymentEvents)
+	mockDB.On("Collection", "invoices").Return(collections.invoices)
+	mockDB.On("Collection", "auditLogs").Return(collections.auditLogs)
+	cfg := &config.Config{
+		TrialDays:               14,
+		PaymentWebhookSecret:    paymentstub.Secret,
+		PaymentWebhookTolerance: 5 * time.Minute,
+		GiftMonthlyPrices:       map[string]int64{models.SubscriptionTypeBasic: 699, models.SubscriptionTypePremium: 1299},
+		GiftCurrency:            "EUR",
+	}
+	return services.NewUserService(mockDB, new(MockHasher), new(MockParser), cfg), services.NewAdminService(mockDB, new(MockHasher), new(MockParser), cfg), collections
+}
+
+// expectPromoCode serves the code when it is looked up by its code.
+func expectPromoCode(promoCodes *MockMongoCollection, promoCode models.PromoCode) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.PromoCode")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PromoCode) = promoCode
+	}).Return(nil)
+	promoCodes.On("FindOne", mock.Anything, bson.M{"code": promoCode.Code}, mock.Anything).Return(result)
+}
+
+// expectRedemptionClaim accepts the redemption record and counts it on the code when matched.
+func expectRedemptionClaim(collections promoCodeTestCollections, matched int64) {
+	collections.redemptions.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PromoRedemption")).Return(*new(db.MongoInsertOneResult), nil)
+	collections.promoCodes.On("UpdateOne", mock.Anything, mock.Anything, bson.M{"$inc": bson.M{"redemptions": 1}}).Return(db.MongoUpdateResult{MatchedCount: matched}, nil)
+}
+
+func newPromoCode(promoType string) models.PromoCode {
+	return models.PromoCode{ID: primitive.NewObjectID(), Code: "SPRING2026", Type: promoType, CreatedAt: time.Now()}
+}
+
+func TestRedeemTrialExtensionMovesTrialEnd(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	userID := primitive.NewObjectID()
+	promoCode := newPromoCode(models.PromoTypeTrialExtension)
+	promoCode.TrialDays = 7
+	expectPromoCode(collections.promoCodes, promoCode)
+	expectRedemptionClaim(collections, 1)
+	trialEnd := time.Now().AddDate(0, 0, 3).Truncate(time.Second)
+	stored := subscriptionWithStatus(models.SubscriptionStatusTrialing, trialEnd)
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Subscription = stored
+	}).Return(nil)
+	collections.users.On("FindOne", mock.Anything, bson.M{"_id": userID}, mock.Anything).Return(result)
+	var set bson.M
+	collections.users.On("UpdateOne", mock.Anything, bson.M{"_id": userID, "subscription.version": bson.M{"$exists": false}}, mock.Anything).Run(func(args mock.Arguments) {
+		set = args.Get(2).(bson.M)["$set"].(bson.M)
+	}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+
+	subscription, err := userService.RedeemPromoCode(context.Background(), userID, " spring2026 ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, trialEnd.AddDate(0, 0, 7), *subscription.EndDate)
+	assert.Equal(t, models.SubscriptionStatusTrialing, subscription.Status)
+	assert.Equal(t, trialEnd.AddDate(0, 0, 7), set["trialEndsAt"])
+}
+
+func TestRedeemGiftGivesNonRenewingPlan(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	userID := primitive.NewObjectID()
+	paidAt := time.Now().Add(-time.Hour)
+	gift := newPromoCode(models.PromoTypeGift)
+	gift.Plan = models.SubscriptionTypeBasic
+	gift.Months = 3
+	gift.MaxRedemptions = 1
+	gift.Gift = &models.GiftDetails{PaidAt: &paidAt}
+	expectPromoCode(collections.promoCodes, gift)
+	expectRedemptionClaim(collections, 1)
+	stored := subscriptionWithStatus(models.SubscriptionStatusExpired, time.Now().Add(-time.Hour))
+	stored.IsActive = false
+	stored.Provider = models.SubscriptionProviderStripe
+	stored.ProviderSubscriptionID = "sub_1"
+	written := expectSubscription(collections.users, userID, stored, 1)
+
+	_, err := userService.RedeemPromoCode(context.Background(), userID, "SPRING2026")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusCancelled, written.Status)
+	assert.Equal(t, models.SubscriptionTypeBasic, written.Type)
+	assert.True(t, written.IsActive)
+	assert.Nil(t, written.NextRenewalDate)
+	assert.Equal(t, models.SubscriptionProviderGift, written.Provider)
+	assert.Empty(t, written.ProviderSubscriptionID)
+	if assert.NotNil(t, written.EndDate) {
+		assert.WithinDuration(t, time.Now().AddDate(0, 3, 0), *written.EndDate, time.Minute)
+	}
+}
+
+func TestRedeemGiftRejectedWhenUnusable(t *testing.T) {
+	unpaid := newPromoCode(models.PromoTypeGift)
+	unpaid.Gift = &models.GiftDetails{}
+	disabledAt := time.Now().Add(-time.Hour)
+	disabled := newPromoCode(models.PromoTypeTrialExtension)
+	disabled.DisabledAt = &disabledAt
+	expired := newPromoCode(models.PromoTypeTrialExtension)
+	expired.ExpiresAt = &disabledAt
+
+	tests := []struct {
+		name      string
+		promoCode models.PromoCode
+		wantErr   error
+	}{
+		{name: "unpaid gift", promoCode: unpaid, wantErr: services.ErrPromoCodeNotFound},
+		{name: "disabled", promoCode: disabled, wantErr: services.ErrPromoCodeNotFound},
+		{name: "expired", promoCode: expired, wantErr: services.ErrPromoCodeExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService, _, collections := newPromoCodeTestServices()
+			expectPromoCode(collections.promoCodes, tt.promoCode)
+
+			_, err := userService.RedeemPromoCode(context.Background(), primitive.NewObjectID(), tt.promoCode.Code)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			collections.redemptions.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+		})
+	}
+
+	userService, _, collections := newPromoCodeTestServices()
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.Anything).Return(mongo.ErrNoDocuments)
+	collections.promoCodes.On("FindOne", mock.Anything, bson.M{"code": "UNKNOWN"}, mock.Anything).Return(result)
+	_, err := userService.RedeemPromoCode(context.Background(), primitive.NewObjectID(), "unknown")
+	assert.ErrorIs(t, err, services.ErrPromoCodeNotFound)
+}
+
+func TestRedeemPromoCodeOncePerUser(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	promoCode := newPromoCode(models.PromoTypeTrialExtension)
+	expectPromoCode(collections.promoCodes, promoCode)
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	collections.redemptions.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PromoRedemption")).Return(*new(db.MongoInsertOneResult), duplicate)
+
+	_, err := userService.RedeemPromoCode(context.Background(), primitive.NewObjectID(), "SPRING2026")
+
+	assert.ErrorIs(t, err, services.ErrPromoCodeAlreadyRedeemed)
+	collections.promoCodes.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedeemExhaustedPromoCode(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	promoCode := newPromoCode(models.PromoTypeTrialExtension)
+	promoCode.MaxRedemptions = 100
+	expectPromoCode(collections.promoCodes, promoCode)
+	var recorded models.PromoRedemption
+	collections.redemptions.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PromoRedemption")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(models.PromoRedemption)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	limit := bson.M{"_id": promoCode.ID, "disabledAt": bson.M{"$exists": false}, "redemptions": bson.M{"$lt": 100}}
+	collections.promoCodes.On("UpdateOne", mock.Anything, limit, mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 0}, nil)
+	collections.redemptions.On("DeleteOne", mock.Anything, mock.Anything).Return(db.MongoDeleteResult{DeletedCount: 1}, nil)
+
+	_, err := userService.RedeemPromoCode(context.Background(), primitive.NewObjectID(), "SPRING2026")
+
+	assert.ErrorIs(t, err, services.ErrPromoCodeExhausted)
+	collections.redemptions.AssertCalled(t, "DeleteOne", mock.Anything, bson.M{"_id": recorded.ID})
+	collections.users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRedeemNotApplicablePromoCodeIsReleased(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	userID := primitive.NewObjectID()
+	promoCode := newPromoCode(models.PromoTypeTrialExtension)
+	promoCode.TrialDays = 7
+	expectPromoCode(collections.promoCodes, promoCode)
+	expectRedemptionClaim(collections, 1)
+	expectSubscription(collections.users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().AddDate(0, 0, 10)), 1)
+	collections.redemptions.On("DeleteOne", mock.Anything, mock.Anything).Return(db.MongoDeleteResult{DeletedCount: 1}, nil)
+	collections.promoCodes.On("UpdateOne", mock.Anything, bson.M{"_id": promoCode.ID}, bson.M{"$inc": bson.M{"redemptions": -1}}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+
+	_, err := userService.RedeemPromoCode(context.Background(), userID, "SPRING2026")
+
+	assert.ErrorIs(t, err, services.ErrPromoCodeNotApplicable)
+	collections.redemptions.AssertCalled(t, "DeleteOne", mock.Anything, bson.M{"codeId": promoCode.ID, "userId": userID})
+	collections.promoCodes.AssertCalled(t, "UpdateOne", mock.Anything, bson.M{"_id": promoCode.ID}, bson.M{"$inc": bson.M{"redemptions": -1}})
+	collections.users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// expectGift serves the gift code when the payment webhook looks it up.
+func expectGift(promoCodes *MockMongoCollection, gift models.PromoCode) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.PromoCode")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PromoCode) = gift
+	}).Return(nil)
+	promoCodes.On("FindOne", mock.Anything, bson.M{"_id": gift.ID, "type": models.PromoTypeGift}, mock.Anything).Return(result)
+}
+
+func TestGiftCheckoutMarksGiftPaid(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	stub := paymentstub.NewProvider()
+	gift := newPromoCode(models.PromoTypeGift)
+	gift.Plan = models.SubscriptionTypeBasic
+	gift.Months = 3
+	collections.paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectPaymentEventDone(collections.paymentEvents)
+	expectGift(collections.promoCodes, gift)
+	filter := bson.M{"_id": gift.ID, "type": models.PromoTypeGift, "gift.paidAt": bson.M{"$exists": false}}
+	collections.promoCodes.On("UpdateOne", mock.Anything, filter, mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+	expectInvoiceInsert(collections.invoices, nil)
+
+	payload, header := stub.Deliver(utils.PaymentEventCheckoutCompleted, paymentstub.GiftCheckout(gift.ID.Hex(), "eur", 1747, 350))
+	err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+	assert.NoError(t, err)
+	collections.promoCodes.AssertCalled(t, "UpdateOne", mock.Anything, filter, mock.Anything)
+	// The subscription of the buyer is left alone
+	collections.users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGiftCheckoutIgnoredWhenChargeIsNotThePrice(t *testing.T) {
+	gift := newPromoCode(models.PromoTypeGift)
+	gift.Plan = models.SubscriptionTypePremium
+	gift.Months = 6
+
+	tests := []struct {
+		name     string
+		currency string
+		subtotal int64
+	}{
+		{name: "one month charged", currency: "eur", subtotal: 1299},
+		{name: "other currency", currency: "usd", subtotal: 7794},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService, _, collections := newPromoCodeTestServices()
+			stub := paymentstub.NewProvider()
+			collections.paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+			expectPaymentEventDone(collections.paymentEvents)
+			expectGift(collections.promoCodes, gift)
+
+			payload, header := stub.Deliver(utils.PaymentEventCheckoutCompleted, paymentstub.GiftCheckout(gift.ID.Hex(), tt.currency, tt.subtotal, 0))
+			err := userService.ProcessPaymentWebhook(context.Background(), payload, header)
+
+			assert.NoError(t, err)
+			collections.promoCodes.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			collections.invoices.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPurchaseGiftCreatesUnpaidCode(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	userID := primitive.NewObjectID()
+	collections.promoCodes.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PromoCode")).Return(*new(db.MongoInsertOneResult), nil)
+
+	gift, err := userService.PurchaseGift(context.Background(), userID, models.GiftPurchaseInput{Plan: models.SubscriptionTypePremium, Months: 6, RecipientEmail: "friend@example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PromoTypeGift, gift.Type)
+	assert.Len(t, gift.Code, 12)
+	assert.Equal(t, 1, gift.MaxRedemptions)
+	assert.Equal(t, userID, gift.CreatedBy)
+	assert.Nil(t, gift.Gift.PaidAt)
+}
+
+func TestCreatePromoCode(t *testing.T) {
+	_, adminService, collections := newPromoCodeTestServices()
+	collections.promoCodes.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PromoCode")).Return(*new(db.MongoInsertOneResult), nil)
+
+	promoCode, err := adminService.CreatePromoCode(context.Background(), primitive.NewObjectID(), models.PromoCodeInput{Code: "summer10", Type: models.PromoTypeTrialExtension, TrialDays: 7})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SUMMER10", promoCode.Code)
+	assert.Equal(t, 7, promoCode.TrialDays)
+
+	_, err = adminService.CreatePromoCode(context.Background(), primitive.NewObjectID(), models.PromoCodeInput{Type: models.PromoTypeTrialExtension})
+	assert.ErrorIs(t, err, services.ErrInvalidPromoCode)
+
+	// Discounts can't be charged until the API creates the checkouts
+	_, err = adminService.CreatePromoCode(context.Background(), primitive.NewObjectID(), models.PromoCodeInput{Type: "percent_off", TrialDays: 7})
+	assert.ErrorIs(t, err, services.ErrInvalidPromoCode)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = adminService.CreatePromoCode(context.Background(), primitive.NewObjectID(), models.PromoCodeInput{Type: models.PromoTypeTrialExtension, TrialDays: 7, ExpiresAt: &past})
+	assert.ErrorIs(t, err, services.ErrInvalidPromoCodeExpiry)
+}
+
+func TestGetPromoCodeStats(t *testing.T) {
+	_, adminService, collections := newPromoCodeTestServices()
+	promoCode := newPromoCode(models.PromoTypeTrialExtension)
+	promoCode.MaxRedemptions = 10
+	promoCode.Redemptions = 3
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.PromoCode")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.PromoCode) = promoCode
+	}).Return(nil)
+	collections.promoCodes.On("FindOne", mock.Anything, bson.M{"_id": promoCode.ID}, mock.Anything).Return(result)
+	day := time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC)
+	cursor := new(MockMongoCursor)
+	cursor.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]models.PromoRedemption) = []models.PromoRedemption{
+			{RedeemedAt: day},
+			{RedeemedAt: day.Add(5 * time.Hour)},
+			{RedeemedAt: day.AddDate(0, 0, 2)},
+		}
+	}).Return(nil)
+	cursor.On("Close", mock.Anything).Return(nil)
+	collections.redemptions.On("Find", mock.Anything, bson.M{"codeId": promoCode.ID}, mock.Anything).Return(cursor, nil)
+
+	stats, err := adminService.GetPromoCodeStats(context.Background(), promoCode.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Redemptions)
+	assert.Equal(t, 7, *stats.Remaining)
+	assert.Equal(t, day, *stats.FirstRedeemedAt)
+	assert.Equal(t, day.AddDate(0, 0, 2), *stats.LastRedeemedAt)
+	assert.Equal(t, []models.PromoRedemptionDay{{Date: "2026-03-20", Redemptions: 2}, {Date: "2026-03-22", Redemptions: 1}}, stats.RedemptionsByDay)
+}
@@ -0,0 +1,68 @@
+package s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPermissionsForRoleSuperAdminHasEverything(t *testing.T) {
+	mockDB := new(MockMongoDatabase)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	permissions, err := adminService.PermissionsForRole(context.Background(), models.SuperAdminRole)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, models.AllPermissions, permissions)
+	mockDB.AssertNotCalled(t, "Collection", mock.Anything)
+}
+
+func TestPermissionsForRoleIsCached(t *testing.T) {
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	mockDB.On("Collection", "roles").Return(mockCollection)
+	mockCollection.On("FindOne", ctx, bson.M{"_id": "analyst"}, mock.Anything).Return(mockMongoSingleResult).Once()
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.Role")).Run(func(args mock.Arguments) {
+		role := args.Get(0).(*models.Role)
+		role.Permissions = []string{models.PermAnalyticsRead}
+	}).Return(nil)
+
+	for i := 0; i < 2; i++ {
+		permissions, err := adminService.PermissionsForRole(ctx, "analyst")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{models.PermAnalyticsRead}, permissions)
+	}
+	mockCollection.AssertNumberOfCalls(t, "FindOne", 1)
+}
+
+func TestCreateRoleFailure_UnknownPermission(t *testing.T) {
+	mockDB := new(MockMongoDatabase)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	_, err := adminService.CreateRole(context.Background(), models.RoleInput{
+		Name:        "editor",
+		Permissions: []string{models.PermExercisesWrite, "everything:write"},
+	})
+	assert.True(t, errors.Is(err, services.ErrUnknownPermission))
+	mockDB.AssertNotCalled(t, "Collection", mock.Anything)
+}
+
+func TestUpdateRoleFailure_SuperAdminPermissions(t *testing.T) {
+	mockDB := new(MockMongoDatabase)
+	adminService := services.NewAdminService(mockDB, new(MockHasher), new(MockParser), &config.Config{})
+
+	_, err := adminService.UpdateRole(context.Background(), models.SuperAdminRole, models.RoleUpdateInput{
+		Permissions: []string{models.PermUsersRead},
+	})
+	assert.Equal(t, services.ErrSuperAdminRoleLock, err)
+}
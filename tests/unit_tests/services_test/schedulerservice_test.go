@@ -0,0 +1,308 @@
+package s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newSchedulerTestService() (*services.Scheduler, *MockMongoCollection, *MockMongoCollection) {
+	locks := new(MockMongoCollection)
+	runs := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "jobLocks").Return(locks)
+	mockDB.On("Collection", "jobRuns").Return(runs)
+	return services.NewScheduler(mockDB), locks, runs
+}
+
+// expectLockAcquired grants the lock to the instance that asks for it and accepts its release.
+func expectLockAcquired(locks *MockMongoCollection, job string) {
+	var owner string
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.JobLock")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.JobLock).Owner = owner
+	}).Return(nil)
+	locks.On("FindOneAndUpdate", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["_id"] == job
+	}), mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		owner = args.Get(2).(bson.M)["$set"].(bson.M)["owner"].(string)
+	}).Return(result)
+	locks.On("UpdateOne", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["_id"] == job
+	}), mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+}
+
+// expectJobRun captures the run written to the history.
+func expectJobRun(runs *MockMongoCollection) *models.JobRun {
+	recorded := new(models.JobRun)
+	runs.On("InsertOne", mock.Anything, mock.AnythingOfType("models.JobRun")).Run(func(args mock.Arguments) {
+		*recorded = args.Get(1).(models.JobRun)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	return recorded
+}
+
+func TestRunJobRecordsSuccessfulRun(t *testing.T) {
+	scheduler, locks, runs := newSchedulerTestService()
+	calls := 0
+	assert.NoError(t, scheduler.Register("cleanup", "*/15 * * * *", time.Minute, func(ctx context.Context) (string, error) {
+		calls++
+		return "3 codes deleted", nil
+	}))
+	expectLockAcquired(locks, "cleanup")
+	recorded := expectJobRun(runs)
+	slot := time.Date(2026, time.March, 2, 10, 15, 0, 0, time.UTC)
+
+	ran, err := scheduler.RunJob(context.Background(), "cleanup", slot)
+
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "cleanup", recorded.Job)
+	assert.Equal(t, models.JobRunSucceeded, recorded.Status)
+	assert.Equal(t, "3 codes deleted", recorded.Summary)
+	assert.Equal(t, slot, recorded.ScheduledAt)
+	assert.NotEmpty(t, recorded.Owner)
+	locks.AssertCalled(t, "UpdateOne", mock.Anything, bson.M{"_id": "cleanup", "owner": recorded.Owner}, mock.Anything)
+}
+
+func TestRunJobRecordsFailedRun(t *testing.T) {
+	scheduler, locks, runs := newSchedulerTestService()
+	assert.NoError(t, scheduler.Register("cleanup", "@daily", time.Minute, func(ctx context.Context) (string, error) {
+		return "", errors.New("database unavailable")
+	}))
+	expectLockAcquired(locks, "cleanup")
+	recorded := expectJobRun(runs)
+
+	ran, err := scheduler.RunJob(context.Background(), "cleanup", time.Now())
+
+	assert.Error(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, models.JobRunFailed, recorded.Status)
+	assert.Equal(t, "database unavailable", recorded.Error)
+}
+
+func TestRunJobRecordsPanicAsFailure(t *testing.T) {
+	scheduler, locks, runs := newSchedulerTestService()
+	assert.NoError(t, scheduler.Register("cleanup", "@daily", time.Minute, func(ctx context.Context) (string, error) {
+		panic("nil map")
+	}))
+	expectLockAcquired(locks, "cleanup")
+	recorded := expectJobRun(runs)
+
+	ran, err := scheduler.RunJob(context.Background(), "cleanup", time.Now())
+
+	assert.Error(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, models.JobRunFailed, recorded.Status)
+	assert.Contains(t, recorded.Error, "nil map")
+}
+
+func TestRunJobSkipsLockHeldByAnotherInstance(t *testing.T) {
+	scheduler, locks, runs := newSchedulerTestService()
+	calls := 0
+	assert.NoError(t, scheduler.Register("cleanup", "@daily", time.Minute, func(ctx context.Context) (string, error) {
+		calls++
+		return "", nil
+	}))
+	// The upsert collides with the lock document the filter didn't match
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.JobLock")).Return(duplicate)
+	locks.On("FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(result)
+
+	ran, err := scheduler.RunJob(context.Background(), "cleanup", time.Now())
+
+	assert.NoError(t, err)
+	assert.False(t, ran)
+	assert.Equal(t, 0, calls)
+	runs.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+	locks.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRunJobLocksOccurrenceOnce(t *testing.T) {
+	scheduler, locks, runs := newSchedulerTestService()
+	assert.NoError(t, scheduler.Register("cleanup", "@daily", 5*time.Minute, func(ctx context.Context) (string, error) {
+		return "", nil
+	}))
+	expectLockAcquired(locks, "cleanup")
+	expectJobRun(runs)
+	slot := time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+	_, err := scheduler.RunJob(context.Background(), "cleanup", slot)
+
+	assert.NoError(t, err)
+	locks.AssertCalled(t, "FindOneAndUpdate", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		// Only a free lock whose last run is an earlier occurrence can be taken
+		lastSlot, ok := filter["lastSlot"].(bson.M)
+		return ok && lastSlot["$lt"] == slot && filter["lockedUntil"] != nil
+	}), mock.MatchedBy(func(update bson.M) bool {
+		set := update["$set"].(bson.M)
+		return set["lastSlot"] == slot && set["lockedUntil"].(time.Time).After(time.Now().Add(4*time.Minute))
+	}), mock.Anything)
+}
+
+func TestRunJobRejectsUnknownJob(t *testing.T) {
+	scheduler, _, _ := newSchedulerTestService()
+
+	_, err := scheduler.RunJob(context.Background(), "missing", time.Now())
+
+	assert.ErrorIs(t, err, services.ErrJobNotFound)
+}
+
+func TestRegisterRejectsInvalidJobs(t *testing.T) {
+	scheduler, _, _ := newSchedulerTestService()
+	noop := func(ctx context.Context) (string, error) { return "", nil }
+
+	assert.ErrorIs(t, scheduler.Register("cleanup", "every day", time.Minute, noop), utils.ErrInvalidCronExpression)
+	assert.ErrorIs(t, scheduler.Register("cleanup", "@daily", 0, noop), services.ErrInvalidJobTimeout)
+	assert.NoError(t, scheduler.Register("cleanup", "@daily", time.Minute, noop))
+	assert.ErrorIs(t, scheduler.Register("cleanup", "@hourly", time.Minute, noop), services.ErrJobAlreadyRegistered)
+}
+
+func TestStopReturnsOnceJobsStopped(t *testing.T) {
+	scheduler, _, _ := newSchedulerTestService()
+	scheduler.Start()
+
+	assert.NoError(t, scheduler.Stop(context.Background()))
+	assert.NoError(t, scheduler.Stop(context.Background()))
+}
+
+// expectDueUsers serves the users a lifecycle job selects.
+func expectDueUsers(users *MockMongoCollection, due ...models.User) {
+	cursor := new(MockMongoCursor)
+	cursor.On("All", mock.Anything, mock.AnythingOfType("*[]models.User")).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]models.User) = due
+	}).Return(nil)
+	cursor.On("Close", mock.Anything).Return(nil)
+	users.On("Find", mock.Anything, mock.Anything, mock.Anything).Return(cursor, nil)
+}
+
+func TestExpireEndedTrialsExpiresTrial(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	expectDueUsers(users, models.User{ID: userID})
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusTrialing, time.Now().Add(-time.Hour)), 1)
+
+	summary, err := userService.ExpireEndedTrials(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1 trials expired", summary)
+	assert.Equal(t, models.SubscriptionStatusExpired, written.Status)
+	assert.False(t, written.IsActive)
+}
+
+func TestExpireEndedSubscriptionsEndsUnrenewedSubscription(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	cancelledID, lapsedID := primitive.NewObjectID(), primitive.NewObjectID()
+	expectDueUsers(users, models.User{ID: cancelledID}, models.User{ID: lapsedID})
+	cancelled := expectSubscription(users, cancelledID, subscriptionWithStatus(models.SubscriptionStatusCancelled, time.Now().Add(-time.Hour)), 1)
+	lapsed := expectSubscription(users, lapsedID, subscriptionWithStatus(models.SubscriptionStatusPastDue, time.Now().AddDate(0, 0, -4)), 1)
+
+	summary, err := userService.ExpireEndedSubscriptions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2 subscriptions expired", summary)
+	assert.Equal(t, models.SubscriptionStatusExpired, cancelled.Status)
+	assert.Equal(t, models.SubscriptionStatusExpired, lapsed.Status)
+	assert.False(t, lapsed.IsActive)
+}
+
+func TestExpireEndedSubscriptionsSkipsRenewedSubscription(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	expectDueUsers(users, models.User{ID: userID})
+	// Renewed between the selection and the update
+	expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().AddDate(0, 1, 0)), 1)
+
+	summary, err := userService.ExpireEndedSubscriptions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0 subscriptions expired", summary)
+	users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpireEndedSubscriptionsSkipsConcurrentChange(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	expectDueUsers(users, models.User{ID: userID})
+	expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusCancelled, time.Now().Add(-time.Hour)), 0)
+
+	summary, err := userService.ExpireEndedSubscriptions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0 subscriptions expired", summary)
+}
+
+type recordingNotifier struct {
+	notified map[string]utils.Notification
+	err      error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, userID string, notification utils.Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.notified[userID] = notification
+	return nil
+}
+
+func renewingUser(renewsAt time.Time) models.User {
+	subscription := subscriptionWithStatus(models.SubscriptionStatusActive, renewsAt)
+	subscription.NextRenewalDate = &renewsAt
+	return models.User{ID: primitive.NewObjectID(), Subscription: subscription}
+}
+
+func TestSendRenewalRemindersNotifiesClaimedUsers(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	notifier := &recordingNotifier{notified: map[string]utils.Notification{}}
+	userService.SetNotifier(notifier)
+	claimed, remindedElsewhere := renewingUser(time.Now().AddDate(0, 0, 2)), renewingUser(time.Now().AddDate(0, 0, 1))
+	expectDueUsers(users, claimed, remindedElsewhere)
+	users.On("UpdateOne", mock.Anything, mock.MatchedBy(func(filter bson.M) bool { return filter["_id"] == claimed.ID }), mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+	users.On("UpdateOne", mock.Anything, mock.MatchedBy(func(filter bson.M) bool { return filter["_id"] == remindedElsewhere.ID }), mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 0}, nil)
+
+	summary, err := userService.SendRenewalReminders(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1 renewal reminders sent", summary)
+	assert.Len(t, notifier.notified, 1)
+	reminder := notifier.notified[claimed.ID.Hex()]
+	assert.Equal(t, utils.NotificationRenewalReminder, reminder.Type)
+	assert.Equal(t, models.SubscriptionTypePremium, reminder.Data["plan"])
+}
+
+func TestSendRenewalRemindersUnclaimsFailedReminder(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userService.SetNotifier(&recordingNotifier{err: errors.New("mail server down")})
+	user := renewingUser(time.Now().AddDate(0, 0, 2))
+	expectDueUsers(users, user)
+	users.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+
+	_, err := userService.SendRenewalReminders(context.Background())
+
+	assert.Error(t, err)
+	users.AssertCalled(t, "UpdateOne", mock.Anything, bson.M{"_id": user.ID}, bson.M{"$unset": bson.M{"subscription.renewalRemindedAt": ""}, "$inc": bson.M{"subscription.version": 1}})
+}
+
+func TestDeleteUnpaidGiftsDeletesAbandonedCodes(t *testing.T) {
+	userService, _, collections := newPromoCodeTestServices()
+	collections.promoCodes.On("DeleteMany", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["type"] == models.PromoTypeGift && filter["gift.paidAt"] != nil
+	})).Return(db.MongoDeleteResult{DeletedCount: 2}, nil)
+
+	summary, err := userService.DeleteUnpaidGifts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2 unpaid gift codes deleted", summary)
+}
@@ -49,8 +49,8 @@ func (mc *MockMongoCollection) Indexes() db.MongoIndexView {
 	return args.Get(0).(db.MongoIndexView)
 }
 
-func (mc *MockMongoCollection) Find(ctx context.Context, filter interface{}) (db.MongoCursor, error) {
-	args := mc.Called(ctx, filter)
+func (mc *MockMongoCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (db.MongoCursor, error) {
+	args := mc.Called(ctx, filter, opts)
 	return args.Get(0).(db.MongoCursor), args.Error(1)
 }
 
@@ -59,11 +59,21 @@ func (mc *MockMongoCollection) FindOne(ctx context.Context, filter interface{},
 	return args.Get(0).(db.MongoSingleResult)
 }
 
+func (mc *MockMongoCollection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (db.MongoCursor, error) {
+	args := mc.Called(ctx, pipeline, opts)
+	return args.Get(0).(db.MongoCursor), args.Error(1)
+}
+
 func (mc *MockMongoCollection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) db.MongoSingleResult {
 	args := mc.Called(ctx, filter, update, opts)
 	return args.Get(0).(db.MongoSingleResult)
 }
 
+func (mc *MockMongoCollection) FindOneAndDelete(ctx context.Context, filter interface{}) db.MongoSingleResult {
+	args := mc.Called(ctx, filter)
+	return args.Get(0).(db.MongoSingleResult)
+}
+
 func (mc *MockMongoCollection) InsertMany(ctx context.Context, documents []interface{}) (db.MongoInsertManyResult, error) {
 	args := mc.Called(ctx, documents)
 	return args.Get(0).(db.MongoInsertManyResult), args.Error(1)
@@ -79,11 +89,21 @@ func (mc *MockMongoCollection) UpdateOne(ctx context.Context, filter interface{}
 	return args.Get(0).(db.MongoUpdateResult), args.Error(1)
 }
 
+func (mc *MockMongoCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (db.MongoUpdateResult, error) {
+	args := mc.Called(ctx, filter, update)
+	return args.Get(0).(db.MongoUpdateResult), args.Error(1)
+}
+
 func (mc *MockMongoCollection) DeleteOne(ctx context.Context, filter interface{}) (db.MongoDeleteResult, error) {
 	args := mc.Called(ctx, filter)
 	return args.Get(0).(db.MongoDeleteResult), args.Error(1)
 }
 
+func (mc *MockMongoCollection) DeleteMany(ctx context.Context, filter interface{}) (db.MongoDeleteResult, error) {
+	args := mc.Called(ctx, filter)
+	return args.Get(0).(db.MongoDeleteResult), args.Error(1)
+}
+
 type MockMongoSingleResult struct {
 	mock.Mock
 	db.MongoSingleResult
@@ -114,7 +134,27 @@ func (mh *MockHasher) CheckPasswordHash(password, hash string) bool {
 	return args.Bool(0)
 }
 
+func (mh *MockHasher) NeedsRehash(hash string) bool {
+	args := mh.Called(hash)
+	return args.Bool(0)
+}
+
 type MockMongoInsertOneResult struct {
 	mock.Mock
 	db.MongoInsertOneResult
 }
+
+type MockMongoCursor struct {
+	mock.Mock
+	db.MongoCursor
+}
+
+func (mc *MockMongoCursor) All(ctx context.Context, results interface{}) error {
+	args := mc.Called(ctx, results)
+	return args.Error(0)
+}
+
+func (mc *MockMongoCursor) Close(ctx context.Context) error {
+	args := mc.Called(ctx)
+	return args.Error(0)
+}
@@ -0,0 +1,220 @@
+package s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newSessionTestService() (*services.UserService, *MockMongoCollection, *MockMongoCollection) {
+	sessions := new(MockMongoCollection)
+	users := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "sessions").Return(sessions)
+	mockDB.On("Collection", "users").Return(users)
+	return services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{}), sessions, users
+}
+
+func expectAccountRestriction(users *MockMongoCollection, restriction *models.UserRestriction) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Restriction = restriction
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, mock.Anything, mock.Anything).Return(result).Once()
+}
+
+func expectSessionLookup(sessions *MockMongoCollection, session *models.Session) {
+	result := new(MockMongoSingleResult)
+	if session == nil {
+		result.On("Decode", mock.AnythingOfType("*models.Session")).Return(mongo.ErrNoDocuments)
+	} else {
+		result.On("Decode", mock.AnythingOfType("*models.Session")).Run(func(args mock.Arguments) {
+			*args.Get(0).(*models.Session) = *session
+		}).Return(nil)
+	}
+	sessions.On("FindOne", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["revokedAt"] != nil && filter["expiresAt"] != nil
+	}), mock.Anything).Return(result).Once()
+}
+
+func TestCreateSessionStoresOnlyTheRefreshTokenHash(t *testing.T) {
+	ctx := context.Background()
+	userService, sessions, users := newSessionTestService()
+	expectAccountRestriction(users, nil)
+
+	var stored models.Session
+	sessions.On("InsertOne", ctx, mock.AnythingOfType("models.Session")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.Session)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	err := userService.CreateSession(ctx, models.Session{ID: primitive.NewObjectID(), DeviceName: "Pixel 8"}, "refresh-token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.HashToken("refresh-token"), stored.RefreshTokenHash)
+	assert.Equal(t, "Pixel 8", stored.DeviceName)
+	assert.WithinDuration(t, time.Now().Add(utils.RefreshTokenLifetime), stored.ExpiresAt, time.Minute)
+}
+
+func TestCreateSessionRefusesRestrictedUsers(t *testing.T) {
+	ctx := context.Background()
+	ended := time.Now().Add(-time.Hour)
+	upcoming := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		restriction *models.UserRestriction
+		expected    error
+	}{
+		{"suspended", &models.UserRestriction{Type: models.UserRestrictionSuspended, Until: &upcoming}, services.ErrUserSuspended},
+		{"banned", &models.UserRestriction{Type: models.UserRestrictionBanned}, services.ErrUserBanned},
+		{"suspension ended", &models.UserRestriction{Type: models.UserRestrictionSuspended, Until: &ended}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService, sessions, users := newSessionTestService()
+			expectAccountRestriction(users, tt.restriction)
+			sessions.On("InsertOne", ctx, mock.AnythingOfType("models.Session")).Return(*new(db.MongoInsertOneResult), nil)
+
+			err := userService.CreateSession(ctx, models.Session{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID()}, "refresh-token")
+
+			assert.ErrorIs(t, err, tt.expected)
+			if tt.expected != nil {
+				sessions.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestIsSessionActive(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	sessionID := primitive.NewObjectID()
+
+	t.Run("revoked or expired session", func(t *testing.T) {
+		userService, sessions, _ := newSessionTestService()
+		expectSessionLookup(sessions, nil)
+
+		active, err := userService.IsSessionActive(ctx, userID, sessionID.Hex())
+
+		assert.NoError(t, err)
+		assert.False(t, active)
+	})
+
+	t.Run("recently used session is not written", func(t *testing.T) {
+		userService, sessions, _ := newSessionTestService()
+		expectSessionLookup(sessions, &models.Session{ID: sessionID, LastUsedAt: time.Now()})
+
+		active, err := userService.IsSessionActive(ctx, userID, sessionID.Hex())
+
+		assert.NoError(t, err)
+		assert.True(t, active)
+		sessions.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("stale last use is updated", func(t *testing.T) {
+		userService, sessions, _ := newSessionTestService()
+		expectSessionLookup(sessions, &models.Session{ID: sessionID, LastUsedAt: time.Now().Add(-time.Hour)})
+		sessions.On("UpdateOne", ctx, bson.M{"_id": sessionID}, mock.Anything).Return(*new(db.MongoUpdateResult), nil).Once()
+
+		active, err := userService.IsSessionActive(ctx, userID, sessionID.Hex())
+
+		assert.NoError(t, err)
+		assert.True(t, active)
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("malformed session ID", func(t *testing.T) {
+		userService, _, _ := newSessionTestService()
+
+		active, err := userService.IsSessionActive(ctx, userID, "")
+
+		assert.NoError(t, err)
+		assert.False(t, active)
+	})
+}
+
+func TestCheckSessionRefreshTokenRevokesOnReuse(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	sessionID := primitive.NewObjectID()
+	userService, sessions, _ := newSessionTestService()
+
+	expectSessionLookup(sessions, &models.Session{ID: sessionID, RefreshTokenHash: utils.HashToken("latest-token")})
+	valid, err := userService.CheckSessionRefreshToken(ctx, userID, sessionID.Hex(), "latest-token")
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	expectSessionLookup(sessions, &models.Session{ID: sessionID, RefreshTokenHash: utils.HashToken("latest-token")})
+	sessions.On("UpdateOne", ctx, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["_id"] == sessionID && filter["userId"] == userID
+	}), mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil).Once()
+
+	valid, err = userService.CheckSessionRefreshToken(ctx, userID, sessionID.Hex(), "rotated-token")
+
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	sessions.AssertExpectations(t)
+}
+
+func TestRotateSessionRefreshTokenRequiresTheCurrentToken(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	sessionID := primitive.NewObjectID()
+	userService, sessions, _ := newSessionTestService()
+
+	sessions.On("UpdateOne", ctx, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["refreshTokenHash"] == utils.HashToken("current-token")
+	}), mock.MatchedBy(func(update bson.M) bool {
+		return update["$set"].(bson.M)["refreshTokenHash"] == utils.HashToken("new-token")
+	})).Return(db.MongoUpdateResult{MatchedCount: 0}, nil).Once()
+
+	rotated, err := userService.RotateSessionRefreshToken(ctx, userID, sessionID.Hex(), "current-token", "new-token")
+
+	assert.NoError(t, err)
+	assert.False(t, rotated, "a token rotated concurrently should not be rotated again")
+	sessions.AssertExpectations(t)
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	sessionID := primitive.NewObjectID()
+
+	t.Run("success", func(t *testing.T) {
+		userService, sessions, _ := newSessionTestService()
+		sessions.On("UpdateOne", ctx, bson.M{"_id": sessionID, "userId": userID, "revokedAt": bson.M{"$exists": false}}, mock.Anything).
+			Return(db.MongoUpdateResult{MatchedCount: 1}, nil).Once()
+
+		assert.NoError(t, userService.RevokeSession(ctx, userID, sessionID.Hex()))
+		sessions.AssertExpectations(t)
+	})
+
+	t.Run("session of another user", func(t *testing.T) {
+		userService, sessions, _ := newSessionTestService()
+		sessions.On("UpdateOne", ctx, mock.Anything, mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 0}, nil).Once()
+
+		err := userService.RevokeSession(ctx, userID, sessionID.Hex())
+
+		assert.ErrorIs(t, err, services.ErrSessionNotFound)
+	})
+
+	t.Run("malformed session ID", func(t *testing.T) {
+		userService, _, _ := newSessionTestService()
+
+		err := userService.RevokeSession(ctx, userID, "not-an-id")
+
+		assert.ErrorIs(t, err, services.ErrSessionNotFound)
+	})
+}
@@ -0,0 +1,216 @@
+package s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/storestub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newStoreTestService() (*services.UserService, *MockMongoCollection, *MockMongoCollection) {
+	users := new(MockMongoCollection)
+	paymentEvents := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	expectSubscriptionEvents(mockDB)
+	mockDB.On("Collection", "paymentEvents").Return(paymentEvents)
+	expectPaymentEventDone(paymentEvents)
+	cfg := &config.Config{TrialDays: 14, StoreProducts: map[string]string{
+		"vigor.basic.monthly":   models.SubscriptionTypeBasic,
+		"vigor.premium.monthly": models.SubscriptionTypePremium,
+	}}
+	return services.NewUserService(mockDB, new(MockHasher), new(MockParser), cfg), users, paymentEvents
+}
+
+func TestVerifyAppStorePurchaseLinksSubscription(t *testing.T) {
+	userService, users, _ := newStoreTestService()
+	store := storestub.NewAppStore()
+	userService.SetAppStoreVerifier(utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, store.Roots()))
+	userID := primitive.NewObjectID()
+	expiresAt := time.Now().AddDate(0, 1, 0).Truncate(time.Millisecond).UTC()
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusTrialing, time.Now().AddDate(0, 0, 7)), 1)
+
+	subscription, err := userService.VerifyAppStorePurchase(context.Background(), userID, store.SignTransaction(storestub.NewTransaction("1000001", "vigor.premium.monthly", expiresAt)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, *written, *subscription)
+	assert.Equal(t, models.SubscriptionStatusActive, written.Status)
+	assert.Equal(t, models.SubscriptionTypePremium, written.Type)
+	assert.Equal(t, models.SubscriptionProviderAppStore, written.Provider)
+	assert.Equal(t, "1000001", written.ProviderSubscriptionID)
+	if assert.NotNil(t, written.EndDate) {
+		assert.Equal(t, expiresAt, *written.EndDate)
+	}
+}
+
+func TestVerifyAppStorePurchaseRejectsUnusablePurchases(t *testing.T) {
+	userService, users, _ := newStoreTestService()
+	store := storestub.NewAppStore()
+	userService.SetAppStoreVerifier(utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, store.Roots()))
+	userID := primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		transaction storestub.Transaction
+		wantErr     error
+	}{
+		{name: "expired", transaction: storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().Add(-time.Hour)), wantErr: services.ErrStorePurchaseExpired},
+		{name: "unknown product", transaction: storestub.NewTransaction("1000001", "vigor.coins.100", time.Now().AddDate(0, 1, 0)), wantErr: services.ErrUnknownStoreProduct},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := userService.VerifyAppStorePurchase(context.Background(), userID, store.SignTransaction(tt.transaction))
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyAppStorePurchaseOwnedByAnotherUser(t *testing.T) {
+	userService, users, _ := newStoreTestService()
+	store := storestub.NewAppStore()
+	userService.SetAppStoreVerifier(utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, store.Roots()))
+	userID := primitive.NewObjectID()
+	stored := subscriptionWithStatus(models.SubscriptionStatusTrialing, time.Now().AddDate(0, 0, 7))
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Subscription = stored
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, bson.M{"_id": userID}, mock.Anything).Return(result)
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+	users.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything).Return(db.MongoUpdateResult{}, duplicate)
+
+	_, err := userService.VerifyAppStorePurchase(context.Background(), userID, store.SignTransaction(storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().AddDate(0, 1, 0))))
+
+	assert.ErrorIs(t, err, services.ErrStorePurchaseOwned)
+}
+
+func TestStorePurchasesNeedConfiguredStore(t *testing.T) {
+	userService, _, _ := newStoreTestService()
+
+	_, err := userService.VerifyAppStorePurchase(context.Background(), primitive.NewObjectID(), "signed")
+	assert.ErrorIs(t, err, services.ErrStoreNotConfigured)
+	_, err = userService.VerifyPlayPurchase(context.Background(), primitive.NewObjectID(), "token")
+	assert.ErrorIs(t, err, services.ErrStoreNotConfigured)
+}
+
+func TestAppStoreRefundNotificationExpiresSubscription(t *testing.T) {
+	userService, users, paymentEvents := newStoreTestService()
+	store := storestub.NewAppStore()
+	userService.SetAppStoreVerifier(utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, store.Roots()))
+	userID := primitive.NewObjectID()
+	var recorded models.PaymentEvent
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(models.PaymentEvent)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "1000001", userID)
+	stored := subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().AddDate(0, 0, 20))
+	stored.Provider = models.SubscriptionProviderAppStore
+	written := expectSubscription(users, userID, stored, 1)
+
+	transaction := storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().AddDate(0, 0, 20))
+	err := userService.ProcessAppStoreNotification(context.Background(), store.SignNotification("uuid-1", "REFUND", "", transaction))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "app_store:uuid-1", recorded.ID)
+	assert.Equal(t, models.SubscriptionStatusExpired, written.Status)
+	assert.False(t, written.IsActive)
+}
+
+func TestRedeliveredAppStoreNotificationIsSkipped(t *testing.T) {
+	userService, users, paymentEvents := newStoreTestService()
+	store := storestub.NewAppStore()
+	userService.SetAppStoreVerifier(utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, store.Roots()))
+	expectRecordedPaymentEvent(paymentEvents, models.PaymentEventDone)
+
+	transaction := storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().AddDate(0, 1, 0))
+	err := userService.ProcessAppStoreNotification(context.Background(), store.SignNotification("uuid-1", "DID_RENEW", "", transaction))
+
+	assert.ErrorIs(t, err, services.ErrPaymentEventAlreadyReceived)
+	users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyPlayPurchaseLinksAndAcknowledges(t *testing.T) {
+	userService, users, _ := newStoreTestService()
+	play := storestub.NewPlay()
+	defer play.Close()
+	userService.SetPlayVerifier(play.Verifier())
+	userID := primitive.NewObjectID()
+	play.SetSubscription("token-1", storestub.PlaySubscription{State: "SUBSCRIPTION_STATE_ACTIVE", ProductID: "vigor.basic.monthly", ExpiresAt: time.Now().AddDate(0, 1, 0), AccountID: userID.Hex()})
+	stored := subscriptionWithStatus(models.SubscriptionStatusExpired, time.Now().Add(-time.Hour))
+	stored.IsActive = false
+	written := expectSubscription(users, userID, stored, 1)
+
+	_, err := userService.VerifyPlayPurchase(context.Background(), userID, "token-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, written.Status)
+	assert.Equal(t, models.SubscriptionTypeBasic, written.Type)
+	assert.Equal(t, models.SubscriptionProviderPlay, written.Provider)
+	assert.Equal(t, "token-1", written.ProviderSubscriptionID)
+	assert.True(t, play.Acknowledged("token-1"))
+}
+
+func TestVerifyPlayPurchaseOfAnotherAccount(t *testing.T) {
+	userService, users, _ := newStoreTestService()
+	play := storestub.NewPlay()
+	defer play.Close()
+	userService.SetPlayVerifier(play.Verifier())
+	play.SetSubscription("token-1", storestub.PlaySubscription{State: "SUBSCRIPTION_STATE_ACTIVE", ProductID: "vigor.basic.monthly", ExpiresAt: time.Now().AddDate(0, 1, 0), AccountID: primitive.NewObjectID().Hex()})
+
+	_, err := userService.VerifyPlayPurchase(context.Background(), primitive.NewObjectID(), "token-1")
+
+	assert.ErrorIs(t, err, services.ErrStorePurchaseOwned)
+	assert.False(t, play.Acknowledged("token-1"))
+	users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPlayNotificationCancelsAtPeriodEnd(t *testing.T) {
+	userService, users, paymentEvents := newStoreTestService()
+	play := storestub.NewPlay()
+	defer play.Close()
+	userService.SetPlayVerifier(play.Verifier())
+	userID := primitive.NewObjectID()
+	expiresAt := time.Now().AddDate(0, 0, 10).Truncate(time.Second).UTC()
+	play.SetSubscription("token-1", storestub.PlaySubscription{State: "SUBSCRIPTION_STATE_CANCELED", ProductID: "vigor.premium.monthly", ExpiresAt: expiresAt, Acknowledged: true})
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	expectProviderSubscription(users, "token-1", userID)
+	stored := subscriptionWithStatus(models.SubscriptionStatusActive, expiresAt)
+	stored.Provider = models.SubscriptionProviderPlay
+	written := expectSubscription(users, userID, stored, 1)
+
+	err := userService.ProcessPlayNotification(context.Background(), storestub.Notification("msg-1", storestub.PackageName, "token-1", 3))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusCancelled, written.Status)
+	assert.True(t, written.IsActive)
+	if assert.NotNil(t, written.EndDate) {
+		assert.Equal(t, expiresAt, *written.EndDate)
+	}
+}
+
+func TestPlayNotificationForUnknownPurchaseIsAcknowledged(t *testing.T) {
+	userService, users, paymentEvents := newStoreTestService()
+	play := storestub.NewPlay()
+	defer play.Close()
+	userService.SetPlayVerifier(play.Verifier())
+	paymentEvents.On("InsertOne", mock.Anything, mock.AnythingOfType("models.PaymentEvent")).Return(*new(db.MongoInsertOneResult), nil)
+
+	err := userService.ProcessPlayNotification(context.Background(), storestub.Notification("msg-1", storestub.PackageName, "token-unknown", 2))
+
+	assert.NoError(t, err)
+	users.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
+	paymentEvents.AssertNotCalled(t, "DeleteOne", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,284 @@
+package s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func subscriptionWithStatus(status string, endDate time.Time) models.UserSubscription {
+	return models.UserSubscription{Type: models.SubscriptionTypePremium, Status: status, StartDate: endDate.AddDate(0, -1, 0), EndDate: &endDate, IsActive: true}
+}
+
+// expectSubscription serves the stored subscription and captures the one written back.
+func expectSubscription(users *MockMongoCollection, userID primitive.ObjectID, stored models.UserSubscription, matched int64) *models.UserSubscription {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Subscription = stored
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, bson.M{"_id": userID}, mock.Anything).Return(result).Once()
+
+	written := new(models.UserSubscription)
+	users.On("UpdateOne", mock.Anything, bson.M{"_id": userID, "subscription.version": bson.M{"$exists": false}}, mock.Anything).Run(func(args mock.Arguments) {
+		*written = args.Get(2).(bson.M)["$set"].(bson.M)["subscription"].(models.UserSubscription)
+	}).Return(db.MongoUpdateResult{MatchedCount: matched}, nil).Maybe()
+	return written
+}
+
+// expectSubscriptionEvents accepts the changes recorded in the history of the subscriptions.
+func expectSubscriptionEvents(mockDB *MockMongoDatabase) *MockMongoCollection {
+	events := new(MockMongoCollection)
+	events.On("InsertOne", mock.Anything, mock.AnythingOfType("models.SubscriptionEvent")).Return(*new(db.MongoInsertOneResult), nil)
+	mockDB.On("Collection", "subscriptionEvents").Return(events)
+	return events
+}
+
+func newSubscriptionTestServices() (*services.UserService, *services.AdminService, *MockMongoCollection) {
+	users := new(MockMongoCollection)
+	auditLogs := new(MockMongoCollection)
+	auditLogs.On("InsertOne", mock.Anything, mock.AnythingOfType("models.AuditLog")).Return(*new(db.MongoInsertOneResult), nil)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "auditLogs").Return(auditLogs)
+	expectSubscriptionEvents(mockDB)
+	cfg := &config.Config{TrialDays: 14}
+	return services.NewUserService(mockDB, new(MockHasher), new(MockParser), cfg), services.NewAdminService(mockDB, new(MockHasher), new(MockParser), cfg), users
+}
+
+func TestRegisterUserStartsConfiguredTrial(t *testing.T) {
+	ctx := context.Background()
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	userService := services.NewUserService(mockDB, &utils.DefaultHasher{}, &utils.DefaultParser{}, &config.Config{TrialDays: 7})
+
+	var inserted models.User
+	mockDB.On("Collection", "users").Return(mockCollection)
+	mockCollection.On("CountDocuments", ctx, mock.Anything).Return(int64(0), nil)
+	mockCollection.On("InsertOne", ctx, mock.AnythingOfType("models.User")).Run(func(args mock.Arguments) {
+		inserted = args.Get(1).(models.User)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	err := userService.RegisterUser(ctx, models.UserRegistrationInput{Email: "user@example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusTrialing, inserted.Subscription.Status)
+	assert.Equal(t, models.SubscriptionTypePremium, inserted.Subscription.Type)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 7), inserted.TrialEndsAt, time.Minute)
+	assert.Equal(t, inserted.TrialEndsAt, *inserted.Subscription.EndDate)
+}
+
+func TestCancelSubscriptionKeepsAccessUntilPeriodEnd(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	periodEnd := time.Now().Add(10 * 24 * time.Hour)
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, periodEnd), 1)
+
+	subscription, err := userService.CancelUserSubscription(context.Background(), userID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusCancelled, written.Status)
+	assert.True(t, subscription.IsActive)
+	assert.NotNil(t, subscription.CancelledAt)
+	assert.Nil(t, subscription.NextRenewalDate)
+	assert.Equal(t, periodEnd, *subscription.EndDate)
+}
+
+func TestUpdateSubscriptionFailure_ConcurrentWriteOfTheSameVersion(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	stored := subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().Add(10*24*time.Hour))
+	stored.Version = 3
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Subscription = stored
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, bson.M{"_id": userID}, mock.Anything).Return(result)
+	var written models.UserSubscription
+	// A renewal wrote version 4 in the meantime, the status is still active
+	users.On("UpdateOne", mock.Anything, bson.M{"_id": userID, "subscription.version": 3}, mock.Anything).Run(func(args mock.Arguments) {
+		written = args.Get(2).(bson.M)["$set"].(bson.M)["subscription"].(models.UserSubscription)
+	}).Return(db.MongoUpdateResult{MatchedCount: 0}, nil)
+
+	_, err := userService.RequestSubscriptionChange(context.Background(), userID, models.SubscriptionChangeInput{Type: models.SubscriptionTypeBasic})
+
+	assert.ErrorIs(t, err, services.ErrSubscriptionChanged)
+	assert.Equal(t, 4, written.Version)
+}
+
+func TestCancelTrialEndsAccess(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	trialEnd := time.Now().Add(10 * 24 * time.Hour)
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Subscription = subscriptionWithStatus(models.SubscriptionStatusTrialing, trialEnd)
+		args.Get(0).(*models.User).TrialEndsAt = trialEnd
+	}).Return(nil)
+	users.On("FindOne", mock.Anything, bson.M{"_id": userID}, mock.Anything).Return(result)
+	var set bson.M
+	users.On("UpdateOne", mock.Anything, bson.M{"_id": userID, "subscription.version": bson.M{"$exists": false}}, mock.Anything).Run(func(args mock.Arguments) {
+		set = args.Get(2).(bson.M)["$set"].(bson.M)
+	}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+
+	subscription, err := userService.CancelUserSubscription(context.Background(), userID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusCancelled, subscription.Status)
+	user := models.User{Subscription: *subscription, TrialEndsAt: trialEnd}
+	assert.Empty(t, user.AccessTier(time.Now()))
+	assert.WithinDuration(t, time.Now(), set["trialEndsAt"].(time.Time), time.Minute)
+}
+
+func TestOverrideTrialToExpiredEndsAccess(t *testing.T) {
+	_, adminService, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	trialEnd := time.Now().Add(10 * 24 * time.Hour)
+	expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusTrialing, trialEnd), 1)
+
+	subscription, err := adminService.OverrideUserSubscription(context.Background(), userID, models.SubscriptionOverrideInput{
+		Type:   models.SubscriptionTypePremium,
+		Status: models.SubscriptionStatusExpired,
+		Reason: "Trial abuse",
+	})
+
+	assert.NoError(t, err)
+	user := models.User{Subscription: *subscription, TrialEndsAt: trialEnd}
+	assert.Empty(t, user.AccessTier(time.Now()))
+}
+
+func TestSubscriptionChangeIsRecordedWithItsActor(t *testing.T) {
+	users := new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	events := expectSubscriptionEvents(mockDB)
+	userService := services.NewUserService(mockDB, new(MockHasher), new(MockParser), &config.Config{TrialDays: 14})
+	userID := primitive.NewObjectID()
+	expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, time.Now().Add(24*time.Hour)), 1)
+	ctx := utils.WithRequestMetadata(context.Background(), utils.RequestMetadata{ActorID: userID, ActorRole: "user", RequestID: "request-1"})
+
+	_, err := userService.CancelUserSubscription(ctx, userID)
+
+	assert.NoError(t, err)
+	events.AssertCalled(t, "InsertOne", ctx, mock.MatchedBy(func(event models.SubscriptionEvent) bool {
+		return event.UserID == userID && event.Before.Status == models.SubscriptionStatusActive &&
+			event.After.Status == models.SubscriptionStatusCancelled && event.ActorID != nil && *event.ActorID == userID &&
+			event.RequestID == "request-1"
+	}))
+}
+
+func TestCancelEndedTrialIsRejected(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	// The trial ended, so it is expired before the cancellation is applied
+	expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusTrialing, time.Now().Add(-time.Hour)), 1)
+
+	_, err := userService.CancelUserSubscription(context.Background(), userID)
+
+	assert.ErrorIs(t, err, services.ErrInvalidSubscriptionTransition)
+	users.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRequestSubscriptionChange(t *testing.T) {
+	ctx := context.Background()
+	periodEnd := time.Now().Add(10 * 24 * time.Hour)
+
+	t.Run("downgrade waits for the end of the period", func(t *testing.T) {
+		userService, _, users := newSubscriptionTestServices()
+		userID := primitive.NewObjectID()
+		expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, periodEnd), 1)
+
+		subscription, err := userService.RequestSubscriptionChange(ctx, userID, models.SubscriptionChangeInput{Type: models.SubscriptionTypeBasic})
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionTypePremium, subscription.Type, "the plan should not change right away")
+		assert.Equal(t, models.SubscriptionTypeBasic, subscription.PendingChange.Type)
+		assert.Equal(t, periodEnd, *subscription.PendingChange.EffectiveAt)
+	})
+
+	t.Run("requesting the current plan withdraws the change", func(t *testing.T) {
+		userService, _, users := newSubscriptionTestServices()
+		userID := primitive.NewObjectID()
+		stored := subscriptionWithStatus(models.SubscriptionStatusActive, periodEnd)
+		stored.PendingChange = &models.SubscriptionChange{Type: models.SubscriptionTypeBasic}
+		expectSubscription(users, userID, stored, 1)
+
+		subscription, err := userService.RequestSubscriptionChange(ctx, userID, models.SubscriptionChangeInput{Type: models.SubscriptionTypePremium})
+
+		assert.NoError(t, err)
+		assert.Nil(t, subscription.PendingChange)
+	})
+
+	t.Run("concurrent transition", func(t *testing.T) {
+		userService, _, users := newSubscriptionTestServices()
+		userID := primitive.NewObjectID()
+		expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusActive, periodEnd), 0)
+
+		_, err := userService.RequestSubscriptionChange(ctx, userID, models.SubscriptionChangeInput{Type: models.SubscriptionTypeBasic})
+
+		assert.ErrorIs(t, err, services.ErrSubscriptionChanged)
+	})
+}
+
+func TestTransitionUserSubscription(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("activation applies the requested upgrade", func(t *testing.T) {
+		_, adminService, users := newSubscriptionTestServices()
+		userID := primitive.NewObjectID()
+		stored := subscriptionWithStatus(models.SubscriptionStatusExpired, time.Now().Add(-time.Hour))
+		stored.Type = models.SubscriptionTypeBasic
+		stored.IsActive = false
+		stored.PendingChange = &models.SubscriptionChange{Type: models.SubscriptionTypePremium}
+		expectSubscription(users, userID, stored, 1)
+
+		subscription, err := adminService.TransitionUserSubscription(ctx, userID, models.SubscriptionStatusActive)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionTypePremium, subscription.Type)
+		assert.Nil(t, subscription.PendingChange)
+		assert.True(t, subscription.IsActive)
+		assert.True(t, subscription.EndDate.After(time.Now()))
+		assert.Equal(t, subscription.EndDate, subscription.NextRenewalDate)
+	})
+
+	t.Run("transitions follow the lifecycle", func(t *testing.T) {
+		invalid := map[string]string{
+			models.SubscriptionStatusTrialing:  models.SubscriptionStatusPastDue,
+			models.SubscriptionStatusActive:    models.SubscriptionStatusExpired,
+			models.SubscriptionStatusCancelled: models.SubscriptionStatusPastDue,
+			models.SubscriptionStatusExpired:   models.SubscriptionStatusCancelled,
+		}
+		for from, to := range invalid {
+			_, adminService, users := newSubscriptionTestServices()
+			userID := primitive.NewObjectID()
+			expectSubscription(users, userID, subscriptionWithStatus(from, time.Now().Add(time.Hour)), 1)
+
+			_, err := adminService.TransitionUserSubscription(ctx, userID, to)
+
+			assert.ErrorIs(t, err, services.ErrInvalidSubscriptionTransition, "%s to %s", from, to)
+		}
+	})
+}
+
+func TestGetUserSubscriptionExpiresEndedTrial(t *testing.T) {
+	userService, _, users := newSubscriptionTestServices()
+	userID := primitive.NewObjectID()
+	written := expectSubscription(users, userID, subscriptionWithStatus(models.SubscriptionStatusTrialing, time.Now().Add(-time.Hour)), 1)
+
+	subscription, err := userService.GetUserSubsctiption(context.Background(), userID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusExpired, subscription.Status)
+	assert.False(t, subscription.IsActive)
+	assert.Equal(t, models.SubscriptionStatusExpired, written.Status)
+}
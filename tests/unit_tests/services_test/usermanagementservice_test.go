@@ -0,0 +1,222 @@
+package s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type userManagementCollections struct {
+	users          *MockMongoCollection
+	sessions       *MockMongoCollection
+	impersonations *MockMongoCollection
+	auditLogs      *MockMongoCollection
+	audited        *models.AuditLog
+}
+
+func newUserManagementTestService() (*services.AdminService, *userManagementCollections) {
+	collections := &userManagementCollections{
+		users:          new(MockMongoCollection),
+		sessions:       new(MockMongoCollection),
+		impersonations: new(MockMongoCollection),
+		auditLogs:      new(MockMongoCollection),
+		audited:        new(models.AuditLog),
+	}
+	collections.auditLogs.On("InsertOne", mock.Anything, mock.AnythingOfType("models.AuditLog")).Run(func(args mock.Arguments) {
+		*collections.audited = args.Get(1).(models.AuditLog)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(collections.users)
+	expectSubscriptionEvents(mockDB)
+	mockDB.On("Collection", "sessions").Return(collections.sessions)
+	mockDB.On("Collection", "impersonations").Return(collections.impersonations)
+	mockDB.On("Collection", "auditLogs").Return(collections.auditLogs)
+	return services.NewAdminService(mockDB, &utils.DefaultHasher{}, new(MockParser), &config.Config{}), collections
+}
+
+// expectRestrictionUpdate serves the restriction the user had before the update.
+func expectRestrictionUpdate(users *MockMongoCollection, userID primitive.ObjectID, previous *models.UserRestriction) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).Restriction = previous
+	}).Return(nil)
+	users.On("FindOneAndUpdate", mock.Anything, bson.M{"_id": userID}, mock.Anything, mock.Anything).Return(result).Once()
+}
+
+func TestSuspendUserSignsOutEveryDevice(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newUserManagementTestService()
+	actorID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+	until := time.Now().Add(7 * 24 * time.Hour)
+
+	expectRestrictionUpdate(collections.users, userID, nil)
+	collections.sessions.On("UpdateMany", ctx, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["userId"] == userID
+	}), mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 2}, nil)
+
+	restriction, err := adminService.SuspendUser(ctx, actorID, userID, models.UserSuspensionInput{Until: until, Reason: "Abusive messages"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserRestrictionSuspended, restriction.Type)
+	assert.Equal(t, actorID, restriction.RestrictedBy)
+	assert.True(t, restriction.InEffect(time.Now()))
+	assert.False(t, restriction.InEffect(until))
+	collections.sessions.AssertExpectations(t)
+	assert.Equal(t, "Abusive messages", collections.audited.Reason)
+	assert.Contains(t, collections.audited.After, "restriction")
+}
+
+func TestSuspendUserFailure_EndInThePast(t *testing.T) {
+	adminService, collections := newUserManagementTestService()
+
+	_, err := adminService.SuspendUser(context.Background(), primitive.NewObjectID(), primitive.NewObjectID(), models.UserSuspensionInput{Until: time.Now().Add(-time.Minute), Reason: "Spam"})
+
+	assert.ErrorIs(t, err, services.ErrInvalidSuspensionEnd)
+	collections.users.AssertNotCalled(t, "FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBanUserFailure_UserNotFound(t *testing.T) {
+	adminService, collections := newUserManagementTestService()
+	userID := primitive.NewObjectID()
+
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Return(mongo.ErrNoDocuments)
+	collections.users.On("FindOneAndUpdate", mock.Anything, bson.M{"_id": userID}, mock.Anything, mock.Anything).Return(result)
+
+	_, err := adminService.BanUser(context.Background(), primitive.NewObjectID(), userID, models.UserBanInput{Reason: "Fraud"})
+
+	assert.ErrorIs(t, err, services.ErrUserNotFound)
+	collections.sessions.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLiftUserRestrictionFailure_NotRestricted(t *testing.T) {
+	adminService, collections := newUserManagementTestService()
+	userID := primitive.NewObjectID()
+	expectRestrictionUpdate(collections.users, userID, nil)
+
+	err := adminService.LiftUserRestriction(context.Background(), userID)
+
+	assert.ErrorIs(t, err, services.ErrUserNotRestricted)
+	collections.auditLogs.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestResetUserPasswordStoresOnlyTheHash(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newUserManagementTestService()
+	userID := primitive.NewObjectID()
+
+	var set bson.M
+	collections.users.On("UpdateOne", ctx, bson.M{"_id": userID}, mock.Anything).Run(func(args mock.Arguments) {
+		set = args.Get(2).(bson.M)["$set"].(bson.M)
+	}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+	collections.sessions.On("UpdateMany", ctx, mock.Anything, mock.Anything).Return(db.MongoUpdateResult{}, nil)
+
+	password, err := adminService.ResetUserPassword(ctx, userID)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, password)
+	assert.NotEqual(t, password, set["passwordHash"])
+	assert.True(t, (&utils.DefaultHasher{}).CheckPasswordHash(password, set["passwordHash"].(string)))
+	collections.sessions.AssertExpectations(t)
+	assert.NotContains(t, collections.audited.After, "passwordHash")
+}
+
+func TestOverrideUserSubscriptionIgnoresTransitions(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newUserManagementTestService()
+	userID := primitive.NewObjectID()
+	endDate := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Millisecond)
+
+	// Expired to cancelled isn't an allowed transition
+	stored := subscriptionWithStatus(models.SubscriptionStatusExpired, time.Now().AddDate(0, 0, -3))
+	stored.Type = models.SubscriptionTypeBasic
+	stored.IsActive = false
+	written := expectSubscription(collections.users, userID, stored, 1)
+
+	input := models.SubscriptionOverrideInput{
+		Type:    models.SubscriptionTypePremium,
+		Status:  models.SubscriptionStatusActive,
+		EndDate: &endDate,
+		Reason:  "Compensation for the outage of March 3",
+	}
+	subscription, err := adminService.OverrideUserSubscription(ctx, userID, input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.SubscriptionStatusActive, written.Status)
+	assert.Equal(t, models.SubscriptionTypePremium, written.Type)
+	assert.True(t, written.IsActive)
+	assert.Equal(t, endDate, *written.EndDate)
+	assert.Equal(t, endDate, *written.NextRenewalDate)
+	assert.Equal(t, written, subscription)
+	assert.Equal(t, input.Reason, collections.audited.Reason)
+}
+
+func TestGetUserDetailListsEverySubscriptionChange(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	users, enrollments, events, invoices := new(MockMongoCollection), new(MockMongoCollection), new(MockMongoCollection), new(MockMongoCollection)
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "users").Return(users)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(enrollments)
+	mockDB.On("Collection", "subscriptionEvents").Return(events)
+	mockDB.On("Collection", "invoices").Return(invoices)
+	adminService := services.NewAdminService(mockDB, &utils.DefaultHasher{}, new(MockParser), &config.Config{})
+
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = userID
+	}).Return(nil)
+	users.On("FindOne", ctx, bson.M{"_id": userID}, mock.Anything).Return(result)
+	expectFound(enrollments, []models.UserWorkoutPlanStatus{})
+	expectFound(invoices, []models.Invoice{})
+	// A renewal by the payment provider, no admin was involved
+	renewal := models.SubscriptionEvent{
+		ID:     primitive.NewObjectID(),
+		UserID: userID,
+		Before: models.UserSubscription{Status: models.SubscriptionStatusActive},
+		After:  models.UserSubscription{Status: models.SubscriptionStatusActive},
+	}
+	expectFound(events, []models.SubscriptionEvent{renewal})
+
+	detail, err := adminService.GetUserDetail(ctx, userID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.SubscriptionEvent{renewal}, detail.SubscriptionChanges)
+	events.AssertCalled(t, "Find", ctx, bson.M{"userId": userID}, mock.Anything)
+}
+
+func TestStartImpersonationIsReadOnlyByDefault(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newUserManagementTestService()
+	adminID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = userID
+		args.Get(0).(*models.User).Email = "user@vigor.com"
+	}).Return(nil)
+	collections.users.On("FindOne", ctx, bson.M{"_id": userID}, mock.Anything).Return(result)
+	collections.impersonations.On("InsertOne", ctx, mock.AnythingOfType("models.Impersonation")).Return(*new(db.MongoInsertOneResult), nil)
+
+	impersonation, user, err := adminService.StartImpersonation(ctx, adminID, userID, models.ImpersonationInput{Reason: "Ticket 4211"})
+
+	assert.NoError(t, err)
+	assert.True(t, impersonation.ReadOnly)
+	assert.Equal(t, adminID, impersonation.AdminID)
+	assert.Equal(t, utils.ImpersonationTokenLifetime, impersonation.ExpiresAt.Sub(impersonation.CreatedAt))
+	assert.Equal(t, "user@vigor.com", user.Email)
+	collections.impersonations.AssertExpectations(t)
+	assert.Equal(t, "Ticket 4211", collections.audited.Reason)
+}
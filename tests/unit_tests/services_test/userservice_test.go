@@ -3,8 +3,10 @@ package s
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/GhostDrew11/vigor-api/internal/config"
 	"github.com/GhostDrew11/vigor-api/internal/db"
 	"github.com/GhostDrew11/vigor-api/internal/models"
 	"github.com/GhostDrew11/vigor-api/internal/services"
@@ -12,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestRegisterUserSuccess(t *testing.T){
@@ -21,7 +24,7 @@ func TestRegisterUserSuccess(t *testing.T){
 	mockCollection := new(MockMongoCollection)
 	hasher := &utils.DefaultHasher{}
 	parser := &utils.DefaultParser{}
-	userService := services.NewUserService(mockDB, hasher, parser)
+	userService := services.NewUserService(mockDB, hasher, parser, &config.Config{})
 	input := new(models.UserRegistrationInput)
 	filter := bson.M{"email": input.Email}
 	usernameFilter := bson.M{"profileInformation.username": input.ProfileInformation.Username}
@@ -46,7 +49,7 @@ func TestRegisterUserFailure_CheckingUser(t *testing.T){
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
 
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(0), errors.New("error checking if user already exists"))
@@ -66,7 +69,7 @@ func TestRegisterUserFailure_UserAlreadyExists(t *testing.T){
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
 
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(1), nil)
@@ -87,7 +90,7 @@ func TestRegisterUserFailure_InsertingOne(t *testing.T){
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
 
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	mockDB.On("Collection", mock.AnythingOfType("string")).Return(mockCollection)
 	mockCollection.On("CountDocuments", ctx, filter).Return(int64(0), nil)
@@ -108,7 +111,7 @@ func TestGetUserByEmailSuccess(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testuser@example.com"
 	password := "securepassword"
@@ -119,6 +122,7 @@ func TestGetUserByEmailSuccess(t *testing.T){
 	mockMongoSingleResult.On("Err").Return(nil)
 	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.User")).Return(nil)
 	mockHasher.On("CheckPasswordHash", password, mock.AnythingOfType("string")).Return(true)
+	mockHasher.On("NeedsRehash", mock.AnythingOfType("string")).Return(false)
 
 	_, err := userService.GetUserByEmail(ctx, email, password)
 
@@ -128,6 +132,72 @@ func TestGetUserByEmailSuccess(t *testing.T){
 	mockHasher.AssertExpectations(t)
 }
 
+func TestGetUserByEmailUpgradesOutdatedHash(t *testing.T){
+	ctx := context.Background()
+
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	mockHasher := new(MockHasher)
+	mockParser := new(MockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
+
+	email := "testuser@example.com"
+	password := "securepassword"
+	userID := primitive.NewObjectID()
+	oldHash := "$2a$10$legacybcrypthash"
+
+	mockDB.On("Collection", "users").Return(mockCollection)
+	mockCollection.On("FindOne", ctx, bson.M{"email": email}, mock.AnythingOfType("[]*options.FindOneOptions")).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Err").Return(nil)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+		user := args.Get(0).(*models.User)
+		user.ID = userID
+		user.PasswordHash = oldHash
+	}).Return(nil)
+	mockHasher.On("CheckPasswordHash", password, oldHash).Return(true)
+	mockHasher.On("NeedsRehash", oldHash).Return(true)
+	// Only replaces the hash that was verified, a concurrent password change wins
+	mockCollection.On("UpdateOne", ctx, bson.M{"_id": userID, "passwordHash": oldHash}, mock.MatchedBy(func(update bson.M) bool {
+		newHash, ok := update["$set"].(bson.M)["passwordHash"].(string)
+		return ok && strings.HasPrefix(newHash, "$argon2id$") && (&utils.DefaultHasher{}).CheckPasswordHash(password, newHash)
+	})).Return(*new(db.MongoUpdateResult), nil)
+
+	user, err := userService.GetUserByEmail(ctx, email, password)
+
+	assert.NoError(t, err)
+	assert.Equal(t, userID, user.ID)
+	mockCollection.AssertExpectations(t)
+	mockHasher.AssertExpectations(t)
+}
+
+func TestGetUserByEmailRehashFailureDoesNotBlockLogin(t *testing.T){
+	ctx := context.Background()
+
+	mockDB := new(MockMongoDatabase)
+	mockCollection := new(MockMongoCollection)
+	mockMongoSingleResult := new(MockMongoSingleResult)
+	mockHasher := new(MockHasher)
+	mockParser := new(MockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
+
+	email := "testuser@example.com"
+	password := "securepassword"
+
+	mockDB.On("Collection", "users").Return(mockCollection)
+	mockCollection.On("FindOne", ctx, bson.M{"email": email}, mock.AnythingOfType("[]*options.FindOneOptions")).Return(mockMongoSingleResult)
+	mockMongoSingleResult.On("Err").Return(nil)
+	mockMongoSingleResult.On("Decode", mock.AnythingOfType("*models.User")).Return(nil)
+	mockHasher.On("CheckPasswordHash", password, mock.AnythingOfType("string")).Return(true)
+	mockHasher.On("NeedsRehash", mock.AnythingOfType("string")).Return(true)
+	mockCollection.On("UpdateOne", ctx, mock.Anything, mock.Anything).Return(*new(db.MongoUpdateResult), errors.New("update failed"))
+
+	_, err := userService.GetUserByEmail(ctx, email, password)
+
+	assert.NoError(t, err)
+	mockCollection.AssertExpectations(t)
+}
+
 func TestGetUserByEmailFailure_FecthingUser(t *testing.T){
 	ctx := context.Background()
 
@@ -136,7 +206,7 @@ func TestGetUserByEmailFailure_FecthingUser(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testuser@example.com"
 	password := "securepassword"
@@ -162,7 +232,7 @@ func TestGetUserByEmailFailure_DecodingResult(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testuser@example.com"
 	password := "securepassword"
@@ -189,7 +259,7 @@ func TestGetUSerByEmailFailure_CheckingPassword(t *testing.T){
 	mockMongoSingleResult := new(MockMongoSingleResult)
 	mockHasher := new(MockHasher)
 	mockParser := new(MockParser)
-	userService := services.NewUserService(mockDB, mockHasher, mockParser)
+	userService := services.NewUserService(mockDB, mockHasher, mockParser, &config.Config{})
 
 	email := "testuser@example.com"
 	password := "securepassword"
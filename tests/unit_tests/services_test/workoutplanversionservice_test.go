@@ -0,0 +1,251 @@
+package s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPublishLegacyWorkoutPlanDraftKeepsPublishedContentAsFirstVersion(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newPlanWorkflowTestService()
+	planID, exerciseID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	reviewed := workoutPlanWithStatus(planID, models.PlanStatusInReview, exerciseID)
+	reviewed.Name = "Full body, revised"
+	expectPlan(collections.drafts, planID, reviewed)
+	expectPlan(collections.plans, planID, workoutPlanWithStatus(planID, "", exerciseID)) // Published before versions
+	collections.exercises.On("CountDocuments", ctx, mock.Anything).Return(int64(1), nil)
+	collections.plans.On("UpdateOne", ctx, bson.M{"_id": planID}, mock.Anything).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+	collections.drafts.On("DeleteOne", ctx, bson.M{"_id": planID}).Return(db.MongoDeleteResult{DeletedCount: 1}, nil)
+	var versions []models.WorkoutPlanVersion
+	collections.versions.On("InsertOne", ctx, mock.AnythingOfType("models.WorkoutPlanVersion")).Run(func(args mock.Arguments) {
+		versions = append(versions, args.Get(1).(models.WorkoutPlanVersion))
+	}).Return(*new(db.MongoInsertOneResult), nil)
+	pinned := bson.M{"workoutPlanId": planID, "version": bson.M{"$exists": false}}
+	collections.enrollments.On("UpdateMany", ctx, pinned, bson.M{"$set": bson.M{"version": 1}}).Return(db.MongoUpdateResult{MatchedCount: 4}, nil)
+
+	workoutPlan, err := adminService.TransitionWorkoutPlan(ctx, planID, models.PlanTransitionInput{Status: models.PlanStatusPublished})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, workoutPlan.Version)
+	if assert.Len(t, versions, 2) {
+		assert.Equal(t, 1, versions[0].Version)
+		assert.Equal(t, "Full body", versions[0].Plan.Name)
+		assert.Equal(t, 2, versions[1].Version)
+		assert.Equal(t, reviewed.Name, versions[1].Plan.Name)
+	}
+	collections.enrollments.AssertExpectations(t)
+}
+
+func TestGetWorkoutPlanVersionFailure_NotFound(t *testing.T) {
+	adminService, collections := newPlanWorkflowTestService()
+	planID := primitive.NewObjectID()
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.Anything).Return(mongo.ErrNoDocuments)
+	collections.versions.On("FindOne", mock.Anything, bson.M{"workoutPlanId": planID, "version": 3}, mock.Anything).Return(result)
+
+	_, err := adminService.GetWorkoutPlanVersion(context.Background(), planID, 3)
+
+	assert.ErrorIs(t, err, services.ErrWorkoutPlanVersionNotFound)
+}
+
+type workoutPlanMigrationCollections struct {
+	versions    *MockMongoCollection
+	enrollments *MockMongoCollection
+	exercises   *MockMongoCollection
+	circuits    *MockMongoCollection
+	days        *MockMongoCollection
+	weeks       *MockMongoCollection
+}
+
+func newWorkoutPlanMigrationTestService() (*services.AdminService, *workoutPlanMigrationCollections) {
+	collections := &workoutPlanMigrationCollections{
+		versions:    new(MockMongoCollection),
+		enrollments: new(MockMongoCollection),
+		exercises:   new(MockMongoCollection),
+		circuits:    new(MockMongoCollection),
+		days:        new(MockMongoCollection),
+		weeks:       new(MockMongoCollection),
+	}
+	auditLogs := new(MockMongoCollection)
+	auditLogs.On("InsertOne", mock.Anything, mock.Anything).Return(*new(db.MongoInsertOneResult), nil)
+
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "workoutPlanVersions").Return(collections.versions)
+	mockDB.On("Collection", "userWorkoutPlanStatus").Return(collections.enrollments)
+	mockDB.On("Collection", "userExerciseStatus").Return(collections.exercises)
+	mockDB.On("Collection", "userCircuitStatus").Return(collections.circuits)
+	mockDB.On("Collection", "userWorkoutDayStatus").Return(collections.days)
+	mockDB.On("Collection", "userWorkoutWeekStatus").Return(collections.weeks)
+	mockDB.On("Collection", "auditLogs").Return(auditLogs)
+	return services.NewAdminService(mockDB, &utils.DefaultHasher{}, new(MockParser), &config.Config{}), collections
+}
+
+// expectVersion serves the given version of the plan.
+func expectVersion(collection *MockMongoCollection, planID primitive.ObjectID, version int, plan models.WorkoutPlan) {
+	result := new(MockMongoSingleResult)
+	result.On("Decode", mock.AnythingOfType("*models.WorkoutPlanVersion")).Run(func(args mock.Arguments) {
+		*args.Get(0).(*models.WorkoutPlanVersion) = models.WorkoutPlanVersion{WorkoutPlanID: planID, Version: version, Plan: plan}
+	}).Return(nil)
+	collection.On("FindOne", mock.Anything, bson.M{"workoutPlanId": planID, "version": version}, mock.Anything).Return(result)
+}
+
+// expectFound serves documents from collection for every Find.
+func expectFound[T any](collection *MockMongoCollection, documents []T) {
+	cursor := new(MockMongoCursor)
+	cursor.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]T) = documents
+	}).Return(nil)
+	cursor.On("Close", mock.Anything).Return(nil)
+	collection.On("Find", mock.Anything, mock.Anything, mock.Anything).Return(cursor, nil)
+}
+
+func TestMigrateWorkoutPlanEnrollmentsCarriesMatchingProgress(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newWorkoutPlanMigrationTestService()
+	planID, userID := primitive.NewObjectID(), primitive.NewObjectID()
+	squat, lunge, plank := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	// Version 2 drops the lunges of the first circuit and the whole second circuit, and adds a day
+	legs, core := primitive.NewObjectID(), primitive.NewObjectID()
+	from := models.WorkoutPlan{ID: planID, Weeks: []models.WorkoutWeek{{ID: primitive.NewObjectID(), WeekNumber: 1, Days: []models.WorkoutDay{{
+		ID:       primitive.NewObjectID(),
+		Workouts: []models.Circuit{{ID: legs, ExerciseIDs: []primitive.ObjectID{squat, lunge}}, {ID: core, ExerciseIDs: []primitive.ObjectID{plank}}},
+	}}}}}
+	newLegs := primitive.NewObjectID()
+	to := models.WorkoutPlan{ID: planID, Weeks: []models.WorkoutWeek{{ID: primitive.NewObjectID(), WeekNumber: 1, Days: []models.WorkoutDay{
+		{ID: primitive.NewObjectID(), Workouts: []models.Circuit{{ID: newLegs, ExerciseIDs: []primitive.ObjectID{squat}}}},
+		{ID: primitive.NewObjectID(), Workouts: []models.Circuit{{ID: primitive.NewObjectID(), ExerciseIDs: []primitive.ObjectID{plank}}}},
+	}}}}
+	expectVersion(collections.versions, planID, 1, from)
+	expectVersion(collections.versions, planID, 2, to)
+
+	enrollment := models.UserWorkoutPlanStatus{ID: primitive.NewObjectID(), UserID: userID, WorkoutPlanID: planID, Progress: 100, Version: 1}
+	filter := bson.M{"workoutPlanId": planID, "completed": false, "version": bson.M{"$lt": 2}}
+	collections.enrollments.On("CountDocuments", ctx, filter).Return(int64(3), nil)
+	expectFound(collections.enrollments, []models.UserWorkoutPlanStatus{enrollment})
+
+	completed := func(exerciseID, circuitID primitive.ObjectID) models.UserExerciseStatus {
+		status := models.NewUserExerciseStatus(userID, exerciseID, circuitID, planID)
+		status.Completed = true
+		status.CompletedLogs = []models.UserExerciseLogInput{{}}
+		return status
+	}
+	expectFound(collections.exercises, []models.UserExerciseStatus{completed(squat, legs), completed(lunge, legs), completed(plank, core)})
+	expectFound(collections.weeks, []models.UserWorkoutWeekStatus{})
+
+	staleFilter := bson.M{"userId": userID, "workoutPlanId": planID, "version": bson.M{"$ne": 2}}
+	written := map[*MockMongoCollection][]bson.M{}
+	var steps []string
+	for _, collection := range []*MockMongoCollection{collections.exercises, collections.circuits, collections.days, collections.weeks} {
+		collection := collection
+		expectStatusWrites(collection, func(set bson.M) {
+			written[collection] = append(written[collection], set)
+			steps = append(steps, "write")
+		})
+		collection.On("DeleteMany", ctx, staleFilter).Run(func(args mock.Arguments) {
+			steps = append(steps, "delete")
+		}).Return(db.MongoDeleteResult{}, nil)
+	}
+	var set bson.M
+	collections.enrollments.On("UpdateOne", ctx, bson.M{"_id": enrollment.ID}, mock.Anything).Run(func(args mock.Arguments) {
+		set = args.Get(2).(bson.M)["$set"].(bson.M)
+		steps = append(steps, "enrollment")
+	}).Return(db.MongoUpdateResult{MatchedCount: 1}, nil)
+
+	report, err := adminService.MigrateWorkoutPlanEnrollments(ctx, planID, models.WorkoutPlanMigrationInput{ToVersion: 2, Limit: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), report.Remaining)
+	if assert.Len(t, report.Users, 1) {
+		migration := report.Users[0]
+		assert.Equal(t, 1, migration.FromVersion)
+		assert.Equal(t, 1, migration.CarriedExercises)
+		assert.ElementsMatch(t, []models.WorkoutProgressLoss{
+			{WeekNumber: 1, Day: 1, Section: models.WorkoutSectionWorkouts, Circuit: 1, ExerciseID: lunge, Reason: models.ProgressLossExerciseRemoved},
+			{WeekNumber: 1, Day: 1, Section: models.WorkoutSectionWorkouts, Circuit: 2, ExerciseID: plank, Reason: models.ProgressLossCircuitRemoved},
+		}, migration.NotCarried)
+	}
+
+	if assert.Len(t, written[collections.exercises], 2) {
+		squatStatus := written[collections.exercises][0]
+		assert.Equal(t, newLegs, squatStatus["circuitId"])
+		assert.Equal(t, true, squatStatus["completed"])
+		assert.Equal(t, 2, squatStatus["version"])
+		assert.Equal(t, false, written[collections.exercises][1]["completed"])
+	}
+	assert.Equal(t, true, written[collections.circuits][0]["completed"])
+	assert.Equal(t, true, written[collections.days][0]["completed"])
+	assert.Equal(t, int32(1), written[collections.weeks][0]["completedDays"])
+	assert.Equal(t, bson.M{"version": 2, "progress": float64(50), "completed": false}, set)
+	// The old statuses are only deleted once the new ones are written and the enrollment follows the new version
+	assert.Equal(t, []string{"write", "write", "write", "write", "write", "write", "write", "enrollment", "delete", "delete", "delete", "delete"}, steps)
+}
+
+func TestMigrateWorkoutPlanEnrollmentsFailure_WriteKeepsOldStatuses(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newWorkoutPlanMigrationTestService()
+	planID, userID, squat := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	plan := *workoutPlanWithStatus(planID, models.PlanStatusPublished, squat)
+	expectVersion(collections.versions, planID, 1, plan)
+	expectVersion(collections.versions, planID, 2, plan)
+	collections.enrollments.On("CountDocuments", ctx, mock.Anything).Return(int64(1), nil)
+	expectFound(collections.enrollments, []models.UserWorkoutPlanStatus{{ID: primitive.NewObjectID(), UserID: userID, WorkoutPlanID: planID, Version: 1}})
+	expectFound(collections.exercises, []models.UserExerciseStatus{})
+	expectFound(collections.weeks, []models.UserWorkoutWeekStatus{})
+	expectStatusWrites(collections.exercises, func(bson.M) {})
+	expectStatusWrites(collections.circuits, func(bson.M) {})
+	failed := new(MockMongoSingleResult)
+	failed.On("Err").Return(errors.New("connection lost"))
+	collections.days.On("FindOneAndUpdate", ctx, mock.Anything, mock.Anything, mock.Anything).Return(failed)
+
+	_, err := adminService.MigrateWorkoutPlanEnrollments(ctx, planID, models.WorkoutPlanMigrationInput{ToVersion: 2})
+
+	assert.Error(t, err)
+	collections.exercises.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+	collections.enrollments.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// expectStatusWrites accepts the upserts of the statuses rebuilt by a migration and passes fn the fields written.
+func expectStatusWrites(collection *MockMongoCollection, fn func(set bson.M)) {
+	result := new(MockMongoSingleResult)
+	result.On("Err").Return(nil)
+	collection.On("FindOneAndUpdate", context.Background(), mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		fn(args.Get(2).(bson.M)["$set"].(bson.M))
+	}).Return(result)
+}
+
+func TestMigrateWorkoutPlanEnrollmentsDryRunChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newWorkoutPlanMigrationTestService()
+	planID, userID, squat := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	plan := *workoutPlanWithStatus(planID, models.PlanStatusPublished, squat)
+	expectVersion(collections.versions, planID, 1, plan)
+	expectVersion(collections.versions, planID, 2, plan)
+	collections.enrollments.On("CountDocuments", ctx, mock.Anything).Return(int64(1), nil)
+	expectFound(collections.enrollments, []models.UserWorkoutPlanStatus{{ID: primitive.NewObjectID(), UserID: userID, WorkoutPlanID: planID, Version: 1}})
+	expectFound(collections.exercises, []models.UserExerciseStatus{})
+	expectFound(collections.weeks, []models.UserWorkoutWeekStatus{})
+
+	report, err := adminService.MigrateWorkoutPlanEnrollments(ctx, planID, models.WorkoutPlanMigrationInput{ToVersion: 2, DryRun: true})
+
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Len(t, report.Users, 1)
+	collections.exercises.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+	collections.enrollments.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+}
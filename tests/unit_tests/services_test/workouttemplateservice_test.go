@@ -0,0 +1,124 @@
+package s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/config"
+	"github.com/GhostDrew11/vigor-api/internal/db"
+	"github.com/GhostDrew11/vigor-api/internal/models"
+	"github.com/GhostDrew11/vigor-api/internal/services"
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type workoutTemplateCollections struct {
+	templates *MockMongoCollection
+	plans     *MockMongoCollection
+}
+
+func newWorkoutTemplateTestService() (*services.AdminService, *workoutTemplateCollections) {
+	collections := &workoutTemplateCollections{
+		templates: new(MockMongoCollection),
+		plans:     new(MockMongoCollection),
+	}
+	auditLogs := new(MockMongoCollection)
+	auditLogs.On("InsertOne", mock.Anything, mock.Anything).Return(*new(db.MongoInsertOneResult), nil)
+
+	mockDB := new(MockMongoDatabase)
+	mockDB.On("Collection", "workoutTemplates").Return(collections.templates)
+	mockDB.On("Collection", "workoutPlans").Return(collections.plans)
+	mockDB.On("Collection", "auditLogs").Return(auditLogs)
+	return services.NewAdminService(mockDB, &utils.DefaultHasher{}, new(MockParser), &config.Config{}), collections
+}
+
+func templateDay(name string) models.WorkoutDay {
+	return models.WorkoutDay{
+		Name:             name,
+		ImageURL:         "https://example.com/day.png",
+		WarmUps:          []models.Circuit{},
+		Workouts:         []models.Circuit{{ExerciseIDs: []primitive.ObjectID{primitive.NewObjectID()}, ProposedLaps: 3}},
+		CoolDowns:        []models.Circuit{},
+		WorkoutTimeRange: [2]int{1800, 3600},
+	}
+}
+
+func TestExpandWorkoutPlanTemplatesCopiesWeekAndDayTemplates(t *testing.T) {
+	adminService, collections := newWorkoutTemplateTestService()
+	weekTemplate := models.WorkoutTemplate{ID: primitive.NewObjectID(), Kind: models.WorkoutTemplateWeek, Days: []models.WorkoutDay{templateDay("Push day"), templateDay("Pull day")}}
+	dayTemplate := models.WorkoutTemplate{ID: primitive.NewObjectID(), Kind: models.WorkoutTemplateDay, Days: []models.WorkoutDay{templateDay("Leg day")}}
+	expectFound(collections.templates, []models.WorkoutTemplate{weekTemplate, dayTemplate})
+
+	workoutPlan := models.WorkoutPlan{Weeks: []models.WorkoutWeek{
+		{WeekNumber: 1, TemplateID: &weekTemplate.ID},
+		{WeekNumber: 2, TemplateID: &weekTemplate.ID},
+		{WeekNumber: 3, Days: []models.WorkoutDay{templateDay("Rest day"), {TemplateID: &dayTemplate.ID}}},
+	}}
+
+	err := adminService.ExpandWorkoutPlanTemplates(context.Background(), &workoutPlan)
+
+	assert.NoError(t, err)
+	assert.Len(t, workoutPlan.Weeks[0].Days, 2)
+	assert.Equal(t, "Pull day", workoutPlan.Weeks[1].Days[1].Name)
+	assert.Equal(t, "Rest day", workoutPlan.Weeks[2].Days[0].Name)
+	assert.Equal(t, "Leg day", workoutPlan.Weeks[2].Days[1].Name)
+	assert.Equal(t, &dayTemplate.ID, workoutPlan.Weeks[2].Days[1].TemplateID)
+	// Each week gets its own circuits, so each gets its own IDs
+	workoutPlan.Weeks[0].Days[0].Workouts[0].ID = primitive.NewObjectID()
+	assert.True(t, workoutPlan.Weeks[1].Days[0].Workouts[0].ID.IsZero())
+	assert.True(t, weekTemplate.Days[0].Workouts[0].ID.IsZero())
+}
+
+func TestExpandWorkoutPlanTemplatesFailure_WrongKind(t *testing.T) {
+	adminService, collections := newWorkoutTemplateTestService()
+	dayTemplate := models.WorkoutTemplate{ID: primitive.NewObjectID(), Kind: models.WorkoutTemplateDay, Days: []models.WorkoutDay{templateDay("Leg day")}}
+	expectFound(collections.templates, []models.WorkoutTemplate{dayTemplate})
+
+	workoutPlan := models.WorkoutPlan{Weeks: []models.WorkoutWeek{{WeekNumber: 1, TemplateID: &dayTemplate.ID}}}
+	err := adminService.ExpandWorkoutPlanTemplates(context.Background(), &workoutPlan)
+
+	assert.ErrorIs(t, err, services.ErrInvalidWorkoutTemplateReference)
+}
+
+func TestCreateWorkoutTemplateFailure_DayTemplateWithSeveralDays(t *testing.T) {
+	adminService, collections := newWorkoutTemplateTestService()
+
+	_, err := adminService.CreateWorkoutTemplate(context.Background(), models.WorkoutTemplateInput{
+		Name: "Upper body",
+		Kind: models.WorkoutTemplateDay,
+		Days: []models.WorkoutDay{templateDay("Push day"), templateDay("Pull day")},
+	})
+
+	assert.ErrorIs(t, err, services.ErrInvalidWorkoutTemplate)
+	collections.templates.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestCloneWorkoutPlanGivesNewIDs(t *testing.T) {
+	ctx := context.Background()
+	adminService, collections := newWorkoutTemplateTestService()
+	planID, exerciseID := primitive.NewObjectID(), primitive.NewObjectID()
+	published := workoutPlanWithStatus(planID, models.PlanStatusPublished, exerciseID)
+	published.Version = 3
+	weekID, circuitID := published.Weeks[0].ID, published.Weeks[0].Days[0].Workouts[0].ID
+	expectPlan(collections.plans, planID, published)
+	collections.plans.On("CountDocuments", ctx, bson.M{"name": "Full body, variation"}).Return(int64(0), nil)
+	var inserted models.WorkoutPlan
+	collections.plans.On("InsertOne", ctx, mock.AnythingOfType("models.WorkoutPlan")).Run(func(args mock.Arguments) {
+		inserted = args.Get(1).(models.WorkoutPlan)
+	}).Return(*new(db.MongoInsertOneResult), nil)
+
+	clone, err := adminService.CloneWorkoutPlan(ctx, planID, models.PlanCloneInput{Name: "Full body, variation"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, inserted, *clone)
+	assert.Equal(t, "Full body, variation", clone.Name)
+	assert.Equal(t, models.PlanStatusDraft, clone.Status)
+	assert.Zero(t, clone.Version)
+	assert.NotEqual(t, planID, clone.ID)
+	assert.NotEqual(t, weekID, clone.Weeks[0].ID)
+	assert.NotEqual(t, circuitID, clone.Weeks[0].Days[0].Workouts[0].ID)
+	assert.Equal(t, []primitive.ObjectID{exerciseID}, clone.Weeks[0].Days[0].Workouts[0].ExerciseIDs)
+}
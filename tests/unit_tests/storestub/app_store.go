@@ -0,0 +1,182 @@
+// Package storestub plays the app stores for tests: an App Store signing with a locally generated certificate
+// chain, and a Play Developer API.
+package storestub
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	BundleID    = "com.vigor.app"
+	Environment = "Sandbox"
+)
+
+var (
+	leafOID         = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 11, 1}
+	intermediateOID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 2, 1}
+)
+
+type certificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// AppStore signs transactions and notifications the way the App Store does, with its own root CA.
+type AppStore struct {
+	root         certificate
+	intermediate certificate
+	leaf         certificate
+}
+
+func NewAppStore() *AppStore {
+	return newAppStore(true)
+}
+
+// NewAppStoreWithoutExtensions has a valid chain whose certificates lack the App Store extensions.
+func NewAppStoreWithoutExtensions() *AppStore {
+	return newAppStore(false)
+}
+
+func newAppStore(appleExtensions bool) *AppStore {
+	var leafExtensions, intermediateExtensions []pkix.Extension
+	if appleExtensions {
+		leafExtensions = []pkix.Extension{{Id: leafOID, Value: []byte{0x05, 0x00}}}
+		intermediateExtensions = []pkix.Extension{{Id: intermediateOID, Value: []byte{0x05, 0x00}}}
+	}
+
+	root := newCertificate("Stub Root CA", true, nil, nil)
+	intermediate := newCertificate("Stub Worldwide Developer Relations", true, &root, intermediateExtensions)
+	leaf := newCertificate("Stub App Store Signing", false, &intermediate, leafExtensions)
+	return &AppStore{root: root, intermediate: intermediate, leaf: leaf}
+}
+
+// Roots is the pool to verify with, it only holds this store's root.
+func (s *AppStore) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.root.cert)
+	return pool
+}
+
+// RootPEM is the root certificate as it would be configured.
+func (s *AppStore) RootPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.root.cert.Raw})
+}
+
+// Transaction is the payload of a signed subscription transaction.
+type Transaction struct {
+	OriginalTransactionID string
+	ProductID             string
+	ExpiresAt             time.Time
+	RevokedAt             *time.Time
+	BundleID              string
+	Environment           string
+}
+
+// NewTransaction is a transaction of the stub app, expiring at expiresAt.
+func NewTransaction(originalTransactionID, productID string, expiresAt time.Time) Transaction {
+	return Transaction{OriginalTransactionID: originalTransactionID, ProductID: productID, ExpiresAt: expiresAt, BundleID: BundleID, Environment: Environment}
+}
+
+func (s *AppStore) SignTransaction(transaction Transaction) string {
+	claims := jwt.MapClaims{
+		"transactionId":         randomSerial().String(),
+		"originalTransactionId": transaction.OriginalTransactionID,
+		"bundleId":              transaction.BundleID,
+		"productId":             transaction.ProductID,
+		"purchaseDate":          transaction.ExpiresAt.AddDate(0, -1, 0).UnixMilli(),
+		"expiresDate":           transaction.ExpiresAt.UnixMilli(),
+		"type":                  "Auto-Renewable Subscription",
+		"environment":           transaction.Environment,
+		"signedDate":            time.Now().UnixMilli(),
+	}
+	if transaction.RevokedAt != nil {
+		claims["revocationDate"] = transaction.RevokedAt.UnixMilli()
+	}
+	return s.Sign(claims)
+}
+
+// SignNotification is the signed payload of a server notification about the transaction.
+func (s *AppStore) SignNotification(notificationUUID, notificationType, subtype string, transaction Transaction) string {
+	return s.Sign(jwt.MapClaims{
+		"notificationType": notificationType,
+		"subtype":          subtype,
+		"notificationUUID": notificationUUID,
+		"version":          "2.0",
+		"signedDate":       time.Now().UnixMilli(),
+		"data": map[string]interface{}{
+			"bundleId":              transaction.BundleID,
+			"environment":           transaction.Environment,
+			"signedTransactionInfo": s.SignTransaction(transaction),
+		},
+	})
+}
+
+// Sign signs the claims with the leaf key and puts the whole chain in the x5c header.
+func (s *AppStore) Sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["x5c"] = []string{
+		base64.StdEncoding.EncodeToString(s.leaf.cert.Raw),
+		base64.StdEncoding.EncodeToString(s.intermediate.cert.Raw),
+		base64.StdEncoding.EncodeToString(s.root.cert.Raw),
+	}
+	signed, err := token.SignedString(s.leaf.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func newCertificate(commonName string, isCA bool, parent *certificate, extensions []pkix.Extension) certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtraExtensions:       extensions,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	return certificate{cert: cert, key: key}
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		panic(err)
+	}
+	return serial
+}
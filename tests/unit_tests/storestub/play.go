@@ -0,0 +1,190 @@
+package storestub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const PackageName = "com.vigor.app"
+
+// PlaySubscription is the state Play reports for a purchase token.
+type PlaySubscription struct {
+	State               string // e.g. SUBSCRIPTION_STATE_ACTIVE
+	ProductID           string
+	ExpiresAt           time.Time
+	AccountID           string
+	LinkedPurchaseToken string
+	Acknowledged        bool
+}
+
+// Play serves the token endpoint of the service account and the subscription endpoints of the Play Developer API.
+type Play struct {
+	Server  *httptest.Server
+	Account *utils.PlayServiceAccount
+
+	mu            sync.Mutex
+	key           *rsa.PrivateKey
+	accessToken   string
+	subscriptions map[string]*PlaySubscription
+	TokenRequests int
+}
+
+func NewPlay() *Play {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &Play{key: key, accessToken: "play-access-token", subscriptions: make(map[string]*PlaySubscription)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/androidpublisher/v3/applications/", p.purchases)
+	p.Server = httptest.NewServer(mux)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	p.Account = &utils.PlayServiceAccount{
+		ClientEmail: "vigor@stub.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+		TokenURI:    p.Server.URL + "/token",
+	}
+	return p
+}
+
+func (p *Play) Close() {
+	p.Server.Close()
+}
+
+// Verifier is a verifier of the stub package calling this API.
+func (p *Play) Verifier() *utils.PlayVerifier {
+	verifier, err := utils.NewPlayVerifier(PackageName, p.Server.URL, p.Account, nil)
+	if err != nil {
+		panic(err)
+	}
+	return verifier
+}
+
+// SetSubscription sets what Play reports for the purchase token.
+func (p *Play) SetSubscription(purchaseToken string, subscription PlaySubscription) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscriptions[purchaseToken] = &subscription
+}
+
+// Acknowledged tells whether the purchase was acknowledged.
+func (p *Play) Acknowledged(purchaseToken string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	subscription, ok := p.subscriptions[purchaseToken]
+	return ok && subscription.Acknowledged
+}
+
+// Notification is the Pub/Sub push body of a subscription notification about the purchase token.
+func Notification(messageID, packageName, purchaseToken string, notificationType int) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"version":         "1.0",
+		"packageName":     packageName,
+		"eventTimeMillis": time.Now().UnixMilli(),
+		"subscriptionNotification": map[string]interface{}{
+			"version":          "1.0",
+			"notificationType": notificationType,
+			"purchaseToken":    purchaseToken,
+		},
+	})
+	body, _ := json.Marshal(map[string]interface{}{
+		"message":      map[string]string{"data": base64.StdEncoding.EncodeToString(data), "messageId": messageID},
+		"subscription": "projects/vigor/subscriptions/play-notifications",
+	})
+	return body
+}
+
+func (p *Play) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	// The assertion must be signed by the service account key
+	_, err := jwt.Parse(r.PostForm.Get("assertion"), func(token *jwt.Token) (interface{}, error) {
+		return &p.key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(p.Account.TokenURI), jwt.WithIssuer(p.Account.ClientEmail))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	p.mu.Lock()
+	p.TokenRequests++
+	p.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": p.accessToken, "token_type": "Bearer", "expires_in": 3600})
+}
+
+func (p *Play) purchases(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+p.accessToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthenticated"})
+		return
+	}
+
+	prefix := "/androidpublisher/v3/applications/" + PackageName + "/purchases/"
+	path := strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+	if path == r.URL.EscapedPath() {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown package"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "subscriptionsv2/tokens/"):
+		subscription, ok := p.subscriptions[strings.TrimPrefix(path, "subscriptionsv2/tokens/")]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "purchase not found"})
+			return
+		}
+		acknowledgement := "ACKNOWLEDGEMENT_STATE_PENDING"
+		if subscription.Acknowledged {
+			acknowledgement = "ACKNOWLEDGEMENT_STATE_ACKNOWLEDGED"
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"kind":                       "androidpublisher#subscriptionPurchaseV2",
+			"subscriptionState":          subscription.State,
+			"linkedPurchaseToken":        subscription.LinkedPurchaseToken,
+			"acknowledgementState":       acknowledgement,
+			"externalAccountIdentifiers": map[string]string{"obfuscatedExternalAccountId": subscription.AccountID},
+			"lineItems": []map[string]interface{}{
+				{"productId": subscription.ProductID, "expiryTime": subscription.ExpiresAt.UTC().Format(time.RFC3339Nano)},
+			},
+		})
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "subscriptions/") && strings.HasSuffix(path, ":acknowledge"):
+		parts := strings.Split(strings.TrimSuffix(path, ":acknowledge"), "/")
+		subscription, ok := p.subscriptions[parts[len(parts)-1]]
+		if !ok || len(parts) != 4 || parts[1] != subscription.ProductID {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "purchase not found"})
+			return
+		}
+		subscription.Acknowledged = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,137 @@
+package u
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/storestub"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStubAppStoreVerifier(store *storestub.AppStore) *utils.AppStoreVerifier {
+	return utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, store.Roots())
+}
+
+func TestAppStoreVerifyTransaction(t *testing.T) {
+	store := storestub.NewAppStore()
+	expiresAt := time.Now().AddDate(0, 1, 0).Truncate(time.Millisecond).UTC()
+
+	purchase, err := newStubAppStoreVerifier(store).VerifyTransaction(store.SignTransaction(storestub.NewTransaction("1000001", "vigor.premium.monthly", expiresAt)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.StoreAppStore, purchase.Store)
+	assert.Equal(t, "1000001", purchase.SubscriptionID)
+	assert.Equal(t, "vigor.premium.monthly", purchase.ProductID)
+	assert.Equal(t, utils.StorePurchaseActive, purchase.State)
+	assert.Equal(t, expiresAt, *purchase.ExpiresAt)
+}
+
+func TestAppStoreVerifyTransactionReportsEndedPurchases(t *testing.T) {
+	store := storestub.NewAppStore()
+	verifier := newStubAppStoreVerifier(store)
+
+	expired, err := verifier.VerifyTransaction(store.SignTransaction(storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().Add(-time.Hour))))
+	assert.NoError(t, err)
+	assert.Equal(t, utils.StorePurchaseExpired, expired.State)
+
+	refunded := storestub.NewTransaction("1000002", "vigor.premium.monthly", time.Now().AddDate(0, 1, 0))
+	revokedAt := time.Now()
+	refunded.RevokedAt = &revokedAt
+	revoked, err := verifier.VerifyTransaction(store.SignTransaction(refunded))
+	assert.NoError(t, err)
+	assert.Equal(t, utils.StorePurchaseExpired, revoked.State)
+}
+
+func TestAppStoreVerifyTransactionRejectsUntrustedTransactions(t *testing.T) {
+	store := storestub.NewAppStore()
+	verifier := newStubAppStoreVerifier(store)
+	transaction := storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().AddDate(0, 1, 0))
+
+	otherApp := transaction
+	otherApp.BundleID = "com.other.app"
+	production := transaction
+	production.Environment = "Production"
+	signed := store.SignTransaction(transaction)
+	parts := strings.Split(signed, ".")
+	forgedPayload := store.SignTransaction(storestub.NewTransaction("1000001", "vigor.premium.yearly", time.Now().AddDate(1, 0, 0)))
+
+	tests := []struct {
+		name        string
+		transaction string
+	}{
+		{name: "other root CA", transaction: storestub.NewAppStore().SignTransaction(transaction)},
+		{name: "chain without App Store extensions", transaction: storestub.NewAppStoreWithoutExtensions().SignTransaction(transaction)},
+		{name: "payload swapped", transaction: parts[0] + "." + strings.Split(forgedPayload, ".")[1] + "." + parts[2]},
+		{name: "other app", transaction: store.SignTransaction(otherApp)},
+		{name: "other environment", transaction: store.SignTransaction(production)},
+		{name: "not a JWS", transaction: "not-a-jws"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchase, err := verifier.VerifyTransaction(tt.transaction)
+			assert.ErrorIs(t, err, utils.ErrInvalidStoreTransaction)
+			assert.Nil(t, purchase)
+		})
+	}
+}
+
+func TestAppStoreVerifyNotification(t *testing.T) {
+	store := storestub.NewAppStore()
+	verifier := newStubAppStoreVerifier(store)
+	transaction := storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().AddDate(0, 1, 0))
+
+	tests := []struct {
+		notificationType string
+		subtype          string
+		wantState        string
+	}{
+		{notificationType: "DID_RENEW", wantState: utils.StorePurchaseActive},
+		{notificationType: "DID_FAIL_TO_RENEW", subtype: "GRACE_PERIOD", wantState: utils.StorePurchasePastDue},
+		{notificationType: "DID_CHANGE_RENEWAL_STATUS", subtype: "AUTO_RENEW_DISABLED", wantState: utils.StorePurchaseCancelled},
+		{notificationType: "REFUND", wantState: utils.StorePurchaseExpired},
+		{notificationType: "EXPIRED", subtype: "VOLUNTARY", wantState: utils.StorePurchaseExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.notificationType, func(t *testing.T) {
+			notification, err := verifier.VerifyNotification(store.SignNotification("uuid-"+tt.notificationType, tt.notificationType, tt.subtype, transaction))
+
+			assert.NoError(t, err)
+			assert.Equal(t, "uuid-"+tt.notificationType, notification.ID)
+			if assert.NotNil(t, notification.Purchase) {
+				assert.Equal(t, tt.wantState, notification.Purchase.State)
+				assert.Equal(t, "1000001", notification.Purchase.SubscriptionID)
+			}
+		})
+	}
+
+	ignored, err := verifier.VerifyNotification(store.SignNotification("uuid-test", "TEST", "", transaction))
+	assert.NoError(t, err)
+	assert.Nil(t, ignored.Purchase)
+
+	_, err = verifier.VerifyNotification(storestub.NewAppStore().SignNotification("uuid-forged", "DID_RENEW", "", transaction))
+	assert.ErrorIs(t, err, utils.ErrInvalidStoreNotification)
+}
+
+func TestLoadCertPool(t *testing.T) {
+	store := storestub.NewAppStore()
+	path := filepath.Join(t.TempDir(), "roots.pem")
+	assert.NoError(t, os.WriteFile(path, store.RootPEM(), 0o600))
+
+	roots, err := utils.LoadCertPool(path)
+	assert.NoError(t, err)
+
+	verifier := utils.NewAppStoreVerifier(storestub.BundleID, storestub.Environment, roots)
+	_, err = verifier.VerifyTransaction(store.SignTransaction(storestub.NewTransaction("1000001", "vigor.premium.monthly", time.Now().AddDate(0, 1, 0))))
+	assert.NoError(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	assert.NoError(t, os.WriteFile(empty, []byte("no certificates here"), 0o600))
+	_, err = utils.LoadCertPool(empty)
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package u
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// Monday, March 2nd 2026
+	from := time.Date(2026, time.March, 2, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expression string
+		want       time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, time.March, 2, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, time.March, 2, 11, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2026, time.March, 3, 3, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, time.March, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2026, time.March, 2, 10, 25, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{"0 0 15 * 3", time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule, err := utils.ParseCron(test.expression)
+		if assert.NoError(t, err, test.expression) {
+			assert.Equal(t, test.want, schedule.Next(from), test.expression)
+		}
+	}
+}
+
+func TestCronScheduleNextIsStrictlyAfter(t *testing.T) {
+	schedule, err := utils.ParseCron("0 * * * *")
+	assert.NoError(t, err)
+
+	due := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, due.Add(time.Hour), schedule.Next(due))
+}
+
+func TestCronScheduleNeverDue(t *testing.T) {
+	schedule, err := utils.ParseCron("0 0 30 2 *")
+	assert.NoError(t, err)
+
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@often"} {
+		_, err := utils.ParseCron(expression)
+		assert.ErrorIs(t, err, utils.ErrInvalidCronExpression, expression)
+	}
+}
@@ -0,0 +1,71 @@
+package u
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPasswordArgon2idRoundTrip(t *testing.T) {
+	hasher := &utils.DefaultHasher{}
+
+	hash, err := hasher.HashPassword("correct horse battery")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+	assert.True(t, hasher.CheckPasswordHash("correct horse battery", hash))
+	assert.False(t, hasher.CheckPasswordHash("correct horse battery!", hash))
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	other, err := hasher.HashPassword("correct horse battery")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other, "each hash should use a new salt")
+}
+
+func TestCheckPasswordHashAcceptsLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("oldpassword"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	hasher := &utils.DefaultHasher{}
+
+	assert.True(t, hasher.CheckPasswordHash("oldpassword", string(legacy)))
+	assert.False(t, hasher.CheckPasswordHash("wrongpassword", string(legacy)))
+	assert.True(t, hasher.NeedsRehash(string(legacy)), "bcrypt hashes should be upgraded to argon2id")
+}
+
+func TestNeedsRehashWhenParametersAreWeaker(t *testing.T) {
+	weak := utils.NewDefaultHasher(utils.HashAlgorithmArgon2id, utils.Argon2Params{Memory: 8 * 1024, Iterations: 1}, 0)
+	hash, err := weak.HashPassword("correct horse battery")
+	assert.NoError(t, err)
+
+	assert.False(t, weak.NeedsRehash(hash))
+	assert.True(t, (&utils.DefaultHasher{}).NeedsRehash(hash))
+	assert.True(t, (&utils.DefaultHasher{}).CheckPasswordHash("correct horse battery", hash), "older parameters should still verify")
+}
+
+func TestBcryptHasherUpgradesLowerCost(t *testing.T) {
+	hasher := utils.NewDefaultHasher(utils.HashAlgorithmBcrypt, utils.Argon2Params{}, bcrypt.MinCost+1)
+	hash, err := hasher.HashPassword("oldpassword")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$2a$"))
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	lowCost, _ := bcrypt.GenerateFromPassword([]byte("oldpassword"), bcrypt.MinCost)
+	assert.True(t, hasher.NeedsRehash(string(lowCost)))
+
+	argon2Hash, _ := (&utils.DefaultHasher{}).HashPassword("oldpassword")
+	assert.True(t, hasher.CheckPasswordHash("oldpassword", argon2Hash))
+	assert.True(t, hasher.NeedsRehash(argon2Hash))
+}
+
+func TestCheckPasswordHashRejectsMalformedHashes(t *testing.T) {
+	hasher := &utils.DefaultHasher{}
+
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=19456,t=2,p=1$c2FsdA", "$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$a2V5", "$argon2i$v=19$m=19456,t=2,p=1$c2FsdA$a2V5"} {
+		assert.False(t, hasher.CheckPasswordHash("password", hash), hash)
+		assert.True(t, hasher.NeedsRehash(hash), hash)
+	}
+}
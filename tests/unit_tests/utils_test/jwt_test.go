@@ -58,7 +58,7 @@ func TestGenerateAccessTokenSuccess(t *testing.T) {
 
     jwtService := utils.NewJWTService(jwtSecretKey, mockHandler)
 
-    accessTokenStr, err := jwtService.GenerateAccessToken(userId, email, role)
+    accessTokenStr, err := jwtService.GenerateAccessToken(userId, email, role, "")
 
     assert.NoError(t, err, "Generating access token should not produce an error")
     assert.NotEmpty(t, accessTokenStr, "Access token should not be empty")
@@ -86,7 +86,7 @@ func TestVerifyTokenSuccess(t *testing.T) {
 
 	jwtService := utils.NewJWTService(jwtSecretKey, mockHandler)
 
-	accessTokenStr, err := jwtService.GenerateAccessToken(userId, email, role)
+	accessTokenStr, err := jwtService.GenerateAccessToken(userId, email, role, "")
 	assert.NoError(t, err, "Generating all tokens should not produce an error")
 
 	accessTokenClaims, err := jwtService.VerifyToken(accessTokenStr)
@@ -0,0 +1,171 @@
+package u
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/oidcstub"
+	"github.com/stretchr/testify/assert"
+)
+
+var stubAccount = oidcstub.Account{Subject: "stub-user-1", Email: "Jane.Doe@example.com", EmailVerified: true, GivenName: "Jane", FamilyName: "Doe"}
+
+func newStubRelyingParty(stub *oidcstub.Provider) *utils.OIDCProvider {
+	return utils.NewOIDCProvider(utils.OIDCProviderConfig{
+		Name:        "stub",
+		Issuer:      stub.Issuer(),
+		ClientID:    oidcstub.ClientID,
+		RedirectURI: oidcstub.RedirectURI,
+	}, nil)
+}
+
+func TestOIDCAuthorizationURLUsesDiscoveryAndPKCE(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+	provider := newStubRelyingParty(stub)
+
+	_, challenge, err := utils.NewPKCEChallenge()
+	assert.NoError(t, err)
+
+	authorizationURL, err := provider.AuthorizationURL(context.Background(), "the-state", "the-nonce", challenge)
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authorizationURL)
+	assert.NoError(t, err)
+	assert.Equal(t, stub.Issuer()+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	query := parsed.Query()
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, oidcstub.ClientID, query.Get("client_id"))
+	assert.Equal(t, oidcstub.RedirectURI, query.Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "the-state", query.Get("state"))
+	assert.Equal(t, "the-nonce", query.Get("nonce"))
+	assert.Equal(t, challenge, query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+}
+
+func TestOIDCExchangeReturnsVerifiedIdentity(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+	provider := newStubRelyingParty(stub)
+
+	verifier, challenge, err := utils.NewPKCEChallenge()
+	assert.NoError(t, err)
+	code := stub.Authorize(stubAccount, "the-nonce", challenge)
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, "the-nonce")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &utils.OIDCIdentity{
+		Issuer:        stub.Issuer(),
+		Subject:       "stub-user-1",
+		Email:         "jane.doe@example.com",
+		EmailVerified: true,
+		GivenName:     "Jane",
+		FamilyName:    "Doe",
+	}, identity)
+
+	// Codes are single use
+	_, err = provider.Exchange(context.Background(), code, verifier, "the-nonce")
+	assert.ErrorIs(t, err, utils.ErrOIDCTokenExchange)
+}
+
+func TestOIDCExchangeRejectsWrongVerifierAndNonce(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+	provider := newStubRelyingParty(stub)
+
+	_, challenge, _ := utils.NewPKCEChallenge()
+	otherVerifier, _, _ := utils.NewPKCEChallenge()
+	code := stub.Authorize(stubAccount, "the-nonce", challenge)
+	_, err := provider.Exchange(context.Background(), code, otherVerifier, "the-nonce")
+	assert.ErrorIs(t, err, utils.ErrOIDCTokenExchange)
+
+	verifier, challenge, _ := utils.NewPKCEChallenge()
+	code = stub.Authorize(stubAccount, "the-nonce", challenge)
+	_, err = provider.Exchange(context.Background(), code, verifier, "another-nonce")
+	assert.ErrorIs(t, err, utils.ErrInvalidIDToken)
+}
+
+func TestOIDCVerifyIDTokenRejectsInvalidClaims(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+	provider := newStubRelyingParty(stub)
+	ctx := context.Background()
+
+	tampered := map[string]func(claims map[string]interface{}){
+		"wrong issuer":    func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"wrong audience":  func(claims map[string]interface{}) { claims["aud"] = "another-client" },
+		"expired":         func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"missing expiry":  func(claims map[string]interface{}) { delete(claims, "exp") },
+		"missing subject": func(claims map[string]interface{}) { delete(claims, "sub") },
+		"other client azp": func(claims map[string]interface{}) {
+			claims["aud"] = []string{oidcstub.ClientID, "other"}
+			claims["azp"] = "other"
+		},
+		"issued in future": func(claims map[string]interface{}) { claims["iat"] = time.Now().Add(time.Hour).Unix() },
+	}
+
+	for name, tamper := range tampered {
+		claims := stub.IDTokenClaims(stubAccount, "the-nonce")
+		tamper(claims)
+
+		_, err := provider.VerifyIDToken(ctx, stub.Sign(claims), "the-nonce")
+		assert.ErrorIs(t, err, utils.ErrInvalidIDToken, name)
+	}
+
+	_, err := provider.VerifyIDToken(ctx, stub.Sign(stub.IDTokenClaims(stubAccount, "the-nonce")), "the-nonce")
+	assert.NoError(t, err, "the untampered token should verify")
+}
+
+func TestOIDCVerifyIDTokenRejectsForgedSignature(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+	forger := oidcstub.NewProvider()
+	defer forger.Close()
+	provider := newStubRelyingParty(stub)
+
+	// Same claims, signed by a key the provider never published
+	claims := forger.IDTokenClaims(stubAccount, "the-nonce")
+	claims["iss"] = stub.Issuer()
+
+	_, err := provider.VerifyIDToken(context.Background(), forger.Sign(claims), "the-nonce")
+	assert.ErrorIs(t, err, utils.ErrInvalidIDToken)
+}
+
+func TestOIDCVerifyIDTokenFollowsKeyRotation(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+	provider := newStubRelyingParty(stub)
+	ctx := context.Background()
+
+	_, err := provider.VerifyIDToken(ctx, stub.Sign(stub.IDTokenClaims(stubAccount, "n")), "n")
+	assert.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, stub.Sign(stub.IDTokenClaims(stubAccount, "n")), "n")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stub.JWKSRequests, "keys should be cached")
+
+	// A rotation right after a fetch waits for the refresh backoff, so unknown key IDs can't be used to flood the provider
+	stub.RotateKey()
+	_, err = provider.VerifyIDToken(ctx, stub.Sign(stub.IDTokenClaims(stubAccount, "n")), "n")
+	assert.ErrorIs(t, err, utils.ErrInvalidIDToken)
+	assert.Equal(t, 1, stub.JWKSRequests)
+}
+
+func TestOIDCDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	stub := oidcstub.NewProvider()
+	defer stub.Close()
+
+	provider := utils.NewOIDCProvider(utils.OIDCProviderConfig{
+		Name:        "stub",
+		Issuer:      stub.Issuer() + "/",
+		ClientID:    oidcstub.ClientID,
+		RedirectURI: oidcstub.RedirectURI,
+	}, nil)
+
+	_, err := provider.AuthorizationURL(context.Background(), "state", "nonce", "challenge")
+	assert.ErrorIs(t, err, utils.ErrOIDCDiscovery)
+}
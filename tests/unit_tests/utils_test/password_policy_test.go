@@ -0,0 +1,42 @@
+package u
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := utils.NewPasswordPolicy(8, 16, []string{"Password123"})
+
+	assert.NoError(t, policy.Validate("tangerine-sky"))
+	assert.ErrorIs(t, policy.Validate("short"), utils.ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.Validate("this passphrase is too long"), utils.ErrPasswordTooLong)
+	assert.ErrorIs(t, policy.Validate("PASSWORD123"), utils.ErrPasswordBreached, "the blocklist should ignore case")
+	// Lengths are counted in characters, not bytes
+	assert.NoError(t, policy.Validate("ééééééééé"))
+}
+
+func TestDefaultPasswordPolicyRejectsCommonPasswords(t *testing.T) {
+	policy := utils.DefaultPasswordPolicy()
+
+	assert.ErrorIs(t, policy.Validate("password123"), utils.ErrPasswordBreached)
+	assert.ErrorIs(t, policy.Validate("12345678"), utils.ErrPasswordBreached)
+	assert.NotContains(t, utils.DefaultPasswordBlocklist(), "", "comments and blank lines should be skipped")
+}
+
+func TestLoadPasswordBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# breached\nhunter2hunter2\n\n  summer2024  \n"), 0o600))
+
+	blocklist, err := utils.LoadPasswordBlocklist(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hunter2hunter2", "summer2024"}, blocklist)
+
+	_, err = utils.LoadPasswordBlocklist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package u
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/paymentstub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripeGatewayParsesSignedEvent(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+	periodEnd := time.Now().AddDate(0, 1, 0).Truncate(time.Second).UTC()
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", periodEnd))
+	event, err := gateway.ParseWebhook(payload, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.PaymentEventInvoicePaid, event.Type)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, "sub_1", event.SubscriptionID)
+	if assert.NotNil(t, event.PeriodEnd) {
+		assert.Equal(t, periodEnd, *event.PeriodEnd)
+	}
+}
+
+func TestStripeGatewayReadsCheckoutAndSubscriptionObjects(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+
+	event, err := gateway.ParseWebhook(stub.Deliver(utils.PaymentEventCheckoutCompleted, paymentstub.CheckoutCompleted("user-1", "premium", "cus_1", "sub_1")))
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", event.UserID)
+	assert.Equal(t, "cus_1", event.CustomerID)
+	assert.Equal(t, "sub_1", event.SubscriptionID)
+	assert.Equal(t, "premium", event.Plan)
+
+	event, err = gateway.ParseWebhook(stub.Deliver(utils.PaymentEventSubscriptionDeleted, paymentstub.Subscription("sub_2")))
+	assert.NoError(t, err)
+	assert.Equal(t, "sub_2", event.SubscriptionID)
+}
+
+func TestStripeGatewayRejectsInvalidSignatures(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+	payload := stub.Event(utils.PaymentEventInvoicePaid, paymentstub.Invoice("sub_1", time.Now()))
+
+	otherSecret := paymentstub.NewProvider()
+	otherSecret.Secret = "whsec_other"
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  http.Header
+	}{
+		{name: "missing header", payload: payload, header: http.Header{}},
+		{name: "tampered payload", payload: append([]byte(" "), payload...), header: stub.Sign(payload, time.Now())},
+		{name: "other secret", payload: payload, header: otherSecret.Sign(payload, time.Now())},
+		{name: "signed too long ago", payload: payload, header: stub.Sign(payload, time.Now().Add(-10*time.Minute))},
+		{name: "signed in the future", payload: payload, header: stub.Sign(payload, time.Now().Add(10*time.Minute))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := gateway.ParseWebhook(tt.payload, tt.header)
+			assert.ErrorIs(t, err, utils.ErrInvalidWebhookSignature)
+			assert.Nil(t, event)
+		})
+	}
+}
+
+func TestStripeGatewayAcceptsAnyOfSeveralSignatures(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+	payload := stub.Event(utils.PaymentEventPaymentFailed, paymentstub.Invoice("sub_1", time.Now()))
+
+	// The endpoint secret is being rolled, the old one signs first
+	old := paymentstub.NewProvider()
+	old.Secret = "whsec_old"
+	header := old.Sign(payload, time.Now())
+	header.Set(utils.StripeSignatureHeader, header.Get(utils.StripeSignatureHeader)+",v1="+utils.SignWebhookPayload([]byte(paymentstub.Secret), timestampOf(header), payload))
+
+	_, err := gateway.ParseWebhook(payload, header)
+	assert.NoError(t, err)
+}
+
+func TestStripeGatewayRejectsSignedGarbage(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+	payload := []byte(`{"type":"invoice.paid"}`)
+
+	_, err := gateway.ParseWebhook(payload, stub.Sign(payload, time.Now()))
+	assert.ErrorIs(t, err, utils.ErrInvalidWebhookPayload)
+}
+
+func timestampOf(header http.Header) string {
+	timestamp, _, _ := strings.Cut(strings.TrimPrefix(header.Get(utils.StripeSignatureHeader), "t="), ",")
+	return timestamp
+}
+
+func TestStripeGatewayParsesInvoiceCharge(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+	periodEnd := time.Now().AddDate(0, 1, 0).Truncate(time.Second).UTC()
+
+	payload, header := stub.Deliver(utils.PaymentEventInvoicePaid, paymentstub.PaidInvoice("sub_1", periodEnd, "eur", 1299, 260))
+	event, err := gateway.ParseWebhook(payload, header)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, event.Charge) {
+		assert.Equal(t, "EUR", event.Charge.Currency)
+		assert.Equal(t, "VIGOR-0001", event.Charge.Number)
+		assert.Equal(t, int64(1299), event.Charge.Subtotal)
+		assert.Equal(t, int64(260), event.Charge.Tax)
+		assert.Equal(t, int64(1559), event.Charge.Total)
+		assert.Equal(t, "jane@example.com", event.Charge.CustomerEmail)
+		if assert.Len(t, event.Charge.Lines, 1) {
+			assert.Equal(t, int64(1299), event.Charge.Lines[0].Amount)
+			assert.Equal(t, periodEnd, *event.Charge.Lines[0].PeriodEnd)
+		}
+	}
+}
+
+func TestStripeGatewayParsesOneTimeCheckoutCharge(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+
+	payload, header := stub.Deliver(utils.PaymentEventCheckoutCompleted, paymentstub.GiftCheckout("gift_1", "usd", 5000, 0))
+	event, err := gateway.ParseWebhook(payload, header)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, event.Charge) {
+		assert.Equal(t, "USD", event.Charge.Currency)
+		assert.Equal(t, int64(5000), event.Charge.Total)
+		assert.Empty(t, event.Charge.Lines)
+	}
+}
+
+func TestStripeGatewayLeavesSubscriptionCheckoutUncharged(t *testing.T) {
+	stub := paymentstub.NewProvider()
+	gateway := utils.NewStripeGateway(paymentstub.Secret, 5*time.Minute)
+	checkout := paymentstub.CheckoutCompleted("user_1", "premium", "cus_1", "sub_1")
+	// The first invoice of the subscription charges it
+	checkout["mode"] = "subscription"
+	checkout["currency"] = "eur"
+	checkout["amount_total"] = 1299
+
+	payload, header := stub.Deliver(utils.PaymentEventCheckoutCompleted, checkout)
+	event, err := gateway.ParseWebhook(payload, header)
+
+	assert.NoError(t, err)
+	assert.Nil(t, event.Charge)
+}
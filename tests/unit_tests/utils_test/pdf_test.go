@@ -0,0 +1,49 @@
+package u
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPDFDocumentWritesValidCrossReference(t *testing.T) {
+	document := utils.NewPDFDocument()
+	document.Text(50, 80, 22, true, "Receipt")
+	document.AddPage()
+	document.Line(50, 100, 545, 100)
+
+	pdf := document.Bytes()
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), "/Count 2")
+
+	// startxref points at the table, each entry at its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if assert.NotNil(t, startxref) {
+		offset, _ := strconv.Atoi(string(startxref[1]))
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte("xref\n")))
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf, -1)
+	assert.Len(t, entries, 8)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+}
+
+func TestPDFDocumentEscapesText(t *testing.T) {
+	document := utils.NewPDFDocument()
+	document.Text(50, 80, 10, false, `Total (incl. tax) \ 12.99 € – café`)
+
+	assert.Contains(t, string(document.Bytes()), `(Total \(incl. tax\) \\ 12.99 \200 ? caf\351) Tj`)
+}
+
+func TestPDFTextWidth(t *testing.T) {
+	// Digits are 556 thousandths wide
+	assert.InDelta(t, 5.56*4, utils.PDFTextWidth("1299", 10), 0.001)
+}
@@ -0,0 +1,101 @@
+package u
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/GhostDrew11/vigor-api/tests/unit_tests/storestub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayVerifyPurchase(t *testing.T) {
+	play := storestub.NewPlay()
+	defer play.Close()
+	expiresAt := time.Now().AddDate(0, 1, 0).Truncate(time.Second).UTC()
+	play.SetSubscription("token-1", storestub.PlaySubscription{State: "SUBSCRIPTION_STATE_ACTIVE", ProductID: "vigor.premium.monthly", ExpiresAt: expiresAt, AccountID: "user-1"})
+	verifier := play.Verifier()
+
+	purchase, err := verifier.VerifyPurchase(context.Background(), "token-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, utils.StorePlay, purchase.Store)
+	assert.Equal(t, "token-1", purchase.SubscriptionID)
+	assert.Equal(t, "vigor.premium.monthly", purchase.ProductID)
+	assert.Equal(t, utils.StorePurchaseActive, purchase.State)
+	assert.Equal(t, expiresAt, *purchase.ExpiresAt)
+	assert.Equal(t, "user-1", purchase.AccountID)
+	assert.True(t, purchase.Unacknowledged)
+
+	// The access token is reused until it expires
+	_, err = verifier.VerifyPurchase(context.Background(), "token-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, play.TokenRequests)
+}
+
+func TestPlayVerifyPurchaseStates(t *testing.T) {
+	play := storestub.NewPlay()
+	defer play.Close()
+	verifier := play.Verifier()
+
+	tests := []struct {
+		state     string
+		wantState string
+		wantErr   error
+	}{
+		{state: "SUBSCRIPTION_STATE_IN_GRACE_PERIOD", wantState: utils.StorePurchasePastDue},
+		{state: "SUBSCRIPTION_STATE_CANCELED", wantState: utils.StorePurchaseCancelled},
+		{state: "SUBSCRIPTION_STATE_ON_HOLD", wantState: utils.StorePurchaseExpired},
+		{state: "SUBSCRIPTION_STATE_EXPIRED", wantState: utils.StorePurchaseExpired},
+		{state: "SUBSCRIPTION_STATE_PENDING", wantErr: utils.ErrStorePurchasePending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			play.SetSubscription(tt.state, storestub.PlaySubscription{State: tt.state, ProductID: "vigor.basic.monthly", ExpiresAt: time.Now()})
+
+			purchase, err := verifier.VerifyPurchase(context.Background(), tt.state)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantState, purchase.State)
+		})
+	}
+
+	_, err := verifier.VerifyPurchase(context.Background(), "unknown-token")
+	assert.ErrorIs(t, err, utils.ErrStorePurchaseNotFound)
+}
+
+func TestPlayAcknowledge(t *testing.T) {
+	play := storestub.NewPlay()
+	defer play.Close()
+	play.SetSubscription("token-1", storestub.PlaySubscription{State: "SUBSCRIPTION_STATE_ACTIVE", ProductID: "vigor.premium.monthly", ExpiresAt: time.Now().AddDate(0, 1, 0)})
+	verifier := play.Verifier()
+
+	assert.NoError(t, verifier.Acknowledge(context.Background(), "vigor.premium.monthly", "token-1"))
+	assert.True(t, play.Acknowledged("token-1"))
+
+	purchase, err := verifier.VerifyPurchase(context.Background(), "token-1")
+	assert.NoError(t, err)
+	assert.False(t, purchase.Unacknowledged)
+}
+
+func TestPlayParseNotification(t *testing.T) {
+	play := storestub.NewPlay()
+	defer play.Close()
+	verifier := play.Verifier()
+
+	messageID, purchaseToken, err := verifier.ParseNotification(storestub.Notification("msg-1", storestub.PackageName, "token-1", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, "msg-1", messageID)
+	assert.Equal(t, "token-1", purchaseToken)
+
+	_, _, err = verifier.ParseNotification(storestub.Notification("msg-2", "com.other.app", "token-1", 2))
+	assert.ErrorIs(t, err, utils.ErrInvalidStoreNotification)
+
+	_, _, err = verifier.ParseNotification([]byte(`{"message":{}}`))
+	assert.ErrorIs(t, err, utils.ErrInvalidStoreNotification)
+}
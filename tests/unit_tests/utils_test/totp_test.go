@@ -0,0 +1,72 @@
+package u
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GhostDrew11/vigor-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Base32 of the RFC 6238 SHA1 test seed "12345678901234567890"
+const rfcTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCodeMatchesRFCVectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, want := range vectors {
+		got, err := utils.GenerateTOTPCode(rfcTestSecret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "code at %d", unix)
+	}
+}
+
+func TestValidateTOTPCodeAcceptsClockSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	previous, _ := utils.GenerateTOTPCode(rfcTestSecret, now.Add(-utils.TOTPPeriod))
+	tooOld, _ := utils.GenerateTOTPCode(rfcTestSecret, now.Add(-3*utils.TOTPPeriod))
+
+	step, ok := utils.ValidateTOTPCode(rfcTestSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/30-1, step)
+
+	_, ok = utils.ValidateTOTPCode(rfcTestSecret, tooOld, now)
+	assert.False(t, ok)
+
+	_, ok = utils.ValidateTOTPCode(rfcTestSecret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecretRoundTrip(t *testing.T) {
+	secret, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, err := utils.GenerateTOTPCode(secret, now)
+	assert.NoError(t, err)
+
+	_, ok := utils.ValidateTOTPCode(secret, code, now)
+	assert.True(t, ok)
+	assert.Contains(t, utils.TOTPProvisioningURI("Vigor", "admin@vigor.com", secret), "secret="+secret)
+}
+
+func TestRecoveryCodesAreUniqueAndHashIgnoresWhitespace(t *testing.T) {
+	codes, err := utils.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	assert.Equal(t, utils.HashToken(codes[0]), utils.HashToken(" "+codes[0]+" "))
+	assert.NotEqual(t, codes[0], utils.HashToken(codes[0]))
+}